@@ -5,11 +5,13 @@ import (
 	"errors"
 	"github.com/golang-migrate/migrate/v4"
 	"manga-reader/internal/analytics"
+	"manga-reader/internal/audit"
 	"manga-reader/internal/auth"
 	"manga-reader/internal/db/postgres"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,7 +23,12 @@ import (
 	"manga-reader/internal/db/sqlite"
 	"manga-reader/internal/handlers"
 	"manga-reader/internal/logger"
+	"manga-reader/internal/mail"
 	"manga-reader/internal/middleware"
+	"manga-reader/internal/oidc"
+	"manga-reader/internal/scheduler"
+	"manga-reader/internal/webhook"
+	"manga-reader/models"
 )
 
 func main() {
@@ -78,14 +85,37 @@ func main() {
 		}
 	}
 
-	auth.SetJWTSecret(cfg.JWTSecret)
+	if cfg.JWTAlgorithm == auth.AlgHS256 && cfg.JWTSecret == config.DefaultJWTSecret && !cfg.IsDev() {
+		log.Error("Используется секрет JWT по умолчанию; задайте JWT_SECRET или APP_ENV=dev")
+		return
+	}
+
+	keySet, err := auth.LoadKeySet(auth.KeyConfig{
+		Algorithm:      cfg.JWTAlgorithm,
+		KeyID:          cfg.JWTKeyID,
+		Secret:         cfg.JWTSecret,
+		PrivateKeyFile: cfg.JWTPrivateKeyFile,
+		VerifyKeys:     cfg.JWTVerifyKeys,
+	})
+	if err != nil {
+		log.Error("Ошибка загрузки ключей подписи JWT", "err", err)
+		return
+	}
+	auth.SetKeySet(keySet)
+	auth.SetTokenTTL(cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
 
 	var mangaRepo db.MangaRepository
 	var chapterRepo db.ChapterRepository
 	var pageRepo db.PageRepository
 	var userRepo db.UserRepository
+	var apiKeyRepo db.APIKeyRepository
+	var followRepo db.FollowRepository
+	var notificationRepo db.NotificationRepository
+	var webhookRepo db.WebhookRepository
+	var groupRepo db.ScanlationGroupRepository
+	var auditRepo db.AuditRepository
+	var viewHistoryRepo db.ViewHistoryRepository
 
-	var err error
 	switch cfg.DBType {
 	case "sqlite":
 		mangaRepo, err = sqlite.NewMangaRepository(cfg.DBSource, log)
@@ -97,6 +127,13 @@ func main() {
 			chapterRepo = sqlite.NewChapterRepository(sqliteRepo.GetDB(), log)
 			pageRepo = sqlite.NewPageRepository(sqliteRepo.GetDB(), log)
 			userRepo = sqlite.NewSQLiteUserRepository(sqliteRepo.GetDB(), log)
+			apiKeyRepo = sqlite.NewAPIKeyRepository(sqliteRepo.GetDB(), log)
+			followRepo = sqlite.NewFollowRepository(sqliteRepo.GetDB(), log)
+			notificationRepo = sqlite.NewNotificationRepository(sqliteRepo.GetDB(), log)
+			webhookRepo = sqlite.NewWebhookRepository(sqliteRepo.GetDB(), log)
+			groupRepo = sqlite.NewScanlationGroupRepository(sqliteRepo.GetDB(), log)
+			auditRepo = sqlite.NewAuditRepository(sqliteRepo.GetDB(), log)
+			viewHistoryRepo = sqlite.NewViewHistoryRepository(sqliteRepo.GetDB(), log)
 		}
 	case "postgres":
 		connectionString := cfg.PostgresConnectionString()
@@ -110,28 +147,105 @@ func main() {
 			chapterRepo = postgres.NewChapterRepository(pgRepo.GetDB(), log)
 			pageRepo = postgres.NewPageRepository(pgRepo.GetDB(), log)
 			userRepo = postgres.NewUserRepository(pgRepo.GetDB(), log)
+			apiKeyRepo = postgres.NewAPIKeyRepository(pgRepo.GetDB(), log)
+			followRepo = postgres.NewFollowRepository(pgRepo.GetDB(), log)
+			notificationRepo = postgres.NewNotificationRepository(pgRepo.GetDB(), log)
+			webhookRepo = postgres.NewWebhookRepository(pgRepo.GetDB(), log)
+			groupRepo = postgres.NewScanlationGroupRepository(pgRepo.GetDB(), log)
+			auditRepo = postgres.NewAuditRepository(pgRepo.GetDB(), log)
+			viewHistoryRepo = postgres.NewViewHistoryRepository(pgRepo.GetDB(), log)
 		}
 	default:
 		log.Error("Неизвестный тип базы данных", "type", cfg.DBType)
 		return
 	}
 
+	if cfg.AdminUsername != "" {
+		if admin, err := userRepo.GetByUsername(cfg.AdminUsername); err != nil {
+			log.Error("Пользователь-администратор не найден", "username", cfg.AdminUsername, "err", err)
+		} else if admin.Role != models.RoleAdmin {
+			if err = userRepo.UpdateRole(admin.ID, models.RoleAdmin); err != nil {
+				log.Error("Ошибка назначения роли администратора", "username", cfg.AdminUsername, "err", err)
+			} else {
+				log.Info("Пользователю назначена роль администратора", "username", cfg.AdminUsername)
+			}
+		}
+	}
+
 	redisCache := cache.NewRedisCache(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, log)
 
+	var authStore cache.Cache = cache.NewMemoryCache()
+	if err = redisCache.GetClient().Ping(context.Background()).Err(); err != nil {
+		log.Error("Redis недоступен, токены хранятся в памяти процесса", "err", err)
+	} else {
+		authStore = redisCache
+	}
+	auth.SetTokenStore(authStore)
+	auth.SetAPIKeyStore(apiKeyRepo)
+
+	loginLimiter := auth.NewLoginLimiter(authStore)
+	loginLimiter.UserThreshold = int64(cfg.LoginMaxAttempts)
+	loginLimiter.IPThreshold = int64(cfg.LoginIPMaxAttempts)
+	loginLimiter.BaseLockout = cfg.LoginLockout
+	loginLimiter.MaxLockout = cfg.LoginMaxLockout
+
 	analyticsService := analytics.NewAnalyticsService(redisCache, log)
 
+	// analytics rebuild восстанавливает просмотры и рейтинги в Redis по
+	// истории из базы данных, например после redis-admin flush.
+	if len(os.Args) > 2 && os.Args[1] == "analytics" && os.Args[2] == "rebuild" {
+		if authStore != redisCache {
+			log.Error("Redis недоступен, восстановление просмотров невозможно")
+			return
+		}
+		log.Info("Восстановление просмотров по истории...")
+		if err = analyticsService.Rebuild(context.Background(), viewHistoryRepo); err != nil {
+			log.Error("Ошибка восстановления просмотров", "err", err)
+			return
+		}
+		log.Info("Просмотры успешно восстановлены")
+		return
+	}
+
+	analyticsService.QueueSize = cfg.AnalyticsQueueSize
+	analyticsService.FlushInterval = cfg.AnalyticsFlushInterval
+	analyticsService.MaxBatch = cfg.AnalyticsMaxBatch
+	analyticsService.TrendingHalfLife = cfg.AnalyticsTrendingHalfLife
+	if cfg.AnalyticsViewerSecret != "" {
+		analyticsService.ViewerSecret = []byte(cfg.AnalyticsViewerSecret)
+	}
+	analyticsService.Start()
+
+	webhooks := webhook.NewDispatcher(webhookRepo, log)
+	webhooks.Workers = cfg.WebhookWorkers
+	webhooks.MaxAttempts = cfg.WebhookMaxAttempts
+	webhooks.BaseDelay = cfg.WebhookRetryDelay
+	webhooks.Client.Timeout = cfg.WebhookTimeout
+	webhooks.Start()
+
+	auditRecorder := audit.NewRecorder(auditRepo, log)
+
 	mangaHandler := &handlers.MangaHandler{
 		Repo:      mangaRepo,
 		Logger:    log,
 		Cache:     redisCache,
 		Analytics: analyticsService,
+		Webhooks:  webhooks,
+		Audit:     auditRecorder,
 	}
 
 	chapterHandler := &handlers.ChapterHandler{
-		Repo:      chapterRepo,
-		Logger:    log,
-		Cache:     redisCache,
-		Analytics: analyticsService,
+		Repo:          chapterRepo,
+		Logger:        log,
+		Cache:         redisCache,
+		Analytics:     analyticsService,
+		Notifications: notificationRepo,
+		Webhooks:      webhooks,
+		Groups:        groupRepo,
+		Users:         userRepo,
+		MangaRepo:     mangaRepo,
+		Pages:         pageRepo,
+		Audit:         auditRecorder,
 	}
 
 	pageHandler := &handlers.PageHandler{
@@ -139,29 +253,169 @@ func main() {
 		Logger:    log,
 		Cache:     redisCache,
 		Analytics: analyticsService,
+		Webhooks:  webhooks,
+		Chapters:  chapterRepo,
+		Audit:     auditRecorder,
+	}
+
+	chapterPublisher := scheduler.NewChapterPublisher(chapterRepo, log)
+	chapterPublisher.Interval = cfg.ChapterPublishInterval
+	chapterPublisher.OnPublish = chapterHandler.Published
+	chapterPublisher.Start()
+
+	trashPurger := scheduler.NewTrashPurger(mangaRepo, chapterRepo, pageRepo, log)
+	trashPurger.Retention = cfg.TrashRetention
+	trashPurger.Interval = cfg.TrashPurgeInterval
+	trashPurger.Start()
+
+	analyticsRollup := scheduler.NewAnalyticsRollup(analyticsService, viewHistoryRepo, log)
+	analyticsRollup.Interval = cfg.AnalyticsRollupInterval
+	analyticsRollup.Start()
+
+	// Без SMTP письма пишутся в лог вместе с действующими токенами сброса
+	// пароля, что допустимо только при разработке; в остальных окружениях
+	// сброс пароля по email отключается.
+	var mailer mail.Sender
+	switch {
+	case cfg.SMTPHost != "":
+		mailer = mail.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	case cfg.IsDev():
+		mailer = mail.NewLogSender(log, cfg.MailOutboxDir)
+	default:
+		log.Warn("Не задан SMTP_HOST; сброс пароля по email отключен")
 	}
 
 	userHandler := &handlers.UserHandler{
+		UserRepo:   userRepo,
+		Logger:     log,
+		Mailer:     mailer,
+		PublicURL:  cfg.PublicURL,
+		Limiter:    loginLimiter,
+		TOTPIssuer: cfg.TOTPIssuer,
+		Audit:      auditRecorder,
+	}
+
+	apiKeyHandler := &handlers.APIKeyHandler{
+		Repo:     apiKeyRepo,
 		UserRepo: userRepo,
 		Logger:   log,
 	}
 
+	notificationHandler := &handlers.NotificationHandler{
+		Repo:      notificationRepo,
+		Follows:   followRepo,
+		MangaRepo: mangaRepo,
+		Logger:    log,
+	}
+
+	trashHandler := &handlers.TrashHandler{
+		MangaRepo: mangaRepo,
+		Chapters:  chapterRepo,
+		Pages:     pageRepo,
+		Cache:     redisCache,
+		Logger:    log,
+		Audit:     auditRecorder,
+	}
+
+	auditHandler := &handlers.AuditHandler{
+		Repo:   auditRepo,
+		Logger: log,
+	}
+
+	groupHandler := &handlers.GroupHandler{
+		Repo:     groupRepo,
+		UserRepo: userRepo,
+		Logger:   log,
+	}
+
+	webhookHandler := &handlers.WebhookHandler{
+		Repo:   webhookRepo,
+		Logger: log,
+	}
+
+	feedHandler := &handlers.FeedHandler{
+		Chapters:  chapterRepo,
+		MangaRepo: mangaRepo,
+		PublicURL: cfg.PublicURL,
+		Logger:    log,
+	}
+
+	opdsHandler := &handlers.OPDSHandler{
+		MangaRepo: mangaRepo,
+		Chapters:  chapterRepo,
+		Pages:     pageRepo,
+		PublicURL: cfg.PublicURL,
+		Logger:    log,
+	}
+
 	analyticsHandler := &handlers.AnalyticsHandler{
 		MangaRepo: mangaRepo,
+		Chapters:  chapterRepo,
+		Pages:     pageRepo,
 		Analytics: analyticsService,
+		History:   viewHistoryRepo,
 		Logger:    log,
 	}
 
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDCIssuer != "" {
+		roleMapping, err := oidc.ParseRoleMapping(cfg.OIDCRoleMapping)
+		if err != nil {
+			log.Error("Ошибка разбора OIDC_ROLE_MAPPING", "err", err)
+			return
+		}
+		redirectURL := cfg.OIDCRedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimRight(cfg.PublicURL, "/") + "/auth/oidc/callback"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.Discover(ctx, oidc.Config{
+			IssuerURL:    cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       cfg.OIDCScopes,
+			RoleClaim:    cfg.OIDCRoleClaim,
+			RoleMapping:  roleMapping,
+		}, nil)
+		cancel()
+		if err != nil {
+			log.Error("Ошибка подключения к провайдеру OIDC, вход через него отключен", "err", err)
+		} else {
+			oidcHandler = &handlers.OIDCHandler{
+				Provider:     provider,
+				UserRepo:     userRepo,
+				Store:        authStore,
+				Logger:       log,
+				Audit:        auditRecorder,
+				SecureCookie: strings.HasPrefix(redirectURL, "https://"),
+			}
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", auth.AuthMiddleware(http.HandlerFunc(handlers.HealthHandler)))
 
+	mux.HandleFunc("/.well-known/jwks.json", handlers.JWKSHandler)
+
 	handlers.RegisterUserRoutes(mux, userHandler)
-	handlers.RegisterMangaRoutes(mux, mangaHandler, chapterHandler)
+	handlers.RegisterAPIKeyRoutes(mux, apiKeyHandler)
+	handlers.RegisterNotificationRoutes(mux, notificationHandler)
+	handlers.RegisterWebhookRoutes(mux, webhookHandler)
+	if oidcHandler != nil {
+		handlers.RegisterOIDCRoutes(mux, oidcHandler)
+	}
+	handlers.RegisterMangaRoutes(mux, mangaHandler, chapterHandler, feedHandler)
+	handlers.RegisterFeedRoutes(mux, feedHandler)
+	handlers.RegisterOPDSRoutes(mux, opdsHandler)
 	handlers.RegisterChapterRoutes(mux, chapterHandler)
+	handlers.RegisterGroupRoutes(mux, groupHandler)
+	handlers.RegisterTrashRoutes(mux, trashHandler)
+	handlers.RegisterAuditRoutes(mux, auditHandler)
 	handlers.RegisterPageRoutes(mux, pageHandler)
 	handlers.RegisterAnalyticsRoutes(mux, analyticsHandler)
 
-	handler := middleware.RecoveryMiddleware(log, middleware.LoggingMiddleware(log, mux))
+	handler := middleware.RecoveryMiddleware(log, middleware.RequestIDMiddleware(middleware.LoggingMiddleware(log, mux)))
 
 	server := &http.Server{
 		Addr:         cfg.ServerAddress,
@@ -170,17 +424,6 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
 	}
-	ctx := context.Background()
-	if err = analyticsService.InitializeDailyStats(ctx); err != nil {
-		log.Error("Ошибка инициализации дневной статистики", "err", err)
-	}
-	if err = analyticsService.InitializeWeeklyStats(ctx); err != nil {
-		log.Error("Ошибка инициализации недельной статистики", "err", err)
-	}
-	if err = analyticsService.InitializeMonthlyStats(ctx); err != nil {
-		log.Error("Ошибка инициализации месячной статистики", "err", err)
-	}
-
 	go func() {
 		log.Info("Запуск сервера на " + cfg.ServerAddress)
 		if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -198,5 +441,16 @@ func main() {
 	if err = server.Shutdown(ctx); err != nil {
 		log.Error("Ошибка при завершении работы сервера", "err", err)
 	}
+	// Планировщик останавливается раньше диспетчера вебхуков, так как
+	// публикация главы отправляет событие.
+	chapterPublisher.Close()
+	trashPurger.Close()
+	// Накопленные просмотры записываются до последнего переноса в историю.
+	analyticsService.Close()
+	if _, err = analyticsRollup.RunOnce(time.Now()); err != nil {
+		log.Error("Ошибка переноса просмотров в историю", "err", err)
+	}
+	analyticsRollup.Close()
+	webhooks.Close()
 	log.Info("Сервер завершил работу")
 }
@@ -6,9 +6,14 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultJWTSecret — секрет по умолчанию, допустимый только в режиме разработки.
+const DefaultJWTSecret = "secret"
+
 type Config struct {
+	AppEnv        string
 	ServerAddress string
 	DBType        string
 	DBSource      string
@@ -22,6 +27,57 @@ type Config struct {
 	RedisPassword string
 	RedisDB       int
 	JWTSecret     string
+	AdminUsername string
+
+	JWTAlgorithm      string
+	JWTKeyID          string
+	JWTPrivateKeyFile string
+	JWTVerifyKeys     []string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginLockout       time.Duration
+	LoginMaxLockout    time.Duration
+
+	TOTPIssuer string
+
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	OIDCRoleClaim    string
+	OIDCRoleMapping  []string
+
+	WebhookWorkers     int
+	WebhookMaxAttempts int
+	WebhookRetryDelay  time.Duration
+	WebhookTimeout     time.Duration
+
+	ChapterPublishInterval time.Duration
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	AnalyticsRollupInterval time.Duration
+	AnalyticsQueueSize      int
+	AnalyticsFlushInterval  time.Duration
+	AnalyticsMaxBatch       int
+	// AnalyticsTrendingHalfLife — период полураспада трендового рейтинга.
+	AnalyticsTrendingHalfLife time.Duration
+	// AnalyticsViewerSecret — ключ идентификаторов анонимных посетителей.
+	AnalyticsViewerSecret string
+
+	PublicURL     string
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+	MailOutboxDir string
 }
 
 func LoadConfig() Config {
@@ -39,6 +95,7 @@ func LoadConfig() Config {
 	}
 
 	return Config{
+		AppEnv:        getEnv("APP_ENV", "production"),
 		ServerAddress: getEnv("SERVER_ADDRESS", ":8080"),
 		DBType:        getEnv("DB_TYPE", "sqlite"),
 		DBSource:      getEnv("DB_SOURCE", "manga.db"),
@@ -51,10 +108,64 @@ func LoadConfig() Config {
 		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvAsInt("REDIS_DB", 1),
-		JWTSecret:     getEnv("JWT_SECRET", "secret"),
+		JWTSecret:     getEnv("JWT_SECRET", DefaultJWTSecret),
+		AdminUsername: getEnv("ADMIN_USERNAME", ""),
+
+		JWTAlgorithm:      getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeyID:          getEnv("JWT_KEY_ID", "default"),
+		JWTPrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTVerifyKeys:     getEnvAsList("JWT_VERIFY_KEYS"),
+
+		AccessTokenTTL:  getEnvAsDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		LoginMaxAttempts:   getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginIPMaxAttempts: getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginLockout:       getEnvAsDuration("LOGIN_LOCKOUT", 30*time.Second),
+		LoginMaxLockout:    getEnvAsDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+
+		TOTPIssuer: getEnv("TOTP_ISSUER", "Manga Reader"),
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       getEnvAsList("OIDC_SCOPES"),
+		OIDCRoleClaim:    getEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMapping:  getEnvAsList("OIDC_ROLE_MAPPING"),
+
+		WebhookWorkers:     getEnvAsInt("WEBHOOK_WORKERS", 4),
+		WebhookMaxAttempts: getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryDelay:  getEnvAsDuration("WEBHOOK_RETRY_DELAY", 10*time.Second),
+		WebhookTimeout:     getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		ChapterPublishInterval: getEnvAsDuration("CHAPTER_PUBLISH_INTERVAL", time.Minute),
+
+		TrashRetention:     getEnvAsDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvAsDuration("TRASH_PURGE_INTERVAL", time.Hour),
+
+		AnalyticsRollupInterval:   getEnvAsDuration("ANALYTICS_ROLLUP_INTERVAL", time.Hour),
+		AnalyticsQueueSize:        getEnvAsInt("ANALYTICS_QUEUE_SIZE", 10000),
+		AnalyticsFlushInterval:    getEnvAsDuration("ANALYTICS_FLUSH_INTERVAL", time.Second),
+		AnalyticsMaxBatch:         getEnvAsInt("ANALYTICS_MAX_BATCH", 1000),
+		AnalyticsTrendingHalfLife: getEnvAsDuration("ANALYTICS_TRENDING_HALF_LIFE", 24*time.Hour),
+		AnalyticsViewerSecret:     getEnv("ANALYTICS_VIEWER_SECRET", ""),
+
+		PublicURL:     getEnv("PUBLIC_URL", "http://localhost:8080"),
+		SMTPHost:      getEnv("SMTP_HOST", ""),
+		SMTPPort:      getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:      getEnv("SMTP_FROM", "noreply@localhost"),
+		MailOutboxDir: getEnv("MAIL_OUTBOX_DIR", ""),
 	}
 }
 
+// IsDev сообщает, запущен ли сервер в режиме разработки.
+func (c *Config) IsDev() bool {
+	return c.AppEnv == "dev" || c.AppEnv == "development"
+}
+
 func (c *Config) PostgresConnectionString() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.PgHost, c.PgPort, c.PgUser, c.PgPassword, c.PgDBName, c.PgSSLMode)
@@ -77,6 +188,24 @@ func getEnvAsInt(key string, defaultValue int) int {
 
 }
 
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if val, err := time.ParseDuration(valueStr); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
 func (c *Config) PostgresMigrationURL() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		c.PgUser, c.PgPassword, c.PgHost, c.PgPort, c.PgDBName, c.PgSSLMode)
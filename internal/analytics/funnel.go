@@ -0,0 +1,184 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"manga-reader/models"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PageFunnelStep — страница в воронке дочитывания главы.
+type PageFunnelStep struct {
+	PageID        int64 `json:"page_id"`
+	Number        int   `json:"number"`
+	Views         int64 `json:"views"`
+	UniqueViewers int64 `json:"unique_viewers"`
+	// Retention — доля посетителей первой страницы, открывших эту.
+	Retention float64 `json:"retention"`
+	// DropOff — доля посетителей первой страницы, потерянных на этом шаге:
+	// открывших предыдущую страницу, но не эту.
+	DropOff float64 `json:"drop_off"`
+}
+
+// ChapterFunnel — воронка дочитывания главы по страницам.
+type ChapterFunnel struct {
+	ChapterID        int64 `json:"chapter_id"`
+	FirstPageViewers int64 `json:"first_page_viewers"`
+	LastPageViewers  int64 `json:"last_page_viewers"`
+	// CompletionRate — доля посетителей первой страницы, дошедших до последней.
+	CompletionRate float64          `json:"completion_rate"`
+	Pages          []PageFunnelStep `json:"pages"`
+}
+
+// ChapterRetentionStep — номер главы в удержании читателей манги.
+type ChapterRetentionStep struct {
+	Number int `json:"number"`
+	// ChapterIDs — переводы главы с этим номером; их посетители объединяются.
+	ChapterIDs    []int64 `json:"chapter_ids"`
+	UniqueViewers int64   `json:"unique_viewers"`
+	// Retention — доля посетителей предыдущей главы, открывших эту.
+	Retention float64 `json:"retention"`
+	// FromFirst — доля посетителей первой главы, дошедших до этой.
+	FromFirst float64 `json:"from_first"`
+}
+
+// MangaRetention — удержание читателей манги от главы к главе.
+type MangaRetention struct {
+	MangaID  int64                  `json:"manga_id"`
+	Chapters []ChapterRetentionStep `json:"chapters"`
+}
+
+// ChapterFunnel строит воронку дочитывания главы chapterID по ее страницам
+// pages. Посетители страниц считаются по HyperLogLog, а общие посетители
+// двух страниц оцениваются как |A| + |B| - |A ∪ B|, поэтому на небольшом
+// числе посетителей доли приблизительны.
+func (s *AnalyticsService) ChapterFunnel(ctx context.Context, chapterID int64, pages []*models.Page) (*ChapterFunnel, error) {
+	pages = append([]*models.Page(nil), pages...)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Number < pages[j].Number })
+
+	funnel := &ChapterFunnel{ChapterID: chapterID, Pages: make([]PageFunnelStep, 0, len(pages))}
+	if len(pages) == 0 {
+		return funnel, nil
+	}
+	first := []string{pageUniquesPrefix + strconv.FormatInt(pages[0].ID, 10)}
+	for i, p := range pages {
+		key := []string{pageUniquesPrefix + strconv.FormatInt(p.ID, 10)}
+		step := PageFunnelStep{PageID: p.ID, Number: p.Number}
+		var err error
+		if step.Views, err = s.counter(ctx, pageViewsPrefix+strconv.FormatInt(p.ID, 10)); err != nil {
+			return nil, err
+		}
+		if step.UniqueViewers, err = s.cache.PFCount(ctx, key...); err != nil {
+			s.logger.Error("Ошибка получения числа посетителей страницы", "page_id", p.ID, "err", err)
+			return nil, err
+		}
+		if i == 0 {
+			funnel.FirstPageViewers = step.UniqueViewers
+			step.Retention = ratio(step.UniqueViewers, step.UniqueViewers)
+		} else {
+			common, err := s.overlap(ctx, first, key, funnel.FirstPageViewers, step.UniqueViewers)
+			if err != nil {
+				return nil, err
+			}
+			step.Retention = ratio(common, funnel.FirstPageViewers)
+			step.DropOff = max(funnel.Pages[i-1].Retention-step.Retention, 0)
+		}
+		funnel.Pages = append(funnel.Pages, step)
+	}
+	last := funnel.Pages[len(funnel.Pages)-1]
+	funnel.LastPageViewers = last.UniqueViewers
+	funnel.CompletionRate = last.Retention
+	return funnel, nil
+}
+
+// MangaRetention строит удержание читателей манги mangaID по главам
+// chapters. Переводы главы с одним номером считаются одной главой: ее
+// посетители — объединение посетителей переводов. Retention следующей
+// главы — доля посетителей предыдущей, открывших и ее; оценка та же, что
+// в ChapterFunnel.
+func (s *AnalyticsService) MangaRetention(ctx context.Context, mangaID int64, chapters []*models.Chapter) (*MangaRetention, error) {
+	byNumber := make(map[int][]int64)
+	for _, ch := range chapters {
+		byNumber[ch.Number] = append(byNumber[ch.Number], ch.ID)
+	}
+	numbers := make([]int, 0, len(byNumber))
+	for n := range byNumber {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	retention := &MangaRetention{MangaID: mangaID, Chapters: make([]ChapterRetentionStep, 0, len(numbers))}
+	var first, prev []string
+	for i, n := range numbers {
+		ids := byNumber[n]
+		sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+		keys := make([]string, len(ids))
+		for j, id := range ids {
+			keys[j] = chapterUniquesPrefix + strconv.FormatInt(id, 10)
+		}
+
+		step := ChapterRetentionStep{Number: n, ChapterIDs: ids}
+		var err error
+		if step.UniqueViewers, err = s.cache.PFCount(ctx, keys...); err != nil {
+			s.logger.Error("Ошибка получения числа посетителей главы", "manga_id", mangaID, "number", n, "err", err)
+			return nil, err
+		}
+		if i == 0 {
+			first = keys
+			step.Retention = ratio(step.UniqueViewers, step.UniqueViewers)
+			step.FromFirst = step.Retention
+		} else {
+			prevStep := retention.Chapters[i-1]
+			common, err := s.overlap(ctx, prev, keys, prevStep.UniqueViewers, step.UniqueViewers)
+			if err != nil {
+				return nil, err
+			}
+			step.Retention = ratio(common, prevStep.UniqueViewers)
+			firstViewers := retention.Chapters[0].UniqueViewers
+			if common, err = s.overlap(ctx, first, keys, firstViewers, step.UniqueViewers); err != nil {
+				return nil, err
+			}
+			step.FromFirst = ratio(common, firstViewers)
+		}
+		prev = keys
+		retention.Chapters = append(retention.Chapters, step)
+	}
+	return retention, nil
+}
+
+// overlap оценивает число посетителей, общих для HyperLogLog a и b с
+// известными оценками countA и countB, по формуле включений-исключений.
+// Погрешность оценок может дать значение вне допустимого диапазона, поэтому
+// результат ограничивается им.
+func (s *AnalyticsService) overlap(ctx context.Context, a, b []string, countA, countB int64) (int64, error) {
+	union, err := s.cache.PFCount(ctx, append(append([]string(nil), a...), b...)...)
+	if err != nil {
+		s.logger.Error("Ошибка оценки общих посетителей", "a", a, "b", b, "err", err)
+		return 0, err
+	}
+	return min(max(countA+countB-union, 0), countA, countB), nil
+}
+
+// counter возвращает значение счетчика просмотров или 0, если его нет.
+func (s *AnalyticsService) counter(ctx context.Context, key string) (int64, error) {
+	raw, err := s.cache.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		s.logger.Error("Ошибка получения счетчика просмотров", "key", key, "err", err)
+		return 0, err
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+// ratio возвращает долю part от total или 0 при пустом total.
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
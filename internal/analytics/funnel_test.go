@@ -0,0 +1,119 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"manga-reader/internal/cache"
+	"manga-reader/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestChapterFunnel(t *testing.T) {
+	now := time.Now()
+	s := newTestService(cache.NewMemoryCache(), &now)
+	ctx := context.Background()
+
+	pages := []*models.Page{{ID: 103, Number: 3}, {ID: 101, Number: 1}, {ID: 102, Number: 2}}
+	// Четыре читателя открывают главу, трое доходят до второй страницы,
+	// один — до последней; пятый открыл сразу последнюю страницу.
+	reads := map[string][]int64{
+		"a": {101, 102, 103},
+		"b": {101, 102},
+		"c": {101, 102, 102},
+		"d": {101},
+		"e": {103},
+	}
+	for viewer, ids := range reads {
+		for _, id := range ids {
+			if err := s.RecordPageView(ctx, id, 10, 1, viewer); err != nil {
+				t.Fatalf("Ошибка записи просмотра: %v", err)
+			}
+		}
+	}
+
+	funnel, err := s.ChapterFunnel(ctx, 10, pages)
+	if err != nil {
+		t.Fatalf("Ошибка построения воронки: %v", err)
+	}
+	if funnel.FirstPageViewers != 4 || funnel.LastPageViewers != 2 || !almostEqual(funnel.CompletionRate, 0.25) {
+		t.Errorf("Неожиданная воронка: %+v", funnel)
+	}
+	want := []PageFunnelStep{
+		{PageID: 101, Number: 1, Views: 4, UniqueViewers: 4, Retention: 1},
+		{PageID: 102, Number: 2, Views: 4, UniqueViewers: 3, Retention: 0.75, DropOff: 0.25},
+		{PageID: 103, Number: 3, Views: 2, UniqueViewers: 2, Retention: 0.25, DropOff: 0.5},
+	}
+	if len(funnel.Pages) != len(want) {
+		t.Fatalf("Ожидалось %+v, получено %+v", want, funnel.Pages)
+	}
+	for i, step := range funnel.Pages {
+		w := want[i]
+		if step.PageID != w.PageID || step.Views != w.Views || step.UniqueViewers != w.UniqueViewers ||
+			!almostEqual(step.Retention, w.Retention) || !almostEqual(step.DropOff, w.DropOff) {
+			t.Errorf("Шаг %d: ожидалось %+v, получено %+v", i, w, step)
+		}
+	}
+
+	if funnel, err = s.ChapterFunnel(ctx, 11, nil); err != nil || len(funnel.Pages) != 0 || funnel.CompletionRate != 0 {
+		t.Errorf("Неожиданная воронка главы без страниц: %+v, %v", funnel, err)
+	}
+}
+
+func TestMangaRetention(t *testing.T) {
+	now := time.Now()
+	s := newTestService(cache.NewMemoryCache(), &now)
+	ctx := context.Background()
+
+	chapters := []*models.Chapter{
+		{ID: 1, Number: 1, Language: "ru"},
+		{ID: 2, Number: 1, Language: "en"},
+		{ID: 3, Number: 2, Language: "ru"},
+		{ID: 4, Number: 3, Language: "ru"},
+	}
+	for i := 0; i < 10; i++ {
+		viewer := fmt.Sprintf("v%d", i)
+		reads := []int64{1}
+		switch {
+		case i >= 8:
+			reads = []int64{2}
+		case i < 2:
+			reads = append(reads, 3, 4)
+		case i < 5:
+			reads = append(reads, 3)
+		}
+		for _, id := range reads {
+			if err := s.RecordChapterView(ctx, id, 1, viewer); err != nil {
+				t.Fatalf("Ошибка записи просмотра: %v", err)
+			}
+		}
+	}
+
+	retention, err := s.MangaRetention(ctx, 1, chapters)
+	if err != nil {
+		t.Fatalf("Ошибка расчета удержания: %v", err)
+	}
+	want := []ChapterRetentionStep{
+		{Number: 1, UniqueViewers: 10, Retention: 1, FromFirst: 1},
+		{Number: 2, UniqueViewers: 5, Retention: 0.5, FromFirst: 0.5},
+		{Number: 3, UniqueViewers: 2, Retention: 0.4, FromFirst: 0.2},
+	}
+	if len(retention.Chapters) != len(want) {
+		t.Fatalf("Ожидалось %+v, получено %+v", want, retention.Chapters)
+	}
+	for i, step := range retention.Chapters {
+		w := want[i]
+		if step.Number != w.Number || step.UniqueViewers != w.UniqueViewers ||
+			!almostEqual(step.Retention, w.Retention) || !almostEqual(step.FromFirst, w.FromFirst) {
+			t.Errorf("Глава %d: ожидалось %+v, получено %+v", w.Number, w, step)
+		}
+	}
+	if ids := retention.Chapters[0].ChapterIDs; len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Переводы первой главы должны объединяться: %v", ids)
+	}
+}
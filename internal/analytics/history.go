@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"manga-reader/models"
+	"time"
+)
+
+// Interval — шаг временного ряда просмотров.
+type Interval string
+
+const (
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// Valid сообщает, является ли шаг известным.
+func (i Interval) Valid() bool {
+	switch i {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+		return true
+	}
+	return false
+}
+
+// start возвращает начало интервала, в который входят сутки day. Неделя
+// начинается с понедельника.
+func (i Interval) start(day time.Time) time.Time {
+	day = day.UTC().Truncate(24 * time.Hour)
+	switch i {
+	case IntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// next возвращает начало интервала, следующего за начинающимся в start.
+func (i Interval) next(start time.Time) time.Time {
+	switch i {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ViewsPoint — точка временного ряда просмотров.
+type ViewsPoint struct {
+	// Date — первый день интервала в формате 2006-01-02.
+	Date  string `json:"date"`
+	Views int64  `json:"views"`
+	// UniqueViewers — сумма посуточных уникальных посетителей: посетитель,
+	// заходивший в разные дни интервала, учитывается несколько раз.
+	UniqueViewers int64 `json:"unique_viewers"`
+}
+
+// ViewsSeries — временной ряд просмотров сущности.
+type ViewsSeries struct {
+	EntityID int64        `json:"entity_id"`
+	Interval Interval     `json:"interval"`
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Points   []ViewsPoint `json:"points"`
+}
+
+// BuildSeries складывает посуточную историю days в ряд с шагом interval за
+// сутки с from по to включительно. Ряд содержит все интервалы диапазона,
+// включая интервалы без просмотров; первый и последний интервалы могут
+// выходить за границы диапазона, но учитывают только сутки внутри него.
+func BuildSeries(days []*models.DailyViews, from, to time.Time, interval Interval) []ViewsPoint {
+	points := []ViewsPoint{}
+	index := make(map[time.Time]int)
+	last := interval.start(to)
+	for start := interval.start(from); !start.After(last); start = interval.next(start) {
+		index[start] = len(points)
+		points = append(points, ViewsPoint{Date: start.Format(time.DateOnly)})
+	}
+	for _, d := range days {
+		i, ok := index[interval.start(d.Day)]
+		if !ok {
+			continue
+		}
+		points[i].Views += d.Views
+		points[i].UniqueViewers += d.UniqueViewers
+	}
+	return points
+}
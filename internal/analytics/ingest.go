@@ -0,0 +1,309 @@
+package analytics
+
+import (
+	"context"
+	"manga-reader/internal/cache"
+	"strconv"
+	"time"
+)
+
+// viewEvent — просмотр, ожидающий записи в Redis. Для просмотра главы
+// pageID равен нулю, для просмотра манги — также и chapterID.
+type viewEvent struct {
+	pageID    int64
+	chapterID int64
+	mangaID   int64
+	viewer    string
+	at        time.Time
+}
+
+// scoreIncr — элемент отсортированного множества, счет которого растет.
+type scoreIncr struct {
+	key    string
+	member string
+}
+
+// uniqueAdd — добавление посетителя в HyperLogLog hll. Если посетитель в нем
+// новый и задан ranking, счет member в ranking увеличивается на единицу.
+type uniqueAdd struct {
+	hll     string
+	viewer  string
+	ranking string
+	member  string
+}
+
+// viewBatch суммирует просмотры перед записью, чтобы повторные просмотры
+// одной сущности превращались в одну команду Redis.
+type viewBatch struct {
+	events   int
+	counters map[string]int64
+	scores   map[scoreIncr]float64
+	uniques  map[uniqueAdd]struct{}
+	ttls     map[string]time.Duration
+	// halfLife — период полураспада трендового рейтинга; нулевой
+	// отключает его.
+	halfLife time.Duration
+}
+
+func newViewBatch(halfLife time.Duration) *viewBatch {
+	return &viewBatch{
+		counters: make(map[string]int64),
+		scores:   make(map[scoreIncr]float64),
+		uniques:  make(map[uniqueAdd]struct{}),
+		ttls:     make(map[string]time.Duration),
+		halfLife: halfLife,
+	}
+}
+
+// incrScore увеличивает счет member в key на единицу; ненулевой ttl задает
+// время жизни корзины после записи.
+func (b *viewBatch) incrScore(key, member string, ttl time.Duration) {
+	b.addScore(key, member, 1, ttl)
+}
+
+func (b *viewBatch) addScore(key, member string, value float64, ttl time.Duration) {
+	b.scores[scoreIncr{key: key, member: member}] += value
+	if ttl > 0 {
+		b.ttls[key] = ttl
+	}
+}
+
+// addUnique добавляет посетителя в hll с рейтингом ranking, если он задан;
+// ненулевой ttl задает время жизни обоих ключей.
+func (b *viewBatch) addUnique(hll, viewer, ranking, member string, ttl time.Duration) {
+	b.uniques[uniqueAdd{hll: hll, viewer: viewer, ranking: ranking, member: member}] = struct{}{}
+	if ttl > 0 {
+		b.ttls[hll] = ttl
+		if ranking != "" {
+			b.ttls[ranking] = ttl
+		}
+	}
+}
+
+// add учитывает просмотр в счетчиках, рейтингах и корзинах окон, в которые
+// он попадает по времени e.at.
+func (b *viewBatch) add(e viewEvent) {
+	b.events++
+	if e.pageID != 0 {
+		member := strconv.FormatInt(e.pageID, 10)
+		b.counters[pageViewsPrefix+member]++
+		if e.viewer != "" {
+			b.addUnique(pageUniquesPrefix+member, e.viewer, "", "", 0)
+		}
+	}
+
+	if e.chapterID != 0 {
+		member := strconv.FormatInt(e.chapterID, 10)
+		bucket := dayBucket(e.at)
+		b.counters[chapterViewsPrefix+member]++
+		b.incrScore(chapterDailyKey+":"+bucket, member, dailyWindow.ttl)
+		if e.viewer != "" {
+			uniquesKey := chapterUniquesPrefix + member
+			b.addUnique(uniquesKey, e.viewer, "", "", 0)
+			b.addUnique(uniquesKey+":"+bucket, e.viewer, chapterDailyUniqueKey+":"+bucket, member, dailyWindow.ttl)
+		}
+	}
+
+	member := strconv.FormatInt(e.mangaID, 10)
+	b.counters[mangaViewsPrefix+member]++
+	b.incrScore(topMangaKey, member, 0)
+	for _, w := range recordWindows {
+		b.incrScore(MetricViews.rankingKey()+":"+w.buckets(e.at)[0], member, w.ttl)
+	}
+	if b.halfLife > 0 {
+		t := newTrending(b.halfLife, e.at)
+		b.addScore(t.key(0), member, t.weight(e.at), t.ttl())
+	}
+	if e.viewer == "" {
+		return
+	}
+	uniquesKey := mangaUniquesPrefix + member
+	b.addUnique(uniquesKey, e.viewer, topMangaUniqueKey, member, 0)
+	for _, w := range recordWindows {
+		bucket := w.buckets(e.at)[0]
+		b.addUnique(uniquesKey+":"+bucket, e.viewer, MetricUnique.rankingKey()+":"+bucket, member, w.ttl)
+	}
+}
+
+// IngestStats — счетчики асинхронной записи просмотров с момента запуска.
+type IngestStats struct {
+	// Enqueued — просмотры, поставленные в очередь.
+	Enqueued int64 `json:"enqueued"`
+	// Dropped — просмотры, отброшенные из-за переполнения очереди.
+	Dropped int64 `json:"dropped"`
+	// Flushed — просмотры, записанные в Redis.
+	Flushed int64 `json:"flushed"`
+	// Failed — просмотры, потерянные из-за ошибки записи в Redis.
+	Failed int64 `json:"failed"`
+	// Batches — число записей в Redis.
+	Batches       int64 `json:"batches"`
+	QueueLength   int   `json:"queue_length"`
+	QueueCapacity int   `json:"queue_capacity"`
+}
+
+// Start включает асинхронную запись: просмотры ставятся в очередь, а
+// фоновый обработчик суммирует их и записывает в Redis конвейером раз в
+// FlushInterval или по накоплении MaxBatch просмотров.
+func (s *AnalyticsService) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue != nil {
+		return
+	}
+	s.queue = make(chan viewEvent, s.QueueSize)
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.ingest()
+}
+
+// Close прекращает прием просмотров в очередь, записывает накопленные и
+// дожидается завершения обработчика. После Close просмотры снова
+// записываются сразу.
+func (s *AnalyticsService) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.queue == nil || s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Stats возвращает счетчики асинхронной записи просмотров.
+func (s *AnalyticsService) Stats() IngestStats {
+	stats := IngestStats{
+		Enqueued: s.enqueued.Load(),
+		Dropped:  s.dropped.Load(),
+		Flushed:  s.flushed.Load(),
+		Failed:   s.failed.Load(),
+		Batches:  s.batches.Load(),
+	}
+	s.mu.RLock()
+	if s.queue != nil {
+		stats.QueueLength = len(s.queue)
+		stats.QueueCapacity = cap(s.queue)
+	}
+	s.mu.RUnlock()
+	return stats
+}
+
+// record ставит просмотр в очередь или, если асинхронная запись не
+// запущена, сразу записывает его.
+func (s *AnalyticsService) record(ctx context.Context, e viewEvent) error {
+	e.at = s.now()
+	if s.enqueue(e) {
+		return nil
+	}
+	b := newViewBatch(s.TrendingHalfLife)
+	b.add(e)
+	return s.flush(ctx, b)
+}
+
+// enqueue сообщает, принят ли просмотр асинхронной записью; при
+// переполненной очереди просмотр отбрасывается.
+func (s *AnalyticsService) enqueue(e viewEvent) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.queue == nil || s.closed {
+		return false
+	}
+	select {
+	case s.queue <- e:
+		s.enqueued.Add(1)
+	default:
+		s.dropped.Add(1)
+	}
+	return true
+}
+
+func (s *AnalyticsService) ingest() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+
+	batch := newViewBatch(s.TrendingHalfLife)
+	var reported int64
+	write := func() {
+		if dropped := s.dropped.Load(); dropped > reported {
+			s.logger.Error("Очередь просмотров переполнена, просмотры отброшены", "dropped", dropped-reported)
+			reported = dropped
+		}
+		if batch.events == 0 {
+			return
+		}
+		_ = s.flush(context.Background(), batch)
+		batch = newViewBatch(s.TrendingHalfLife)
+	}
+
+	for {
+		select {
+		case e := <-s.queue:
+			batch.add(e)
+			if batch.events >= s.MaxBatch {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case <-s.done:
+			// Новые просмотры в очередь уже не попадают, поэтому ее можно
+			// разобрать до конца.
+			for {
+				select {
+				case e := <-s.queue:
+					batch.add(e)
+				default:
+					write()
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush записывает пачку за два запроса: первый увеличивает счетчики и
+// добавляет посетителей в HyperLogLog, второй по результатам PFADD
+// увеличивает рейтинги по посетителям и обновляет TTL корзин.
+func (s *AnalyticsService) flush(ctx context.Context, b *viewBatch) error {
+	s.batches.Add(1)
+	pipe := s.cache.Pipeline()
+	for key, n := range b.counters {
+		pipe.IncrBy(key, n)
+	}
+	for z, n := range b.scores {
+		pipe.ZIncrBy(z.key, n, z.member)
+	}
+	added := make(map[uniqueAdd]*cache.BoolResult, len(b.uniques))
+	for u := range b.uniques {
+		added[u] = pipe.PFAdd(u.hll, u.viewer)
+	}
+	if err := pipe.Exec(ctx); err != nil {
+		s.failed.Add(int64(b.events))
+		s.logger.Error("Ошибка записи просмотров", "views", b.events, "err", err)
+		return err
+	}
+
+	uniques := make(map[scoreIncr]int64)
+	for u, result := range added {
+		if result.Val && u.ranking != "" {
+			uniques[scoreIncr{key: u.ranking, member: u.member}]++
+		}
+	}
+	for z, n := range uniques {
+		pipe.ZIncrBy(z.key, float64(n), z.member)
+	}
+	for key, ttl := range b.ttls {
+		pipe.Expire(key, ttl)
+	}
+	if err := pipe.Exec(ctx); err != nil {
+		s.failed.Add(int64(b.events))
+		s.logger.Error("Ошибка обновления рейтингов по посетителям", "views", b.events, "err", err)
+		return err
+	}
+	s.flushed.Add(int64(b.events))
+	return nil
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"manga-reader/internal/cache"
+	"testing"
+	"time"
+)
+
+func TestAsyncIngest(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+	s.FlushInterval = time.Hour
+	s.Start()
+	ctx := context.Background()
+
+	for _, viewer := range []string{"a", "a", "b"} {
+		if err := s.RecordPageView(ctx, 100, 10, 1, viewer); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+	if err := s.RecordMangaView(ctx, 2, "a"); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+
+	// Закрытие записывает накопленные просмотры одной пачкой.
+	s.Close()
+	if stats := s.Stats(); stats.Enqueued != 4 || stats.Flushed != 4 || stats.Batches != 1 || stats.Dropped != 0 {
+		t.Errorf("Неожиданные счетчики записи: %+v", stats)
+	}
+
+	if views, _ := s.GetMangaView(ctx, 1); views != 3 {
+		t.Errorf("Ожидалось 3 просмотра манги 1, получено %d", views)
+	}
+	if n, _ := s.GetChapterUniqueViewers(ctx, 10); n != 2 {
+		t.Errorf("Ожидалось 2 посетителя главы, получено %d", n)
+	}
+	if raw, _ := c.Get(ctx, pageViewsPrefix+"100"); raw != "3" {
+		t.Errorf("Ожидалось 3 просмотра страницы, получено %q", raw)
+	}
+	for _, period := range []string{"day", "all"} {
+		entries, err := s.GetTopManga(ctx, period, MetricUnique, 10)
+		want := []TopMangaEntry{{MangaID: 1, Views: 3, UniqueViewers: 2}, {MangaID: 2, Views: 1, UniqueViewers: 1}}
+		if err != nil || len(entries) != 2 || entries[0] != want[0] || entries[1] != want[1] {
+			t.Errorf("Неожиданный рейтинг за %s: %v, %v", period, entries, err)
+		}
+	}
+	stats, err := s.DailyViews(ctx, now)
+	if err != nil || len(stats) != 3 || stats[2].EntityID != 10 || stats[2].Views != 3 || stats[2].UniqueViewers != 2 {
+		t.Errorf("Неожиданные суточные просмотры: %+v, %v", stats, err)
+	}
+
+	// После закрытия просмотры записываются сразу.
+	if err = s.RecordMangaView(ctx, 2, "b"); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+	if views, _ := s.GetMangaView(ctx, 2); views != 2 {
+		t.Errorf("Ожидалось 2 просмотра манги 2, получено %d", views)
+	}
+}
+
+// blockingCache задерживает запись пачки, пока тест не освободит ее.
+type blockingCache struct {
+	*cache.MemoryCache
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) Pipeline() cache.Pipeline {
+	return &blockingPipeline{Pipeline: c.MemoryCache.Pipeline(), cache: c}
+}
+
+type blockingPipeline struct {
+	cache.Pipeline
+	cache *blockingCache
+}
+
+func (p *blockingPipeline) Exec(ctx context.Context) error {
+	select {
+	case p.cache.entered <- struct{}{}:
+		<-p.cache.release
+	default:
+	}
+	return p.Pipeline.Exec(ctx)
+}
+
+func TestAsyncIngestDropsOnOverflow(t *testing.T) {
+	c := &blockingCache{MemoryCache: cache.NewMemoryCache(), entered: make(chan struct{}), release: make(chan struct{})}
+	s := NewAnalyticsService(c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.QueueSize = 1
+	s.MaxBatch = 1
+	s.Start()
+	ctx := context.Background()
+
+	go func() {
+		_ = s.RecordMangaView(ctx, 1, "")
+	}()
+	// Обработчик забрал первый просмотр и ждет записи пачки.
+	<-c.entered
+	for i := 0; i < 3; i++ {
+		if err := s.RecordMangaView(ctx, 1, ""); err != nil {
+			t.Fatalf("Переполнение очереди не должно возвращать ошибку: %v", err)
+		}
+	}
+	if stats := s.Stats(); stats.Enqueued != 2 || stats.Dropped != 2 || stats.QueueLength != 1 || stats.QueueCapacity != 1 {
+		t.Errorf("Неожиданные счетчики записи: %+v", stats)
+	}
+
+	close(c.release)
+	s.Close()
+	if views, _ := s.GetMangaView(ctx, 1); views != 2 {
+		t.Errorf("Ожидалось 2 записанных просмотра, получено %d", views)
+	}
+	if stats := s.Stats(); stats.Flushed != 2 {
+		t.Errorf("Неожиданные счетчики записи: %+v", stats)
+	}
+}
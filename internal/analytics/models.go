@@ -1,24 +1,66 @@
 package analytics
 
+import (
+	"manga-reader/models"
+	"time"
+)
+
 // TopMangaEntry представляет элемент рейтинга манги
 type TopMangaEntry struct {
-	MangaID int64 `json:"manga_id"`
-	Views   int64 `json:"views"`
+	MangaID       int64 `json:"manga_id"`
+	Views         int64 `json:"views"`
+	UniqueViewers int64 `json:"unique_viewers"`
+	// Score — затухающий счет для PeriodTrending и прирост для PeriodRising.
+	Score float64 `json:"score,omitempty"`
+	// Previous — значение показателя за предыдущие сутки для PeriodRising.
+	Previous int64 `json:"previous,omitempty"`
+}
+
+func (e *TopMangaEntry) get(m Metric) int64 {
+	if m == MetricUnique {
+		return e.UniqueViewers
+	}
+	return e.Views
+}
+
+func (e *TopMangaEntry) set(m Metric, value int64) {
+	if m == MetricUnique {
+		e.UniqueViewers = value
+	} else {
+		e.Views = value
+	}
 }
 
 // MangaWithViews представляет мангу с информацией о просмотрах
 type MangaWithViews struct {
-	ID          int64  `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Views       int64  `json:"views"`
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Views       int64     `json:"views"`
+	// UniqueViewers — оценка числа уникальных посетителей.
+	UniqueViewers int64 `json:"unique_viewers"`
+	// Score и Previous заполняются для рейтингов trending и rising (см.
+	// TopMangaEntry).
+	Score    float64 `json:"score,omitempty"`
+	Previous int64   `json:"previous,omitempty"`
 }
 
 // ChapterWithViews представляет главу с информацией о просмотрах
 type ChapterWithViews struct {
-	ID      int64  `json:"id"`
-	MangaID int64  `json:"manga_id"`
-	Number  int    `json:"number"`
-	Title   string `json:"title"`
-	Views   int64  `json:"views"`
+	ID          int64                `json:"id"`
+	MangaID     int64                `json:"manga_id"`
+	Number      int                  `json:"number"`
+	Title       string               `json:"title"`
+	Language    string               `json:"language"`
+	GroupID     *int64               `json:"group_id,omitempty"`
+	Status      models.ChapterStatus `json:"status"`
+	PublishAt   *time.Time           `json:"publish_at,omitempty"`
+	PublishedAt *time.Time           `json:"published_at,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Views       int64                `json:"views"`
+	// UniqueViewers — оценка числа уникальных посетителей.
+	UniqueViewers int64 `json:"unique_viewers"`
 }
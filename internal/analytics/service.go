@@ -2,10 +2,19 @@ package analytics
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"github.com/go-redis/redis/v8"
 	"log/slog"
 	"manga-reader/internal/cache"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"math"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,88 +23,216 @@ const (
 	mangaViewsPrefix   = "views:manga:"
 	chapterViewsPrefix = "views:chapter:"
 	pageViewsPrefix    = "views:page:"
+	// HyperLogLog уникальных посетителей манги и главы за все время; для
+	// манги есть также почасовые и посуточные HLL, например
+	// uniques:manga:42:h:2024031514.
+	mangaUniquesPrefix   = "uniques:manga:"
+	chapterUniquesPrefix = "uniques:chapter:"
+	// HyperLogLog посетителей страницы; по ним строится воронка дочитывания.
+	pageUniquesPrefix = "uniques:page:"
+
+	// Посуточные просмотры и посетители глав, например
+	// ranking:chapter:d:20240315; из них строится история просмотров.
+	chapterDailyKey       = "ranking:chapter"
+	chapterDailyUniqueKey = "ranking:chapter:unique"
+
+	// topMangaKey — рейтинг манги по просмотрам за все время.
+	topMangaKey = "ranking:manga"
+	// topMangaUniqueKey — рейтинг манги по уникальным посетителям за все время.
+	topMangaUniqueKey = "ranking:manga:unique"
+	// windowKeyPrefix — временный ключ с объединением корзин окна.
+	windowKeyPrefix = "ranking:manga:window:"
+	windowKeyTTL    = time.Minute
+)
 
-	// Ключи для рейтингов
-	topMangaKey        = "ranking:manga"
-	topMangaDailyKey   = "ranking:manga:daily"
-	topMangaWeeklyKey  = "ranking:manga:weekly"
-	topMangaMonthlyKey = "ranking:manga:monthly"
+// Period — окно рейтинга манги.
+type Period string
 
-	// Время жизни ключей
-	dailyExpire   = 24 * time.Hour
-	weeklyExpire  = 7 * 24 * time.Hour
-	monthlyExpire = 30 * 24 * time.Hour
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+	PeriodAll   Period = "all"
+	// PeriodTrending — рейтинг по просмотрам, затухающим со временем.
+	PeriodTrending Period = "trending"
+	// PeriodRising — рейтинг по приросту за последние сутки относительно
+	// предыдущих.
+	PeriodRising Period = "rising"
 )
 
-type AnalyticsService struct {
-	cache  cache.Cache
-	logger *slog.Logger
-}
-
-func NewAnalyticsService(cache cache.Cache, logger *slog.Logger) *AnalyticsService {
-	return &AnalyticsService{
-		cache:  cache,
-		logger: logger,
+// ParsePeriod разбирает окно рейтинга; daily, weekly и monthly принимаются
+// как синонимы. Неизвестные и пустые значения означают рейтинг за все время.
+func ParsePeriod(value string) Period {
+	switch value {
+	case "trending":
+		return PeriodTrending
+	case "rising":
+		return PeriodRising
+	case "day", "daily":
+		return PeriodDay
+	case "week", "weekly":
+		return PeriodWeek
+	case "month", "monthly":
+		return PeriodMonth
+	default:
+		return PeriodAll
 	}
 }
 
-func (s *AnalyticsService) RecordMangaView(ctx context.Context, mangaID int64) error {
-	mangaKey := fmt.Sprintf("%s%d", mangaViewsPrefix, mangaID)
+// Metric — показатель, по которому строится рейтинг.
+type Metric string
 
-	_, err := s.cache.Incr(ctx, mangaKey)
-	if err != nil {
-		s.logger.Error("Ошибка инкремента счетчика просмотров манги", "manga_id", mangaID, "err", err)
-		return err
-	}
+const (
+	// MetricViews — все просмотры, включая повторные.
+	MetricViews Metric = "views"
+	// MetricUnique — уникальные посетители.
+	MetricUnique Metric = "unique"
+)
 
-	_, err = s.cache.ZIncrBy(ctx, topMangaKey, 1, strconv.FormatInt(mangaID, 10))
-	if err != nil {
-		s.logger.Error("Ошибка обновления рейтинга манги", "manga_id", mangaID, "err", err)
-		return err
+// ParseMetric разбирает показатель рейтинга; неизвестные и пустые значения
+// означают просмотры.
+func ParseMetric(value string) Metric {
+	if value == "unique" || value == "unique_viewers" {
+		return MetricUnique
 	}
+	return MetricViews
+}
 
-	_, err = s.cache.ZIncrBy(ctx, topMangaDailyKey, 1, strconv.FormatInt(mangaID, 10))
-	if err != nil {
-		s.logger.Error("Ошибка обновления дневного рейтинга манги", "manga_id", mangaID, "err", err)
-		return err
+// rankingKey возвращает рейтинг показателя за все время; корзины окон
+// хранятся в ключах с тем же префиксом: ranking:manga:h:2024031514,
+// ranking:manga:unique:d:20240315.
+func (m Metric) rankingKey() string {
+	if m == MetricUnique {
+		return topMangaUniqueKey
 	}
+	return topMangaKey
+}
 
-	_, err = s.cache.ZIncrBy(ctx, topMangaWeeklyKey, 1, strconv.FormatInt(mangaID, 10))
-	if err != nil {
-		s.logger.Error("Ошибка обновления недельного рейтинга манги", "manga_id", mangaID, "err", err)
-		return err
+// window описывает окно рейтинга как набор последних корзин.
+type window struct {
+	kind   string
+	layout string
+	step   time.Duration
+	count  int
+	ttl    time.Duration
+}
+
+// Окно за сутки складывается из 24 почасовых корзин и сдвигается каждый
+// час; окна за неделю и месяц — из суточных корзин UTC, включая текущие
+// сутки. Корзины хранятся на одну корзину дольше, чем нужны, и затем
+// удаляются по TTL; почасовые нужны двое суток, так как PeriodRising
+// сравнивает окно за сутки с предыдущим.
+var windows = map[Period]window{
+	PeriodDay:   {kind: "h", layout: "2006010215", step: time.Hour, count: 24, ttl: 49 * time.Hour},
+	PeriodWeek:  {kind: "d", layout: "20060102", step: 24 * time.Hour, count: 7, ttl: 31 * 24 * time.Hour},
+	PeriodMonth: {kind: "d", layout: "20060102", step: 24 * time.Hour, count: 30, ttl: 31 * 24 * time.Hour},
+}
+
+// buckets возвращает суффиксы корзин окна, заканчивающегося в now, начиная
+// с текущей.
+func (w window) buckets(now time.Time) []string {
+	now = now.UTC().Truncate(w.step)
+	buckets := make([]string, w.count)
+	for i := range buckets {
+		buckets[i] = w.kind + ":" + now.Add(-time.Duration(i)*w.step).Format(w.layout)
 	}
+	return buckets
+}
 
-	_, err = s.cache.ZIncrBy(ctx, topMangaMonthlyKey, 1, strconv.FormatInt(mangaID, 10))
-	if err != nil {
-		s.logger.Error("Ошибка обновления месячного рейтинга манги", "manga_id", mangaID, "err", err)
-		return err
+// keys возвращает ключи корзин окна для рейтинга по показателю m.
+func (w window) keys(m Metric, now time.Time) []string {
+	keys := w.buckets(now)
+	for i, bucket := range keys {
+		keys[i] = m.rankingKey() + ":" + bucket
 	}
-	return nil
+	return keys
 }
 
-func (s *AnalyticsService) RecordChapterView(ctx context.Context, chapterID, mangaID int64) error {
-	mangaKey := fmt.Sprintf("%s%d", chapterViewsPrefix, chapterID)
+// recordWindows — окна, в текущие корзины которых записывается просмотр:
+// почасовые и суточные.
+var recordWindows = []window{windows[PeriodDay], windows[PeriodMonth]}
 
-	_, err := s.cache.Incr(ctx, mangaKey)
-	if err != nil {
-		s.logger.Error("Ошибка инкремента счетчика просмотров главы", "chapter_id", chapterID, "err", err)
-		return err
-	}
+// dailyWindow — окно суточных корзин, из которых строится история просмотров.
+var dailyWindow = windows[PeriodMonth]
 
-	return s.RecordMangaView(ctx, mangaID)
+// dayBucket возвращает суффикс суточной корзины для суток UTC, в которые
+// входит t.
+func dayBucket(t time.Time) string {
+	return dailyWindow.buckets(t)[0]
 }
 
-func (s *AnalyticsService) RecordPageView(ctx context.Context, pageID, chapterID, mangaID int64) error {
-	mangaKey := fmt.Sprintf("%s%d", pageViewsPrefix, pageID)
+type AnalyticsService struct {
+	cache  cache.Cache
+	logger *slog.Logger
+	now    func() time.Time
+
+	// QueueSize — емкость очереди просмотров; при переполнении новые
+	// просмотры отбрасываются, чтобы не задерживать запросы.
+	QueueSize int
+	// FlushInterval — период записи накопленных просмотров в Redis.
+	FlushInterval time.Duration
+	// MaxBatch — число накопленных просмотров, при котором они
+	// записываются, не дожидаясь FlushInterval.
+	MaxBatch int
+	// TrendingHalfLife — время, за которое вклад просмотра в трендовый
+	// рейтинг уменьшается вдвое; нулевое значение отключает рейтинг.
+	TrendingHalfLife time.Duration
+	// ViewerSecret — ключ для идентификаторов анонимных посетителей. По
+	// умолчанию случайный, и после перезапуска посетители за текущие сутки
+	// учитываются заново.
+	ViewerSecret []byte
+
+	queue chan viewEvent
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	flushed  atomic.Int64
+	failed   atomic.Int64
+	batches  atomic.Int64
+}
 
-	_, err := s.cache.Incr(ctx, mangaKey)
-	if err != nil {
-		s.logger.Error("Ошибка инкремента счетчика просмотров страницы", "page_id", pageID, "err", err)
-		return err
+// NewAnalyticsService создает сервис, записывающий просмотры в Redis сразу
+// при вызове Record*; метод Start включает асинхронную запись.
+func NewAnalyticsService(cache cache.Cache, logger *slog.Logger) *AnalyticsService {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return &AnalyticsService{
+		cache:            cache,
+		logger:           logger,
+		now:              time.Now,
+		QueueSize:        10000,
+		FlushInterval:    time.Second,
+		MaxBatch:         1000,
+		TrendingHalfLife: 24 * time.Hour,
+		ViewerSecret:     secret,
 	}
+}
+
+// SetClock подменяет источник времени, что позволяет тестам проверять
+// сдвиг окон рейтинга без ожидания.
+func (s *AnalyticsService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// RecordMangaView учитывает просмотр манги посетителем viewer (см.
+// ViewerID). Пустой viewer учитывается только в просмотрах.
+func (s *AnalyticsService) RecordMangaView(ctx context.Context, mangaID int64, viewer string) error {
+	return s.record(ctx, viewEvent{mangaID: mangaID, viewer: viewer})
+}
+
+// RecordChapterView учитывает просмотр главы и ее манги.
+func (s *AnalyticsService) RecordChapterView(ctx context.Context, chapterID, mangaID int64, viewer string) error {
+	return s.record(ctx, viewEvent{chapterID: chapterID, mangaID: mangaID, viewer: viewer})
+}
 
-	return s.RecordChapterView(ctx, chapterID, mangaID)
+// RecordPageView учитывает просмотр страницы, ее главы и манги.
+func (s *AnalyticsService) RecordPageView(ctx context.Context, pageID, chapterID, mangaID int64, viewer string) error {
+	return s.record(ctx, viewEvent{pageID: pageID, chapterID: chapterID, mangaID: mangaID, viewer: viewer})
 }
 
 func (s *AnalyticsService) GetMangaView(ctx context.Context, mangaID int64) (int64, error) {
@@ -119,83 +256,380 @@ func (s *AnalyticsService) GetMangaView(ctx context.Context, mangaID int64) (int
 	return views, nil
 }
 
-func (s *AnalyticsService) GetTopManga(ctx context.Context, period string, limit int64) ([]TopMangaEntry, error) {
-	var key string
-	switch period {
-	case "day":
-		key = topMangaDailyKey
-	case "week":
-		key = topMangaWeeklyKey
-	case "month":
-		key = topMangaMonthlyKey
-	default:
-		key = topMangaKey
+// GetMangaUniqueViewers возвращает оценку числа уникальных посетителей манги
+// за все время. Погрешность HyperLogLog в Redis — около 0,81%.
+func (s *AnalyticsService) GetMangaUniqueViewers(ctx context.Context, mangaID int64) (int64, error) {
+	count, err := s.cache.PFCount(ctx, fmt.Sprintf("%s%d", mangaUniquesPrefix, mangaID))
+	if err != nil {
+		s.logger.Error("Ошибка получения числа посетителей манги", "manga_id", mangaID, "err", err)
+		return 0, err
 	}
+	return count, nil
+}
 
-	scoreMap, err := s.cache.ZRevRangeWithScores(ctx, key, 0, limit-1)
+// GetChapterUniqueViewers возвращает оценку числа уникальных посетителей главы.
+func (s *AnalyticsService) GetChapterUniqueViewers(ctx context.Context, chapterID int64) (int64, error) {
+	count, err := s.cache.PFCount(ctx, fmt.Sprintf("%s%d", chapterUniquesPrefix, chapterID))
 	if err != nil {
-		s.logger.Error("Ошибка получения топ манги", "period", period, "err", err)
+		s.logger.Error("Ошибка получения числа посетителей главы", "chapter_id", chapterID, "err", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetTopManga возвращает limit манг с наибольшим значением показателя
+// metric за окно period (см. ParsePeriod) вместе со значениями обоих
+// показателей. Посетитель учитывается в окне один раз за корзину: за час
+// в суточном окне и за сутки в недельном и месячном.
+//
+// Для PeriodTrending манги упорядочены по затухающему счету просмотров
+// Score, а metric не учитывается. Для PeriodRising — по приросту metric за
+// последние сутки относительно предыдущих: Score равен приросту, Previous —
+// значению за предыдущие сутки, а манги без прироста не выводятся. В обоих
+// случаях Views и UniqueViewers относятся к последним суткам.
+func (s *AnalyticsService) GetTopManga(ctx context.Context, period string, metric Metric, limit int64) ([]TopMangaEntry, error) {
+	p := ParsePeriod(period)
+	if p == PeriodTrending && s.TrendingHalfLife <= 0 {
+		return []TopMangaEntry{}, nil
+	}
+	valuesPeriod := p
+	if p == PeriodTrending || p == PeriodRising {
+		valuesPeriod = PeriodDay
+	}
+	keys := make(map[Metric]string)
+	for _, m := range []Metric{MetricViews, MetricUnique} {
+		key, err := s.windowKey(ctx, valuesPeriod, m)
+		if err != nil {
+			return nil, err
+		}
+		keys[m] = key
+	}
+
+	rankingKey := keys[metric]
+	var previousKey string
+	var err error
+	switch p {
+	case PeriodTrending:
+		rankingKey, err = s.trendingKey(ctx)
+	case PeriodRising:
+		rankingKey, previousKey, err = s.risingKeys(ctx, metric)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scoreMap, err := s.cache.ZRevRangeWithScores(ctx, rankingKey, 0, limit-1)
+	if err != nil {
+		s.logger.Error("Ошибка получения топ манги", "period", p, "metric", metric, "err", err)
 		return nil, err
 	}
 
-	var results []TopMangaEntry
+	results := make([]TopMangaEntry, 0, len(scoreMap))
 	for member, score := range scoreMap {
+		if p == PeriodRising && score <= 0 {
+			continue
+		}
 		mangaID, err := strconv.ParseInt(member, 10, 64)
 		if err != nil {
 			s.logger.Error("Ошибка парсинга ID манги", "member", member, "err", err)
 			continue
 		}
 
-		results = append(results, TopMangaEntry{
-			MangaID: mangaID,
-			Views:   int64(score),
-		})
+		entry := TopMangaEntry{MangaID: mangaID}
+		for m, key := range keys {
+			value, err := s.score(ctx, key, member)
+			if err != nil {
+				return nil, err
+			}
+			entry.set(m, value)
+		}
+		switch p {
+		case PeriodTrending:
+			entry.Score = score
+		case PeriodRising:
+			entry.Score = score
+			if entry.Previous, err = s.score(ctx, previousKey, member); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, entry)
 	}
+
+	rank := func(e TopMangaEntry) float64 {
+		if p == PeriodTrending || p == PeriodRising {
+			return e.Score
+		}
+		return float64(e.get(metric))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := rank(results[i]), rank(results[j])
+		if a != b {
+			return a > b
+		}
+		return results[i].MangaID < results[j].MangaID
+	})
 	return results, nil
 }
 
-func (s *AnalyticsService) InitializeDailyStats(ctx context.Context) error {
-	err := s.cache.Delete(ctx, topMangaDailyKey)
+// score возвращает счет member в key или 0, если его нет.
+func (s *AnalyticsService) score(ctx context.Context, key, member string) (int64, error) {
+	score, err := s.cache.ZScore(ctx, key, member)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.logger.Error("Ошибка получения счета манги", "key", key, "member", member, "err", err)
+		return 0, err
+	}
+	return int64(score), nil
+}
+
+// trendingKey сводит рейтинги текущей и предыдущей эпох во временный ключ
+// со счетами, затухшими к текущему моменту.
+func (s *AnalyticsService) trendingKey(ctx context.Context) (string, error) {
+	now := s.now()
+	t := newTrending(s.TrendingHalfLife, now)
+	current := 1 / t.weight(now)
+	key := windowKeyPrefix + string(PeriodTrending)
+	err := s.cache.ZUnionStoreWeights(ctx, key, []string{t.key(0), t.key(-1)},
+		[]float64{current, current / math.Exp2(trendingEpochHalfLives)})
 	if err != nil {
-		s.logger.Error("Ошибка удаления дневного рейтинга", "err", err)
-		return err
+		s.logger.Error("Ошибка объединения трендового рейтинга", "err", err)
+		return "", err
+	}
+	if err = s.cache.Expire(ctx, key, windowKeyTTL); err != nil {
+		s.logger.Error("Ошибка установки времени жизни рейтинга", "period", PeriodTrending, "err", err)
+	}
+	return key, nil
+}
+
+// risingKeys возвращает временные ключи с приростом показателя m за
+// последние сутки относительно предыдущих и со значениями за предыдущие
+// сутки.
+func (s *AnalyticsService) risingKeys(ctx context.Context, m Metric) (rising, previous string, err error) {
+	w := windows[PeriodDay]
+	now := s.now()
+	current := w.keys(m, now)
+	before := w.keys(m, now.Add(-time.Duration(w.count)*w.step))
+
+	previous = windowKeyPrefix + string(m) + ":previous-day"
+	rising = windowKeyPrefix + string(m) + ":" + string(PeriodRising)
+	keys := append(append([]string(nil), current...), before...)
+	weights := make([]float64, len(keys))
+	for i := range weights {
+		weights[i] = 1
+		if i >= len(current) {
+			weights[i] = -1
+		}
+	}
+	if err = s.cache.ZUnionStore(ctx, previous, before...); err == nil {
+		err = s.cache.ZUnionStoreWeights(ctx, rising, keys, weights)
 	}
-	err = s.cache.Set(ctx, topMangaDailyKey+":expire", "1", dailyExpire)
 	if err != nil {
-		s.logger.Error("Ошибка установки времени жизни для дневного рейтинга", "err", err)
-		return err
+		s.logger.Error("Ошибка объединения корзин рейтинга", "period", PeriodRising, "metric", m, "err", err)
+		return "", "", err
+	}
+	for _, key := range []string{previous, rising} {
+		if err = s.cache.Expire(ctx, key, windowKeyTTL); err != nil {
+			s.logger.Error("Ошибка установки времени жизни рейтинга", "period", PeriodRising, "metric", m, "err", err)
+		}
+	}
+	return rising, previous, nil
+}
+
+// windowKey возвращает ключ рейтинга по показателю m за окно p, при
+// необходимости объединяя корзины окна во временный ключ.
+func (s *AnalyticsService) windowKey(ctx context.Context, p Period, m Metric) (string, error) {
+	w, ok := windows[p]
+	if !ok {
+		return m.rankingKey(), nil
+	}
+	key := windowKeyPrefix + string(m) + ":" + string(p)
+	if err := s.cache.ZUnionStore(ctx, key, w.keys(m, s.now())...); err != nil {
+		s.logger.Error("Ошибка объединения корзин рейтинга", "period", p, "metric", m, "err", err)
+		return "", err
+	}
+	if err := s.cache.Expire(ctx, key, windowKeyTTL); err != nil {
+		s.logger.Error("Ошибка установки времени жизни рейтинга", "period", p, "metric", m, "err", err)
+	}
+	return key, nil
+}
+
+// ResetRanking удаляет просмотры и посетителей, входящих в окно period, а
+// для PeriodAll — рейтинги за все время. Окна за неделю и месяц используют
+// общие суточные корзины, поэтому сброс недельного рейтинга затрагивает и
+// месячный.
+func (s *AnalyticsService) ResetRanking(ctx context.Context, period Period) error {
+	var keys []string
+	for _, m := range []Metric{MetricViews, MetricUnique} {
+		if w, ok := windows[period]; ok {
+			keys = append(keys, w.keys(m, s.now())...)
+			keys = append(keys, windowKeyPrefix+string(m)+":"+string(period))
+		} else {
+			keys = append(keys, m.rankingKey())
+		}
+	}
+	for _, key := range keys {
+		if err := s.cache.Delete(ctx, key); err != nil {
+			s.logger.Error("Ошибка сброса рейтинга", "period", period, "key", key, "err", err)
+			return err
+		}
 	}
 	return nil
 }
 
-func (s *AnalyticsService) InitializeWeeklyStats(ctx context.Context) error {
-	err := s.cache.Delete(ctx, topMangaWeeklyKey)
-	if err != nil {
-		s.logger.Error("Ошибка удаления недельного рейтинга", "err", err)
-		return err
+// dailyKeys возвращает ключи суточных корзин просмотров и посетителей
+// сущностей типа entity.
+func dailyKeys(entity models.ViewEntity, bucket string) (views, uniques string) {
+	if entity == models.ViewChapter {
+		return chapterDailyKey + ":" + bucket, chapterDailyUniqueKey + ":" + bucket
 	}
+	return MetricViews.rankingKey() + ":" + bucket, MetricUnique.rankingKey() + ":" + bucket
+}
 
-	err = s.cache.Set(ctx, topMangaWeeklyKey+":expire", "1", weeklyExpire)
-	if err != nil {
-		s.logger.Error("Ошибка установки времени жизни для недельного рейтинга", "err", err)
-		return err
+// DailyViews возвращает просмотры и посетителей манги и глав за сутки UTC, в
+// которые входит day. Данные берутся из суточных корзин, поэтому доступны,
+// пока корзины не удалены по TTL.
+func (s *AnalyticsService) DailyViews(ctx context.Context, day time.Time) ([]*models.DailyViews, error) {
+	bucket := dayBucket(day)
+	start := day.UTC().Truncate(24 * time.Hour)
+	var stats []*models.DailyViews
+	for _, entity := range []models.ViewEntity{models.ViewManga, models.ViewChapter} {
+		viewsKey, uniquesKey := dailyKeys(entity, bucket)
+		views, err := s.cache.ZRevRangeWithScores(ctx, viewsKey, 0, -1)
+		if err != nil {
+			s.logger.Error("Ошибка получения суточных просмотров", "key", viewsKey, "err", err)
+			return nil, err
+		}
+		uniques, err := s.cache.ZRevRangeWithScores(ctx, uniquesKey, 0, -1)
+		if err != nil {
+			s.logger.Error("Ошибка получения суточных посетителей", "key", uniquesKey, "err", err)
+			return nil, err
+		}
+
+		byID := make(map[int64]*models.DailyViews)
+		add := func(scores map[string]float64, set func(*models.DailyViews, int64)) {
+			for member, score := range scores {
+				id, err := strconv.ParseInt(member, 10, 64)
+				if err != nil {
+					s.logger.Error("Ошибка парсинга ID в суточной корзине", "entity_type", entity, "member", member, "err", err)
+					continue
+				}
+				if byID[id] == nil {
+					byID[id] = &models.DailyViews{EntityType: entity, EntityID: id, Day: start}
+				}
+				set(byID[id], int64(score))
+			}
+		}
+		add(views, func(d *models.DailyViews, v int64) { d.Views = v })
+		add(uniques, func(d *models.DailyViews, v int64) { d.UniqueViewers = v })
+
+		ids := make([]int64, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			stats = append(stats, byID[id])
+		}
 	}
+	return stats, nil
+}
 
+// Rebuild восстанавливает данные Redis по истории просмотров: счетчики
+// просмотров манги и глав, рейтинг манги за все время и суточные корзины,
+// которые еще не истекли бы по TTL, — а с ними рейтинги за неделю и месяц.
+// Значения только увеличиваются, поэтому восстановление можно запускать и
+// на неочищенном Redis. Почасовые корзины, HyperLogLog посетителей и рейтинг
+// по посетителям за все время по суточной истории не восстановить: они
+// накапливаются заново.
+func (s *AnalyticsService) Rebuild(ctx context.Context, history db.ViewHistoryRepository) error {
+	now := s.now()
+	for _, entity := range []models.ViewEntity{models.ViewManga, models.ViewChapter} {
+		totals, err := history.Totals(entity)
+		if err != nil {
+			return err
+		}
+		prefix := mangaViewsPrefix
+		if entity == models.ViewChapter {
+			prefix = chapterViewsPrefix
+		}
+		for id, views := range totals {
+			member := strconv.FormatInt(id, 10)
+			if err = s.raiseCounter(ctx, prefix+member, views); err != nil {
+				return err
+			}
+			if entity == models.ViewManga {
+				if err = s.raiseScore(ctx, topMangaKey, member, views, 0); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Корзина хранится dailyWindow.ttl после последней записи, то есть
+		// после окончания суток.
+		from := now.UTC().Truncate(24 * time.Hour).Add(-dailyWindow.ttl)
+		days, err := history.List(entity, 0, from, now.Add(24*time.Hour))
+		if err != nil {
+			return err
+		}
+		for _, d := range days {
+			ttl := d.Day.Add(24 * time.Hour).Add(dailyWindow.ttl).Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+			member := strconv.FormatInt(d.EntityID, 10)
+			viewsKey, uniquesKey := dailyKeys(entity, dayBucket(d.Day))
+			if err = s.raiseScore(ctx, viewsKey, member, d.Views, ttl); err != nil {
+				return err
+			}
+			if err = s.raiseScore(ctx, uniquesKey, member, d.UniqueViewers, ttl); err != nil {
+				return err
+			}
+		}
+		s.logger.Info("Просмотры восстановлены по истории", "entity_type", entity,
+			"entities", len(totals), "days", len(days))
+	}
 	return nil
 }
 
-func (s *AnalyticsService) InitializeMonthlyStats(ctx context.Context) error {
-	err := s.cache.Delete(ctx, topMangaMonthlyKey)
-	if err != nil {
-		s.logger.Error("Ошибка удаления месячного рейтинга", "err", err)
+// raiseCounter увеличивает счетчик key до value, если он меньше.
+func (s *AnalyticsService) raiseCounter(ctx context.Context, key string, value int64) error {
+	raw, err := s.cache.Get(ctx, key)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.logger.Error("Ошибка получения счетчика просмотров", "key", key, "err", err)
 		return err
 	}
-
-	err = s.cache.Set(ctx, topMangaMonthlyKey+":expire", "1", monthlyExpire)
-	if err != nil {
-		s.logger.Error("Ошибка установки времени жизни для месячного рейтинга", "err", err)
+	if current, _ := strconv.ParseInt(raw, 10, 64); current >= value {
+		return nil
+	}
+	if err = s.cache.Set(ctx, key, strconv.FormatInt(value, 10), 0); err != nil {
+		s.logger.Error("Ошибка восстановления счетчика просмотров", "key", key, "err", err)
 		return err
 	}
+	return nil
+}
 
+// raiseScore увеличивает счет member в key до value, если он меньше, и при
+// ненулевом ttl задает время жизни ключа.
+func (s *AnalyticsService) raiseScore(ctx context.Context, key, member string, value int64, ttl time.Duration) error {
+	if value <= 0 {
+		return nil
+	}
+	current, err := s.cache.ZScore(ctx, key, member)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.logger.Error("Ошибка получения счета в рейтинге", "key", key, "member", member, "err", err)
+		return err
+	}
+	if current >= float64(value) {
+		return nil
+	}
+	if err = s.cache.ZAdd(ctx, key, float64(value), member); err != nil {
+		s.logger.Error("Ошибка восстановления счета в рейтинге", "key", key, "member", member, "err", err)
+		return err
+	}
+	if ttl > 0 {
+		if err = s.cache.Expire(ctx, key, ttl); err != nil {
+			s.logger.Error("Ошибка установки времени жизни корзины", "key", key, "err", err)
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,341 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/models"
+	"testing"
+	"time"
+)
+
+func newTestService(c *cache.MemoryCache, now *time.Time) *AnalyticsService {
+	s := NewAnalyticsService(c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetClock(func() time.Time { return *now })
+	return s
+}
+
+func topViews(t *testing.T, s *AnalyticsService, period string) map[int64]int64 {
+	t.Helper()
+	entries, err := s.GetTopManga(context.Background(), period, MetricViews, 10)
+	if err != nil {
+		t.Fatalf("Ошибка получения рейтинга %s: %v", period, err)
+	}
+	views := make(map[int64]int64)
+	for _, e := range entries {
+		views[e.MangaID] = e.Views
+	}
+	return views
+}
+
+func TestRankingWindowsRoll(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := s.RecordMangaView(ctx, 1, ""); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+	now = now.Add(2 * time.Hour)
+	if err := s.RecordMangaView(ctx, 2, ""); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+
+	if views := topViews(t, s, "day"); views[1] != 3 || views[2] != 1 {
+		t.Errorf("Неожиданный рейтинг за сутки: %v", views)
+	}
+
+	// Перезапуск сервиса не сбрасывает статистику.
+	s = newTestService(c, &now)
+
+	// Через сутки после первых просмотров они выходят из суточного окна,
+	// но остаются в недельном и месячном.
+	now = now.Add(23 * time.Hour)
+	if views := topViews(t, s, "daily"); views[1] != 0 || views[2] != 1 {
+		t.Errorf("Неожиданный рейтинг за сутки: %v", views)
+	}
+	if views := topViews(t, s, "week"); views[1] != 3 || views[2] != 1 {
+		t.Errorf("Неожиданный рейтинг за неделю: %v", views)
+	}
+
+	now = now.Add(7 * 24 * time.Hour)
+	if views := topViews(t, s, "week"); len(views) != 0 {
+		t.Errorf("Недельный рейтинг должен быть пуст: %v", views)
+	}
+	if views := topViews(t, s, "month"); views[1] != 3 || views[2] != 1 {
+		t.Errorf("Неожиданный рейтинг за месяц: %v", views)
+	}
+
+	now = now.Add(30 * 24 * time.Hour)
+	if views := topViews(t, s, "month"); len(views) != 0 {
+		t.Errorf("Месячный рейтинг должен быть пуст: %v", views)
+	}
+	if views := topViews(t, s, "all"); views[1] != 3 || views[2] != 1 {
+		t.Errorf("Неожиданный рейтинг за все время: %v", views)
+	}
+}
+
+func TestGetTopMangaOrder(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Now()
+	s := newTestService(c, &now)
+	ctx := context.Background()
+
+	for id, views := range map[int64]int{1: 1, 2: 5, 3: 3, 4: 3} {
+		for i := 0; i < views; i++ {
+			if err := s.RecordMangaView(ctx, id, ""); err != nil {
+				t.Fatalf("Ошибка записи просмотра: %v", err)
+			}
+		}
+	}
+
+	entries, err := s.GetTopManga(ctx, "week", MetricViews, 3)
+	if err != nil {
+		t.Fatalf("Ошибка получения рейтинга: %v", err)
+	}
+	want := []TopMangaEntry{{MangaID: 2, Views: 5}, {MangaID: 3, Views: 3}, {MangaID: 4, Views: 3}}
+	if len(entries) != len(want) {
+		t.Fatalf("Ожидалось %v, получено %v", want, entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Ожидалось %v, получено %v", want, entries)
+			break
+		}
+	}
+}
+
+func TestResetRanking(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Now()
+	s := newTestService(c, &now)
+	ctx := context.Background()
+
+	if err := s.RecordMangaView(ctx, 1, ""); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+	if err := s.ResetRanking(ctx, PeriodDay); err != nil {
+		t.Fatalf("Ошибка сброса рейтинга: %v", err)
+	}
+	if views := topViews(t, s, "day"); len(views) != 0 {
+		t.Errorf("Суточный рейтинг должен быть пуст: %v", views)
+	}
+	if views := topViews(t, s, "month"); views[1] != 1 {
+		t.Errorf("Сброс суточного рейтинга не должен затрагивать месячный: %v", views)
+	}
+}
+
+func TestUniqueViewers(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+	ctx := context.Background()
+
+	alice := s.ViewerID(1, "203.0.113.5", "Firefox")
+	guest := s.ViewerID(0, "203.0.113.5", "Firefox")
+	views := []struct {
+		mangaID int64
+		viewer  string
+	}{
+		{1, alice}, {1, alice}, {1, alice}, {1, alice},
+		{2, alice}, {2, guest}, {2, s.ViewerID(0, "198.51.100.7", "Firefox")},
+	}
+	for _, v := range views {
+		if err := s.RecordMangaView(ctx, v.mangaID, v.viewer); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+
+	if n, err := s.GetMangaUniqueViewers(ctx, 1); err != nil || n != 1 {
+		t.Errorf("Повторные просмотры не должны увеличивать число посетителей: %d, %v", n, err)
+	}
+	if n, err := s.GetMangaUniqueViewers(ctx, 2); err != nil || n != 3 {
+		t.Errorf("Ожидалось 3 посетителя манги 2, получено %d, %v", n, err)
+	}
+
+	for _, period := range []string{"day", "week", "all"} {
+		entries, err := s.GetTopManga(ctx, period, MetricUnique, 10)
+		if err != nil {
+			t.Fatalf("Ошибка получения рейтинга %s: %v", period, err)
+		}
+		want := []TopMangaEntry{{MangaID: 2, Views: 3, UniqueViewers: 3}, {MangaID: 1, Views: 4, UniqueViewers: 1}}
+		if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+			t.Errorf("Неожиданный рейтинг по посетителям за %s: %v", period, entries)
+		}
+	}
+
+	// В следующий час тот же посетитель снова учитывается в суточном окне,
+	// но не в общем счетчике.
+	now = now.Add(time.Hour)
+	if err := s.RecordMangaView(ctx, 1, alice); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+	if n, _ := s.GetMangaUniqueViewers(ctx, 1); n != 1 {
+		t.Errorf("Ожидался 1 посетитель манги 1, получено %d", n)
+	}
+	entries, err := s.GetTopManga(ctx, "all", MetricUnique, 1)
+	if err != nil || len(entries) != 1 || entries[0].MangaID != 2 {
+		t.Errorf("Неожиданный общий рейтинг по посетителям: %v, %v", entries, err)
+	}
+}
+
+func TestViewerID(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	s := newTestService(cache.NewMemoryCache(), &now)
+	s.ViewerSecret = []byte("secret")
+
+	if s.ViewerID(5, "203.0.113.5", "Firefox") != s.ViewerID(5, "198.51.100.7", "Chrome") {
+		t.Error("Идентификатор пользователя не должен зависеть от адреса и браузера")
+	}
+	guest := s.ViewerID(0, "203.0.113.5", "Firefox")
+	if guest == s.ViewerID(0, "203.0.113.5", "Chrome") {
+		t.Error("Анонимные посетители с разными браузерами должны различаться")
+	}
+	if guest == s.ViewerID(5, "203.0.113.5", "Firefox") {
+		t.Error("Анонимный посетитель не должен совпадать с пользователем")
+	}
+
+	now = now.Add(10 * time.Hour)
+	if s.ViewerID(0, "203.0.113.5", "Firefox") != guest {
+		t.Error("В течение суток идентификатор посетителя не должен меняться")
+	}
+	now = now.Add(2 * time.Hour)
+	if s.ViewerID(0, "203.0.113.5", "Firefox") == guest {
+		t.Error("Идентификатор анонимного посетителя должен меняться каждые сутки")
+	}
+
+	other := newTestService(cache.NewMemoryCache(), &now)
+	if other.ViewerID(0, "203.0.113.5", "Firefox") == s.ViewerID(0, "203.0.113.5", "Firefox") {
+		t.Error("Без секрета идентификатор посетителя нельзя вычислить")
+	}
+}
+
+func TestRebuildAfterFlush(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Ошибка открытия in-memory базы: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	history := sqlite.NewViewHistoryRepository(conn, logger)
+	ctx := context.Background()
+
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+
+	record := func(chapterID, mangaID int64, viewer string) {
+		t.Helper()
+		if err := s.RecordChapterView(ctx, chapterID, mangaID, viewer); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+	rollup := func() {
+		t.Helper()
+		stats, err := s.DailyViews(ctx, now)
+		if err != nil {
+			t.Fatalf("Ошибка получения суточных просмотров: %v", err)
+		}
+		if err = history.Save(stats); err != nil {
+			t.Fatalf("Ошибка сохранения истории: %v", err)
+		}
+	}
+
+	record(10, 1, "a")
+	record(10, 1, "a")
+	record(11, 1, "b")
+	record(20, 2, "a")
+	rollup()
+	now = now.Add(24 * time.Hour)
+	record(20, 2, "b")
+	record(20, 2, "c")
+	rollup()
+
+	days, err := history.List(models.ViewManga, 1, now.AddDate(0, 0, -7), now.AddDate(0, 0, 1))
+	if err != nil || len(days) != 1 || days[0].Views != 3 || days[0].UniqueViewers != 2 ||
+		!days[0].Day.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Неожиданная история манги 1: %+v, %v", days, err)
+	}
+
+	// После очистки Redis повторный перенос не затирает историю.
+	c = cache.NewMemoryCache()
+	c.SetClock(func() time.Time { return now })
+	s = newTestService(c, &now)
+	rollup()
+	totals, err := history.Totals(models.ViewManga)
+	if err != nil || totals[1] != 3 || totals[2] != 3 {
+		t.Fatalf("Неожиданные суммы просмотров: %v, %v", totals, err)
+	}
+
+	if err = s.Rebuild(ctx, history); err != nil {
+		t.Fatalf("Ошибка восстановления: %v", err)
+	}
+	if views, _ := s.GetMangaView(ctx, 2); views != 3 {
+		t.Errorf("Ожидалось 3 просмотра манги 2, получено %d", views)
+	}
+	if views := topViews(t, s, "all"); views[1] != 3 || views[2] != 3 {
+		t.Errorf("Неожиданный рейтинг за все время: %v", views)
+	}
+	if views := topViews(t, s, "week"); views[1] != 3 || views[2] != 3 {
+		t.Errorf("Неожиданный рейтинг за неделю: %v", views)
+	}
+	entries, err := s.GetTopManga(ctx, "week", MetricUnique, 10)
+	if err != nil || len(entries) != 2 || entries[0].MangaID != 2 || entries[0].UniqueViewers != 3 {
+		t.Errorf("Неожиданный рейтинг по посетителям за неделю: %v, %v", entries, err)
+	}
+
+	// Повторное восстановление не удваивает просмотры.
+	if err = s.Rebuild(ctx, history); err != nil {
+		t.Fatalf("Ошибка восстановления: %v", err)
+	}
+	if views := topViews(t, s, "all"); views[2] != 3 {
+		t.Errorf("Повторное восстановление изменило рейтинг: %v", views)
+	}
+
+	chapters, err := history.List(models.ViewChapter, 0, now, now.AddDate(0, 0, 1))
+	if err != nil || len(chapters) != 1 || chapters[0].EntityID != 20 || chapters[0].Views != 2 {
+		t.Errorf("Неожиданная история глав: %+v, %v", chapters, err)
+	}
+}
+
+func TestBuildSeries(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	days := []*models.DailyViews{
+		{Day: day(3), Views: 1, UniqueViewers: 1},
+		{Day: day(4), Views: 2, UniqueViewers: 1},
+		{Day: day(12), Views: 5, UniqueViewers: 4},
+	}
+
+	points := BuildSeries(days, day(3), day(12), IntervalWeek)
+	want := []ViewsPoint{
+		{Date: "2024-02-26", Views: 1, UniqueViewers: 1},
+		{Date: "2024-03-04", Views: 2, UniqueViewers: 1},
+		{Date: "2024-03-11", Views: 5, UniqueViewers: 4},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("Ожидалось %v, получено %v", want, points)
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("Ожидалось %v, получено %v", want, points)
+			break
+		}
+	}
+
+	if points = BuildSeries(days, day(4), day(5), IntervalDay); len(points) != 2 || points[0].Views != 2 || points[1].Views != 0 {
+		t.Errorf("Неожиданный ряд по дням: %v", points)
+	}
+	if points = BuildSeries(days, day(1), day(31), IntervalMonth); len(points) != 1 || points[0].Date != "2024-03-01" || points[0].Views != 8 {
+		t.Errorf("Неожиданный ряд по месяцам: %v", points)
+	}
+}
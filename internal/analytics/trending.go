@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// trendingKeyPrefix — трендовый рейтинг манги, например
+	// ranking:manga:trending:86400:20; в ключе указаны период полураспада
+	// в секундах и номер эпохи.
+	trendingKeyPrefix = "ranking:manga:trending:"
+	// trendingEpochHalfLives — длина эпохи в периодах полураспада. Вес
+	// просмотра растет внутри эпохи до 2^32, что далеко от переполнения
+	// float64 и сохраняет точность сложения.
+	trendingEpochHalfLives = 32
+)
+
+// trending описывает эпоху трендового рейтинга, в которую входит момент
+// времени. Вместо того чтобы уменьшать счета всех манг со временем, каждый
+// просмотр добавляет вес 2^((t - начало эпохи) / halfLife), растущий
+// с той же скоростью, с какой должны затухать прежние просмотры; порядок
+// манг от этого не меняется, а затухшие счета на момент now получаются
+// умножением на 2^(-(now - начало эпохи) / halfLife). Чтобы веса не росли
+// неограниченно, рейтинг каждой эпохи хранится в своем ключе.
+type trending struct {
+	halfLife time.Duration
+	epoch    int64
+	start    time.Time
+}
+
+func newTrending(halfLife time.Duration, t time.Time) trending {
+	length := int64(halfLife) * trendingEpochHalfLives
+	epoch := t.UnixNano() / length
+	return trending{halfLife: halfLife, epoch: epoch, start: time.Unix(0, epoch*length)}
+}
+
+// key возвращает ключ рейтинга эпохи со сдвигом offset относительно
+// текущей: 0 — текущая, -1 — предыдущая.
+func (t trending) key(offset int64) string {
+	return fmt.Sprintf("%s%d:%d", trendingKeyPrefix, int64(t.halfLife/time.Second), t.epoch+offset)
+}
+
+// weight возвращает вес просмотра в момент at в счетах текущей эпохи.
+func (t trending) weight(at time.Time) float64 {
+	return math.Exp2(float64(at.Sub(t.start)) / float64(t.halfLife))
+}
+
+// ttl — время жизни ключа эпохи после записи: рейтинг предыдущей эпохи
+// учитывается до конца текущей, а затем его счета затухают в 2^32 раз и
+// им можно пренебречь.
+func (t trending) ttl() time.Duration {
+	return 2 * trendingEpochHalfLives * t.halfLife
+}
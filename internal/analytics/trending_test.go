@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"context"
+	"manga-reader/internal/cache"
+	"testing"
+	"time"
+)
+
+func recordViews(t *testing.T, s *AnalyticsService, mangaID int64, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := s.RecordMangaView(context.Background(), mangaID, ""); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+}
+
+func topEntries(t *testing.T, s *AnalyticsService, period string) []TopMangaEntry {
+	t.Helper()
+	entries, err := s.GetTopManga(context.Background(), period, MetricViews, 10)
+	if err != nil {
+		t.Fatalf("Ошибка получения рейтинга %s: %v", period, err)
+	}
+	return entries
+}
+
+func TestTrendingDecay(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+	s.TrendingHalfLife = time.Hour
+
+	// Четыре просмотра за два периода полураспада затухают до одного и
+	// уступают двум свежим.
+	recordViews(t, s, 1, 4)
+	now = now.Add(2 * time.Hour)
+	recordViews(t, s, 2, 2)
+
+	entries := topEntries(t, s, "trending")
+	if len(entries) != 2 || entries[0].MangaID != 2 || entries[1].MangaID != 1 {
+		t.Fatalf("Неожиданный трендовый рейтинг: %+v", entries)
+	}
+	if !almostEqual(entries[0].Score, 2) || !almostEqual(entries[1].Score, 1) {
+		t.Errorf("Неожиданные счета: %+v", entries)
+	}
+	if entries[0].Views != 2 || entries[1].Views != 4 {
+		t.Errorf("Неожиданные просмотры за сутки: %+v", entries)
+	}
+
+	s.TrendingHalfLife = 0
+	if entries := topEntries(t, s, "trending"); len(entries) != 0 {
+		t.Errorf("Без периода полураспада рейтинг должен быть пуст: %+v", entries)
+	}
+}
+
+func TestTrendingAcrossEpochs(t *testing.T) {
+	c := cache.NewMemoryCache()
+	halfLife := time.Hour
+	// Последний час эпохи: следующие просмотры попадут уже в новую.
+	now := newTrending(halfLife, time.Now()).start.Add(trendingEpochHalfLives*halfLife - time.Hour)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+	s.TrendingHalfLife = halfLife
+
+	recordViews(t, s, 1, 8)
+	now = now.Add(2 * time.Hour)
+	recordViews(t, s, 2, 1)
+	if newTrending(halfLife, now).epoch == newTrending(halfLife, now.Add(-2*time.Hour)).epoch {
+		t.Fatal("Просмотры должны попасть в разные эпохи")
+	}
+
+	entries := topEntries(t, s, "trending")
+	if len(entries) != 2 || entries[0].MangaID != 1 {
+		t.Fatalf("Неожиданный трендовый рейтинг: %+v", entries)
+	}
+	if !almostEqual(entries[0].Score, 2) || !almostEqual(entries[1].Score, 1) {
+		t.Errorf("Неожиданные счета: %+v", entries)
+	}
+}
+
+func TestRising(t *testing.T) {
+	c := cache.NewMemoryCache()
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	c.SetClock(func() time.Time { return now })
+	s := newTestService(c, &now)
+
+	recordViews(t, s, 1, 10)
+	recordViews(t, s, 2, 1)
+	now = now.Add(24 * time.Hour)
+	recordViews(t, s, 1, 5)
+	recordViews(t, s, 2, 4)
+	recordViews(t, s, 3, 2)
+
+	entries := topEntries(t, s, "rising")
+	if len(entries) != 2 {
+		t.Fatalf("Ожидались две манги с приростом, получено %+v", entries)
+	}
+	want := []TopMangaEntry{
+		{MangaID: 2, Views: 4, Score: 3, Previous: 1},
+		{MangaID: 3, Views: 2, Score: 2},
+	}
+	for i, e := range entries {
+		w := want[i]
+		if e.MangaID != w.MangaID || e.Views != w.Views || e.Previous != w.Previous || !almostEqual(e.Score, w.Score) {
+			t.Errorf("Позиция %d: ожидалось %+v, получено %+v", i, w, e)
+		}
+	}
+}
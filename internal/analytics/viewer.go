@@ -0,0 +1,25 @@
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// ViewerID возвращает идентификатор посетителя для подсчета уникальных
+// просмотров: ID пользователя, а для анонимных запросов — HMAC адреса и
+// User-Agent. Ключ HMAC выводится из ViewerSecret и текущих суток UTC:
+// без секрета адрес нельзя подобрать перебором, а идентификаторы одного
+// посетителя за разные сутки не связаны между собой.
+func (s *AnalyticsService) ViewerID(userID int64, ip, userAgent string) string {
+	if userID > 0 {
+		return "u:" + strconv.FormatInt(userID, 10)
+	}
+	dayKey := hmac.New(sha256.New, s.ViewerSecret)
+	dayKey.Write([]byte(s.now().UTC().Format(time.DateOnly)))
+	mac := hmac.New(sha256.New, dayKey.Sum(nil))
+	mac.Write([]byte(ip + "\x00" + userAgent))
+	return "f:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -3,6 +3,7 @@ package apperror
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type AppError struct {
@@ -11,6 +12,8 @@ type AppError struct {
 	Message    string `json:"message"`
 	Err        error  `json:"-"`
 	Details    any    `json:"details,omitempty"`
+	// RetryAfter задает заголовок Retry-After для ответов 429.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -27,8 +30,12 @@ func (e *AppError) Unwrap() error {
 const (
 	ErrBadRequest          = "BAD_REQUEST"
 	ErrUnauthorized        = "UNAUTHORIZED"
+	ErrForbidden           = "FORBIDDEN"
 	ErrNotFound            = "NOT_FOUND"
+	ErrConflict            = "CONFLICT"
+	ErrTooManyRequests     = "TOO_MANY_REQUESTS"
 	ErrInternalServerError = "INTERNAL_SERVER_ERROR"
+	ErrServiceUnavailable  = "SERVICE_UNAVAILABLE"
 	ErrValidation          = "VALIDATION_ERROR"
 	ErrDatabaseError       = "DATABASE_ERROR"
 )
@@ -51,6 +58,15 @@ func NewUnauthorizedError(msg string, err error) *AppError {
 	}
 }
 
+func NewForbiddenError(msg string, err error) *AppError {
+	return &AppError{
+		StatusCode: http.StatusForbidden,
+		Code:       ErrForbidden,
+		Message:    msg,
+		Err:        err,
+	}
+}
+
 func NewNotFoundError(msg string, err error) *AppError {
 	return &AppError{
 		StatusCode: http.StatusNotFound,
@@ -60,6 +76,24 @@ func NewNotFoundError(msg string, err error) *AppError {
 	}
 }
 
+func NewConflictError(msg string, err error) *AppError {
+	return &AppError{
+		StatusCode: http.StatusConflict,
+		Code:       ErrConflict,
+		Message:    msg,
+		Err:        err,
+	}
+}
+
+func NewTooManyRequestsError(msg string, retryAfter time.Duration) *AppError {
+	return &AppError{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       ErrTooManyRequests,
+		Message:    msg,
+		RetryAfter: retryAfter,
+	}
+}
+
 func NewInternalServerError(msg string, err error) *AppError {
 	return &AppError{
 		StatusCode: http.StatusInternalServerError,
@@ -69,6 +103,15 @@ func NewInternalServerError(msg string, err error) *AppError {
 	}
 }
 
+func NewServiceUnavailableError(msg string, err error) *AppError {
+	return &AppError{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       ErrServiceUnavailable,
+		Message:    msg,
+		Err:        err,
+	}
+}
+
 func NewDatabaseError(msg string, err error) *AppError {
 	return &AppError{
 		StatusCode: http.StatusInternalServerError,
@@ -0,0 +1,99 @@
+// Package audit записывает в журнал изменения каталога и пользователей:
+// кто, когда и с какого адреса изменил сущность и какие поля изменились.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/db"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+)
+
+// Recorder добавляет записи в журнал аудита. Ошибки записи только
+// логируются: сбой журнала не должен отменять уже выполненное изменение.
+// Nil-получатель ничего не записывает.
+type Recorder struct {
+	repo   db.AuditRepository
+	logger *slog.Logger
+}
+
+func NewRecorder(repo db.AuditRepository, logger *slog.Logger) *Recorder {
+	return &Recorder{repo: repo, logger: logger}
+}
+
+// Record записывает изменение сущности, выполненное запросом r. before и
+// after — состояние сущности до и после изменения; при создании before,
+// а при удалении after равны nil. В журнал попадают только поля,
+// сериализуемые в JSON, поэтому скрытые поля вроде хеша пароля не сохраняются.
+func (rec *Recorder) Record(r *http.Request, action models.AuditAction, entityType models.AuditEntity, entityID int64, before, after any) {
+	if rec == nil {
+		return
+	}
+	changes, err := Diff(before, after)
+	if err != nil {
+		rec.logger.Error("Ошибка вычисления изменений для журнала аудита",
+			"action", action, "entity_type", entityType, "entity_id", entityID, "err", err)
+		return
+	}
+	actorID, _ := auth.UserIDFrom(r.Context())
+	entry := &models.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+		RequestID:  middleware.RequestIDFrom(r.Context()),
+		IP:         middleware.ClientIP(r),
+	}
+	if err = rec.repo.Record(entry); err != nil {
+		rec.logger.Error("Ошибка записи в журнал аудита",
+			"action", action, "entity_type", entityType, "entity_id", entityID, "err", err)
+	}
+}
+
+// Diff сравнивает JSON-представления двух состояний сущности и возвращает
+// изменившиеся поля верхнего уровня. nil означает отсутствие состояния.
+func Diff(before, after any) (map[string]models.AuditChange, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.AuditChange{}
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = models.AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = models.AuditChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+// fields возвращает поля JSON-представления v. json.Marshal пишет без
+// пробелов, поэтому значения полей можно сравнивать побайтно.
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"manga-reader/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyPrefix = "mrk_"
+	// apiKeyDisplayLength — длина начала ключа, которое хранится открыто,
+	// чтобы пользователь мог отличать ключи в списке.
+	apiKeyDisplayLength = 12
+	// apiKeyTouchInterval ограничивает частоту записи last_used_at.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyStore — хранилище API-ключей, которое использует AuthMiddleware.
+type APIKeyStore interface {
+	GetByHash(hash string) (*models.APIKey, error)
+	TouchLastUsed(id int64, at time.Time) error
+}
+
+var (
+	apiKeyStore APIKeyStore
+
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// SetAPIKeyStore включает аутентификацию по API-ключам.
+func SetAPIKeyStore(store APIKeyStore) {
+	apiKeyStore = store
+}
+
+// GenerateAPIKey создает новый ключ и возвращает его вместе с открытым
+// префиксом и хешем для хранения. Сам ключ показывается только один раз.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + token
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает хеш ключа, под которым он хранится в БД.
+func HashAPIKey(key string) string {
+	return hashToken(key)
+}
+
+// apiKeyFromRequest извлекает ключ из X-API-Key или Authorization: ApiKey.
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) == 2 && parts[0] == "ApiKey" {
+		return parts[1], true
+	}
+	return "", false
+}
+
+// AuthenticateAPIKey проверяет ключ и отмечает время его использования.
+func AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if apiKeyStore == nil || !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := apiKeyStore.GetByHash(HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	current := time.Now()
+	if apiKey.Expired(current) {
+		return nil, ErrInvalidAPIKey
+	}
+	if apiKey.LastUsedAt == nil || current.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		// Ошибка записи времени использования не должна мешать запросу.
+		_ = apiKeyStore.TouchLastUsed(apiKey.ID, current.UTC())
+	}
+	return apiKey, nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"database/sql"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memoryAPIKeyStore struct {
+	keys    map[string]*models.APIKey
+	touched map[int64]time.Time
+}
+
+func (s *memoryAPIKeyStore) GetByHash(hash string) (*models.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (s *memoryAPIKeyStore) TouchLastUsed(id int64, at time.Time) error {
+	s.touched[id] = at
+	return nil
+}
+
+func newTestAPIKey(t *testing.T, store *memoryAPIKeyStore, role models.Role, expiresAt *time.Time, scopes ...models.Scope) string {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Ошибка генерации ключа: %v", err)
+	}
+	store.keys[hash] = &models.APIKey{
+		ID:        int64(len(store.keys) + 1),
+		UserID:    42,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		OwnerRole: role,
+	}
+	return key
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	store := &memoryAPIKeyStore{keys: map[string]*models.APIKey{}, touched: map[int64]time.Time{}}
+	SetAPIKeyStore(store)
+	defer SetAPIKeyStore(nil)
+
+	key := newTestAPIKey(t, store, models.RoleUploader, nil, models.ScopeUploadPages)
+	past := time.Now().Add(-time.Hour)
+	expired := newTestAPIKey(t, store, models.RoleUploader, &past, models.ScopeUploadPages)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) })
+	tests := []struct {
+		name    string
+		handler http.Handler
+		header  string
+		value   string
+		want    int
+	}{
+		{"X-API-Key", AuthMiddleware(ok), "X-API-Key", key, http.StatusOK},
+		{"Authorization ApiKey", AuthMiddleware(ok), "Authorization", "ApiKey " + key, http.StatusOK},
+		{"Неизвестный ключ", AuthMiddleware(ok), "X-API-Key", "mrk_unknown", http.StatusUnauthorized},
+		{"Истекший ключ", AuthMiddleware(ok), "X-API-Key", expired, http.StatusUnauthorized},
+		{"Выданная область", RequireScope(models.RoleUploader, models.ScopeUploadPages, ok), "X-API-Key", key, http.StatusOK},
+		{"Невыданная область", RequireScope(models.RoleUploader, models.ScopeWriteManga, ok), "X-API-Key", key, http.StatusForbidden},
+		{"Недостаточная роль владельца", RequireScope(models.RoleModerator, models.ScopeUploadPages, ok), "X-API-Key", key, http.StatusForbidden},
+		{"Маршрут без области", RequireRole(models.RoleUploader, ok), "X-API-Key", key, http.StatusForbidden},
+		{"Управление учетной записью", RequireSession(ok), "X-API-Key", key, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("Ожидался статус %d, получен %d", tt.want, rr.Code)
+			}
+		})
+	}
+
+	if _, touched := store.touched[1]; !touched {
+		t.Error("Ожидалось обновление времени последнего использования ключа")
+	}
+}
@@ -6,66 +6,262 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"manga-reader/internal/apperror"
 	"manga-reader/internal/response"
+	"manga-reader/models"
 	"net/http"
 	"strings"
 	"time"
 )
 
-var JWTSecret []byte
+var keySet *KeySet
 
+// SetJWTSecret настраивает подпись токенов одним секретом HS256.
 func SetJWTSecret(secret string) {
-	JWTSecret = []byte(secret)
+	keySet, _ = NewKeySet(NewHMACKey("default", []byte(secret)))
 }
 
-func GenerateToken(userID int64) (string, error) {
+// SetKeySet задает набор ключей подписи и проверки токенов.
+func SetKeySet(ks *KeySet) {
+	keySet = ks
+}
+
+// CurrentKeySet возвращает действующий набор ключей.
+func CurrentKeySet() *KeySet {
+	return keySet
+}
+
+// Claims содержит данные, извлеченные из токена.
+type Claims struct {
+	UserID  int64
+	Role    models.Role
+	TokenID string
+	// Generation — поколение токенов пользователя на момент выпуска.
+	Generation int64
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
+}
+
+// GenerateToken выпускает токен доступа текущего поколения токенов
+// пользователя, так что он действует до следующего отзыва всех токенов.
+func GenerateToken(ctx context.Context, userID int64, role models.Role) (string, error) {
+	generation, err := tokenGeneration(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return signAccessToken(userID, role, generation)
+}
+
+func signAccessToken(userID int64, role models.Role, generation int64) (string, error) {
+	if keySet == nil {
+		return "", errors.New("signing keys are not configured")
+	}
+	if role == "" {
+		role = models.RoleReader
+	}
+	tokenID, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
+		"role":    string(role),
+		"jti":     tokenID,
+		"gen":     generation,
+		"iat":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTSecret)
+	key := keySet.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
 
-func ParseToken(tokenStr string) (int64, error) {
+func ParseClaims(tokenStr string) (*Claims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if keySet == nil {
+			return nil, errors.New("signing keys are not configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keySet.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		// Алгоритм токена обязан совпадать с алгоритмом ключа, иначе открытый
+		// ключ RSA можно было бы подсунуть как секрет HMAC.
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return JWTSecret, nil
+		return key.verifyKey, nil
 	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if uid, ok := claims["user_id"].(float64); ok {
-			return int64(uid), nil
-		}
-		return 0, errors.New("user_id not found in token")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	uid, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("user_id not found in token")
+	}
+	// Токены, выпущенные до появления ролей, считаются токенами читателя.
+	role := models.RoleReader
+	if r, ok := claims["role"].(string); ok && models.Role(r).Valid() {
+		role = models.Role(r)
+	}
+	result := &Claims{UserID: int64(uid), Role: role}
+	if jti, ok := claims["jti"].(string); ok {
+		result.TokenID = jti
+	}
+	// Токены без поколения выпущены до первого отзыва и относятся к нулевому.
+	if gen, ok := claims["gen"].(float64); ok {
+		result.Generation = int64(gen)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		result.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		result.ExpiresAt = time.Unix(int64(exp), 0)
 	}
-	return 0, errors.New("invalid token")
+	return result, nil
 }
 
+func ParseToken(tokenStr string) (int64, error) {
+	claims, err := ParseClaims(tokenStr)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// AuthMiddleware принимает токен доступа (Authorization: Bearer) или
+// API-ключ (X-API-Key либо Authorization: ApiKey) и кладет Principal в
+// контекст запроса.
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			err := apperror.NewUnauthorizedError("Отсутствует заголовок Authorization", nil)
+		principal, err := authenticate(r)
+		if err != nil {
 			response.Error(w, nil, err)
 			return
 		}
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			err := apperror.NewUnauthorizedError("Неверный формат заголовка Authorization", nil)
+		if principal == nil {
+			err := apperror.NewUnauthorizedError("Отсутствует заголовок Authorization", nil)
 			response.Error(w, nil, err)
 			return
 		}
-		userID, err := ParseToken(parts[1])
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// OptionalAuth работает как AuthMiddleware, но пропускает запросы без
+// учетных данных анонимно. Недействительные учетные данные по-прежнему
+// отклоняются, чтобы клиент узнал об истекшем токене.
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticate(r)
 		if err != nil {
-			err := apperror.NewUnauthorizedError("Неверный токен: "+err.Error(), err)
 			response.Error(w, nil, err)
 			return
 		}
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		if principal != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticate проверяет учетные данные запроса. Возвращает nil без
+// ошибки, если учетных данных нет.
+func authenticate(r *http.Request) (*Principal, *apperror.AppError) {
+	if key, ok := apiKeyFromRequest(r); ok {
+		apiKey, err := AuthenticateAPIKey(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, ErrInvalidAPIKey) {
+				return nil, apperror.NewUnauthorizedError("Недействительный API-ключ", nil)
+			}
+			return nil, apperror.NewInternalServerError("Не удалось проверить API-ключ", err)
+		}
+		return &Principal{
+			UserID:   apiKey.UserID,
+			Role:     apiKey.OwnerRole,
+			Method:   AuthMethodAPIKey,
+			APIKeyID: apiKey.ID,
+			Scopes:   apiKey.Scopes,
+		}, nil
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, apperror.NewUnauthorizedError("Неверный формат заголовка Authorization", nil)
+	}
+	claims, err := ParseClaims(parts[1])
+	if err != nil {
+		return nil, apperror.NewUnauthorizedError("Неверный токен: "+err.Error(), err)
+	}
+	revoked, err := IsRevoked(r.Context(), claims)
+	if err != nil {
+		return nil, apperror.NewInternalServerError("Не удалось проверить токен", err)
+	}
+	if revoked {
+		return nil, apperror.NewUnauthorizedError("Токен отозван", nil)
+	}
+	return &Principal{
+		UserID:         claims.UserID,
+		Role:           claims.Role,
+		Method:         AuthMethodToken,
+		TokenID:        claims.TokenID,
+		TokenExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+// RequireSession пропускает только запросы с токеном доступа пользователя.
+// Используется для управления учетной записью и ключами, чтобы утекший
+// API-ключ не позволял выпустить новые ключи или сменить пароль.
+func RequireSession(next http.Handler) http.Handler {
+	return AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, _ := PrincipalFrom(r.Context()); principal.Method == AuthMethodAPIKey {
+			err := apperror.NewForbiddenError("Операция недоступна по API-ключу", nil)
+			response.Error(w, nil, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// RequireRole пропускает запрос только аутентифицированным пользователям,
+// роль которых не ниже указанной. Запросы по API-ключам отклоняются:
+// для них маршрут должен явно указать область через RequireScope.
+func RequireRole(role models.Role, next http.Handler) http.Handler {
+	return RequireScope(role, "", next)
+}
+
+// RequireScope работает как RequireRole, но дополнительно пропускает
+// API-ключи, которым выдана указанная область. Роль владельца ключа
+// проверяется в любом случае.
+func RequireScope(role models.Role, scope models.Scope, next http.Handler) http.Handler {
+	return AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFrom(r.Context())
+		if !principal.HasRole(role) {
+			err := apperror.NewForbiddenError("Недостаточно прав для выполнения операции", nil)
+			response.Error(w, nil, err)
+			return
+		}
+		if principal.Method == AuthMethodAPIKey {
+			if scope == "" {
+				err := apperror.NewForbiddenError("Операция недоступна по API-ключу", nil)
+				response.Error(w, nil, err)
+				return
+			}
+			if !principal.HasScope(scope) {
+				err := apperror.NewForbiddenError("API-ключу не выдана область "+string(scope), nil)
+				response.Error(w, nil, err)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"log/slog"
+	"manga-reader/models"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -13,7 +14,7 @@ import (
 func TestGenerateAndParseToken(t *testing.T) {
 	SetJWTSecret("test-secret")
 
-	token, err := GenerateToken(42)
+	token, err := GenerateToken(context.Background(), 42, models.RoleReader)
 	if err != nil {
 		t.Fatalf("Ошибка генерации токена: %v", err)
 	}
@@ -58,7 +59,7 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Errorf("Ожидался статус %d, получен %d", http.StatusUnauthorized, rr.Code)
 	}
 
-	token, _ := GenerateToken(42)
+	token, _ := GenerateToken(context.Background(), 42, models.RoleReader)
 	req, _ = http.NewRequest("GET", "/", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	rr = httptest.NewRecorder()
@@ -70,3 +71,61 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Errorf("Ожидался ответ OK, получен %s", rr.Body.String())
 	}
 }
+
+func TestParseClaimsRole(t *testing.T) {
+	SetJWTSecret("test-secret")
+
+	token, err := GenerateToken(context.Background(), 7, models.RoleModerator)
+	if err != nil {
+		t.Fatalf("Ошибка генерации токена: %v", err)
+	}
+	claims, err := ParseClaims(token)
+	if err != nil {
+		t.Fatalf("Ошибка парсинга токена: %v", err)
+	}
+	if claims.UserID != 7 {
+		t.Errorf("Ожидался user_id 7, получен %d", claims.UserID)
+	}
+	if claims.Role != models.RoleModerator {
+		t.Errorf("Ожидалась роль %q, получена %q", models.RoleModerator, claims.Role)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	SetJWTSecret("test-secret")
+
+	handler := RequireRole(models.RoleUploader, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+
+	tests := []struct {
+		name   string
+		role   models.Role
+		status int
+	}{
+		{"читатель", models.RoleReader, http.StatusForbidden},
+		{"загрузчик", models.RoleUploader, http.StatusOK},
+		{"модератор", models.RoleModerator, http.StatusOK},
+		{"администратор", models.RoleAdmin, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := GenerateToken(context.Background(), 1, tt.role)
+			req, _ := http.NewRequest("POST", "/manga", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.status {
+				t.Errorf("Ожидался статус %d, получен %d", tt.status, rr.Code)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest("POST", "/manga", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидался статус %d без токена, получен %d", http.StatusUnauthorized, rr.Code)
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey — ключ подписи или проверки токенов с идентификатором kid.
+// У ключей, загруженных только для проверки, signKey равен nil.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign сообщает, можно ли подписывать токены этим ключом.
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewHMACKey создает симметричный ключ HS256.
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{ID: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParsePrivateKeyPEM создает ключ подписи RS256 или EdDSA из закрытого ключа
+// в формате PEM. Открытая часть используется для проверки.
+func ParsePrivateKeyPEM(id, alg string, data []byte) (*SigningKey, error) {
+	switch alg {
+	case AlgRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, signKey: key, verifyKey: key.Public()}, nil
+	case AlgEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("ожидался закрытый ключ Ed25519")
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, signKey: edKey, verifyKey: edKey.Public()}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый алгоритм для PEM-ключа: %s", alg)
+	}
+}
+
+// ParsePublicKeyPEM создает ключ только для проверки подписи.
+func ParsePublicKeyPEM(id, alg string, data []byte) (*SigningKey, error) {
+	switch alg {
+	case AlgRS256:
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case AlgEdDSA:
+		key, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый алгоритм для PEM-ключа: %s", alg)
+	}
+}
+
+// KeySet хранит активный ключ подписи и набор ключей, которыми еще можно
+// проверять токены. Во время ротации старый ключ остается в наборе, пока
+// не истекут выпущенные им токены.
+type KeySet struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeySet(active *SigningKey, verifyKeys ...*SigningKey) (*KeySet, error) {
+	if active == nil || !active.CanSign() {
+		return nil, errors.New("активный ключ должен поддерживать подпись")
+	}
+	ks := &KeySet{active: active, keys: map[string]*SigningKey{active.ID: active}}
+	for _, key := range verifyKeys {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("повторяющийся идентификатор ключа: %s", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+	return ks, nil
+}
+
+// Active возвращает ключ, которым подписываются новые токены.
+func (ks *KeySet) Active() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Lookup ищет ключ по kid. Токены без kid, выпущенные до введения набора
+// ключей, проверяются активным ключом.
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if kid == "" {
+		return ks.active, true
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// Rotate делает новый ключ активным, оставляя предыдущий для проверки.
+func (ks *KeySet) Rotate(next *SigningKey) error {
+	if next == nil || !next.CanSign() {
+		return errors.New("активный ключ должен поддерживать подпись")
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[next.ID] = next
+	ks.active = next
+	return nil
+}
+
+// Remove исключает ключ из набора. Активный ключ удалить нельзя.
+func (ks *KeySet) Remove(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.active.ID == kid {
+		return errors.New("нельзя удалить активный ключ")
+	}
+	delete(ks.keys, kid)
+	return nil
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS — набор открытых ключей для других сервисов.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи набора. Симметричные ключи не публикуются.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	result := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			result.Keys = append(result.Keys, JWK{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: key.ID,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			result.Keys = append(result.Keys, JWK{
+				Kty: "OKP",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: key.ID,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(result.Keys, func(i, j int) bool { return result.Keys[i].Kid < result.Keys[j].Kid })
+	return result
+}
+
+// KeyConfig описывает источники ключей подписи.
+type KeyConfig struct {
+	Algorithm      string
+	KeyID          string
+	Secret         string
+	PrivateKeyFile string
+	// VerifyKeys — дополнительные ключи проверки в формате "kid:alg:path".
+	// Для HS256 файл содержит секрет, для RS256/EdDSA — открытый ключ PEM.
+	VerifyKeys []string
+}
+
+// LoadKeySet собирает набор ключей по конфигурации.
+func LoadKeySet(cfg KeyConfig) (*KeySet, error) {
+	kid := cfg.KeyID
+	if kid == "" {
+		kid = "default"
+	}
+
+	var active *SigningKey
+	switch cfg.Algorithm {
+	case "", AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("не задан секрет для HS256")
+		}
+		active = NewHMACKey(kid, []byte(cfg.Secret))
+	case AlgRS256, AlgEdDSA:
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("для %s необходимо указать файл закрытого ключа", cfg.Algorithm)
+		}
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("чтение закрытого ключа: %w", err)
+		}
+		if active, err = ParsePrivateKeyPEM(kid, cfg.Algorithm, data); err != nil {
+			return nil, fmt.Errorf("разбор закрытого ключа: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемый алгоритм: %s", cfg.Algorithm)
+	}
+
+	var verifyKeys []*SigningKey
+	for _, spec := range cfg.VerifyKeys {
+		parts := strings.SplitN(spec, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("некорректное описание ключа проверки: %q", spec)
+		}
+		data, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("чтение ключа %s: %w", parts[0], err)
+		}
+		var key *SigningKey
+		if parts[1] == AlgHS256 {
+			key = NewHMACKey(parts[0], []byte(strings.TrimSpace(string(data))))
+		} else if key, err = ParsePublicKeyPEM(parts[0], parts[1], data); err != nil {
+			return nil, fmt.Errorf("разбор ключа %s: %w", parts[0], err)
+		}
+		verifyKeys = append(verifyKeys, key)
+	}
+
+	return NewKeySet(active, verifyKeys...)
+}
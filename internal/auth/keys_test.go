@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"manga-reader/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Не удалось записать ключ: %v", err)
+	}
+	return path
+}
+
+func generateRSAKeyFiles(t *testing.T, dir, prefix string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Ошибка генерации RSA-ключа: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Ошибка кодирования открытого ключа: %v", err)
+	}
+	return writePEM(t, dir, prefix+".pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(t, dir, prefix+".pub.pem", "PUBLIC KEY", pubDER)
+}
+
+func generateEdKeyFile(t *testing.T, dir, prefix string) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Ошибка генерации Ed25519-ключа: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Ошибка кодирования закрытого ключа: %v", err)
+	}
+	return writePEM(t, dir, prefix+".pem", "PRIVATE KEY", der)
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	defer SetJWTSecret("test-secret")
+	dir := t.TempDir()
+	rsaPrivate, _ := generateRSAKeyFiles(t, dir, "rsa")
+	edPrivate := generateEdKeyFile(t, dir, "ed")
+
+	for _, tc := range []struct {
+		alg  string
+		file string
+	}{
+		{AlgRS256, rsaPrivate},
+		{AlgEdDSA, edPrivate},
+	} {
+		t.Run(tc.alg, func(t *testing.T) {
+			ks, err := LoadKeySet(KeyConfig{Algorithm: tc.alg, KeyID: "k1", PrivateKeyFile: tc.file})
+			if err != nil {
+				t.Fatalf("Ошибка загрузки ключей: %v", err)
+			}
+			SetKeySet(ks)
+
+			token, err := GenerateToken(context.Background(), 42, models.RoleAdmin)
+			if err != nil {
+				t.Fatalf("Ошибка генерации токена: %v", err)
+			}
+			claims, err := ParseClaims(token)
+			if err != nil {
+				t.Fatalf("Ошибка проверки токена: %v", err)
+			}
+			if claims.UserID != 42 || claims.Role != models.RoleAdmin {
+				t.Errorf("Неверные данные токена: %+v", claims)
+			}
+
+			jwks := ks.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "k1" || jwks.Keys[0].Alg != tc.alg {
+				t.Errorf("Неверный JWKS: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	defer SetJWTSecret("test-secret")
+	dir := t.TempDir()
+	oldPrivate, oldPublic := generateRSAKeyFiles(t, dir, "old")
+	newPrivate, _ := generateRSAKeyFiles(t, dir, "new")
+
+	oldSet, err := LoadKeySet(KeyConfig{Algorithm: AlgRS256, KeyID: "old", PrivateKeyFile: oldPrivate})
+	if err != nil {
+		t.Fatalf("Ошибка загрузки старого ключа: %v", err)
+	}
+	SetKeySet(oldSet)
+	oldToken, _ := GenerateToken(context.Background(), 1, models.RoleReader)
+
+	// Новый ключ подписывает, старый принимается только для проверки.
+	newSet, err := LoadKeySet(KeyConfig{
+		Algorithm:      AlgRS256,
+		KeyID:          "new",
+		PrivateKeyFile: newPrivate,
+		VerifyKeys:     []string{"old:RS256:" + oldPublic},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка загрузки нового набора: %v", err)
+	}
+	SetKeySet(newSet)
+
+	if _, err = ParseClaims(oldToken); err != nil {
+		t.Errorf("Токен старого ключа должен приниматься во время ротации: %v", err)
+	}
+	newToken, _ := GenerateToken(context.Background(), 1, models.RoleReader)
+	if _, err = ParseClaims(newToken); err != nil {
+		t.Errorf("Токен нового ключа должен приниматься: %v", err)
+	}
+	if len(newSet.JWKS().Keys) != 2 {
+		t.Errorf("Ожидалось 2 ключа в JWKS, получено %d", len(newSet.JWKS().Keys))
+	}
+
+	if err = newSet.Remove("old"); err != nil {
+		t.Fatalf("Ошибка удаления ключа: %v", err)
+	}
+	if _, err = ParseClaims(oldToken); err == nil {
+		t.Error("Токен удаленного ключа не должен приниматься")
+	}
+}
+
+func TestRejectsAlgorithmMismatch(t *testing.T) {
+	SetJWTSecret("test-secret")
+	hsToken, _ := GenerateToken(context.Background(), 1, models.RoleReader)
+
+	dir := t.TempDir()
+	private, _ := generateRSAKeyFiles(t, dir, "rsa")
+	ks, err := LoadKeySet(KeyConfig{Algorithm: AlgRS256, KeyID: "default", PrivateKeyFile: private})
+	if err != nil {
+		t.Fatalf("Ошибка загрузки ключей: %v", err)
+	}
+	SetKeySet(ks)
+	defer SetJWTSecret("test-secret")
+
+	if _, err = ParseClaims(hsToken); err == nil {
+		t.Error("Токен HS256 не должен проверяться ключом RS256")
+	}
+}
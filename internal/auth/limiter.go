@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"manga-reader/internal/cache"
+)
+
+const (
+	loginFailPrefix = "auth:login_fail:"
+	loginLockPrefix = "auth:login_lock:"
+)
+
+// LoginLimiter ограничивает подбор паролей. Неудачные попытки входа
+// считаются отдельно для имени пользователя и для IP-адреса; после
+// превышения порога ключ блокируется, и каждая следующая неудача удваивает
+// время блокировки вплоть до MaxLockout.
+type LoginLimiter struct {
+	store cache.Cache
+	now   func() time.Time
+
+	// UserThreshold — число неудач подряд для имени пользователя до блокировки.
+	UserThreshold int64
+	// IPThreshold — то же для IP-адреса; выше, так как за одним адресом
+	// может находиться много пользователей.
+	IPThreshold int64
+	// Window — время, в течение которого хранится счетчик неудач.
+	Window time.Duration
+	// BaseLockout — длительность первой блокировки.
+	BaseLockout time.Duration
+	// MaxLockout — верхняя граница длительности блокировки.
+	MaxLockout time.Duration
+}
+
+func NewLoginLimiter(store cache.Cache) *LoginLimiter {
+	return &LoginLimiter{
+		store:         store,
+		now:           time.Now,
+		UserThreshold: 5,
+		IPThreshold:   20,
+		Window:        15 * time.Minute,
+		BaseLockout:   30 * time.Second,
+		MaxLockout:    time.Hour,
+	}
+}
+
+// SetClock подменяет источник времени для тестов.
+func (l *LoginLimiter) SetClock(now func() time.Time) {
+	l.now = now
+}
+
+// Check возвращает оставшееся время блокировки по имени пользователя или
+// IP-адресу. Нулевое значение означает, что попытку входа можно выполнить.
+func (l *LoginLimiter) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, key := range l.subjects(username, ip) {
+		wait, err := l.lockedFor(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+// RegisterFailure учитывает неудачную попытку входа и при превышении порога
+// блокирует имя пользователя и/или IP-адрес. Возвращает наибольшее время
+// блокировки, установленное этим вызовом.
+func (l *LoginLimiter) RegisterFailure(ctx context.Context, username, ip string) (time.Duration, error) {
+	var lockout time.Duration
+	for _, key := range l.subjects(username, ip) {
+		count, err := l.store.Incr(ctx, loginFailPrefix+key)
+		if err != nil {
+			return 0, err
+		}
+		if err = l.store.Expire(ctx, loginFailPrefix+key, l.Window); err != nil {
+			return 0, err
+		}
+
+		threshold := l.UserThreshold
+		if strings.HasPrefix(key, "ip:") {
+			threshold = l.IPThreshold
+		}
+		if count < threshold {
+			continue
+		}
+
+		duration := l.lockoutFor(count - threshold)
+		until := l.now().Add(duration).Unix()
+		if err = l.store.Set(ctx, loginLockPrefix+key, strconv.FormatInt(until, 10), duration); err != nil {
+			return 0, err
+		}
+		// Счетчик должен пережить блокировку, иначе backoff не будет расти.
+		if err = l.store.Expire(ctx, loginFailPrefix+key, l.Window+duration); err != nil {
+			return 0, err
+		}
+		if duration > lockout {
+			lockout = duration
+		}
+	}
+	return lockout, nil
+}
+
+// RegisterSuccess сбрасывает счетчик неудач для имени пользователя после
+// успешного входа. Счетчик IP-адреса сохраняется, чтобы вход в свою учетную
+// запись не обнулял перебор чужих.
+func (l *LoginLimiter) RegisterSuccess(ctx context.Context, username string) error {
+	return l.Unlock(ctx, username)
+}
+
+// Unlock снимает блокировку с имени пользователя и сбрасывает его счетчик.
+func (l *LoginLimiter) Unlock(ctx context.Context, username string) error {
+	key := userSubject(username)
+	if err := l.store.Delete(ctx, loginLockPrefix+key); err != nil {
+		return err
+	}
+	return l.store.Delete(ctx, loginFailPrefix+key)
+}
+
+// lockoutFor вычисляет длительность блокировки: BaseLockout, удваиваемая
+// за каждую неудачу сверх порога.
+func (l *LoginLimiter) lockoutFor(excess int64) time.Duration {
+	duration := l.BaseLockout
+	for i := int64(0); i < excess && duration < l.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > l.MaxLockout {
+		duration = l.MaxLockout
+	}
+	return duration
+}
+
+func (l *LoginLimiter) lockedFor(ctx context.Context, key string) (time.Duration, error) {
+	value, err := l.store.Get(ctx, loginLockPrefix+key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, nil
+	}
+	wait := time.Unix(until, 0).Sub(l.now())
+	if wait <= 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+func (l *LoginLimiter) subjects(username, ip string) []string {
+	subjects := make([]string, 0, 2)
+	if username != "" {
+		subjects = append(subjects, userSubject(username))
+	}
+	if ip != "" {
+		subjects = append(subjects, "ip:"+ip)
+	}
+	return subjects
+}
+
+// userSubject нормализует имя пользователя, чтобы смена регистра не
+// позволяла обойти счетчик.
+func userSubject(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
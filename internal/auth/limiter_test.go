@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"manga-reader/internal/cache"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clock := func() time.Time { return now }
+	store := cache.NewMemoryCache()
+	store.SetClock(clock)
+	limiter := NewLoginLimiter(store)
+	limiter.SetClock(clock)
+	limiter.UserThreshold = 3
+	limiter.BaseLockout = 10 * time.Second
+	limiter.MaxLockout = 30 * time.Second
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		lockout, err := limiter.RegisterFailure(ctx, "alice", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Ошибка учета неудачи: %v", err)
+		}
+		if lockout != 0 {
+			t.Fatalf("Блокировка до достижения порога: %v", lockout)
+		}
+	}
+
+	if lockout, _ := limiter.RegisterFailure(ctx, "Alice", "10.0.0.2"); lockout != 10*time.Second {
+		t.Errorf("Ожидалась блокировка 10s, получено %v", lockout)
+	}
+	if wait, _ := limiter.Check(ctx, "alice", "10.0.0.3"); wait != 10*time.Second {
+		t.Errorf("Ожидалось ожидание 10s, получено %v", wait)
+	}
+
+	if lockout, _ := limiter.RegisterFailure(ctx, "alice", ""); lockout != 20*time.Second {
+		t.Errorf("Ожидалось удвоение блокировки до 20s, получено %v", lockout)
+	}
+	if lockout, _ := limiter.RegisterFailure(ctx, "alice", ""); lockout != 30*time.Second {
+		t.Errorf("Блокировка должна быть ограничена MaxLockout, получено %v", lockout)
+	}
+
+	now = now.Add(31 * time.Second)
+	if wait, _ := limiter.Check(ctx, "alice", ""); wait != 0 {
+		t.Errorf("Блокировка должна истечь, осталось %v", wait)
+	}
+}
+
+func TestLoginLimiterPerIP(t *testing.T) {
+	limiter := NewLoginLimiter(cache.NewMemoryCache())
+	limiter.IPThreshold = 2
+	ctx := context.Background()
+
+	limiter.RegisterFailure(ctx, "alice", "10.0.0.1")
+	limiter.RegisterFailure(ctx, "bob", "10.0.0.1")
+
+	if wait, _ := limiter.Check(ctx, "carol", "10.0.0.1"); wait == 0 {
+		t.Error("IP-адрес должен быть заблокирован после перебора разных имен")
+	}
+	if wait, _ := limiter.Check(ctx, "carol", "10.0.0.2"); wait != 0 {
+		t.Errorf("Другой IP-адрес не должен блокироваться, ожидание %v", wait)
+	}
+}
+
+func TestLoginLimiterUnlock(t *testing.T) {
+	limiter := NewLoginLimiter(cache.NewMemoryCache())
+	limiter.UserThreshold = 1
+	ctx := context.Background()
+
+	limiter.RegisterFailure(ctx, "alice", "")
+	if wait, _ := limiter.Check(ctx, "alice", ""); wait == 0 {
+		t.Fatal("Ожидалась блокировка")
+	}
+	if err := limiter.Unlock(ctx, "alice"); err != nil {
+		t.Fatalf("Ошибка снятия блокировки: %v", err)
+	}
+	if wait, _ := limiter.Check(ctx, "alice", ""); wait != 0 {
+		t.Errorf("Блокировка должна быть снята, ожидание %v", wait)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"manga-reader/models"
+	"time"
+)
+
+const (
+	// AuthMethodToken — запрос аутентифицирован токеном доступа JWT.
+	AuthMethodToken = "token"
+	// AuthMethodAPIKey — запрос аутентифицирован API-ключом.
+	AuthMethodAPIKey = "api_key"
+)
+
+// Principal описывает аутентифицированного отправителя запроса.
+type Principal struct {
+	UserID int64
+	Role   models.Role
+	// Method — способ аутентификации: AuthMethodToken или AuthMethodAPIKey.
+	Method string
+
+	// TokenID и TokenExpiresAt заполняются для токенов доступа.
+	TokenID        string
+	TokenExpiresAt time.Time
+
+	// APIKeyID и Scopes заполняются для API-ключей.
+	APIKeyID int64
+	Scopes   []models.Scope
+}
+
+// HasRole сообщает, покрывает ли роль пользователя указанную.
+// Безопасен для nil, что соответствует анонимному запросу.
+func (p *Principal) HasRole(role models.Role) bool {
+	return p != nil && p.Role.Includes(role)
+}
+
+// HasScope сообщает, разрешена ли область. Для токенов доступа области не
+// ограничивают права, поэтому всегда возвращается true.
+func (p *Principal) HasScope(scope models.Scope) bool {
+	if p == nil {
+		return false
+	}
+	if p.Method != AuthMethodAPIKey {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey — неэкспортируемый тип ключа контекста, исключающий
+// пересечение с ключами других пакетов.
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с данными об отправителе запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom возвращает отправителя запроса, если запрос аутентифицирован.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserIDFrom возвращает ID аутентифицированного пользователя.
+func UserIDFrom(ctx context.Context) (int64, bool) {
+	p, ok := PrincipalFrom(ctx)
+	if !ok {
+		return 0, false
+	}
+	return p.UserID, true
+}
+
+// RoleFrom возвращает роль пользователя; для анонимных запросов — пустую роль.
+func RoleFrom(ctx context.Context) models.Role {
+	if p, ok := PrincipalFrom(ctx); ok {
+		return p.Role
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptionalAuth(t *testing.T) {
+	SetJWTSecret("test-secret")
+
+	var got *Principal
+	handler := OptionalAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFrom(r.Context())
+		w.Write([]byte("OK"))
+	}))
+
+	token, err := GenerateToken(context.Background(), 7, models.RoleUploader)
+	if err != nil {
+		t.Fatalf("Ошибка генерации токена: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantUser int64
+	}{
+		{"анонимный запрос", "", http.StatusOK, 0},
+		{"действительный токен", "Bearer " + token, http.StatusOK, 7},
+		{"недействительный токен", "Bearer invalid", http.StatusUnauthorized, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.wantCode, rr.Code)
+			}
+			if tt.wantUser == 0 {
+				if got != nil {
+					t.Errorf("Ожидался анонимный запрос, получен пользователь %d", got.UserID)
+				}
+				return
+			}
+			if got == nil || got.UserID != tt.wantUser || got.Role != models.RoleUploader || got.Method != AuthMethodToken {
+				t.Errorf("Неверные данные отправителя: %+v", got)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	var anonymous *Principal
+	if anonymous.HasRole(models.RoleReader) || anonymous.HasScope(models.ScopeWriteManga) {
+		t.Error("Анонимный запрос не должен иметь прав")
+	}
+
+	session := &Principal{UserID: 1, Role: models.RoleUploader, Method: AuthMethodToken}
+	if !session.HasScope(models.ScopeWriteManga) {
+		t.Error("Области не должны ограничивать токен доступа")
+	}
+
+	key := &Principal{UserID: 1, Role: models.RoleUploader, Method: AuthMethodAPIKey, Scopes: []models.Scope{models.ScopeUploadPages}}
+	if !key.HasScope(models.ScopeUploadPages) || key.HasScope(models.ScopeWriteManga) {
+		t.Error("API-ключ должен иметь только выданные области")
+	}
+}
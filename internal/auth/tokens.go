@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"manga-reader/internal/cache"
+	"manga-reader/models"
+	"strconv"
+	"time"
+)
+
+const (
+	refreshTokenPrefix  = "auth:refresh:"
+	revokedTokenPrefix  = "auth:revoked:"
+	generationPrefix    = "auth:generation:"
+	passwordResetPrefix = "auth:reset:"
+)
+
+var (
+	// AccessTokenTTL — время жизни токена доступа.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL — время жизни токена обновления.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	// tokenStore хранит токены обновления и список отозванных токенов.
+	// По умолчанию используется хранилище в памяти процесса.
+	tokenStore cache.Cache = cache.NewMemoryCache()
+
+	// PasswordResetTTL — время жизни токена сброса пароля.
+	PasswordResetTTL = time.Hour
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidResetToken   = errors.New("invalid password reset token")
+)
+
+// SetTokenStore задает хранилище токенов, обычно Redis.
+func SetTokenStore(store cache.Cache) {
+	tokenStore = store
+}
+
+// SetTokenTTL задает время жизни токенов доступа и обновления.
+func SetTokenTTL(access, refresh time.Duration) {
+	if access > 0 {
+		AccessTokenTTL = access
+	}
+	if refresh > 0 {
+		RefreshTokenTTL = refresh
+	}
+}
+
+// TokenPair — пара токенов, выдаваемая при входе и обновлении.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type refreshRecord struct {
+	UserID int64 `json:"user_id"`
+	// Generation — поколение токенов пользователя на момент выпуска.
+	Generation int64 `json:"generation"`
+}
+
+// IssueTokens выпускает короткоживущий токен доступа и токен обновления,
+// который сохраняется на сервере.
+func IssueTokens(ctx context.Context, userID int64, role models.Role) (*TokenPair, error) {
+	generation, err := tokenGeneration(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := signAccessToken(userID, role, generation)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := json.Marshal(refreshRecord{UserID: userID, Generation: generation})
+	if err != nil {
+		return nil, err
+	}
+	if err = tokenStore.Set(ctx, refreshTokenPrefix+hashToken(refreshToken), string(record), RefreshTokenTTL); err != nil {
+		return nil, fmt.Errorf("сохранение токена обновления: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// ConsumeRefreshToken проверяет токен обновления и удаляет его, так что
+// каждый токен можно использовать только один раз. Возвращает ID владельца.
+func ConsumeRefreshToken(ctx context.Context, refreshToken string) (int64, error) {
+	key := refreshTokenPrefix + hashToken(refreshToken)
+	// Чтение и удаление выполняются одной командой, иначе два параллельных
+	// запроса успели бы прочитать токен до его удаления.
+	data, err := tokenStore.GetDel(ctx, key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrInvalidRefreshToken
+		}
+		return 0, err
+	}
+
+	var record refreshRecord
+	if err = json.Unmarshal([]byte(data), &record); err != nil {
+		return 0, ErrInvalidRefreshToken
+	}
+
+	generation, err := tokenGeneration(ctx, record.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if record.Generation < generation {
+		return 0, ErrInvalidRefreshToken
+	}
+
+	return record.UserID, nil
+}
+
+// RevokeRefreshToken удаляет токен обновления из хранилища.
+func RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	return tokenStore.Delete(ctx, refreshTokenPrefix+hashToken(refreshToken))
+}
+
+// RevokeAccessToken добавляет токен доступа в список отозванных до момента
+// его естественного истечения.
+func RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return tokenStore.Set(ctx, revokedTokenPrefix+tokenID, "1", ttl)
+}
+
+// RevokeAllTokens отзывает все выпущенные токены пользователя — «выход со
+// всех устройств». Токены несут поколение, в котором выпущены, а отзыв
+// начинает новое, поэтому токены, выданные сразу после отзыва, действуют
+// независимо от точности часов.
+func RevokeAllTokens(ctx context.Context, userID int64) error {
+	// Счетчик хранится без срока: после сброса в ноль токены прежних
+	// поколений снова считались бы действующими.
+	_, err := tokenStore.Incr(ctx, fmt.Sprintf("%s%d", generationPrefix, userID))
+	return err
+}
+
+// IsRevoked сообщает, был ли отозван токен доступа.
+func IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.TokenID != "" {
+		revoked, err := tokenStore.Exists(ctx, revokedTokenPrefix+claims.TokenID)
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	generation, err := tokenGeneration(ctx, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return claims.Generation < generation, nil
+}
+
+// tokenGeneration возвращает текущее поколение токенов пользователя:
+// число отзывов всех его токенов.
+func tokenGeneration(ctx context.Context, userID int64) (int64, error) {
+	value, err := tokenStore.Get(ctx, fmt.Sprintf("%s%d", generationPrefix, userID))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// IssuePasswordResetToken выпускает одноразовый токен сброса пароля.
+func IssuePasswordResetToken(ctx context.Context, userID int64) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	key := passwordResetPrefix + hashToken(token)
+	if err = tokenStore.Set(ctx, key, strconv.FormatInt(userID, 10), PasswordResetTTL); err != nil {
+		return "", fmt.Errorf("сохранение токена сброса пароля: %w", err)
+	}
+	return token, nil
+}
+
+// ConsumePasswordResetToken проверяет и удаляет токен сброса пароля,
+// возвращая ID пользователя.
+func ConsumePasswordResetToken(ctx context.Context, token string) (int64, error) {
+	key := passwordResetPrefix + hashToken(token)
+	value, err := tokenStore.GetDel(ctx, key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, err
+	}
+	userID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidResetToken
+	}
+	return userID, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken хеширует токен перед сохранением, чтобы утечка хранилища
+// не раскрывала действующие токены.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
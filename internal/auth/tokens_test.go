@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"manga-reader/internal/cache"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	SetJWTSecret("test-secret")
+	SetTokenStore(cache.NewMemoryCache())
+	ctx := context.Background()
+
+	pair, err := IssueTokens(ctx, 42, models.RoleReader)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токенов: %v", err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatal("Ожидались непустые токены")
+	}
+
+	userID, err := ConsumeRefreshToken(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Ошибка использования токена обновления: %v", err)
+	}
+	if userID != 42 {
+		t.Errorf("Ожидался user_id 42, получен %d", userID)
+	}
+
+	if _, err = ConsumeRefreshToken(ctx, pair.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("Повторное использование токена обновления должно быть запрещено, получено %v", err)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	SetJWTSecret("test-secret")
+	SetTokenStore(cache.NewMemoryCache())
+
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+
+	token, _ := GenerateToken(context.Background(), 42, models.RoleReader)
+	claims, err := ParseClaims(token)
+	if err != nil {
+		t.Fatalf("Ошибка парсинга токена: %v", err)
+	}
+	if err = RevokeAccessToken(context.Background(), claims.TokenID, claims.ExpiresAt); err != nil {
+		t.Fatalf("Ошибка отзыва токена: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидался статус %d для отозванного токена, получен %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	SetJWTSecret("test-secret")
+	SetTokenStore(cache.NewMemoryCache())
+	ctx := context.Background()
+
+	pair, err := IssueTokens(ctx, 42, models.RoleReader)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токенов: %v", err)
+	}
+	claims, _ := ParseClaims(pair.AccessToken)
+
+	if err = RevokeAllTokens(ctx, 42); err != nil {
+		t.Fatalf("Ошибка отзыва всех токенов: %v", err)
+	}
+
+	revoked, err := IsRevoked(ctx, claims)
+	if err != nil {
+		t.Fatalf("Ошибка проверки отзыва: %v", err)
+	}
+	if !revoked {
+		t.Error("Токен доступа должен быть отозван")
+	}
+	if _, err = ConsumeRefreshToken(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Токен обновления должен быть отозван, получено %v", err)
+	}
+
+	// Токены, выпущенные сразу после отзыва, действуют.
+	fresh, err := IssueTokens(ctx, 42, models.RoleReader)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токенов: %v", err)
+	}
+	freshClaims, _ := ParseClaims(fresh.AccessToken)
+	if revoked, _ = IsRevoked(ctx, freshClaims); revoked {
+		t.Error("Токен, выпущенный после отзыва, должен действовать")
+	}
+	if userID, err := ConsumeRefreshToken(ctx, fresh.RefreshToken); err != nil || userID != 42 {
+		t.Errorf("Ожидался действующий токен обновления, получено %d (err=%v)", userID, err)
+	}
+
+	other, _ := IssueTokens(ctx, 7, models.RoleReader)
+	otherClaims, _ := ParseClaims(other.AccessToken)
+	if revoked, _ = IsRevoked(ctx, otherClaims); revoked {
+		t.Error("Отзыв не должен затрагивать других пользователей")
+	}
+}
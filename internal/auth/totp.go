@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew — допустимое расхождение часов клиента в шагах.
+	totpSkew = 1
+
+	totpUsedPrefix = "auth:totp_used:"
+	preAuthPrefix  = "auth:preauth:"
+)
+
+var (
+	// PreAuthTokenTTL — время, за которое нужно ввести код второго фактора.
+	PreAuthTokenTTL = 5 * time.Minute
+	// MaxPreAuthAttempts — число неверных кодов, после которого токен
+	// предварительной аутентификации аннулируется.
+	MaxPreAuthAttempts = 5
+
+	ErrInvalidPreAuthToken = errors.New("invalid pre-auth token")
+
+	// now — источник времени для проверки кодов TOTP и срока действия
+	// токенов предварительной аутентификации.
+	now = time.Now
+
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// SetClock подменяет источник времени для TOTP и токенов предварительной
+// аутентификации, чтобы тесты могли вычислять коды для заданного момента
+// и проверять истечение токенов.
+func SetClock(clock func() time.Time) {
+	if clock == nil {
+		clock = time.Now
+	}
+	now = clock
+}
+
+// GenerateTOTPSecret создает случайный секрет в base32, как его ожидают
+// приложения-аутентификаторы.
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPURI формирует otpauth:// URI для QR-кода.
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	params := url.Values{}
+	params.Set("secret", secret)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(totpDigits))
+	params.Set("period", strconv.Itoa(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPCode вычисляет код для момента t по RFC 6238.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCodeAt(secret, t.Unix()/int64(totpPeriod.Seconds()))
+}
+
+func totpCodeAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("некорректный секрет TOTP: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// VerifyTOTP проверяет код с учетом расхождения часов. Каждый шаг можно
+// использовать только один раз, чтобы перехваченный код нельзя было
+// повторить.
+func VerifyTOTP(ctx context.Context, userID int64, secret, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return false, nil
+	}
+	current := now().Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totpCodeAt(secret, step)
+		if err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		key := fmt.Sprintf("%s%d:%d", totpUsedPrefix, userID, step)
+		used, err := tokenStore.Exists(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if used {
+			return false, nil
+		}
+		if err = tokenStore.Set(ctx, key, "1", totpPeriod*(2*totpSkew+1)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// GenerateRecoveryCodes создает n кодов восстановления и их хеши для
+// хранения в БД. Сами коды показываются пользователю один раз.
+func GenerateRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 5)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode хеширует код восстановления, не различая регистр и дефисы.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashToken(normalized)
+}
+
+// MatchRecoveryCode ищет код среди хешей и возвращает оставшиеся хеши,
+// если код найден.
+func MatchRecoveryCode(hashes []string, code string) ([]string, bool) {
+	hashed := HashRecoveryCode(code)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hashed)) == 1 {
+			remaining := append(append([]string(nil), hashes[:i]...), hashes[i+1:]...)
+			return remaining, true
+		}
+	}
+	return hashes, false
+}
+
+type preAuthRecord struct {
+	UserID    int64 `json:"user_id"`
+	Attempts  int   `json:"attempts"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// IssuePreAuthToken выпускает короткоживущий токен, подтверждающий, что
+// пароль уже проверен и осталось ввести второй фактор.
+func IssuePreAuthToken(ctx context.Context, userID int64) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	record := preAuthRecord{UserID: userID, ExpiresAt: now().Add(PreAuthTokenTTL).Unix()}
+	if err = savePreAuth(ctx, hashToken(token), record); err != nil {
+		return "", fmt.Errorf("сохранение токена предварительной аутентификации: %w", err)
+	}
+	return token, nil
+}
+
+// PreAuthUser возвращает ID пользователя, которому выдан токен.
+func PreAuthUser(ctx context.Context, token string) (int64, error) {
+	record, err := loadPreAuth(ctx, hashToken(token))
+	if err != nil {
+		return 0, err
+	}
+	return record.UserID, nil
+}
+
+// FailPreAuthToken учитывает неверный код и аннулирует токен после
+// MaxPreAuthAttempts попыток.
+func FailPreAuthToken(ctx context.Context, token string) error {
+	key := hashToken(token)
+	record, err := loadPreAuth(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPreAuthToken) {
+			return nil
+		}
+		return err
+	}
+	record.Attempts++
+	if record.Attempts >= MaxPreAuthAttempts {
+		return tokenStore.Delete(ctx, preAuthPrefix+key)
+	}
+	return savePreAuth(ctx, key, *record)
+}
+
+// RevokePreAuthToken удаляет токен после успешного входа.
+func RevokePreAuthToken(ctx context.Context, token string) error {
+	return tokenStore.Delete(ctx, preAuthPrefix+hashToken(token))
+}
+
+func savePreAuth(ctx context.Context, key string, record preAuthRecord) error {
+	ttl := time.Unix(record.ExpiresAt, 0).Sub(now())
+	if ttl <= 0 {
+		return tokenStore.Delete(ctx, preAuthPrefix+key)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tokenStore.Set(ctx, preAuthPrefix+key, string(data), ttl)
+}
+
+func loadPreAuth(ctx context.Context, key string) (*preAuthRecord, error) {
+	data, err := tokenStore.Get(ctx, preAuthPrefix+key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidPreAuthToken
+		}
+		return nil, err
+	}
+	var record preAuthRecord
+	if err = json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, ErrInvalidPreAuthToken
+	}
+	// Срок действия проверяется и здесь: время жизни ключа в хранилище
+	// отсчитывается по его собственным часам.
+	if !now().Before(time.Unix(record.ExpiresAt, 0)) {
+		return nil, ErrInvalidPreAuthToken
+	}
+	return &record, nil
+}
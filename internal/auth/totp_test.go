@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"manga-reader/internal/cache"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из RFC 6238 ("12345678901234567890") в base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		got, err := TOTPCode(rfcSecret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("Ошибка вычисления кода: %v", err)
+		}
+		if got != want {
+			t.Errorf("Для t=%d ожидался код %s, получен %s", ts, want, got)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	SetTokenStore(cache.NewMemoryCache())
+	current := time.Unix(1_700_000_000, 0)
+	SetClock(func() time.Time { return current })
+	defer SetClock(nil)
+	ctx := context.Background()
+
+	previous, _ := TOTPCode(rfcSecret, current.Add(-30*time.Second))
+	if ok, err := VerifyTOTP(ctx, 1, rfcSecret, previous); err != nil || !ok {
+		t.Errorf("Код предыдущего шага должен приниматься: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := VerifyTOTP(ctx, 1, rfcSecret, previous); ok {
+		t.Error("Повторное использование кода должно быть запрещено")
+	}
+
+	stale, _ := TOTPCode(rfcSecret, current.Add(-2*time.Minute))
+	if ok, _ := VerifyTOTP(ctx, 1, rfcSecret, stale); ok {
+		t.Error("Устаревший код не должен приниматься")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("Ошибка генерации кодов: %v", err)
+	}
+	if len(codes) != 3 || len(hashes) != 3 {
+		t.Fatalf("Ожидалось 3 кода, получено %d", len(codes))
+	}
+
+	remaining, ok := MatchRecoveryCode(hashes, strings.ToUpper(codes[1]))
+	if !ok {
+		t.Fatal("Код восстановления должен подходить без учета регистра")
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Использованный код должен удаляться, осталось %d", len(remaining))
+	}
+	if _, ok = MatchRecoveryCode(remaining, codes[1]); ok {
+		t.Error("Код восстановления должен быть одноразовым")
+	}
+}
+
+func TestPreAuthTokenAttempts(t *testing.T) {
+	SetTokenStore(cache.NewMemoryCache())
+	ctx := context.Background()
+
+	token, err := IssuePreAuthToken(ctx, 7)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токена: %v", err)
+	}
+	if userID, err := PreAuthUser(ctx, token); err != nil || userID != 7 {
+		t.Fatalf("Ожидался user_id 7, получено %d (%v)", userID, err)
+	}
+
+	for i := 0; i < MaxPreAuthAttempts; i++ {
+		if err = FailPreAuthToken(ctx, token); err != nil {
+			t.Fatalf("Ошибка учета попытки: %v", err)
+		}
+	}
+	if _, err = PreAuthUser(ctx, token); err != ErrInvalidPreAuthToken {
+		t.Errorf("Токен должен аннулироваться после %d попыток, получено %v", MaxPreAuthAttempts, err)
+	}
+}
+
+func TestPreAuthTokenExpiry(t *testing.T) {
+	SetTokenStore(cache.NewMemoryCache())
+	current := time.Unix(1700000000, 0)
+	SetClock(func() time.Time { return current })
+	t.Cleanup(func() { SetClock(nil) })
+	ctx := context.Background()
+
+	token, err := IssuePreAuthToken(ctx, 7)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токена: %v", err)
+	}
+	current = current.Add(PreAuthTokenTTL - time.Second)
+	if userID, err := PreAuthUser(ctx, token); err != nil || userID != 7 {
+		t.Fatalf("Токен еще должен действовать, получено %d (%v)", userID, err)
+	}
+
+	current = current.Add(time.Second)
+	if _, err = PreAuthUser(ctx, token); err != ErrInvalidPreAuthToken {
+		t.Errorf("Токен должен истечь через %s, получено %v", PreAuthTokenTTL, err)
+	}
+	if err = FailPreAuthToken(ctx, token); err != nil {
+		t.Errorf("Попытка по истекшему токену не должна возвращать ошибку: %v", err)
+	}
+}
@@ -10,9 +10,12 @@ import (
 type Cache interface {
 	// Базовые операции ключ-значение
 	Get(ctx context.Context, key string) (string, error)
+	// GetDel атомарно читает и удаляет значение; если ключа нет, возвращает redis.Nil.
+	GetDel(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key, value string, expiration time.Duration) error
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
 
 	// Операции со списками
 	LPush(ctx context.Context, key string, values ...interface{}) error
@@ -33,5 +36,22 @@ type Cache interface {
 	ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error)
 	ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error)
 	ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) (map[string]float64, error)
+	// ZUnionStore записывает в dest сумму счетов множеств keys, заменяя
+	// прежнее значение dest. Отсутствующие множества считаются пустыми.
+	ZUnionStore(ctx context.Context, dest string, keys ...string) error
+	// ZUnionStoreWeights работает как ZUnionStore, но перед сложением
+	// умножает счета каждого множества keys[i] на weights[i].
+	ZUnionStoreWeights(ctx context.Context, dest string, keys []string, weights []float64) error
+	// ZScore возвращает счет элемента или redis.Nil, если его нет.
+	ZScore(ctx context.Context, key, member string) (float64, error)
+
+	// Операции HyperLogLog (для подсчета уникальных посетителей)
+	// PFAdd возвращает true, если оценка числа элементов изменилась.
+	PFAdd(ctx context.Context, key string, elements ...interface{}) (bool, error)
+	// PFCount оценивает число уникальных элементов в объединении keys.
+	PFCount(ctx context.Context, keys ...string) (int64, error)
+
+	// Pipeline возвращает конвейер для отправки нескольких команд одним запросом.
+	Pipeline() Pipeline
 	GetClient() *redis.Client
 }
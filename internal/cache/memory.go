@@ -0,0 +1,433 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryCache реализует Cache в памяти процесса. Используется как запасной
+// вариант, когда Redis недоступен, и в тестах. Для отсутствующих ключей
+// возвращает redis.Nil, чтобы вызывающий код вел себя так же, как с Redis.
+type MemoryCache struct {
+	mu      sync.Mutex
+	strings map[string]string
+	lists   map[string][]string
+	sets    map[string]map[string]struct{}
+	zsets   map[string]map[string]float64
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		strings: make(map[string]string),
+		lists:   make(map[string][]string),
+		sets:    make(map[string]map[string]struct{}),
+		zsets:   make(map[string]map[string]float64),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// SetClock подменяет источник времени, что позволяет тестам проверять
+// истечение ключей без ожидания.
+func (c *MemoryCache) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// evictIfExpired удаляет ключ с истекшим временем жизни. Вызывается под мьютексом.
+func (c *MemoryCache) evictIfExpired(key string) {
+	exp, ok := c.expires[key]
+	if ok && !c.now().Before(exp) {
+		c.deleteLocked(key)
+	}
+}
+
+func (c *MemoryCache) deleteLocked(key string) {
+	delete(c.strings, key)
+	delete(c.lists, key)
+	delete(c.sets, key)
+	delete(c.zsets, key)
+	delete(c.expires, key)
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	value, ok := c.strings[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (c *MemoryCache) GetDel(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	value, ok := c.strings[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	c.deleteLocked(key)
+	return value, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLocked(key)
+	c.strings[key] = value
+	if expiration > 0 {
+		c.expires[key] = c.now().Add(expiration)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLocked(key)
+	return nil
+}
+
+func (c *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	return c.existsLocked(key), nil
+}
+
+func (c *MemoryCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	if !c.existsLocked(key) {
+		return nil
+	}
+	if expiration <= 0 {
+		c.deleteLocked(key)
+		return nil
+	}
+	c.expires[key] = c.now().Add(expiration)
+	return nil
+}
+
+func (c *MemoryCache) existsLocked(key string) bool {
+	if _, ok := c.strings[key]; ok {
+		return true
+	}
+	if _, ok := c.lists[key]; ok {
+		return true
+	}
+	if _, ok := c.sets[key]; ok {
+		return true
+	}
+	_, ok := c.zsets[key]
+	return ok
+}
+
+func (c *MemoryCache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	for _, v := range values {
+		c.lists[key] = append([]string{toString(v)}, c.lists[key]...)
+	}
+	return nil
+}
+
+func (c *MemoryCache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	for _, v := range values {
+		c.lists[key] = append(c.lists[key], toString(v))
+	}
+	return nil
+}
+
+func (c *MemoryCache) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	list := c.lists[key]
+	from, to, ok := normalizeRange(start, stop, int64(len(list)))
+	if !ok {
+		return []string{}, nil
+	}
+	return append([]string(nil), list[from:to+1]...), nil
+}
+
+func (c *MemoryCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	set, ok := c.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		c.sets[key] = set
+	}
+	for _, m := range members {
+		set[toString(m)] = struct{}{}
+	}
+	return nil
+}
+
+func (c *MemoryCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	members := make([]string, 0, len(c.sets[key]))
+	for m := range c.sets[key] {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (c *MemoryCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	set := c.sets[key]
+	for _, m := range members {
+		delete(set, toString(m))
+	}
+	if len(set) == 0 {
+		delete(c.sets, key)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+func (c *MemoryCache) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	var current int64
+	if raw, ok := c.strings[key]; ok {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, errors.New("ERR value is not an integer or out of range")
+		}
+		current = parsed
+	}
+	current += value
+	c.strings[key] = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+func (c *MemoryCache) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	c.zsetLocked(key)[member] = score
+	return nil
+}
+
+func (c *MemoryCache) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	zset := c.zsetLocked(key)
+	zset[member] += increment
+	return zset[member], nil
+}
+
+func (c *MemoryCache) zsetLocked(key string) map[string]float64 {
+	zset, ok := c.zsets[key]
+	if !ok {
+		zset = make(map[string]float64)
+		c.zsets[key] = zset
+	}
+	return zset
+}
+
+func (c *MemoryCache) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	entries := c.sortedDescLocked(key)
+	from, to, ok := normalizeRange(start, stop, int64(len(entries)))
+	if !ok {
+		return []string{}, nil
+	}
+	members := make([]string, 0, to-from+1)
+	for _, e := range entries[from : to+1] {
+		members = append(members, e.member)
+	}
+	return members, nil
+}
+
+func (c *MemoryCache) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) (map[string]float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	entries := c.sortedDescLocked(key)
+	scoreMap := make(map[string]float64)
+	from, to, ok := normalizeRange(start, stop, int64(len(entries)))
+	if !ok {
+		return scoreMap, nil
+	}
+	for _, e := range entries[from : to+1] {
+		scoreMap[e.member] = e.score
+	}
+	return scoreMap, nil
+}
+
+func (c *MemoryCache) ZUnionStore(ctx context.Context, dest string, keys ...string) error {
+	weights := make([]float64, len(keys))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return c.ZUnionStoreWeights(ctx, dest, keys, weights)
+}
+
+func (c *MemoryCache) ZUnionStoreWeights(ctx context.Context, dest string, keys []string, weights []float64) error {
+	if len(weights) != len(keys) {
+		return errors.New("ERR syntax error")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	union := make(map[string]float64)
+	for i, key := range keys {
+		c.evictIfExpired(key)
+		for member, score := range c.zsets[key] {
+			union[member] += score * weights[i]
+		}
+	}
+	c.deleteLocked(dest)
+	// Как и Redis, пустой результат не сохраняется.
+	if len(union) > 0 {
+		c.zsets[dest] = union
+	}
+	return nil
+}
+
+func (c *MemoryCache) ZScore(ctx context.Context, key, member string) (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	score, ok := c.zsets[key][member]
+	if !ok {
+		return 0, redis.Nil
+	}
+	return score, nil
+}
+
+// PFAdd хранит элементы HyperLogLog как обычное множество, поэтому кеш в
+// памяти считает уникальные элементы точно, а не приближенно.
+func (c *MemoryCache) PFAdd(ctx context.Context, key string, elements ...interface{}) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictIfExpired(key)
+	set, ok := c.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		c.sets[key] = set
+	}
+	changed := !ok
+	for _, e := range elements {
+		if _, seen := set[toString(e)]; !seen {
+			set[toString(e)] = struct{}{}
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func (c *MemoryCache) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	union := make(map[string]struct{})
+	for _, key := range keys {
+		c.evictIfExpired(key)
+		for e := range c.sets[key] {
+			union[e] = struct{}{}
+		}
+	}
+	return int64(len(union)), nil
+}
+
+// Pipeline выполняет команды по очереди: кешу в памяти незачем экономить
+// на сетевых запросах.
+func (c *MemoryCache) Pipeline() Pipeline {
+	return NewSequentialPipeline(c)
+}
+
+type zEntry struct {
+	member string
+	score  float64
+}
+
+// sortedDescLocked возвращает элементы множества в порядке убывания счета,
+// при равенстве — в обратном лексикографическом порядке, как ZREVRANGE.
+func (c *MemoryCache) sortedDescLocked(key string) []zEntry {
+	entries := make([]zEntry, 0, len(c.zsets[key]))
+	for m, s := range c.zsets[key] {
+		entries = append(entries, zEntry{member: m, score: s})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score > entries[j].score
+		}
+		return entries[i].member > entries[j].member
+	})
+	return entries
+}
+
+// GetClient возвращает nil: у кеша в памяти нет клиента Redis.
+func (c *MemoryCache) GetClient() *redis.Client {
+	return nil
+}
+
+// normalizeRange переводит индексы в стиле Redis (с отрицательными значениями
+// от конца) в границы среза.
+func normalizeRange(start, stop, length int64) (int64, int64, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if length == 0 || start > stop {
+		return 0, 0, false
+	}
+	return start, stop, true
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	c := NewMemoryCache()
+	now := time.Now()
+	c.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Ошибка записи: %v", err)
+	}
+	if value, err := c.Get(ctx, "key"); err != nil || value != "value" {
+		t.Fatalf("Ожидалось значение %q, получено %q (err=%v)", "value", value, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, redis.Nil) {
+		t.Errorf("Ожидалась ошибка redis.Nil для истекшего ключа, получено %v", err)
+	}
+}
+
+func TestMemoryCacheGetDel(t *testing.T) {
+	c := NewMemoryCache()
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "value", time.Minute)
+	if value, err := c.GetDel(ctx, "key"); err != nil || value != "value" {
+		t.Fatalf("Ожидалось значение %q, получено %q (err=%v)", "value", value, err)
+	}
+	if _, err := c.GetDel(ctx, "key"); !errors.Is(err, redis.Nil) {
+		t.Errorf("Ключ должен быть удален, получено %v", err)
+	}
+}
+
+func TestMemoryCacheSortedSet(t *testing.T) {
+	c := NewMemoryCache()
+	ctx := context.Background()
+
+	_, _ = c.ZIncrBy(ctx, "ranking", 3, "a")
+	_, _ = c.ZIncrBy(ctx, "ranking", 5, "b")
+	_, _ = c.ZIncrBy(ctx, "ranking", 1, "c")
+
+	members, err := c.ZRevRange(ctx, "ranking", 0, 1)
+	if err != nil {
+		t.Fatalf("Ошибка чтения множества: %v", err)
+	}
+	if len(members) != 2 || members[0] != "b" || members[1] != "a" {
+		t.Errorf("Ожидался порядок [b a], получено %v", members)
+	}
+
+	count, err := c.Incr(ctx, "counter")
+	if err != nil || count != 1 {
+		t.Errorf("Ожидался счетчик 1, получено %d (err=%v)", count, err)
+	}
+}
+
+func TestMemoryCacheZUnionStore(t *testing.T) {
+	c := NewMemoryCache()
+	ctx := context.Background()
+
+	_, _ = c.ZIncrBy(ctx, "h1", 2, "a")
+	_, _ = c.ZIncrBy(ctx, "h2", 3, "a")
+	_, _ = c.ZIncrBy(ctx, "h2", 1, "b")
+	_, _ = c.ZIncrBy(ctx, "union", 100, "stale")
+
+	if err := c.ZUnionStore(ctx, "union", "h1", "h2", "missing"); err != nil {
+		t.Fatalf("Ошибка объединения множеств: %v", err)
+	}
+	scores, err := c.ZRevRangeWithScores(ctx, "union", 0, -1)
+	if err != nil {
+		t.Fatalf("Ошибка чтения множества: %v", err)
+	}
+	if len(scores) != 2 || scores["a"] != 5 || scores["b"] != 1 {
+		t.Errorf("Ожидалось {a:5 b:1}, получено %v", scores)
+	}
+
+	if err = c.ZUnionStoreWeights(ctx, "union", []string{"h1", "h2"}, []float64{0.5, -1}); err != nil {
+		t.Fatalf("Ошибка объединения множеств с весами: %v", err)
+	}
+	if scores, _ = c.ZRevRangeWithScores(ctx, "union", 0, -1); len(scores) != 2 || scores["a"] != -2 || scores["b"] != -1 {
+		t.Errorf("Ожидалось {a:-2 b:-1}, получено %v", scores)
+	}
+}
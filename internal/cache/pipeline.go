@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Pipeline накапливает команды и отправляет их одним запросом методом Exec.
+// Результаты команд доступны только после Exec.
+type Pipeline interface {
+	IncrBy(key string, value int64)
+	ZIncrBy(key string, increment float64, member string)
+	Expire(key string, expiration time.Duration)
+	PFAdd(key string, elements ...interface{}) *BoolResult
+	// Exec выполняет накопленные команды и возвращает первую ошибку.
+	Exec(ctx context.Context) error
+}
+
+// BoolResult — результат команды конвейера; Val заполняется при Exec.
+type BoolResult struct {
+	Val bool
+}
+
+// sequentialPipeline выполняет команды конвейера по одной через Cache.
+// Используется кешами без настоящих конвейеров.
+type sequentialPipeline struct {
+	cache Cache
+	cmds  []func(ctx context.Context) error
+}
+
+// NewSequentialPipeline возвращает конвейер, который при Exec выполняет
+// команды по очереди обычными методами c.
+func NewSequentialPipeline(c Cache) Pipeline {
+	return &sequentialPipeline{cache: c}
+}
+
+func (p *sequentialPipeline) IncrBy(key string, value int64) {
+	p.cmds = append(p.cmds, func(ctx context.Context) error {
+		_, err := p.cache.IncrBy(ctx, key, value)
+		return err
+	})
+}
+
+func (p *sequentialPipeline) ZIncrBy(key string, increment float64, member string) {
+	p.cmds = append(p.cmds, func(ctx context.Context) error {
+		_, err := p.cache.ZIncrBy(ctx, key, increment, member)
+		return err
+	})
+}
+
+func (p *sequentialPipeline) Expire(key string, expiration time.Duration) {
+	p.cmds = append(p.cmds, func(ctx context.Context) error {
+		return p.cache.Expire(ctx, key, expiration)
+	})
+}
+
+func (p *sequentialPipeline) PFAdd(key string, elements ...interface{}) *BoolResult {
+	result := &BoolResult{}
+	p.cmds = append(p.cmds, func(ctx context.Context) (err error) {
+		result.Val, err = p.cache.PFAdd(ctx, key, elements...)
+		return err
+	})
+	return result
+}
+
+func (p *sequentialPipeline) Exec(ctx context.Context) error {
+	var first error
+	for _, cmd := range p.cmds {
+		if err := cmd(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	p.cmds = nil
+	return first
+}
+
+// redisPipeline оборачивает конвейер go-redis.
+type redisPipeline struct {
+	pipe    redis.Pipeliner
+	pfAdds  []*redis.IntCmd
+	results []*BoolResult
+}
+
+func (p *redisPipeline) IncrBy(key string, value int64) {
+	p.pipe.IncrBy(context.Background(), key, value)
+}
+
+func (p *redisPipeline) ZIncrBy(key string, increment float64, member string) {
+	p.pipe.ZIncrBy(context.Background(), key, increment, member)
+}
+
+func (p *redisPipeline) Expire(key string, expiration time.Duration) {
+	p.pipe.Expire(context.Background(), key, expiration)
+}
+
+func (p *redisPipeline) PFAdd(key string, elements ...interface{}) *BoolResult {
+	result := &BoolResult{}
+	p.pfAdds = append(p.pfAdds, p.pipe.PFAdd(context.Background(), key, elements...))
+	p.results = append(p.results, result)
+	return result
+}
+
+func (p *redisPipeline) Exec(ctx context.Context) error {
+	_, err := p.pipe.Exec(ctx)
+	for i, cmd := range p.pfAdds {
+		p.results[i].Val = cmd.Val() == 1
+	}
+	p.pfAdds, p.results = nil, nil
+	return err
+}
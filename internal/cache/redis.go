@@ -25,6 +25,10 @@ func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	return c.client.Get(ctx, key).Result()
 }
 
+func (c *RedisCache) GetDel(ctx context.Context, key string) (string, error) {
+	return c.client.GetDel(ctx, key).Result()
+}
+
 func (c *RedisCache) Set(ctx context.Context, key, value string, expiration time.Duration) error {
 	return c.client.Set(ctx, key, value, expiration).Err()
 }
@@ -38,6 +42,10 @@ func (c *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, err
 }
 
+func (c *RedisCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return c.client.Expire(ctx, key, expiration).Err()
+}
+
 func (c *RedisCache) LPush(ctx context.Context, key string, values ...interface{}) error {
 	return c.client.LPush(ctx, key, values).Err()
 }
@@ -97,6 +105,31 @@ func (c *RedisCache) ZRevRangeWithScores(ctx context.Context, key string, start
 	return scoreMap, nil
 }
 
+func (c *RedisCache) ZUnionStore(ctx context.Context, dest string, keys ...string) error {
+	return c.client.ZUnionStore(ctx, dest, &redis.ZStore{Keys: keys}).Err()
+}
+
+func (c *RedisCache) ZUnionStoreWeights(ctx context.Context, dest string, keys []string, weights []float64) error {
+	return c.client.ZUnionStore(ctx, dest, &redis.ZStore{Keys: keys, Weights: weights}).Err()
+}
+
+func (c *RedisCache) ZScore(ctx context.Context, key, member string) (float64, error) {
+	return c.client.ZScore(ctx, key, member).Result()
+}
+
+func (c *RedisCache) PFAdd(ctx context.Context, key string, elements ...interface{}) (bool, error) {
+	changed, err := c.client.PFAdd(ctx, key, elements...).Result()
+	return changed == 1, err
+}
+
+func (c *RedisCache) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return c.client.PFCount(ctx, keys...).Result()
+}
+
+func (c *RedisCache) Pipeline() Pipeline {
+	return &redisPipeline{pipe: c.client.Pipeline()}
+}
+
 func (c *RedisCache) GetClient() *redis.Client {
 	return c.client
 }
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresAPIKeyRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAPIKeyRepository(db *sql.DB, logger *slog.Logger) db.APIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db, logger: logger}
+}
+
+const apiKeyColumns = "k.id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at"
+
+func scanAPIKey(row interface{ Scan(...any) error }, extra ...any) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var (
+		scopes    string
+		expiresAt sql.NullTime
+		lastUsed  sql.NullTime
+	)
+	dest := append([]any{&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&expiresAt, &lastUsed, &key.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	key.Scopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	return key, nil
+}
+
+func joinScopes(scopes []models.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitScopes(value string) []models.Scope {
+	scopes := []models.Scope{}
+	for _, s := range strings.Split(value, ",") {
+		if s != "" {
+			scopes = append(scopes, models.Scope(s))
+		}
+	}
+	return scopes
+}
+
+func (r *PostgresAPIKeyRepository) Create(key *models.APIKey) (int64, error) {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		key.UserID, key.Name, key.Prefix, key.KeyHash, joinScopes(key.Scopes), key.ExpiresAt, key.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		r.logger.Error("Ошибка создания API-ключа в PostgreSQL", "err", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PostgresAPIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	var role models.Role
+	key, err := scanAPIKey(r.db.QueryRow(
+		"SELECT "+apiKeyColumns+", u.role FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = $1",
+		hash,
+	), &role)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка поиска API-ключа в PostgreSQL", "err", err)
+		}
+		return nil, err
+	}
+	key.OwnerRole = role
+	return key, nil
+}
+
+func (r *PostgresAPIKeyRepository) ListByUser(userID int64) ([]*models.APIKey, error) {
+	rows, err := r.db.Query("SELECT "+apiKeyColumns+" FROM api_keys k WHERE k.user_id = $1 ORDER BY k.id", userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения API-ключей из PostgreSQL", "err", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования API-ключа из PostgreSQL", "err", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *PostgresAPIKeyRepository) Delete(id, userID int64) error {
+	result, err := r.db.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления API-ключа из PostgreSQL", "err", err, "id", id)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchLastUsed(id int64, at time.Time) error {
+	_, err := r.db.Exec("UPDATE api_keys SET last_used_at = $1 WHERE id = $2", at, id)
+	if err != nil {
+		r.logger.Error("Ошибка обновления времени использования API-ключа в PostgreSQL", "err", err, "id", id)
+	}
+	return err
+}
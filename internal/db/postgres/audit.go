@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresAuditRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAuditRepository(db *sql.DB, logger *slog.Logger) db.AuditRepository {
+	return &PostgresAuditRepository{db: db, logger: logger}
+}
+
+func (r *PostgresAuditRepository) Record(e *models.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	var actorID sql.NullInt64
+	if e.ActorID != 0 {
+		actorID = sql.NullInt64{Int64: e.ActorID, Valid: true}
+	}
+	err = r.db.QueryRow(`INSERT INTO audit_log
+		(actor_id, action, entity_type, entity_id, changes, request_id, ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		actorID, e.Action, e.EntityType, e.EntityID, string(changes), e.RequestID, e.IP, e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		r.logger.Error("Ошибка записи в журнал аудита в PostgreSQL", "action", e.Action, "entity_type", e.EntityType,
+			"entity_id", e.EntityID, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAuditRepository) List(filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != 0 {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if !filter.Since.IsZero() {
+		where("created_at >= $%d", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where("created_at < $%d", filter.Until.UTC())
+	}
+
+	query := `SELECT id, actor_id, action, entity_type, entity_id, changes, request_id, ip, created_at FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения журнала аудита из PostgreSQL", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var actorID sql.NullInt64
+		var changes []byte
+		if err = rows.Scan(&e.ID, &actorID, &e.Action, &e.EntityType, &e.EntityID, &changes,
+			&e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования записи журнала аудита", "err", err)
+			return nil, err
+		}
+		e.ActorID = actorID.Int64
+		if err = json.Unmarshal(changes, &e.Changes); err != nil {
+			r.logger.Error("Ошибка разбора изменений в журнале аудита", "id", e.ID, "err", err)
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
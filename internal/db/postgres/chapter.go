@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"manga-reader/internal/db"
 	"manga-reader/models"
@@ -18,11 +20,53 @@ func NewChapterRepository(db *sql.DB, logger *slog.Logger) db.ChapterRepository
 	return &PostgresChapterRepository{db: db, logger: logger}
 }
 
+const chapterColumns = "id, manga_id, number, title, language, group_id, status, publish_at, published_at, created_at, updated_at, deleted_at"
+
+func scanChapter(row interface{ Scan(...any) error }) (*models.Chapter, error) {
+	ch := &models.Chapter{}
+	var groupID sql.NullInt64
+	var publishAt, publishedAt, deletedAt sql.NullTime
+	if err := row.Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title, &ch.Language, &groupID, &ch.Status, &publishAt, &publishedAt,
+		&ch.CreatedAt, &ch.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		ch.DeletedAt = &deletedAt.Time
+	}
+	if groupID.Valid {
+		ch.GroupID = &groupID.Int64
+	}
+	if publishAt.Valid {
+		ch.PublishAt = &publishAt.Time
+	}
+	if publishedAt.Valid {
+		ch.PublishedAt = &publishedAt.Time
+	}
+	return ch, nil
+}
+
+// Create добавляет главу и, если она опубликована, в той же транзакции
+// отмечает мангу обновленной.
 func (r *PostgresChapterRepository) Create(ch *models.Chapter) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции в PostgreSQL", "err", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
 	var id int64
-	err := r.db.QueryRow(
-		"INSERT INTO chapters (manga_id, number, title) VALUES ($1, $2, $3) RETURNING id",
-		ch.MangaID, ch.Number, ch.Title,
+	now := time.Now().UTC()
+	err = tx.QueryRow(
+		"INSERT INTO chapters (manga_id, number, title, language, group_id, status, publish_at, published_at, created_at, updated_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9) RETURNING id",
+		ch.MangaID, ch.Number, ch.Title, ch.Language, ch.GroupID, ch.Status, ch.PublishAt, ch.PublishedAt, now,
 	).Scan(&id)
 
 	if err != nil {
@@ -30,16 +74,24 @@ func (r *PostgresChapterRepository) Create(ch *models.Chapter) (int64, error) {
 		return 0, err
 	}
 
+	if ch.Published() {
+		if _, err = tx.Exec("UPDATE manga SET updated_at = $1 WHERE id = $2", now, ch.MangaID); err != nil {
+			r.logger.Error("Ошибка обновления времени изменения манги в PostgreSQL", "err", err, "manga_id", ch.MangaID)
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции в PostgreSQL", "err", err)
+		return 0, err
+	}
+
+	ch.CreatedAt, ch.UpdatedAt = now, now
 	return id, nil
 }
 
 func (r *PostgresChapterRepository) GetByID(id int64) (*models.Chapter, error) {
-	ch := &models.Chapter{}
-	err := r.db.QueryRow(
-		"SELECT id, manga_id, number, title FROM chapters WHERE id = $1",
-		id,
-	).Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title)
-
+	ch, err := scanChapter(r.db.QueryRow("SELECT "+chapterColumns+" FROM chapters WHERE id = $1 AND deleted_at IS NULL", id))
 	if err != nil {
 		r.logger.Error("Ошибка получения главы из PostgreSQL", "err", err, "id", id)
 		return nil, err
@@ -49,28 +101,58 @@ func (r *PostgresChapterRepository) GetByID(id int64) (*models.Chapter, error) {
 }
 
 func (r *PostgresChapterRepository) ListByManga(mangaID int64) ([]*models.Chapter, error) {
+	rows, err := r.db.Query("SELECT "+chapterColumns+" FROM chapters WHERE manga_id = $1 AND deleted_at IS NULL ORDER BY number, id", mangaID)
+	if err != nil {
+		r.logger.Error("Ошибка получения списка глав из PostgreSQL", "err", err, "manga_id", mangaID)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *PostgresChapterRepository) ListPublished(mangaID int64, limit int) ([]*models.Chapter, error) {
+	query := "SELECT " + chapterColumns + " FROM chapters WHERE status = 'published' AND published_at IS NOT NULL AND deleted_at IS NULL"
+	args := []any{}
+	if mangaID != 0 {
+		args = append(args, mangaID)
+		query += fmt.Sprintf(" AND manga_id = $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY published_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения опубликованных глав из PostgreSQL", "err", err, "manga_id", mangaID)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *PostgresChapterRepository) ListLatest(limit, offset int) ([]*models.Chapter, error) {
 	rows, err := r.db.Query(
-		"SELECT id, manga_id, number, title FROM chapters WHERE manga_id = $1 ORDER BY number",
-		mangaID,
+		"SELECT "+chapterColumns+" FROM chapters WHERE status = 'published' AND deleted_at IS NULL ORDER BY published_at DESC NULLS LAST, id DESC LIMIT $1 OFFSET $2",
+		limit, offset,
 	)
-
 	if err != nil {
-		r.logger.Error("Ошибка получения списка глав из PostgreSQL", "err", err, "manga_id", mangaID)
+		r.logger.Error("Ошибка получения последних глав из PostgreSQL", "err", err)
 		return nil, err
 	}
+	return r.scanChapters(rows)
+}
+
+func (r *PostgresChapterRepository) scanChapters(rows *sql.Rows) ([]*models.Chapter, error) {
 	defer rows.Close()
 
 	var chapters []*models.Chapter
 	for rows.Next() {
-		ch := &models.Chapter{}
-		if err := rows.Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title); err != nil {
+		ch, err := scanChapter(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования главы из PostgreSQL", "err", err)
 			return nil, err
 		}
 		chapters = append(chapters, ch)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		r.logger.Error("Ошибка итерации по результатам из PostgreSQL", "err", err)
 		return nil, err
 	}
@@ -79,9 +161,17 @@ func (r *PostgresChapterRepository) ListByManga(mangaID int64) ([]*models.Chapte
 }
 
 func (r *PostgresChapterRepository) Update(ch *models.Chapter) error {
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
+	now := time.Now().UTC()
 	result, err := r.db.Exec(
-		"UPDATE chapters SET number = $1, title = $2 WHERE id = $3",
-		ch.Number, ch.Title, ch.ID,
+		"UPDATE chapters SET number = $1, title = $2, language = $3, group_id = $4, status = $5, publish_at = $6, published_at = $7, "+
+			"updated_at = $8 WHERE id = $9 AND deleted_at IS NULL",
+		ch.Number, ch.Title, ch.Language, ch.GroupID, ch.Status, ch.PublishAt, ch.PublishedAt, now, ch.ID,
 	)
 
 	if err != nil {
@@ -100,28 +190,113 @@ func (r *PostgresChapterRepository) Update(ch *models.Chapter) error {
 		r.logger.Error("Глава не найдена для обновления в PostgreSQL", "id", ch.ID)
 		return err
 	}
+	ch.UpdatedAt = now
 
 	return nil
 }
 
+// Delete переносит главу и ее страницы в корзину с общим временем
+// удаления, по которому Restore находит удаленные вместе с главой страницы.
 func (r *PostgresChapterRepository) Delete(id int64) error {
-	result, err := r.db.Exec("DELETE FROM chapters WHERE id = $1", id)
+	var deleted bool
+	err := r.db.QueryRow(
+		`WITH deleted AS (
+			UPDATE chapters SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL RETURNING id
+		), pages_deleted AS (
+			UPDATE pages SET deleted_at = $1 WHERE chapter_id IN (SELECT id FROM deleted) AND deleted_at IS NULL
+		)
+		SELECT EXISTS(SELECT 1 FROM deleted)`,
+		time.Now().UTC(), id,
+	).Scan(&deleted)
 	if err != nil {
 		r.logger.Error("Ошибка удаления главы из PostgreSQL", "err", err, "id", id)
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		r.logger.Error("Ошибка получения количества удаленных строк в PostgreSQL", "err", err)
+	if !deleted {
+		err = fmt.Errorf("глава с id %d не найдена", id)
+		r.logger.Error("Глава не найдена для удаления в PostgreSQL", "id", id)
 		return err
 	}
 
-	if rowsAffected == 0 {
-		err = fmt.Errorf("глава с id %d не найдена", id)
-		r.logger.Error("Глава не найдена для удаления в PostgreSQL", "id", id)
+	return nil
+}
+
+func (r *PostgresChapterRepository) ListDeleted() ([]*models.Chapter, error) {
+	rows, err := r.db.Query(
+		"SELECT " + chapterColumns + " FROM chapters WHERE deleted_at IS NOT NULL AND manga_id IN " +
+			"(SELECT id FROM manga WHERE deleted_at IS NULL) ORDER BY deleted_at DESC, id DESC",
+	)
+	if err != nil {
+		r.logger.Error("Ошибка получения глав из корзины в PostgreSQL", "err", err)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *PostgresChapterRepository) Restore(id int64) error {
+	var restored bool
+	err := r.db.QueryRow(
+		`WITH target AS (
+			SELECT id, deleted_at FROM chapters
+			WHERE id = $1 AND deleted_at IS NOT NULL AND manga_id IN (SELECT id FROM manga WHERE deleted_at IS NULL)
+			FOR UPDATE
+		), restored AS (
+			UPDATE chapters c SET deleted_at = NULL FROM target t WHERE c.id = t.id RETURNING c.id
+		), pages_restored AS (
+			UPDATE pages p SET deleted_at = NULL FROM target t WHERE p.chapter_id = t.id AND p.deleted_at = t.deleted_at
+		)
+		SELECT EXISTS(SELECT 1 FROM restored)`,
+		id,
+	).Scan(&restored)
+	if err != nil {
+		r.logger.Error("Ошибка восстановления главы в PostgreSQL", "err", err, "id", id)
 		return err
 	}
+	if !restored {
+		return sql.ErrNoRows
+	}
 
 	return nil
 }
+
+func (r *PostgresChapterRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec(
+		`DELETE FROM chapters WHERE (deleted_at IS NOT NULL AND deleted_at <= $1) OR manga_id IN (
+			SELECT id FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= $1)`,
+		before.UTC(),
+	)
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины глав в PostgreSQL", "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PublishDue публикует наступившие запланированные главы одним запросом.
+// Временем публикации становится запланированное время, а манга
+// отмечается обновленной. FOR UPDATE SKIP LOCKED не дает нескольким
+// экземплярам сервера опубликовать одну главу дважды.
+func (r *PostgresChapterRepository) PublishDue(now time.Time) ([]*models.Chapter, error) {
+	rows, err := r.db.Query(
+		`WITH due AS (
+			SELECT id FROM chapters
+			WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+			FOR UPDATE SKIP LOCKED
+		), published AS (
+			UPDATE chapters c SET status = 'published', published_at = c.publish_at, updated_at = $1
+			FROM due WHERE c.id = due.id
+			RETURNING c.`+strings.ReplaceAll(chapterColumns, ", ", ", c.")+`
+		), touched AS (
+			UPDATE manga m SET updated_at = $1
+			WHERE m.id IN (SELECT manga_id FROM published)
+		)
+		SELECT * FROM published ORDER BY publish_at, id`,
+		now.UTC(),
+	)
+	if err != nil {
+		r.logger.Error("Ошибка публикации запланированных глав в PostgreSQL", "err", err)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
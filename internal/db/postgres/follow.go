@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresFollowRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewFollowRepository(db *sql.DB, logger *slog.Logger) db.FollowRepository {
+	return &PostgresFollowRepository{db: db, logger: logger}
+}
+
+func (r *PostgresFollowRepository) Follow(userID, mangaID int64) error {
+	_, err := r.db.Exec("INSERT INTO manga_follows (user_id, manga_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, mangaID)
+	if err != nil {
+		r.logger.Error("Ошибка создания подписки в PostgreSQL", "user_id", userID, "manga_id", mangaID, "err", err)
+	}
+	return err
+}
+
+func (r *PostgresFollowRepository) Unfollow(userID, mangaID int64) error {
+	result, err := r.db.Exec("DELETE FROM manga_follows WHERE user_id = $1 AND manga_id = $2", userID, mangaID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления подписки в PostgreSQL", "user_id", userID, "manga_id", mangaID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresFollowRepository) IsFollowing(userID, mangaID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM manga_follows WHERE user_id = $1 AND manga_id = $2)",
+		userID, mangaID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Ошибка проверки подписки в PostgreSQL", "user_id", userID, "manga_id", mangaID, "err", err)
+	}
+	return exists, err
+}
+
+func (r *PostgresFollowRepository) ListByUser(userID int64) ([]*models.Follow, error) {
+	rows, err := r.db.Query(`SELECT f.manga_id, m.title, f.created_at FROM manga_follows f
+		JOIN manga m ON m.id = f.manga_id WHERE f.user_id = $1 AND m.deleted_at IS NULL ORDER BY f.created_at DESC, f.manga_id`, userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения подписок из PostgreSQL", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []*models.Follow{}
+	for rows.Next() {
+		f := &models.Follow{}
+		if err = rows.Scan(&f.MangaID, &f.MangaTitle, &f.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования подписки", "err", err)
+			return nil, err
+		}
+		follows = append(follows, f)
+	}
+	return follows, rows.Err()
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"database/sql"
 	"log/slog"
+	"time"
 
 	_ "github.com/lib/pq"
 	"manga-reader/internal/db"
@@ -27,27 +28,39 @@ func NewMangaRepository(connectionString string, logger *slog.Logger) (db.MangaR
 	return repo, nil
 }
 
+const mangaColumns = "id, title, COALESCE(description, ''), created_at, updated_at, deleted_at"
+
+func scanManga(row interface{ Scan(...any) error }) (*models.Manga, error) {
+	m := &models.Manga{}
+	var deletedAt sql.NullTime
+	if err := row.Scan(&m.ID, &m.Title, &m.Description, &m.CreatedAt, &m.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		m.DeletedAt = &deletedAt.Time
+	}
+	return m, nil
+}
+
 func (r *PostgresMangaRepository) Create(m *models.Manga) (int64, error) {
 	var id int64
+	now := time.Now().UTC()
 	err := r.db.QueryRow(
-		"INSERT INTO manga (title, description) VALUES ($1, $2) RETURNING id",
-		m.Title, m.Description,
+		"INSERT INTO manga (title, description, created_at, updated_at) VALUES ($1, $2, $3, $3) RETURNING id",
+		m.Title, m.Description, now,
 	).Scan(&id)
 
 	if err != nil {
 		r.logger.Error("Ошибка вставки манги в PostgreSQL", "err", err)
 		return 0, err
 	}
+	m.CreatedAt, m.UpdatedAt = now, now
 
 	return id, nil
 }
 
 func (r *PostgresMangaRepository) GetByID(id int64) (*models.Manga, error) {
-	m := &models.Manga{}
-	err := r.db.QueryRow(
-		"SELECT id, title, description FROM manga WHERE id = $1",
-		id,
-	).Scan(&m.ID, &m.Title, &m.Description)
+	m, err := scanManga(r.db.QueryRow("SELECT "+mangaColumns+" FROM manga WHERE id = $1 AND deleted_at IS NULL", id))
 
 	if err != nil {
 		r.logger.Error("Ошибка получения манги из PostgreSQL", "err", err, "id", id)
@@ -58,24 +71,37 @@ func (r *PostgresMangaRepository) GetByID(id int64) (*models.Manga, error) {
 }
 
 func (r *PostgresMangaRepository) List() ([]*models.Manga, error) {
-	rows, err := r.db.Query("SELECT id, title, description FROM manga")
+	rows, err := r.db.Query("SELECT " + mangaColumns + " FROM manga WHERE deleted_at IS NULL")
 	if err != nil {
 		r.logger.Error("Ошибка получения списка манги из PostgreSQL", "err", err)
 		return nil, err
 	}
+	return r.scanMangas(rows)
+}
+
+func (r *PostgresMangaRepository) ListDeleted() ([]*models.Manga, error) {
+	rows, err := r.db.Query("SELECT " + mangaColumns + " FROM manga WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		r.logger.Error("Ошибка получения манги из корзины в PostgreSQL", "err", err)
+		return nil, err
+	}
+	return r.scanMangas(rows)
+}
+
+func (r *PostgresMangaRepository) scanMangas(rows *sql.Rows) ([]*models.Manga, error) {
 	defer rows.Close()
 
 	var mangas []*models.Manga
 	for rows.Next() {
-		m := &models.Manga{}
-		if err := rows.Scan(&m.ID, &m.Title, &m.Description); err != nil {
+		m, err := scanManga(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования строки из PostgreSQL", "err", err)
 			return nil, err
 		}
 		mangas = append(mangas, m)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		r.logger.Error("Ошибка итерации по результатам из PostgreSQL", "err", err)
 		return nil, err
 	}
@@ -84,9 +110,10 @@ func (r *PostgresMangaRepository) List() ([]*models.Manga, error) {
 }
 
 func (r *PostgresMangaRepository) Update(m *models.Manga) error {
+	now := time.Now().UTC()
 	result, err := r.db.Exec(
-		"UPDATE manga SET title = $1, description = $2 WHERE id = $3",
-		m.Title, m.Description, m.ID,
+		"UPDATE manga SET title = $1, description = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL",
+		m.Title, m.Description, now, m.ID,
 	)
 
 	if err != nil {
@@ -104,31 +131,79 @@ func (r *PostgresMangaRepository) Update(m *models.Manga) error {
 		r.logger.Error("Манга не найдена для обновления в PostgreSQL", "id", m.ID)
 		return sql.ErrNoRows
 	}
+	m.UpdatedAt = now
 
 	return nil
 }
 
+// Delete переносит мангу, ее главы и страницы в корзину одним запросом с
+// общим временем удаления, по которому Restore находит удаленное вместе с мангой.
 func (r *PostgresMangaRepository) Delete(id int64) error {
-	result, err := r.db.Exec("DELETE FROM manga WHERE id = $1", id)
+	var deleted bool
+	err := r.db.QueryRow(
+		`WITH deleted AS (
+			UPDATE manga SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL RETURNING id
+		), chapters_deleted AS (
+			UPDATE chapters SET deleted_at = $1
+			WHERE manga_id IN (SELECT id FROM deleted) AND deleted_at IS NULL
+		), pages_deleted AS (
+			UPDATE pages SET deleted_at = $1
+			WHERE chapter_id IN (SELECT c.id FROM chapters c JOIN deleted d ON d.id = c.manga_id) AND deleted_at IS NULL
+		)
+		SELECT EXISTS(SELECT 1 FROM deleted)`,
+		time.Now().UTC(), id,
+	).Scan(&deleted)
 	if err != nil {
 		r.logger.Error("Ошибка удаления манги из PostgreSQL", "err", err, "id", id)
 		return err
 	}
+	if !deleted {
+		r.logger.Error("Манга не найдена для удаления в PostgreSQL", "id", id)
+		return sql.ErrNoRows
+	}
 
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+// Restore не восстанавливает главы и страницы, удаленные до манги: они
+// остаются в корзине со своим временем удаления.
+func (r *PostgresMangaRepository) Restore(id int64) error {
+	var restored bool
+	err := r.db.QueryRow(
+		`WITH target AS (
+			SELECT id, deleted_at FROM manga WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE
+		), restored AS (
+			UPDATE manga m SET deleted_at = NULL FROM target t WHERE m.id = t.id RETURNING m.id
+		), chapters_restored AS (
+			UPDATE chapters c SET deleted_at = NULL FROM target t
+			WHERE c.manga_id = t.id AND c.deleted_at = t.deleted_at
+		), pages_restored AS (
+			UPDATE pages p SET deleted_at = NULL FROM chapters c, target t
+			WHERE p.chapter_id = c.id AND c.manga_id = t.id AND c.deleted_at = t.deleted_at AND p.deleted_at = t.deleted_at
+		)
+		SELECT EXISTS(SELECT 1 FROM restored)`,
+		id,
+	).Scan(&restored)
 	if err != nil {
-		r.logger.Error("Ошибка получения количества удаленных строк в PostgreSQL", "err", err)
+		r.logger.Error("Ошибка восстановления манги в PostgreSQL", "err", err, "id", id)
 		return err
 	}
-
-	if rowsAffected == 0 {
-		r.logger.Error("Манга не найдена для удаления в PostgreSQL", "id", id)
+	if !restored {
 		return sql.ErrNoRows
 	}
 
 	return nil
 }
 
+func (r *PostgresMangaRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= $1", before.UTC())
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины манги в PostgreSQL", "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *PostgresMangaRepository) GetDB() *sql.DB {
 	return r.db
 }
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresNotificationRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewNotificationRepository(db *sql.DB, logger *slog.Logger) db.NotificationRepository {
+	return &PostgresNotificationRepository{db: db, logger: logger}
+}
+
+// NotifyFollowers рассылает уведомление одним запросом INSERT ... SELECT,
+// чтобы число подписчиков не влияло на число обращений к базе.
+func (r *PostgresNotificationRepository) NotifyFollowers(n *models.Notification) (int64, error) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	result, err := r.db.Exec(`INSERT INTO notifications (user_id, event, manga_id, chapter_id, message, is_read, created_at)
+		SELECT f.user_id, $1, f.manga_id, $2, $3, FALSE, $4
+		FROM manga_follows f
+		LEFT JOIN notification_preferences p ON p.user_id = f.user_id AND p.event = $1
+		WHERE f.manga_id = $5 AND COALESCE(p.enabled, TRUE)`,
+		n.Event, nullInt64(n.ChapterID), n.Message, n.CreatedAt, n.MangaID)
+	if err != nil {
+		r.logger.Error("Ошибка рассылки уведомлений в PostgreSQL", "manga_id", n.MangaID, "event", n.Event, "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *PostgresNotificationRepository) ListByUser(userID int64, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	query := `SELECT n.id, n.user_id, n.event, n.manga_id, COALESCE(m.title, ''), COALESCE(n.chapter_id, 0),
+		n.message, n.is_read, n.created_at
+		FROM notifications n LEFT JOIN manga m ON m.id = n.manga_id
+		WHERE n.user_id = $1`
+	if unreadOnly {
+		query += " AND NOT n.is_read"
+	}
+	query += " ORDER BY n.id DESC LIMIT $2 OFFSET $3"
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		r.logger.Error("Ошибка получения уведомлений из PostgreSQL", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		if err = rows.Scan(&n.ID, &n.UserID, &n.Event, &n.MangaID, &n.MangaTitle, &n.ChapterID,
+			&n.Message, &n.Read, &n.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования уведомления", "err", err)
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *PostgresNotificationRepository) CountUnread(userID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND NOT is_read", userID).Scan(&count)
+	if err != nil {
+		r.logger.Error("Ошибка подсчета непрочитанных уведомлений в PostgreSQL", "user_id", userID, "err", err)
+	}
+	return count, err
+}
+
+func (r *PostgresNotificationRepository) MarkRead(id, userID int64) error {
+	result, err := r.db.Exec("UPDATE notifications SET is_read = TRUE WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		r.logger.Error("Ошибка отметки уведомления в PostgreSQL", "id", id, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresNotificationRepository) MarkAllRead(userID int64) (int64, error) {
+	result, err := r.db.Exec("UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND NOT is_read", userID)
+	if err != nil {
+		r.logger.Error("Ошибка отметки уведомлений в PostgreSQL", "user_id", userID, "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *PostgresNotificationRepository) GetPreferences(userID int64) (map[models.NotificationEvent]bool, error) {
+	rows, err := r.db.Query("SELECT event, enabled FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения настроек уведомлений из PostgreSQL", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := map[models.NotificationEvent]bool{}
+	for rows.Next() {
+		var (
+			event   models.NotificationEvent
+			enabled bool
+		)
+		if err = rows.Scan(&event, &enabled); err != nil {
+			r.logger.Error("Ошибка сканирования настройки уведомлений", "err", err)
+			return nil, err
+		}
+		prefs[event] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+func (r *PostgresNotificationRepository) SetPreference(userID int64, event models.NotificationEvent, enabled bool) error {
+	_, err := r.db.Exec(`INSERT INTO notification_preferences (user_id, event, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, event) DO UPDATE SET enabled = EXCLUDED.enabled`, userID, event, enabled)
+	if err != nil {
+		r.logger.Error("Ошибка сохранения настройки уведомлений в PostgreSQL", "user_id", userID, "event", event, "err", err)
+	}
+	return err
+}
+
+// nullInt64 сохраняет нулевой идентификатор как NULL.
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"manga-reader/models"
+	"time"
 )
 
 type PostgresPageRepository struct {
@@ -19,27 +20,39 @@ func NewPageRepository(db *sql.DB, logger *slog.Logger) *PostgresPageRepository
 	}
 }
 
+const pageColumns = "id, chapter_id, number, image_path, created_at, updated_at, deleted_at"
+
+func scanPage(row interface{ Scan(...any) error }) (*models.Page, error) {
+	page := &models.Page{}
+	var deletedAt sql.NullTime
+	if err := row.Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath, &page.CreatedAt, &page.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		page.DeletedAt = &deletedAt.Time
+	}
+	return page, nil
+}
+
 func (r *PostgresPageRepository) Create(p *models.Page) (int64, error) {
 	var id int64
+	now := time.Now().UTC()
 	err := r.db.QueryRow(
-		"INSERT INTO pages (chapter_id, number, image_path) VALUES ($1, $2, $3) RETURNING id",
-		p.ChapterID, p.Number, p.ImagePath,
+		"INSERT INTO pages (chapter_id, number, image_path, created_at, updated_at) VALUES ($1, $2, $3, $4, $4) RETURNING id",
+		p.ChapterID, p.Number, p.ImagePath, now,
 	).Scan(&id)
 
 	if err != nil {
 		r.logger.Error("Ошибка вставки страницы в PostgreSQL", "err", err)
 		return 0, err
 	}
+	p.CreatedAt, p.UpdatedAt = now, now
 
 	return id, nil
 }
 
 func (r *PostgresPageRepository) GetByID(id int64) (*models.Page, error) {
-	page := &models.Page{}
-	err := r.db.QueryRow(
-		"SELECT id, chapter_id, number, image_path FROM pages WHERE id = $1",
-		id,
-	).Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath)
+	page, err := scanPage(r.db.QueryRow("SELECT "+pageColumns+" FROM pages WHERE id = $1 AND deleted_at IS NULL", id))
 
 	if err != nil {
 		r.logger.Error("Ошибка получения страницы из PostgreSQL", "err", err, "id", id)
@@ -51,7 +64,7 @@ func (r *PostgresPageRepository) GetByID(id int64) (*models.Page, error) {
 
 func (r *PostgresPageRepository) ListByChapter(chapterID int64) ([]*models.Page, error) {
 	rows, err := r.db.Query(
-		"SELECT id, chapter_id, number, image_path FROM pages WHERE chapter_id = $1 ORDER BY number",
+		"SELECT "+pageColumns+" FROM pages WHERE chapter_id = $1 AND deleted_at IS NULL ORDER BY number",
 		chapterID,
 	)
 
@@ -59,19 +72,23 @@ func (r *PostgresPageRepository) ListByChapter(chapterID int64) ([]*models.Page,
 		r.logger.Error("Ошибка получения списка страниц из PostgreSQL", "err", err, "chapter_id", chapterID)
 		return nil, err
 	}
+	return r.scanPages(rows)
+}
+
+func (r *PostgresPageRepository) scanPages(rows *sql.Rows) ([]*models.Page, error) {
 	defer rows.Close()
 
 	var pages []*models.Page
 	for rows.Next() {
-		page := &models.Page{}
-		if err := rows.Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath); err != nil {
+		page, err := scanPage(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования страницы из PostgreSQL", "err", err)
 			return nil, err
 		}
 		pages = append(pages, page)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		r.logger.Error("Ошибка итерации по результатам из PostgreSQL", "err", err)
 		return nil, err
 	}
@@ -80,9 +97,10 @@ func (r *PostgresPageRepository) ListByChapter(chapterID int64) ([]*models.Page,
 }
 
 func (r *PostgresPageRepository) Update(p *models.Page) error {
+	now := time.Now().UTC()
 	result, err := r.db.Exec(
-		"UPDATE pages SET chapter_id = $1, number = $2, image_path = $3 WHERE id = $4",
-		p.ChapterID, p.Number, p.ImagePath, p.ID,
+		"UPDATE pages SET chapter_id = $1, number = $2, image_path = $3, updated_at = $4 WHERE id = $5 AND deleted_at IS NULL",
+		p.ChapterID, p.Number, p.ImagePath, now, p.ID,
 	)
 
 	if err != nil {
@@ -101,12 +119,13 @@ func (r *PostgresPageRepository) Update(p *models.Page) error {
 		r.logger.Error("Страница не найдена для обновления в PostgreSQL", "id", p.ID)
 		return err
 	}
+	p.UpdatedAt = now
 
 	return nil
 }
 
 func (r *PostgresPageRepository) Delete(id int64) error {
-	result, err := r.db.Exec("DELETE FROM pages WHERE id = $1", id)
+	result, err := r.db.Exec("UPDATE pages SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", time.Now().UTC(), id)
 	if err != nil {
 		r.logger.Error("Ошибка удаления страницы из PostgreSQL", "err", err, "id", id)
 		return err
@@ -126,3 +145,48 @@ func (r *PostgresPageRepository) Delete(id int64) error {
 
 	return nil
 }
+
+func (r *PostgresPageRepository) ListDeleted() ([]*models.Page, error) {
+	rows, err := r.db.Query(
+		"SELECT " + pageColumns + " FROM pages WHERE deleted_at IS NOT NULL AND chapter_id IN " +
+			"(SELECT id FROM chapters WHERE deleted_at IS NULL) ORDER BY deleted_at DESC, id DESC",
+	)
+	if err != nil {
+		r.logger.Error("Ошибка получения страниц из корзины в PostgreSQL", "err", err)
+		return nil, err
+	}
+	return r.scanPages(rows)
+}
+
+func (r *PostgresPageRepository) Restore(id int64) error {
+	result, err := r.db.Exec(
+		"UPDATE pages SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND chapter_id IN "+
+			"(SELECT id FROM chapters WHERE deleted_at IS NULL)",
+		id,
+	)
+	if err != nil {
+		r.logger.Error("Ошибка восстановления страницы в PostgreSQL", "err", err, "id", id)
+		return err
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *PostgresPageRepository) PurgeDeleted(before time.Time) ([]*models.Page, error) {
+	rows, err := r.db.Query(
+		`DELETE FROM pages WHERE (deleted_at IS NOT NULL AND deleted_at <= $1) OR chapter_id IN (
+			SELECT id FROM chapters WHERE (deleted_at IS NOT NULL AND deleted_at <= $1) OR manga_id IN (
+				SELECT id FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= $1))
+		RETURNING `+pageColumns,
+		before.UTC(),
+	)
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины страниц в PostgreSQL", "err", err)
+		return nil, err
+	}
+	return r.scanPages(rows)
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresScanlationGroupRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewScanlationGroupRepository(db *sql.DB, logger *slog.Logger) db.ScanlationGroupRepository {
+	return &PostgresScanlationGroupRepository{db: db, logger: logger}
+}
+
+const groupColumns = "id, name, website, created_at, updated_at"
+
+func scanGroup(row interface{ Scan(...any) error }) (*models.ScanlationGroup, error) {
+	g := &models.ScanlationGroup{}
+	if err := row.Scan(&g.ID, &g.Name, &g.Website, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *PostgresScanlationGroupRepository) Create(g *models.ScanlationGroup) (int64, error) {
+	var id int64
+	now := time.Now().UTC()
+	err := r.db.QueryRow(
+		"INSERT INTO scanlation_groups (name, website, created_at, updated_at) VALUES ($1, $2, $3, $3) RETURNING id",
+		g.Name, g.Website, now,
+	).Scan(&id)
+	if err != nil {
+		r.logger.Error("Ошибка создания команды переводчиков в PostgreSQL", "err", err)
+		return 0, err
+	}
+	g.CreatedAt, g.UpdatedAt = now, now
+	return id, nil
+}
+
+func (r *PostgresScanlationGroupRepository) GetByID(id int64) (*models.ScanlationGroup, error) {
+	g, err := scanGroup(r.db.QueryRow("SELECT "+groupColumns+" FROM scanlation_groups WHERE id = $1", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения команды переводчиков из PostgreSQL", "err", err, "id", id)
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT u.id, u.username FROM scanlation_group_members gm
+		JOIN users u ON u.id = gm.user_id WHERE gm.group_id = $1 ORDER BY u.username`, id)
+	if err != nil {
+		r.logger.Error("Ошибка получения участников команды из PostgreSQL", "err", err, "id", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	g.Members = []models.GroupMember{}
+	for rows.Next() {
+		var member models.GroupMember
+		if err = rows.Scan(&member.UserID, &member.Username); err != nil {
+			r.logger.Error("Ошибка сканирования участника команды из PostgreSQL", "err", err)
+			return nil, err
+		}
+		g.Members = append(g.Members, member)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Ошибка итерации по результатам из PostgreSQL", "err", err)
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *PostgresScanlationGroupRepository) List() ([]*models.ScanlationGroup, error) {
+	rows, err := r.db.Query("SELECT " + groupColumns + " FROM scanlation_groups ORDER BY name")
+	if err != nil {
+		r.logger.Error("Ошибка получения списка команд переводчиков из PostgreSQL", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*models.ScanlationGroup
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования команды переводчиков из PostgreSQL", "err", err)
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Ошибка итерации по результатам из PostgreSQL", "err", err)
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *PostgresScanlationGroupRepository) Update(g *models.ScanlationGroup) error {
+	now := time.Now().UTC()
+	result, err := r.db.Exec("UPDATE scanlation_groups SET name = $1, website = $2, updated_at = $3 WHERE id = $4",
+		g.Name, g.Website, now, g.ID)
+	if err = r.checkAffected(result, err, "Ошибка обновления команды переводчиков в PostgreSQL", g.ID); err != nil {
+		return err
+	}
+	g.UpdatedAt = now
+	return nil
+}
+
+// Delete удаляет команду. Участники удаляются каскадно, а главы теряют
+// ссылку на команду благодаря ON DELETE SET NULL.
+func (r *PostgresScanlationGroupRepository) Delete(id int64) error {
+	result, err := r.db.Exec("DELETE FROM scanlation_groups WHERE id = $1", id)
+	return r.checkAffected(result, err, "Ошибка удаления команды переводчиков из PostgreSQL", id)
+}
+
+func (r *PostgresScanlationGroupRepository) AddMember(groupID, userID int64) error {
+	_, err := r.db.Exec("INSERT INTO scanlation_group_members (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		groupID, userID)
+	if err != nil {
+		r.logger.Error("Ошибка добавления участника команды в PostgreSQL", "group_id", groupID, "user_id", userID, "err", err)
+	}
+	return err
+}
+
+func (r *PostgresScanlationGroupRepository) RemoveMember(groupID, userID int64) error {
+	result, err := r.db.Exec("DELETE FROM scanlation_group_members WHERE group_id = $1 AND user_id = $2", groupID, userID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления участника команды в PostgreSQL", "group_id", groupID, "user_id", userID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresScanlationGroupRepository) IsMember(groupID, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM scanlation_group_members WHERE group_id = $1 AND user_id = $2)",
+		groupID, userID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Ошибка проверки участия в команде в PostgreSQL", "group_id", groupID, "user_id", userID, "err", err)
+	}
+	return exists, err
+}
+
+func (r *PostgresScanlationGroupRepository) checkAffected(result sql.Result, err error, msg string, id int64) error {
+	if err != nil {
+		r.logger.Error(msg, "err", err, "id", id)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Ошибка получения количества измененных строк в PostgreSQL", "err", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		r.logger.Error("Команда переводчиков не найдена в PostgreSQL", "id", id)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"log/slog"
 	"manga-reader/models"
+	"strings"
 )
 
 type PostgresUserRepository struct {
@@ -18,11 +19,37 @@ func NewUserRepository(db *sql.DB, logger *slog.Logger) *PostgresUserRepository
 	}
 }
 
+const userColumns = "id, username, COALESCE(email, ''), password, role, " +
+	"COALESCE(totp_secret, ''), totp_enabled, COALESCE(recovery_codes, ''), COALESCE(preferred_language, '')"
+
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
+	user := &models.User{}
+	var recoveryCodes string
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role,
+		&user.TOTPSecret, &user.TOTPEnabled, &recoveryCodes, &user.PreferredLanguage); err != nil {
+		return nil, err
+	}
+	if recoveryCodes != "" {
+		user.RecoveryCodes = strings.Split(recoveryCodes, ",")
+	}
+	return user, nil
+}
+
+// nullString сохраняет пустую строку как NULL, чтобы не нарушать
+// уникальность необязательных полей.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 func (r *PostgresUserRepository) Create(user *models.User) (int64, error) {
+	if user.Role == "" {
+		user.Role = models.RoleReader
+	}
+
 	var id int64
 	err := r.db.QueryRow(
-		"INSERT INTO users (username, password) VALUES ($1, $2) RETURNING id",
-		user.Username, user.Password,
+		"INSERT INTO users (username, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id",
+		user.Username, nullString(user.Email), user.Password, user.Role,
 	).Scan(&id)
 
 	if err != nil {
@@ -33,13 +60,18 @@ func (r *PostgresUserRepository) Create(user *models.User) (int64, error) {
 	return id, nil
 }
 
-func (r *PostgresUserRepository) GetByUsername(username string) (*models.User, error) {
-	user := &models.User{}
-	err := r.db.QueryRow(
-		"SELECT id, username, password FROM users WHERE username = $1",
-		username,
-	).Scan(&user.ID, &user.Username, &user.Password)
+func (r *PostgresUserRepository) GetByID(id int64) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", id))
+	if err != nil {
+		r.logger.Error("Ошибка получения пользователя по id из PostgreSQL", "err", err, "id", id)
+		return nil, err
+	}
+
+	return user, nil
+}
 
+func (r *PostgresUserRepository) GetByUsername(username string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = $1", username))
 	if err != nil {
 		r.logger.Error("Ошибка получения пользователя по username из PostgreSQL", "err", err, "username", username)
 		return nil, err
@@ -47,3 +79,87 @@ func (r *PostgresUserRepository) GetByUsername(username string) (*models.User, e
 
 	return user, nil
 }
+
+func (r *PostgresUserRepository) GetByEmail(email string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = $1", email))
+	if err != nil {
+		r.logger.Error("Ошибка получения пользователя по email из PostgreSQL", "err", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) Update(user *models.User) error {
+	result, err := r.db.Exec(
+		"UPDATE users SET username = $1, email = $2, preferred_language = $3 WHERE id = $4",
+		user.Username, nullString(user.Email), nullString(user.PreferredLanguage), user.ID,
+	)
+	return r.checkAffected(result, err, "Ошибка обновления пользователя в PostgreSQL", user.ID)
+}
+
+func (r *PostgresUserRepository) UpdatePassword(id int64, passwordHash string) error {
+	result, err := r.db.Exec("UPDATE users SET password = $1 WHERE id = $2", passwordHash, id)
+	return r.checkAffected(result, err, "Ошибка обновления пароля в PostgreSQL", id)
+}
+
+func (r *PostgresUserRepository) UpdateRole(id int64, role models.Role) error {
+	result, err := r.db.Exec("UPDATE users SET role = $1 WHERE id = $2", role, id)
+	return r.checkAffected(result, err, "Ошибка обновления роли пользователя в PostgreSQL", id)
+}
+
+func (r *PostgresUserRepository) UpdateTOTP(id int64, secret string, enabled bool, recoveryCodes []string) error {
+	result, err := r.db.Exec(
+		"UPDATE users SET totp_secret = $1, totp_enabled = $2, recovery_codes = $3 WHERE id = $4",
+		nullString(secret), enabled, nullString(strings.Join(recoveryCodes, ",")), id,
+	)
+	return r.checkAffected(result, err, "Ошибка обновления настроек 2FA в PostgreSQL", id)
+}
+
+func (r *PostgresUserRepository) GetByIdentity(issuer, subject string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+
+		" FROM users WHERE id = (SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2)", issuer, subject))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения пользователя по внешней учетной записи из PostgreSQL", "err", err)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) LinkIdentity(userID int64, issuer, subject string) error {
+	_, err := r.db.Exec("INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)", userID, issuer, subject)
+	if err != nil {
+		r.logger.Error("Ошибка привязки внешней учетной записи в PostgreSQL", "err", err, "user_id", userID)
+	}
+	return err
+}
+
+// Delete удаляет пользователя. Связанные с ним данные удаляются каскадно
+// внешними ключами.
+func (r *PostgresUserRepository) Delete(id int64) error {
+	result, err := r.db.Exec("DELETE FROM users WHERE id = $1", id)
+	return r.checkAffected(result, err, "Ошибка удаления пользователя из PostgreSQL", id)
+}
+
+func (r *PostgresUserRepository) checkAffected(result sql.Result, err error, msg string, id int64) error {
+	if err != nil {
+		r.logger.Error(msg, "err", err, "id", id)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Ошибка получения количества измененных строк в PostgreSQL", "err", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		r.logger.Error("Пользователь не найден в PostgreSQL", "id", id)
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresViewHistoryRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewViewHistoryRepository(db *sql.DB, logger *slog.Logger) db.ViewHistoryRepository {
+	return &PostgresViewHistoryRepository{db: db, logger: logger}
+}
+
+func (r *PostgresViewHistoryRepository) Save(stats []*models.DailyViews) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO view_history (entity_type, entity_id, day, views, unique_viewers)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (entity_type, entity_id, day) DO UPDATE SET
+			views = GREATEST(view_history.views, EXCLUDED.views),
+			unique_viewers = GREATEST(view_history.unique_viewers, EXCLUDED.unique_viewers)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range stats {
+		day := s.Day.UTC().Format(time.DateOnly)
+		if _, err = stmt.Exec(s.EntityType, s.EntityID, day, s.Views, s.UniqueViewers); err != nil {
+			r.logger.Error("Ошибка сохранения истории просмотров в PostgreSQL", "entity_type", s.EntityType,
+				"entity_id", s.EntityID, "day", day, "err", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresViewHistoryRepository) List(entity models.ViewEntity, entityID int64, from, to time.Time) ([]*models.DailyViews, error) {
+	query := `SELECT entity_type, entity_id, day, views, unique_viewers FROM view_history
+		WHERE entity_type = $1 AND day >= $2 AND day < $3`
+	args := []any{entity, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly)}
+	if entityID != 0 {
+		query += " AND entity_id = $4"
+		args = append(args, entityID)
+	}
+	query += " ORDER BY day, entity_id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения истории просмотров из PostgreSQL", "entity_type", entity, "entity_id", entityID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*models.DailyViews{}
+	for rows.Next() {
+		s := &models.DailyViews{}
+		if err = rows.Scan(&s.EntityType, &s.EntityID, &s.Day, &s.Views, &s.UniqueViewers); err != nil {
+			r.logger.Error("Ошибка сканирования истории просмотров", "err", err)
+			return nil, err
+		}
+		s.Day = s.Day.UTC()
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func (r *PostgresViewHistoryRepository) Totals(entity models.ViewEntity) (map[int64]int64, error) {
+	rows, err := r.db.Query(`SELECT entity_id, SUM(views) FROM view_history
+		WHERE entity_type = $1 GROUP BY entity_id`, entity)
+	if err != nil {
+		r.logger.Error("Ошибка подсчета просмотров по истории в PostgreSQL", "entity_type", entity, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int64]int64)
+	for rows.Next() {
+		var id, views int64
+		if err = rows.Scan(&id, &views); err != nil {
+			r.logger.Error("Ошибка сканирования суммы просмотров", "err", err)
+			return nil, err
+		}
+		totals[id] = views
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"manga-reader/internal/db"
+	"manga-reader/models"
+)
+
+type PostgresWebhookRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewWebhookRepository(db *sql.DB, logger *slog.Logger) db.WebhookRepository {
+	return &PostgresWebhookRepository{db: db, logger: logger}
+}
+
+const webhookColumns = "id, url, secret, events, active, created_at"
+
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	var events string
+	if err := row.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.Active, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+	hook.Events = splitEvents(events)
+	return hook, nil
+}
+
+func joinEvents(events []models.WebhookEvent) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = string(e)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitEvents(value string) []models.WebhookEvent {
+	events := []models.WebhookEvent{}
+	for _, e := range strings.Split(value, ",") {
+		if e != "" {
+			events = append(events, models.WebhookEvent(e))
+		}
+	}
+	return events
+}
+
+func (r *PostgresWebhookRepository) Create(hook *models.Webhook) (int64, error) {
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now().UTC()
+	}
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO webhooks (url, secret, events, active, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		hook.URL, hook.Secret, joinEvents(hook.Events), hook.Active, hook.CreatedAt).Scan(&id)
+	if err != nil {
+		r.logger.Error("Ошибка создания вебхука в PostgreSQL", "err", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PostgresWebhookRepository) GetByID(id int64) (*models.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения вебхука из PostgreSQL", "id", id, "err", err)
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (r *PostgresWebhookRepository) List() ([]*models.Webhook, error) {
+	rows, err := r.db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		r.logger.Error("Ошибка получения списка вебхуков из PostgreSQL", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования вебхука", "err", err)
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *PostgresWebhookRepository) Update(hook *models.Webhook) error {
+	result, err := r.db.Exec("UPDATE webhooks SET url = $1, secret = $2, events = $3, active = $4 WHERE id = $5",
+		hook.URL, hook.Secret, joinEvents(hook.Events), hook.Active, hook.ID)
+	if err != nil {
+		r.logger.Error("Ошибка обновления вебхука в PostgreSQL", "id", hook.ID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete удаляет вебхук; журнал доставки удаляется каскадно.
+func (r *PostgresWebhookRepository) Delete(id int64) error {
+	result, err := r.db.Exec("DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		r.logger.Error("Ошибка удаления вебхука из PostgreSQL", "id", id, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) LogDelivery(d *models.WebhookDelivery) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	err := r.db.QueryRow(`INSERT INTO webhook_deliveries
+		(webhook_id, event_id, event, attempt, status_code, error, success, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		d.WebhookID, d.EventID, d.Event, d.Attempt, d.StatusCode, d.Error, d.Success, d.DurationMS, d.CreatedAt).Scan(&d.ID)
+	if err != nil {
+		r.logger.Error("Ошибка записи журнала доставки вебхука в PostgreSQL", "webhook_id", d.WebhookID, "err", err)
+	}
+	return err
+}
+
+func (r *PostgresWebhookRepository) ListDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`SELECT id, webhook_id, event_id, event, attempt, status_code, error, success, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`, webhookID, limit, offset)
+	if err != nil {
+		r.logger.Error("Ошибка получения журнала доставки вебхука из PostgreSQL", "webhook_id", webhookID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		if err = rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Attempt, &d.StatusCode,
+			&d.Error, &d.Success, &d.DurationMS, &d.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования записи журнала доставки", "err", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
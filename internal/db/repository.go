@@ -1,6 +1,9 @@
 package db
 
-import "manga-reader/models"
+import (
+	"manga-reader/models"
+	"time"
+)
 
 // MangaRepository описывает операции над мангой.
 type MangaRepository interface {
@@ -8,16 +11,61 @@ type MangaRepository interface {
 	GetByID(id int64) (*models.Manga, error)
 	List() ([]*models.Manga, error)
 	Update(m *models.Manga) error
+	// Delete переносит мангу в корзину вместе с ее главами и страницами.
 	Delete(id int64) error
+	// ListDeleted возвращает мангу из корзины, начиная с удаленной последней.
+	ListDeleted() ([]*models.Manga, error)
+	// Restore возвращает мангу из корзины вместе с главами и страницами,
+	// удаленными вместе с ней. Если манги нет в корзине, возвращает sql.ErrNoRows.
+	Restore(id int64) error
+	// PurgeDeleted окончательно удаляет мангу, перенесенную в корзину не
+	// позже before, и возвращает число удаленных записей.
+	PurgeDeleted(before time.Time) (int64, error)
 }
 
 // ChapterRepository описывает операции над главами манги.
 type ChapterRepository interface {
+	// Create добавляет главу и, если она опубликована, обновляет updated_at ее манги.
 	Create(ch *models.Chapter) (int64, error)
 	GetByID(id int64) (*models.Chapter, error)
 	ListByManga(mangaID int64) ([]*models.Chapter, error)
+	// ListPublished возвращает последние опубликованные главы манги mangaID,
+	// а при mangaID == 0 — всего каталога, от новых к старым.
+	ListPublished(mangaID int64, limit int) ([]*models.Chapter, error)
+	// ListLatest возвращает недавно добавленные опубликованные главы всего каталога.
+	ListLatest(limit, offset int) ([]*models.Chapter, error)
+	// Update сохраняет номер, название, язык, команду и состояние публикации главы.
 	Update(ch *models.Chapter) error
+	// PublishDue публикует запланированные главы, время публикации которых
+	// наступило к now, и возвращает их.
+	PublishDue(now time.Time) ([]*models.Chapter, error)
+	// Delete переносит главу в корзину вместе с ее страницами.
 	Delete(id int64) error
+	// ListDeleted возвращает главы из корзины, манга которых не удалена.
+	ListDeleted() ([]*models.Chapter, error)
+	// Restore возвращает главу из корзины вместе со страницами, удаленными
+	// вместе с ней. Возвращает sql.ErrNoRows, если главы нет в корзине или
+	// в корзине находится ее манга.
+	Restore(id int64) error
+	// PurgeDeleted окончательно удаляет главы, перенесенные в корзину не
+	// позже before, а также все главы манги, удаляемой из корзины.
+	PurgeDeleted(before time.Time) (int64, error)
+}
+
+// ScanlationGroupRepository описывает команды переводчиков и их участников.
+type ScanlationGroupRepository interface {
+	Create(g *models.ScanlationGroup) (int64, error)
+	// GetByID возвращает команду вместе со списком участников.
+	GetByID(id int64) (*models.ScanlationGroup, error)
+	List() ([]*models.ScanlationGroup, error)
+	Update(g *models.ScanlationGroup) error
+	// Delete удаляет команду; ее главы остаются без команды.
+	Delete(id int64) error
+	// AddMember добавляет участника; повторное добавление не считается ошибкой.
+	AddMember(groupID, userID int64) error
+	// RemoveMember возвращает sql.ErrNoRows, если пользователь не состоял в команде.
+	RemoveMember(groupID, userID int64) error
+	IsMember(groupID, userID int64) (bool, error)
 }
 
 // PageRepository описывает операции над страницами глав.
@@ -26,11 +74,103 @@ type PageRepository interface {
 	GetByID(id int64) (*models.Page, error)
 	ListByChapter(chapterID int64) ([]*models.Page, error)
 	Update(p *models.Page) error
+	// Delete переносит страницу в корзину; файл изображения сохраняется.
 	Delete(id int64) error
+	// ListDeleted возвращает страницы из корзины, глава которых не удалена.
+	ListDeleted() ([]*models.Page, error)
+	// Restore возвращает sql.ErrNoRows, если страницы нет в корзине или в
+	// корзине находится ее глава.
+	Restore(id int64) error
+	// PurgeDeleted окончательно удаляет страницы, перенесенные в корзину не
+	// позже before, а также все страницы глав и манги, удаляемых из
+	// корзины, и возвращает их, чтобы можно было удалить файлы.
+	PurgeDeleted(before time.Time) ([]*models.Page, error)
 }
 
 // UserRepository описывает операции над пользователями.
 type UserRepository interface {
 	Create(user *models.User) (int64, error)
+	GetByID(id int64) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
+	GetByEmail(email string) (*models.User, error)
+	Update(user *models.User) error
+	UpdatePassword(id int64, passwordHash string) error
+	UpdateRole(id int64, role models.Role) error
+	// UpdateTOTP сохраняет секрет TOTP, признак включения 2FA и хеши кодов восстановления.
+	UpdateTOTP(id int64, secret string, enabled bool, recoveryCodes []string) error
+	// GetByIdentity ищет пользователя, связанного с учетной записью внешнего провайдера.
+	GetByIdentity(issuer, subject string) (*models.User, error)
+	LinkIdentity(userID int64, issuer, subject string) error
+	Delete(id int64) error
+}
+
+// APIKeyRepository описывает операции над API-ключами пользователей.
+type APIKeyRepository interface {
+	Create(key *models.APIKey) (int64, error)
+	// GetByHash ищет ключ по хешу и заполняет роль его владельца.
+	GetByHash(hash string) (*models.APIKey, error)
+	ListByUser(userID int64) ([]*models.APIKey, error)
+	// Delete удаляет ключ, только если он принадлежит пользователю userID.
+	Delete(id, userID int64) error
+	TouchLastUsed(id int64, at time.Time) error
+}
+
+// FollowRepository описывает подписки пользователей на мангу.
+type FollowRepository interface {
+	// Follow подписывает пользователя на мангу; повторная подписка не считается ошибкой.
+	Follow(userID, mangaID int64) error
+	// Unfollow возвращает sql.ErrNoRows, если подписки не было.
+	Unfollow(userID, mangaID int64) error
+	IsFollowing(userID, mangaID int64) (bool, error)
+	ListByUser(userID int64) ([]*models.Follow, error)
+}
+
+// NotificationRepository описывает входящие уведомления и настройки пользователей.
+type NotificationRepository interface {
+	// NotifyFollowers создает копию уведомления n для каждого подписчика
+	// манги n.MangaID, не отключившего событие n.Event, и возвращает их число.
+	NotifyFollowers(n *models.Notification) (int64, error)
+	ListByUser(userID int64, unreadOnly bool, limit, offset int) ([]*models.Notification, error)
+	CountUnread(userID int64) (int64, error)
+	// MarkRead возвращает sql.ErrNoRows, если уведомление не принадлежит пользователю.
+	MarkRead(id, userID int64) error
+	MarkAllRead(userID int64) (int64, error)
+	// GetPreferences возвращает только явно сохраненные настройки;
+	// отсутствующие события считаются включенными.
+	GetPreferences(userID int64) (map[models.NotificationEvent]bool, error)
+	SetPreference(userID int64, event models.NotificationEvent, enabled bool) error
+}
+
+// WebhookRepository описывает подписки на вебхуки и журнал их доставки.
+type WebhookRepository interface {
+	Create(hook *models.Webhook) (int64, error)
+	GetByID(id int64) (*models.Webhook, error)
+	List() ([]*models.Webhook, error)
+	Update(hook *models.Webhook) error
+	Delete(id int64) error
+	LogDelivery(d *models.WebhookDelivery) error
+	// ListDeliveries возвращает попытки доставки от новых к старым.
+	ListDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error)
+}
+
+// AuditRepository описывает журнал аудита изменений.
+type AuditRepository interface {
+	// Record добавляет запись в журнал и заполняет ее ID.
+	Record(e *models.AuditEntry) error
+	// List возвращает записи, подходящие под фильтр, от новых к старым.
+	List(filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error)
+}
+
+// ViewHistoryRepository хранит посуточную историю просмотров, переживающую
+// очистку Redis.
+type ViewHistoryRepository interface {
+	// Save добавляет или обновляет записи за сутки. Значения существующих
+	// записей только увеличиваются, поэтому повторный перенос из Redis после
+	// его очистки не затирает историю.
+	Save(stats []*models.DailyViews) error
+	// List возвращает записи сущностей типа entity за сутки из [from, to) по
+	// возрастанию дня; нулевой entityID означает все сущности этого типа.
+	List(entity models.ViewEntity, entityID int64, from, to time.Time) ([]*models.DailyViews, error)
+	// Totals возвращает сумму просмотров каждой сущности типа entity за все время.
+	Totals(entity models.ViewEntity) (map[int64]int64, error)
 }
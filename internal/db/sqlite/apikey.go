@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"strings"
+	"time"
+)
+
+type SQLiteAPIKeyRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAPIKeyRepository(conn *sql.DB, logger *slog.Logger) db.APIKeyRepository {
+	repo := &SQLiteAPIKeyRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для API-ключей", "err", err)
+	}
+	return repo
+}
+
+func (r *SQLiteAPIKeyRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы api_keys", "err", err)
+	}
+	return err
+}
+
+const apiKeyColumns = "k.id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at"
+
+func scanAPIKey(row interface{ Scan(...any) error }, extra ...any) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var (
+		scopes    string
+		expiresAt sql.NullTime
+		lastUsed  sql.NullTime
+	)
+	dest := append([]any{&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&expiresAt, &lastUsed, &key.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	key.Scopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	return key, nil
+}
+
+func joinScopes(scopes []models.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitScopes(value string) []models.Scope {
+	scopes := []models.Scope{}
+	for _, s := range strings.Split(value, ",") {
+		if s != "" {
+			scopes = append(scopes, models.Scope(s))
+		}
+	}
+	return scopes
+}
+
+func (r *SQLiteAPIKeyRepository) Create(key *models.APIKey) (int64, error) {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+	result, err := r.db.Exec(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		key.UserID, key.Name, key.Prefix, key.KeyHash, joinScopes(key.Scopes), key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		r.logger.Error("Ошибка создания API-ключа", "err", err)
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByHash возвращает ключ вместе с ролью владельца. Ключи удаленных
+// пользователей не находятся благодаря JOIN, даже если строка осталась.
+func (r *SQLiteAPIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	var role models.Role
+	key, err := scanAPIKey(r.db.QueryRow(
+		"SELECT "+apiKeyColumns+", u.role FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = ?",
+		hash), &role)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка поиска API-ключа", "err", err)
+		}
+		return nil, err
+	}
+	key.OwnerRole = role
+	return key, nil
+}
+
+func (r *SQLiteAPIKeyRepository) ListByUser(userID int64) ([]*models.APIKey, error) {
+	rows, err := r.db.Query("SELECT "+apiKeyColumns+" FROM api_keys k WHERE k.user_id = ? ORDER BY k.id", userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения API-ключей", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования API-ключа", "err", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *SQLiteAPIKeyRepository) Delete(id, userID int64) error {
+	result, err := r.db.Exec("DELETE FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления API-ключа", "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteAPIKeyRepository) TouchLastUsed(id int64, at time.Time) error {
+	_, err := r.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", at, id)
+	if err != nil {
+		r.logger.Error("Ошибка обновления времени использования API-ключа", "err", err)
+	}
+	return err
+}
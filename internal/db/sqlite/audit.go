@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"strings"
+	"time"
+)
+
+type SQLiteAuditRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAuditRepository(conn *sql.DB, logger *slog.Logger) db.AuditRepository {
+	repo := &SQLiteAuditRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для журнала аудита", "err", err)
+	}
+	return repo
+}
+
+// initSchema не связывает actor_id с таблицей users: записи журнала
+// должны переживать удаление пользователя.
+func (r *SQLiteAuditRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER,
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL,
+		entity_id INTEGER NOT NULL,
+		changes TEXT NOT NULL DEFAULT '{}',
+		request_id TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы журнала аудита", "err", err)
+	}
+	return err
+}
+
+func (r *SQLiteAuditRepository) Record(e *models.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	var actorID sql.NullInt64
+	if e.ActorID != 0 {
+		actorID = sql.NullInt64{Int64: e.ActorID, Valid: true}
+	}
+	result, err := r.db.Exec(`INSERT INTO audit_log
+		(actor_id, action, entity_type, entity_id, changes, request_id, ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		actorID, e.Action, e.EntityType, e.EntityID, string(changes), e.RequestID, e.IP, e.CreatedAt)
+	if err != nil {
+		r.logger.Error("Ошибка записи в журнал аудита", "action", e.Action, "entity_type", e.EntityType,
+			"entity_id", e.EntityID, "err", err)
+		return err
+	}
+	e.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *SQLiteAuditRepository) List(filter models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	if filter.ActorID != 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	query := `SELECT id, actor_id, action, entity_type, entity_id, changes, request_id, ip, created_at FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения журнала аудита", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var actorID sql.NullInt64
+		var changes string
+		if err = rows.Scan(&e.ID, &actorID, &e.Action, &e.EntityType, &e.EntityID, &changes,
+			&e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования записи журнала аудита", "err", err)
+			return nil, err
+		}
+		e.ActorID = actorID.Int64
+		if err = json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+			r.logger.Error("Ошибка разбора изменений в журнале аудита", "id", e.ID, "err", err)
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
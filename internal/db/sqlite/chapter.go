@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"manga-reader/internal/db"
 	"manga-reader/models"
+	"time"
 )
 
 type SQLiteChapterRepository struct {
@@ -29,28 +30,123 @@ func (r *SQLiteChapterRepository) initSchema() error {
 		manga_id INTEGER NOT NULL,
 		number INTEGER NOT NULL,
 		title TEXT NOT NULL,
+		language TEXT NOT NULL DEFAULT 'ru',
+		group_id INTEGER REFERENCES scanlation_group(id),
+		status TEXT NOT NULL DEFAULT 'published',
+		publish_at DATETIME,
+		published_at DATETIME,
+		created_at DATETIME,
+		updated_at DATETIME,
+		deleted_at DATETIME,
 		FOREIGN KEY(manga_id) REFERENCES manga(id)
 	);`
 	_, err := r.db.Exec(schema)
 	if err != nil {
 		r.logger.Error("Ошибка создания таблицы chapter", "err", err)
+		return err
+	}
+	for _, column := range []struct{ name, definition string }{
+		{"published_at", "DATETIME"},
+		{"status", "TEXT NOT NULL DEFAULT 'published'"},
+		{"publish_at", "DATETIME"},
+		{"language", "TEXT NOT NULL DEFAULT 'ru'"},
+		{"group_id", "INTEGER REFERENCES scanlation_group(id)"},
+		{"deleted_at", "DATETIME"},
+	} {
+		if err = ensureColumn(r.db, "chapter", column.name, column.definition); err != nil {
+			r.logger.Error("Ошибка добавления колонки в таблицу chapter", "column", column.name, "err", err)
+			return err
+		}
+	}
+	if err = ensureTimestamps(r.db, "chapter"); err != nil {
+		r.logger.Error("Ошибка добавления временных меток в таблицу chapter", "err", err)
+		return err
+	}
+	for _, index := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_chapter_published_at ON chapter(published_at)",
+		"CREATE INDEX IF NOT EXISTS idx_chapter_created_at ON chapter(created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_chapter_status_publish_at ON chapter(status, publish_at)",
+		"CREATE INDEX IF NOT EXISTS idx_chapter_group_id ON chapter(group_id)",
+		"CREATE INDEX IF NOT EXISTS idx_chapter_deleted_at ON chapter(deleted_at)",
+	} {
+		if _, err = r.db.Exec(index); err != nil {
+			r.logger.Error("Ошибка создания индекса таблицы chapter", "err", err)
+			return err
+		}
 	}
-	return err
+	return nil
 }
 
+const chapterColumns = "id, manga_id, number, title, language, group_id, status, publish_at, published_at, created_at, updated_at, deleted_at"
+
+func scanChapter(row interface{ Scan(...any) error }) (*models.Chapter, error) {
+	ch := &models.Chapter{}
+	var groupID sql.NullInt64
+	var publishAt, publishedAt, deletedAt sql.NullTime
+	if err := row.Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title, &ch.Language, &groupID, &ch.Status, &publishAt, &publishedAt,
+		&ch.CreatedAt, &ch.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		ch.DeletedAt = &deletedAt.Time
+	}
+	if groupID.Valid {
+		ch.GroupID = &groupID.Int64
+	}
+	if publishAt.Valid {
+		ch.PublishAt = &publishAt.Time
+	}
+	if publishedAt.Valid {
+		ch.PublishedAt = &publishedAt.Time
+	}
+	return ch, nil
+}
+
+// Create добавляет главу и, если она опубликована, в той же транзакции
+// отмечает мангу обновленной.
 func (r *SQLiteChapterRepository) Create(ch *models.Chapter) (int64, error) {
-	result, err := r.db.Exec("INSERT INTO chapter (manga_id, number, title) VALUES (?, ?, ?)", ch.MangaID, ch.Number, ch.Title)
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
+	now := time.Now().UTC()
+	result, err := tx.Exec("INSERT INTO chapter (manga_id, number, title, language, group_id, status, publish_at, published_at, created_at, updated_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		ch.MangaID, ch.Number, ch.Title, ch.Language, ch.GroupID, ch.Status, ch.PublishAt, ch.PublishedAt, now, now)
 	if err != nil {
 		r.logger.Error("Ошибка вставки главы", "err", err)
 		return 0, err
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if ch.Published() {
+		if _, err = tx.Exec("UPDATE manga SET updated_at = ? WHERE id = ?", now, ch.MangaID); err != nil {
+			r.logger.Error("Ошибка обновления времени изменения манги", "err", err)
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return 0, err
+	}
+	ch.CreatedAt, ch.UpdatedAt = now, now
+	return id, nil
 }
 
 func (r *SQLiteChapterRepository) GetByID(id int64) (*models.Chapter, error) {
-	row := r.db.QueryRow("SELECT id, manga_id, number, title FROM chapter WHERE id = ?", id)
-	ch := &models.Chapter{}
-	if err := row.Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title); err != nil {
+	ch, err := scanChapter(r.db.QueryRow("SELECT "+chapterColumns+" FROM chapter WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
 		r.logger.Error("Ошибка получения главы", "err", err)
 		return nil, err
 	}
@@ -58,27 +154,67 @@ func (r *SQLiteChapterRepository) GetByID(id int64) (*models.Chapter, error) {
 }
 
 func (r *SQLiteChapterRepository) ListByManga(mangaID int64) ([]*models.Chapter, error) {
-	rows, err := r.db.Query("SELECT id, manga_id, number, title FROM chapter WHERE manga_id = ? ORDER BY number", mangaID)
+	rows, err := r.db.Query("SELECT "+chapterColumns+" FROM chapter WHERE manga_id = ? AND deleted_at IS NULL ORDER BY number, id", mangaID)
 	if err != nil {
 		r.logger.Error("Ошибка получения списка глав", "err", err)
 		return nil, err
 	}
+	return r.scanChapters(rows)
+}
+
+func (r *SQLiteChapterRepository) ListPublished(mangaID int64, limit int) ([]*models.Chapter, error) {
+	query := "SELECT " + chapterColumns + " FROM chapter WHERE status = 'published' AND published_at IS NOT NULL AND deleted_at IS NULL"
+	args := []any{}
+	if mangaID != 0 {
+		query += " AND manga_id = ?"
+		args = append(args, mangaID)
+	}
+	query += " ORDER BY published_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения опубликованных глав", "err", err)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *SQLiteChapterRepository) ListLatest(limit, offset int) ([]*models.Chapter, error) {
+	rows, err := r.db.Query("SELECT "+chapterColumns+" FROM chapter WHERE status = 'published' AND deleted_at IS NULL ORDER BY published_at DESC, id DESC LIMIT ? OFFSET ?",
+		limit, offset)
+	if err != nil {
+		r.logger.Error("Ошибка получения последних глав", "err", err)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *SQLiteChapterRepository) scanChapters(rows *sql.Rows) ([]*models.Chapter, error) {
 	defer rows.Close()
 
 	var chapters []*models.Chapter
 	for rows.Next() {
-		ch := &models.Chapter{}
-		if err := rows.Scan(&ch.ID, &ch.MangaID, &ch.Number, &ch.Title); err != nil {
+		ch, err := scanChapter(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования главы", "err", err)
 			return nil, err
 		}
 		chapters = append(chapters, ch)
 	}
-	return chapters, nil
+	return chapters, rows.Err()
 }
 
 func (r *SQLiteChapterRepository) Update(ch *models.Chapter) error {
-	result, err := r.db.Exec("UPDATE chapter SET number = ?, title = ? WHERE id = ?", ch.Number, ch.Title, ch.ID)
+	now := time.Now().UTC()
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
+	result, err := r.db.Exec("UPDATE chapter SET number = ?, title = ?, language = ?, group_id = ?, status = ?, publish_at = ?, published_at = ?, "+
+		"updated_at = ? WHERE id = ? AND deleted_at IS NULL", ch.Number, ch.Title, ch.Language, ch.GroupID, ch.Status, ch.PublishAt, ch.PublishedAt, now, ch.ID)
 	if err != nil {
 		r.logger.Error("Ошибка обновления главы", "err", err)
 		return err
@@ -88,11 +224,22 @@ func (r *SQLiteChapterRepository) Update(ch *models.Chapter) error {
 		r.logger.Error("Ошибка обновления главы", "err", err)
 		return err
 	}
+	ch.UpdatedAt = now
 	return nil
 }
 
+// Delete переносит главу и ее страницы в корзину с одним временем
+// удаления, по которому Restore находит удаленные вместе с главой страницы.
 func (r *SQLiteChapterRepository) Delete(id int64) error {
-	result, err := r.db.Exec("DELETE FROM chapter WHERE id = ?", id)
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec("UPDATE chapter SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", now, id)
 	if err != nil {
 		r.logger.Error("Ошибка удаления главы", "err", err)
 		return err
@@ -102,5 +249,110 @@ func (r *SQLiteChapterRepository) Delete(id int64) error {
 		r.logger.Error("Ошибка удаления главы", "err", err)
 		return err
 	}
+	if _, err = tx.Exec("UPDATE pages SET deleted_at = ? WHERE chapter_id = ? AND deleted_at IS NULL", now, id); err != nil {
+		r.logger.Error("Ошибка удаления страниц главы", "id", id, "err", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (r *SQLiteChapterRepository) ListDeleted() ([]*models.Chapter, error) {
+	rows, err := r.db.Query("SELECT " + chapterColumns + " FROM chapter WHERE deleted_at IS NOT NULL AND manga_id IN " +
+		"(SELECT id FROM manga WHERE deleted_at IS NULL) ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		r.logger.Error("Ошибка получения глав из корзины", "err", err)
+		return nil, err
+	}
+	return r.scanChapters(rows)
+}
+
+func (r *SQLiteChapterRepository) Restore(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRow("SELECT deleted_at FROM chapter WHERE id = ? AND deleted_at IS NOT NULL AND manga_id IN "+
+		"(SELECT id FROM manga WHERE deleted_at IS NULL)", id).Scan(&deletedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения главы из корзины", "id", id, "err", err)
+		}
+		return err
+	}
+	if _, err = tx.Exec("UPDATE pages SET deleted_at = NULL WHERE chapter_id = ? AND deleted_at = ?", id, deletedAt); err != nil {
+		r.logger.Error("Ошибка восстановления страниц главы", "id", id, "err", err)
+		return err
+	}
+	if _, err = tx.Exec("UPDATE chapter SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		r.logger.Error("Ошибка восстановления главы", "id", id, "err", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return err
+	}
 	return nil
 }
+
+func (r *SQLiteChapterRepository) PurgeDeleted(before time.Time) (int64, error) {
+	before = before.UTC()
+	result, err := r.db.Exec("DELETE FROM chapter WHERE (deleted_at IS NOT NULL AND deleted_at <= ?) OR manga_id IN ("+
+		"SELECT id FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= ?)", before, before)
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины глав", "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PublishDue публикует наступившие запланированные главы в одной
+// транзакции. Временем публикации становится запланированное время, а
+// манга отмечается обновленной.
+func (r *SQLiteChapterRepository) PublishDue(now time.Time) ([]*models.Chapter, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT "+chapterColumns+" FROM chapter WHERE status = 'scheduled' AND publish_at <= ? AND deleted_at IS NULL ORDER BY publish_at, id",
+		now.UTC())
+	if err != nil {
+		r.logger.Error("Ошибка получения запланированных глав", "err", err)
+		return nil, err
+	}
+	due, err := r.scanChapters(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt := now.UTC()
+	for _, ch := range due {
+		ch.Status = models.ChapterPublished
+		ch.PublishedAt = ch.PublishAt
+		ch.UpdatedAt = updatedAt
+		if _, err = tx.Exec("UPDATE chapter SET status = ?, published_at = ?, updated_at = ? WHERE id = ?",
+			ch.Status, ch.PublishedAt, updatedAt, ch.ID); err != nil {
+			r.logger.Error("Ошибка публикации главы", "chapter_id", ch.ID, "err", err)
+			return nil, err
+		}
+		if _, err = tx.Exec("UPDATE manga SET updated_at = ? WHERE id = ?", updatedAt, ch.MangaID); err != nil {
+			r.logger.Error("Ошибка обновления времени изменения манги", "manga_id", ch.MangaID, "err", err)
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return nil, err
+	}
+	return due, nil
+}
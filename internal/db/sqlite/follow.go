@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"time"
+)
+
+type SQLiteFollowRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewFollowRepository(conn *sql.DB, logger *slog.Logger) db.FollowRepository {
+	repo := &SQLiteFollowRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для подписок", "err", err)
+	}
+	return repo
+}
+
+func (r *SQLiteFollowRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS manga_follows (
+		user_id INTEGER NOT NULL,
+		manga_id INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, manga_id),
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY(manga_id) REFERENCES manga(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_manga_follows_manga_id ON manga_follows(manga_id);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы manga_follows", "err", err)
+	}
+	return err
+}
+
+func (r *SQLiteFollowRepository) Follow(userID, mangaID int64) error {
+	_, err := r.db.Exec("INSERT OR IGNORE INTO manga_follows (user_id, manga_id, created_at) VALUES (?, ?, ?)",
+		userID, mangaID, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("Ошибка создания подписки", "user_id", userID, "manga_id", mangaID, "err", err)
+	}
+	return err
+}
+
+func (r *SQLiteFollowRepository) Unfollow(userID, mangaID int64) error {
+	result, err := r.db.Exec("DELETE FROM manga_follows WHERE user_id = ? AND manga_id = ?", userID, mangaID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления подписки", "user_id", userID, "manga_id", mangaID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteFollowRepository) IsFollowing(userID, mangaID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM manga_follows WHERE user_id = ? AND manga_id = ?)",
+		userID, mangaID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Ошибка проверки подписки", "user_id", userID, "manga_id", mangaID, "err", err)
+	}
+	return exists, err
+}
+
+func (r *SQLiteFollowRepository) ListByUser(userID int64) ([]*models.Follow, error) {
+	rows, err := r.db.Query(`SELECT f.manga_id, m.title, f.created_at FROM manga_follows f
+		JOIN manga m ON m.id = f.manga_id WHERE f.user_id = ? AND m.deleted_at IS NULL ORDER BY f.created_at DESC, f.manga_id`, userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения подписок", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []*models.Follow{}
+	for rows.Next() {
+		f := &models.Follow{}
+		if err = rows.Scan(&f.MangaID, &f.MangaTitle, &f.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования подписки", "err", err)
+			return nil, err
+		}
+		follows = append(follows, f)
+	}
+	return follows, rows.Err()
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"manga-reader/internal/db"
 	"manga-reader/models"
+	"strings"
+	"time"
 )
 
 type SQLiteMangaRepository struct {
@@ -14,7 +16,7 @@ type SQLiteMangaRepository struct {
 }
 
 func NewMangaRepository(dataSourceName string, logger *slog.Logger) (db.MangaRepository, error) {
-	conn, err := sql.Open("sqlite3", dataSourceName)
+	conn, err := sql.Open("sqlite3", withForeignKeys(dataSourceName))
 	if err != nil {
 		return nil, err
 	}
@@ -29,33 +31,83 @@ func NewMangaRepository(dataSourceName string, logger *slog.Logger) (db.MangaRep
 	return repo, nil
 }
 
+// withForeignKeys включает в DSN проверку внешних ключей. Без нее SQLite
+// игнорирует ограничения REFERENCES и не удаляет связанные строки по
+// ON DELETE CASCADE. Параметр действует на каждое соединение пула.
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_foreign_keys=on"
+}
+
 func (r *SQLiteMangaRepository) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS manga (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		title TEXT NOT NULL,
-		description TEXT
+		description TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
 	);`
 	_, err := r.db.Exec(schema)
 	if err != nil {
 		r.logger.Error("Ошибка создания схемы таблицы manga", "err", err)
+		return err
+	}
+	if err = ensureTimestamps(r.db, "manga"); err != nil {
+		r.logger.Error("Ошибка добавления временных меток в таблицу manga", "err", err)
+		return err
+	}
+	if err = ensureColumn(r.db, "manga", "deleted_at", "DATETIME"); err != nil {
+		r.logger.Error("Ошибка добавления колонки в таблицу manga", "column", "deleted_at", "err", err)
+		return err
+	}
+	for _, index := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_manga_updated_at ON manga(updated_at)",
+		"CREATE INDEX IF NOT EXISTS idx_manga_deleted_at ON manga(deleted_at)",
+	} {
+		if _, err = r.db.Exec(index); err != nil {
+			r.logger.Error("Ошибка создания индекса таблицы manga", "err", err)
+			return err
+		}
 	}
-	return err
+	return nil
+}
+
+const mangaColumns = "id, title, COALESCE(description, ''), created_at, updated_at, deleted_at"
+
+func scanManga(row interface{ Scan(...any) error }) (*models.Manga, error) {
+	m := &models.Manga{}
+	var deletedAt sql.NullTime
+	if err := row.Scan(&m.ID, &m.Title, &m.Description, &m.CreatedAt, &m.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		m.DeletedAt = &deletedAt.Time
+	}
+	return m, nil
 }
 
 func (r *SQLiteMangaRepository) Create(m *models.Manga) (int64, error) {
-	result, err := r.db.Exec("INSERT INTO manga (title, description) VALUES (?, ?)", m.Title, m.Description)
+	now := time.Now().UTC()
+	result, err := r.db.Exec("INSERT INTO manga (title, description, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		m.Title, m.Description, now, now)
 	if err != nil {
 		r.logger.Error("Ошибка вставки манги", "err", err)
 		return 0, err
 	}
+	m.CreatedAt, m.UpdatedAt = now, now
 	return result.LastInsertId()
 }
 
 func (r *SQLiteMangaRepository) GetByID(id int64) (*models.Manga, error) {
-	row := r.db.QueryRow("SELECT id, title, description FROM manga WHERE id = ?", id)
-	m := &models.Manga{}
-	if err := row.Scan(&m.ID, &m.Title, &m.Description); err != nil {
+	m, err := scanManga(r.db.QueryRow("SELECT "+mangaColumns+" FROM manga WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
 		r.logger.Error("Ошибка получения манги", "err", err)
 		return nil, err
 	}
@@ -63,27 +115,42 @@ func (r *SQLiteMangaRepository) GetByID(id int64) (*models.Manga, error) {
 }
 
 func (r *SQLiteMangaRepository) List() ([]*models.Manga, error) {
-	rows, err := r.db.Query("SELECT id, title, description FROM manga")
+	rows, err := r.db.Query("SELECT " + mangaColumns + " FROM manga WHERE deleted_at IS NULL")
 	if err != nil {
 		r.logger.Error("Ошибка получения списка манги", "err", err)
 		return nil, err
 	}
+	return r.scanMangas(rows)
+}
+
+func (r *SQLiteMangaRepository) ListDeleted() ([]*models.Manga, error) {
+	rows, err := r.db.Query("SELECT " + mangaColumns + " FROM manga WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		r.logger.Error("Ошибка получения манги из корзины", "err", err)
+		return nil, err
+	}
+	return r.scanMangas(rows)
+}
+
+func (r *SQLiteMangaRepository) scanMangas(rows *sql.Rows) ([]*models.Manga, error) {
 	defer rows.Close()
 
 	var mangas []*models.Manga
 	for rows.Next() {
-		m := &models.Manga{}
-		if err := rows.Scan(&m.ID, &m.Title, &m.Description); err != nil {
+		m, err := scanManga(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования строки", "err", err)
 			return nil, err
 		}
 		mangas = append(mangas, m)
 	}
-	return mangas, nil
+	return mangas, rows.Err()
 }
 
 func (r *SQLiteMangaRepository) Update(m *models.Manga) error {
-	result, err := r.db.Exec("UPDATE manga SET title = ?, description = ? WHERE id = ?", m.Title, m.Description, m.ID)
+	now := time.Now().UTC()
+	result, err := r.db.Exec("UPDATE manga SET title = ?, description = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		m.Title, m.Description, now, m.ID)
 	if err != nil {
 		r.logger.Error("Ошибка обновления манги", "err", err)
 		return err
@@ -92,22 +159,92 @@ func (r *SQLiteMangaRepository) Update(m *models.Manga) error {
 		r.logger.Error("Манга не найдена для обновления", "err", err)
 		return err
 	}
+	m.UpdatedAt = now
 	return nil
 }
 
+// Delete переносит мангу, ее главы и страницы в корзину с одним временем
+// удаления, по которому Restore находит удаленное вместе с мангой.
 func (r *SQLiteMangaRepository) Delete(id int64) error {
-	result, err := r.db.Exec("DELETE FROM manga WHERE id = ?", id)
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec("UPDATE manga SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", now, id)
 	if err != nil {
 		r.logger.Error("Ошибка удаления манги", "err", err)
 		return err
 	}
 	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
-		r.logger.Error("Манга не найдена для удаления", "err", err)
+		r.logger.Error("Манга не найдена для удаления", "id", id, "err", err)
+		return sql.ErrNoRows
+	}
+	if _, err = tx.Exec("UPDATE pages SET deleted_at = ? WHERE deleted_at IS NULL AND chapter_id IN "+
+		"(SELECT id FROM chapter WHERE manga_id = ?)", now, id); err != nil {
+		r.logger.Error("Ошибка удаления страниц манги", "id", id, "err", err)
+		return err
+	}
+	if _, err = tx.Exec("UPDATE chapter SET deleted_at = ? WHERE manga_id = ? AND deleted_at IS NULL", now, id); err != nil {
+		r.logger.Error("Ошибка удаления глав манги", "id", id, "err", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return err
+	}
+	return nil
+}
+
+// Restore не восстанавливает главы и страницы, удаленные до манги: они
+// остаются в корзине со своим временем удаления.
+func (r *SQLiteMangaRepository) Restore(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	if err = tx.QueryRow("SELECT deleted_at FROM manga WHERE id = ? AND deleted_at IS NOT NULL", id).Scan(&deletedAt); err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения манги из корзины", "id", id, "err", err)
+		}
+		return err
+	}
+	if _, err = tx.Exec("UPDATE pages SET deleted_at = NULL WHERE deleted_at = ? AND chapter_id IN "+
+		"(SELECT id FROM chapter WHERE manga_id = ? AND deleted_at = ?)", deletedAt, id, deletedAt); err != nil {
+		r.logger.Error("Ошибка восстановления страниц манги", "id", id, "err", err)
+		return err
+	}
+	if _, err = tx.Exec("UPDATE chapter SET deleted_at = NULL WHERE manga_id = ? AND deleted_at = ?", id, deletedAt); err != nil {
+		r.logger.Error("Ошибка восстановления глав манги", "id", id, "err", err)
+		return err
+	}
+	if _, err = tx.Exec("UPDATE manga SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		r.logger.Error("Ошибка восстановления манги", "id", id, "err", err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
 		return err
 	}
 	return nil
 }
 
+func (r *SQLiteMangaRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= ?", before.UTC())
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины манги", "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *SQLiteMangaRepository) GetDB() *sql.DB {
 	return r.db
 }
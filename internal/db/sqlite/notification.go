@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"time"
+)
+
+type SQLiteNotificationRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewNotificationRepository(conn *sql.DB, logger *slog.Logger) db.NotificationRepository {
+	repo := &SQLiteNotificationRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для уведомлений", "err", err)
+	}
+	return repo
+}
+
+func (r *SQLiteNotificationRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		manga_id INTEGER NOT NULL,
+		chapter_id INTEGER,
+		message TEXT NOT NULL,
+		is_read BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, id);
+	CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL,
+		PRIMARY KEY (user_id, event),
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблиц уведомлений", "err", err)
+	}
+	return err
+}
+
+// NotifyFollowers рассылает уведомление одним запросом INSERT ... SELECT,
+// чтобы число подписчиков не влияло на число обращений к базе. JOIN с users
+// отсекает подписки, оставшиеся от пользователей, удаленных до включения
+// проверки внешних ключей: иначе вставка нарушила бы ограничение.
+func (r *SQLiteNotificationRepository) NotifyFollowers(n *models.Notification) (int64, error) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	result, err := r.db.Exec(`INSERT INTO notifications (user_id, event, manga_id, chapter_id, message, is_read, created_at)
+		SELECT f.user_id, ?, f.manga_id, ?, ?, 0, ?
+		FROM manga_follows f
+		JOIN users u ON u.id = f.user_id
+		LEFT JOIN notification_preferences p ON p.user_id = f.user_id AND p.event = ?
+		WHERE f.manga_id = ? AND COALESCE(p.enabled, 1) = 1`,
+		n.Event, nullInt64(n.ChapterID), n.Message, n.CreatedAt, n.Event, n.MangaID)
+	if err != nil {
+		r.logger.Error("Ошибка рассылки уведомлений", "manga_id", n.MangaID, "event", n.Event, "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *SQLiteNotificationRepository) ListByUser(userID int64, unreadOnly bool, limit, offset int) ([]*models.Notification, error) {
+	query := `SELECT n.id, n.user_id, n.event, n.manga_id, COALESCE(m.title, ''), COALESCE(n.chapter_id, 0),
+		n.message, n.is_read, n.created_at
+		FROM notifications n LEFT JOIN manga m ON m.id = n.manga_id
+		WHERE n.user_id = ?`
+	if unreadOnly {
+		query += " AND n.is_read = 0"
+	}
+	query += " ORDER BY n.id DESC LIMIT ? OFFSET ?"
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		r.logger.Error("Ошибка получения уведомлений", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		if err = rows.Scan(&n.ID, &n.UserID, &n.Event, &n.MangaID, &n.MangaTitle, &n.ChapterID,
+			&n.Message, &n.Read, &n.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования уведомления", "err", err)
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (r *SQLiteNotificationRepository) CountUnread(userID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0", userID).Scan(&count)
+	if err != nil {
+		r.logger.Error("Ошибка подсчета непрочитанных уведомлений", "user_id", userID, "err", err)
+	}
+	return count, err
+}
+
+func (r *SQLiteNotificationRepository) MarkRead(id, userID int64) error {
+	result, err := r.db.Exec("UPDATE notifications SET is_read = 1 WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		r.logger.Error("Ошибка отметки уведомления", "id", id, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteNotificationRepository) MarkAllRead(userID int64) (int64, error) {
+	result, err := r.db.Exec("UPDATE notifications SET is_read = 1 WHERE user_id = ? AND is_read = 0", userID)
+	if err != nil {
+		r.logger.Error("Ошибка отметки уведомлений", "user_id", userID, "err", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *SQLiteNotificationRepository) GetPreferences(userID int64) (map[models.NotificationEvent]bool, error) {
+	rows, err := r.db.Query("SELECT event, enabled FROM notification_preferences WHERE user_id = ?", userID)
+	if err != nil {
+		r.logger.Error("Ошибка получения настроек уведомлений", "user_id", userID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := map[models.NotificationEvent]bool{}
+	for rows.Next() {
+		var (
+			event   models.NotificationEvent
+			enabled bool
+		)
+		if err = rows.Scan(&event, &enabled); err != nil {
+			r.logger.Error("Ошибка сканирования настройки уведомлений", "err", err)
+			return nil, err
+		}
+		prefs[event] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+func (r *SQLiteNotificationRepository) SetPreference(userID int64, event models.NotificationEvent, enabled bool) error {
+	_, err := r.db.Exec(`INSERT INTO notification_preferences (user_id, event, enabled) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, event) DO UPDATE SET enabled = excluded.enabled`, userID, event, enabled)
+	if err != nil {
+		r.logger.Error("Ошибка сохранения настройки уведомлений", "user_id", userID, "event", event, "err", err)
+	}
+	return err
+}
+
+// nullInt64 сохраняет нулевой идентификатор как NULL.
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
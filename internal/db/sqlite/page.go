@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"manga-reader/models"
+	"time"
 )
 
 type SQLitePageRepository struct {
@@ -23,33 +24,80 @@ func NewPageRepository(db *sql.DB, logger *slog.Logger) *SQLitePageRepository {
 	return repo
 }
 
-func (r *SQLitePageRepository) initSchema() error {
-	schema := `CREATE TABLE IF NOT EXISTS pages (
+// pagesSchema — схема таблицы страниц; %s заменяется именем таблицы.
+const pagesSchema = `CREATE TABLE IF NOT EXISTS %s (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     chapter_id INTEGER NOT NULL,
     number INTEGER NOT NULL,
     image_path TEXT NOT NULL,
-    FOREIGN KEY(chapter_id) REFERENCES chapters(id));`
-	_, err := r.db.Exec(schema)
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+    FOREIGN KEY(chapter_id) REFERENCES chapter(id) ON DELETE CASCADE);`
+
+func (r *SQLitePageRepository) initSchema() error {
+	_, err := r.db.Exec(fmt.Sprintf(pagesSchema, "pages"))
 	if err != nil {
 		r.logger.Error("Ошибка создания таблицы pages", "err", err)
+		return err
+	}
+	if err = ensureTimestamps(r.db, "pages"); err != nil {
+		r.logger.Error("Ошибка добавления временных меток в таблицу pages", "err", err)
+		return err
+	}
+	if err = ensureColumn(r.db, "pages", "deleted_at", "DATETIME"); err != nil {
+		r.logger.Error("Ошибка добавления колонки в таблицу pages", "column", "deleted_at", "err", err)
+		return err
+	}
+	// Старые базы ссылаются на несуществующую таблицу chapters, из-за чего
+	// при проверке внешних ключей любая вставка страницы завершается ошибкой.
+	target, err := foreignKeyTarget(r.db, "pages", "chapter_id")
+	if err != nil {
+		r.logger.Error("Ошибка чтения внешних ключей таблицы pages", "err", err)
+		return err
+	}
+	if target != "chapter" {
+		if err = rebuildTable(r.db, "pages", pagesSchema, pageColumns); err != nil {
+			r.logger.Error("Ошибка пересоздания таблицы pages", "err", err)
+			return err
+		}
+	}
+	_, err = r.db.Exec("CREATE INDEX IF NOT EXISTS idx_pages_deleted_at ON pages(deleted_at)")
+	if err != nil {
+		r.logger.Error("Ошибка создания индекса idx_pages_deleted_at", "err", err)
 	}
 	return err
 }
 
+const pageColumns = "id, chapter_id, number, image_path, created_at, updated_at, deleted_at"
+
+func scanPage(row interface{ Scan(...any) error }) (*models.Page, error) {
+	page := &models.Page{}
+	var deletedAt sql.NullTime
+	if err := row.Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath, &page.CreatedAt, &page.UpdatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		page.DeletedAt = &deletedAt.Time
+	}
+	return page, nil
+}
+
 func (r *SQLitePageRepository) Create(p *models.Page) (int64, error) {
-	res, err := r.db.Exec("INSERT INTO pages (chapter_id, number, image_path) VALUES (?, ?, ?)", p.ChapterID, p.Number, p.ImagePath)
+	now := time.Now().UTC()
+	res, err := r.db.Exec("INSERT INTO pages (chapter_id, number, image_path, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		p.ChapterID, p.Number, p.ImagePath, now, now)
 	if err != nil {
 		r.logger.Error("Ошибка создания новой страницы", "err", err)
 		return 0, err
 	}
+	p.CreatedAt, p.UpdatedAt = now, now
 	return res.LastInsertId()
 }
 
 func (r *SQLitePageRepository) GetByID(id int64) (*models.Page, error) {
-	row := r.db.QueryRow("SELECT id, chapter_id, number, image_path FROM pages WHERE id = ?", id)
-	page := &models.Page{}
-	if err := row.Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath); err != nil {
+	page, err := scanPage(r.db.QueryRow("SELECT "+pageColumns+" FROM pages WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
 		r.logger.Error("Ошибка получения страницы", "err", err)
 		return nil, err
 	}
@@ -57,26 +105,32 @@ func (r *SQLitePageRepository) GetByID(id int64) (*models.Page, error) {
 }
 
 func (r *SQLitePageRepository) ListByChapter(chapterID int64) ([]*models.Page, error) {
-	rows, err := r.db.Query("SELECT id, chapter_id, number, image_path FROM pages WHERE chapter_id = ?", chapterID)
+	rows, err := r.db.Query("SELECT "+pageColumns+" FROM pages WHERE chapter_id = ? AND deleted_at IS NULL", chapterID)
 	if err != nil {
 		r.logger.Error("Ошибка получения списка страниц", "err", err)
 		return nil, err
 	}
+	return r.scanPages(rows)
+}
+
+func (r *SQLitePageRepository) scanPages(rows *sql.Rows) ([]*models.Page, error) {
 	defer rows.Close()
 	pages := []*models.Page{}
 	for rows.Next() {
-		page := &models.Page{}
-		if err = rows.Scan(&page.ID, &page.ChapterID, &page.Number, &page.ImagePath); err != nil {
+		page, err := scanPage(rows)
+		if err != nil {
 			r.logger.Error("Ошибка сканирования страницы", "err", err)
 			return nil, err
 		}
 		pages = append(pages, page)
 	}
-	return pages, nil
+	return pages, rows.Err()
 }
 
 func (r *SQLitePageRepository) Update(p *models.Page) error {
-	res, err := r.db.Exec("UPDATE pages SET chapter_id = ?, number = ?, image_path = ? WHERE id = ?", p.ChapterID, p.Number, p.ImagePath, p.ID)
+	now := time.Now().UTC()
+	res, err := r.db.Exec("UPDATE pages SET chapter_id = ?, number = ?, image_path = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		p.ChapterID, p.Number, p.ImagePath, now, p.ID)
 	if err != nil {
 		r.logger.Error("Ошибка обновления страницы", "err", err)
 		return err
@@ -91,11 +145,12 @@ func (r *SQLitePageRepository) Update(p *models.Page) error {
 		r.logger.Error("Ошибка обновления страницы", "err", err)
 		return err
 	}
+	p.UpdatedAt = now
 	return nil
 }
 
 func (r *SQLitePageRepository) Delete(id int64) error {
-	res, err := r.db.Exec("DELETE FROM pages WHERE id = ?", id)
+	res, err := r.db.Exec("UPDATE pages SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now().UTC(), id)
 	if err != nil {
 		r.logger.Error("Ошибка удаления страницы", "err", err)
 		return err
@@ -112,3 +167,39 @@ func (r *SQLitePageRepository) Delete(id int64) error {
 	}
 	return nil
 }
+
+func (r *SQLitePageRepository) ListDeleted() ([]*models.Page, error) {
+	rows, err := r.db.Query("SELECT " + pageColumns + " FROM pages WHERE deleted_at IS NOT NULL AND chapter_id IN " +
+		"(SELECT id FROM chapter WHERE deleted_at IS NULL) ORDER BY deleted_at DESC, id DESC")
+	if err != nil {
+		r.logger.Error("Ошибка получения страниц из корзины", "err", err)
+		return nil, err
+	}
+	return r.scanPages(rows)
+}
+
+func (r *SQLitePageRepository) Restore(id int64) error {
+	res, err := r.db.Exec("UPDATE pages SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL AND chapter_id IN "+
+		"(SELECT id FROM chapter WHERE deleted_at IS NULL)", id)
+	if err != nil {
+		r.logger.Error("Ошибка восстановления страницы", "id", id, "err", err)
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLitePageRepository) PurgeDeleted(before time.Time) ([]*models.Page, error) {
+	before = before.UTC()
+	rows, err := r.db.Query("DELETE FROM pages WHERE (deleted_at IS NOT NULL AND deleted_at <= ?) OR chapter_id IN ("+
+		"SELECT id FROM chapter WHERE (deleted_at IS NOT NULL AND deleted_at <= ?) OR manga_id IN ("+
+		"SELECT id FROM manga WHERE deleted_at IS NOT NULL AND deleted_at <= ?)) RETURNING "+pageColumns,
+		before, before, before)
+	if err != nil {
+		r.logger.Error("Ошибка очистки корзины страниц", "err", err)
+		return nil, err
+	}
+	return r.scanPages(rows)
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"time"
+)
+
+type SQLiteScanlationGroupRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewScanlationGroupRepository(conn *sql.DB, logger *slog.Logger) db.ScanlationGroupRepository {
+	repo := &SQLiteScanlationGroupRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для команд переводчиков", "err", err)
+	}
+	return repo
+}
+
+func (r *SQLiteScanlationGroupRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS scanlation_group (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		website TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS scanlation_group_members (
+		group_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		PRIMARY KEY (group_id, user_id),
+		FOREIGN KEY(group_id) REFERENCES scanlation_group(id) ON DELETE CASCADE,
+		FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_scanlation_group_members_user_id ON scanlation_group_members(user_id);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы scanlation_group", "err", err)
+	}
+	return err
+}
+
+const groupColumns = "id, name, website, created_at, updated_at"
+
+func scanGroup(row interface{ Scan(...any) error }) (*models.ScanlationGroup, error) {
+	g := &models.ScanlationGroup{}
+	if err := row.Scan(&g.ID, &g.Name, &g.Website, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *SQLiteScanlationGroupRepository) Create(g *models.ScanlationGroup) (int64, error) {
+	now := time.Now().UTC()
+	result, err := r.db.Exec("INSERT INTO scanlation_group (name, website, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		g.Name, g.Website, now, now)
+	if err != nil {
+		r.logger.Error("Ошибка создания команды переводчиков", "err", err)
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	g.CreatedAt, g.UpdatedAt = now, now
+	return id, nil
+}
+
+func (r *SQLiteScanlationGroupRepository) GetByID(id int64) (*models.ScanlationGroup, error) {
+	g, err := scanGroup(r.db.QueryRow("SELECT "+groupColumns+" FROM scanlation_group WHERE id = ?", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения команды переводчиков", "id", id, "err", err)
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT u.id, u.username FROM scanlation_group_members gm
+		JOIN users u ON u.id = gm.user_id WHERE gm.group_id = ? ORDER BY u.username`, id)
+	if err != nil {
+		r.logger.Error("Ошибка получения участников команды", "id", id, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	g.Members = []models.GroupMember{}
+	for rows.Next() {
+		var member models.GroupMember
+		if err = rows.Scan(&member.UserID, &member.Username); err != nil {
+			r.logger.Error("Ошибка сканирования участника команды", "err", err)
+			return nil, err
+		}
+		g.Members = append(g.Members, member)
+	}
+	return g, rows.Err()
+}
+
+func (r *SQLiteScanlationGroupRepository) List() ([]*models.ScanlationGroup, error) {
+	rows, err := r.db.Query("SELECT " + groupColumns + " FROM scanlation_group ORDER BY name")
+	if err != nil {
+		r.logger.Error("Ошибка получения списка команд переводчиков", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*models.ScanlationGroup
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования команды переводчиков", "err", err)
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *SQLiteScanlationGroupRepository) Update(g *models.ScanlationGroup) error {
+	now := time.Now().UTC()
+	result, err := r.db.Exec("UPDATE scanlation_group SET name = ?, website = ?, updated_at = ? WHERE id = ?",
+		g.Name, g.Website, now, g.ID)
+	if err = r.checkAffected(result, err, "Ошибка обновления команды переводчиков", g.ID); err != nil {
+		return err
+	}
+	g.UpdatedAt = now
+	return nil
+}
+
+// Delete снимает команду с глав и удаляет ее в одной транзакции; участники
+// удаляются каскадно. Колонка chapter.group_id добавлена через ALTER TABLE
+// без ON DELETE SET NULL, поэтому главы обновляются явно.
+func (r *SQLiteScanlationGroupRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.logger.Error("Ошибка начала транзакции", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE chapter SET group_id = NULL WHERE group_id = ?", id); err != nil {
+		r.logger.Error("Ошибка снятия команды с глав", "id", id, "err", err)
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM scanlation_group WHERE id = ?", id)
+	if err = r.checkAffected(result, err, "Ошибка удаления команды переводчиков", id); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Ошибка фиксации транзакции", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (r *SQLiteScanlationGroupRepository) AddMember(groupID, userID int64) error {
+	_, err := r.db.Exec("INSERT OR IGNORE INTO scanlation_group_members (group_id, user_id) VALUES (?, ?)", groupID, userID)
+	if err != nil {
+		r.logger.Error("Ошибка добавления участника команды", "group_id", groupID, "user_id", userID, "err", err)
+	}
+	return err
+}
+
+func (r *SQLiteScanlationGroupRepository) RemoveMember(groupID, userID int64) error {
+	result, err := r.db.Exec("DELETE FROM scanlation_group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		r.logger.Error("Ошибка удаления участника команды", "group_id", groupID, "user_id", userID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteScanlationGroupRepository) IsMember(groupID, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM scanlation_group_members WHERE group_id = ? AND user_id = ?)",
+		groupID, userID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("Ошибка проверки участия в команде", "group_id", groupID, "user_id", userID, "err", err)
+	}
+	return exists, err
+}
+
+func (r *SQLiteScanlationGroupRepository) checkAffected(result sql.Result, err error, msg string, id int64) error {
+	if err != nil {
+		r.logger.Error(msg, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		err = fmt.Errorf("команда переводчиков с id %d не найдена", id)
+		r.logger.Error(msg, "err", err)
+		return err
+	}
+	return nil
+}
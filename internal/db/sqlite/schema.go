@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ensureColumn добавляет колонку в существующую таблицу, если ее еще нет.
+// CREATE TABLE IF NOT EXISTS не меняет уже созданные таблицы, поэтому новые
+// поля для старых баз данных добавляются через ALTER TABLE.
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err = rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// ensureTimestamps добавляет в таблицу колонки created_at и updated_at.
+// SQLite не позволяет добавить колонку со значением по умолчанию
+// CURRENT_TIMESTAMP, поэтому существующие строки получают время миграции
+// отдельным запросом.
+func ensureTimestamps(db *sql.DB, table string) error {
+	for _, column := range []string{"created_at", "updated_at"} {
+		if err := ensureColumn(db, table, column, "DATETIME"); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(fmt.Sprintf("UPDATE %s SET created_at = COALESCE(created_at, CURRENT_TIMESTAMP), "+
+		"updated_at = COALESCE(updated_at, created_at, CURRENT_TIMESTAMP) WHERE created_at IS NULL OR updated_at IS NULL", table))
+	return err
+}
+
+// foreignKeyTarget возвращает таблицу, на которую ссылается колонка column
+// таблицы table, или пустую строку, если внешнего ключа нет.
+func foreignKeyTarget(db *sql.DB, table, column string) (string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s)", table))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, seq                   int
+			target, from              string
+			to                        sql.NullString
+			onUpdate, onDelete, match string
+		)
+		if err = rows.Scan(&id, &seq, &target, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return "", err
+		}
+		if from == column {
+			return target, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// rebuildTable пересоздает таблицу по схеме schema (%s заменяется именем
+// таблицы), перенося значения колонок columns. ALTER TABLE в SQLite не
+// меняет ограничения, поэтому данные копируются в новую таблицу, которая
+// затем занимает место старой. Проверка внешних ключей на время переноса
+// отключается: PRAGMA действует только вне транзакции и только на свое
+// соединение, поэтому перенос выполняется на выделенном соединении.
+// Индексы старой таблицы удаляются вместе с ней и создаются заново
+// вызывающим кодом.
+func rebuildTable(db *sql.DB, table, schema, columns string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tmp := table + "_rebuild"
+	for _, stmt := range []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", tmp),
+		fmt.Sprintf(schema, tmp),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmp, columns, columns, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table),
+	} {
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
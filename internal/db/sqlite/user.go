@@ -2,9 +2,11 @@ package sqlite
 
 import (
 	"database/sql"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"log/slog"
 	"manga-reader/models"
+	"strings"
 )
 
 type SQLiteUserRepository struct {
@@ -22,28 +24,174 @@ func (r *SQLiteUserRepository) initSchema() error {
 	schema := `CREATE TABLE IF NOT EXISTS users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     username TEXT UNIQUE NOT NULL,
-	password TEXT NOT NULL);`
+	email TEXT,
+	password TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'reader',
+	totp_secret TEXT,
+	totp_enabled BOOLEAN NOT NULL DEFAULT 0,
+	recovery_codes TEXT,
+	preferred_language TEXT);`
 	_, err := r.db.Exec(schema)
 	if err != nil {
 		r.logger.Error("Ошибка создания таблицы users", "err", err)
+		return err
+	}
+	if err = ensureColumn(r.db, "users", "role", "TEXT NOT NULL DEFAULT 'reader'"); err != nil {
+		r.logger.Error("Ошибка добавления колонки role в таблицу users", "err", err)
+		return err
+	}
+	if err = ensureColumn(r.db, "users", "email", "TEXT"); err != nil {
+		r.logger.Error("Ошибка добавления колонки email в таблицу users", "err", err)
+		return err
+	}
+	for _, column := range []struct{ name, definition string }{
+		{"totp_secret", "TEXT"},
+		{"totp_enabled", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"recovery_codes", "TEXT"},
+		{"preferred_language", "TEXT"},
+	} {
+		if err = ensureColumn(r.db, "users", column.name, column.definition); err != nil {
+			r.logger.Error("Ошибка добавления колонки в таблицу users", "column", column.name, "err", err)
+			return err
+		}
+	}
+	_, err = r.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL")
+	if err != nil {
+		r.logger.Error("Ошибка создания индекса idx_users_email", "err", err)
+		return err
+	}
+	_, err = r.db.Exec(`CREATE TABLE IF NOT EXISTS user_identities (
+	user_id INTEGER NOT NULL,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	PRIMARY KEY (issuer, subject),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы user_identities", "err", err)
 	}
 	return err
 }
 
+const userColumns = "id, username, COALESCE(email, ''), password, role, " +
+	"COALESCE(totp_secret, ''), totp_enabled, COALESCE(recovery_codes, ''), COALESCE(preferred_language, '')"
+
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
+	user := &models.User{}
+	var recoveryCodes string
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role,
+		&user.TOTPSecret, &user.TOTPEnabled, &recoveryCodes, &user.PreferredLanguage); err != nil {
+		return nil, err
+	}
+	if recoveryCodes != "" {
+		user.RecoveryCodes = strings.Split(recoveryCodes, ",")
+	}
+	return user, nil
+}
+
+// nullString сохраняет пустую строку как NULL, чтобы не нарушать
+// уникальность необязательных полей.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 func (r *SQLiteUserRepository) Create(user *models.User) (int64, error) {
-	result, err := r.db.Exec("INSERT INTO users (username, password) VALUES (?, ?)", user.Username, user.Password)
+	if user.Role == "" {
+		user.Role = models.RoleReader
+	}
+	result, err := r.db.Exec("INSERT INTO users (username, email, password, role) VALUES (?, ?, ?, ?)",
+		user.Username, nullString(user.Email), user.Password, user.Role)
 	if err != nil {
 		r.logger.Error("Ошибка создания нового пользователя", "err", err)
+		return 0, err
 	}
 	return result.LastInsertId()
 }
 
+func (r *SQLiteUserRepository) GetByID(id int64) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+	if err != nil {
+		r.logger.Error("Ошибка получения пользователя по id", "err", err)
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *SQLiteUserRepository) GetByUsername(username string) (*models.User, error) {
-	row := r.db.QueryRow("SELECT id, username, password FROM users WHERE username = ?", username)
-	user := &models.User{}
-	if err := row.Scan(&user.ID, &user.Username, &user.Password); err != nil {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = ?", username))
+	if err != nil {
 		r.logger.Error("Ошибка получения пользователя по username", "err", err)
 		return nil, err
 	}
 	return user, nil
 }
+
+func (r *SQLiteUserRepository) GetByEmail(email string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = ?", email))
+	if err != nil {
+		r.logger.Error("Ошибка получения пользователя по email", "err", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) Update(user *models.User) error {
+	result, err := r.db.Exec("UPDATE users SET username = ?, email = ?, preferred_language = ? WHERE id = ?",
+		user.Username, nullString(user.Email), nullString(user.PreferredLanguage), user.ID)
+	return r.checkAffected(result, err, "Ошибка обновления пользователя", user.ID)
+}
+
+func (r *SQLiteUserRepository) UpdatePassword(id int64, passwordHash string) error {
+	result, err := r.db.Exec("UPDATE users SET password = ? WHERE id = ?", passwordHash, id)
+	return r.checkAffected(result, err, "Ошибка обновления пароля", id)
+}
+
+func (r *SQLiteUserRepository) UpdateRole(id int64, role models.Role) error {
+	result, err := r.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, id)
+	return r.checkAffected(result, err, "Ошибка обновления роли пользователя", id)
+}
+
+func (r *SQLiteUserRepository) UpdateTOTP(id int64, secret string, enabled bool, recoveryCodes []string) error {
+	result, err := r.db.Exec("UPDATE users SET totp_secret = ?, totp_enabled = ?, recovery_codes = ? WHERE id = ?",
+		nullString(secret), enabled, nullString(strings.Join(recoveryCodes, ",")), id)
+	return r.checkAffected(result, err, "Ошибка обновления настроек 2FA", id)
+}
+
+func (r *SQLiteUserRepository) GetByIdentity(issuer, subject string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+userColumns+
+		" FROM users WHERE id = (SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?)", issuer, subject))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения пользователя по внешней учетной записи", "err", err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) LinkIdentity(userID int64, issuer, subject string) error {
+	_, err := r.db.Exec("INSERT INTO user_identities (user_id, issuer, subject) VALUES (?, ?, ?)", userID, issuer, subject)
+	if err != nil {
+		r.logger.Error("Ошибка привязки внешней учетной записи", "err", err)
+	}
+	return err
+}
+
+// Delete удаляет пользователя; связанные с ним данные удаляются каскадно
+// по внешним ключам.
+func (r *SQLiteUserRepository) Delete(id int64) error {
+	result, err := r.db.Exec("DELETE FROM users WHERE id = ?", id)
+	return r.checkAffected(result, err, "Ошибка удаления пользователя", id)
+}
+
+func (r *SQLiteUserRepository) checkAffected(result sql.Result, err error, msg string, id int64) error {
+	if err != nil {
+		r.logger.Error(msg, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		err = fmt.Errorf("пользователь с id %d не найден", id)
+		r.logger.Error(msg, "err", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"time"
+)
+
+type SQLiteViewHistoryRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewViewHistoryRepository(conn *sql.DB, logger *slog.Logger) db.ViewHistoryRepository {
+	repo := &SQLiteViewHistoryRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для истории просмотров", "err", err)
+	}
+	return repo
+}
+
+// initSchema не связывает entity_id с мангой и главами: история просмотров
+// должна переживать их окончательное удаление.
+func (r *SQLiteViewHistoryRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS view_history (
+		entity_type TEXT NOT NULL,
+		entity_id INTEGER NOT NULL,
+		day DATE NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		unique_viewers INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (entity_type, entity_id, day)
+	);
+	CREATE INDEX IF NOT EXISTS idx_view_history_day ON view_history(entity_type, day);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблицы истории просмотров", "err", err)
+	}
+	return err
+}
+
+// dayString переводит начало суток в строку, которая хранится в столбце day:
+// так сравнение дат не зависит от того, как драйвер сохраняет время.
+func dayString(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+func (r *SQLiteViewHistoryRepository) Save(stats []*models.DailyViews) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO view_history (entity_type, entity_id, day, views, unique_viewers)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (entity_type, entity_id, day) DO UPDATE SET
+			views = MAX(views, excluded.views),
+			unique_viewers = MAX(unique_viewers, excluded.unique_viewers)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range stats {
+		if _, err = stmt.Exec(s.EntityType, s.EntityID, dayString(s.Day), s.Views, s.UniqueViewers); err != nil {
+			r.logger.Error("Ошибка сохранения истории просмотров", "entity_type", s.EntityType,
+				"entity_id", s.EntityID, "day", dayString(s.Day), "err", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteViewHistoryRepository) List(entity models.ViewEntity, entityID int64, from, to time.Time) ([]*models.DailyViews, error) {
+	query := `SELECT entity_type, entity_id, day, views, unique_viewers FROM view_history
+		WHERE entity_type = ? AND day >= ? AND day < ?`
+	args := []any{entity, dayString(from), dayString(to)}
+	if entityID != 0 {
+		query += " AND entity_id = ?"
+		args = append(args, entityID)
+	}
+	query += " ORDER BY day, entity_id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		r.logger.Error("Ошибка получения истории просмотров", "entity_type", entity, "entity_id", entityID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*models.DailyViews{}
+	for rows.Next() {
+		s := &models.DailyViews{}
+		var day string
+		if err = rows.Scan(&s.EntityType, &s.EntityID, &day, &s.Views, &s.UniqueViewers); err != nil {
+			r.logger.Error("Ошибка сканирования истории просмотров", "err", err)
+			return nil, err
+		}
+		// Драйвер может вернуть DATE как время в формате RFC 3339.
+		if len(day) > len(time.DateOnly) {
+			day = day[:len(time.DateOnly)]
+		}
+		if s.Day, err = time.Parse(time.DateOnly, day); err != nil {
+			r.logger.Error("Ошибка разбора дня в истории просмотров", "day", day, "err", err)
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func (r *SQLiteViewHistoryRepository) Totals(entity models.ViewEntity) (map[int64]int64, error) {
+	rows, err := r.db.Query(`SELECT entity_id, SUM(views) FROM view_history
+		WHERE entity_type = ? GROUP BY entity_id`, entity)
+	if err != nil {
+		r.logger.Error("Ошибка подсчета просмотров по истории", "entity_type", entity, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int64]int64)
+	for rows.Next() {
+		var id, views int64
+		if err = rows.Scan(&id, &views); err != nil {
+			r.logger.Error("Ошибка сканирования суммы просмотров", "err", err)
+			return nil, err
+		}
+		totals[id] = views
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"strings"
+	"time"
+)
+
+type SQLiteWebhookRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewWebhookRepository(conn *sql.DB, logger *slog.Logger) db.WebhookRepository {
+	repo := &SQLiteWebhookRepository{db: conn, logger: logger}
+	if err := repo.initSchema(); err != nil {
+		logger.Error("Ошибка создания схемы для вебхуков", "err", err)
+	}
+	return repo
+}
+
+func (r *SQLiteWebhookRepository) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '',
+		active BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		success BOOLEAN NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);`
+	_, err := r.db.Exec(schema)
+	if err != nil {
+		r.logger.Error("Ошибка создания таблиц вебхуков", "err", err)
+	}
+	return err
+}
+
+const webhookColumns = "id, url, secret, events, active, created_at"
+
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	var events string
+	if err := row.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.Active, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+	hook.Events = splitEvents(events)
+	return hook, nil
+}
+
+func joinEvents(events []models.WebhookEvent) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = string(e)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitEvents(value string) []models.WebhookEvent {
+	events := []models.WebhookEvent{}
+	for _, e := range strings.Split(value, ",") {
+		if e != "" {
+			events = append(events, models.WebhookEvent(e))
+		}
+	}
+	return events
+}
+
+func (r *SQLiteWebhookRepository) Create(hook *models.Webhook) (int64, error) {
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now().UTC()
+	}
+	result, err := r.db.Exec("INSERT INTO webhooks (url, secret, events, active, created_at) VALUES (?, ?, ?, ?, ?)",
+		hook.URL, hook.Secret, joinEvents(hook.Events), hook.Active, hook.CreatedAt)
+	if err != nil {
+		r.logger.Error("Ошибка создания вебхука", "err", err)
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *SQLiteWebhookRepository) GetByID(id int64) (*models.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			r.logger.Error("Ошибка получения вебхука", "id", id, "err", err)
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (r *SQLiteWebhookRepository) List() ([]*models.Webhook, error) {
+	rows, err := r.db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		r.logger.Error("Ошибка получения списка вебхуков", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.Error("Ошибка сканирования вебхука", "err", err)
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *SQLiteWebhookRepository) Update(hook *models.Webhook) error {
+	result, err := r.db.Exec("UPDATE webhooks SET url = ?, secret = ?, events = ?, active = ? WHERE id = ?",
+		hook.URL, hook.Secret, joinEvents(hook.Events), hook.Active, hook.ID)
+	if err != nil {
+		r.logger.Error("Ошибка обновления вебхука", "id", hook.ID, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete удаляет вебхук; журнал доставки удаляется каскадно.
+func (r *SQLiteWebhookRepository) Delete(id int64) error {
+	result, err := r.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		r.logger.Error("Ошибка удаления вебхука", "id", id, "err", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteWebhookRepository) LogDelivery(d *models.WebhookDelivery) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	result, err := r.db.Exec(`INSERT INTO webhook_deliveries
+		(webhook_id, event_id, event, attempt, status_code, error, success, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.WebhookID, d.EventID, d.Event, d.Attempt, d.StatusCode, d.Error, d.Success, d.DurationMS, d.CreatedAt)
+	if err != nil {
+		r.logger.Error("Ошибка записи журнала доставки вебхука", "webhook_id", d.WebhookID, "err", err)
+		return err
+	}
+	d.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *SQLiteWebhookRepository) ListDeliveries(webhookID int64, limit, offset int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`SELECT id, webhook_id, event_id, event, attempt, status_code, error, success, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, webhookID, limit, offset)
+	if err != nil {
+		r.logger.Error("Ошибка получения журнала доставки вебхука", "webhook_id", webhookID, "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		if err = rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Attempt, &d.StatusCode,
+			&d.Error, &d.Success, &d.DurationMS, &d.CreatedAt); err != nil {
+			r.logger.Error("Ошибка сканирования записи журнала доставки", "err", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
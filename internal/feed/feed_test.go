@@ -0,0 +1,63 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+	return &Feed{
+		ID:      "https://manga.example/feed.atom",
+		Title:   "Новые главы",
+		Link:    "https://manga.example/manga",
+		Self:    "https://manga.example/feed.atom",
+		Updated: published,
+		Items: []Item{{
+			ID:        "https://manga.example/chapter/1",
+			Title:     "Берсерк — Глава 1: <Черный мечник>",
+			Link:      "https://manga.example/chapter/1",
+			Published: published,
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Ошибка сериализации Atom: %v", err)
+	}
+	if !strings.Contains(string(body), `xmlns="http://www.w3.org/2005/Atom"`) {
+		t.Error("Отсутствует пространство имен Atom")
+	}
+
+	var doc atomFeed
+	if err = xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Ошибка разбора Atom: %v", err)
+	}
+	if doc.Updated != "2024-05-01T09:00:00Z" {
+		t.Errorf("Время должно выводиться в UTC, получено %s", doc.Updated)
+	}
+	if len(doc.Entries) != 1 || doc.Entries[0].Title != "Берсерк — Глава 1: <Черный мечник>" {
+		t.Errorf("Неожиданные записи: %+v", doc.Entries)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("Ошибка сериализации RSS: %v", err)
+	}
+	for _, want := range []string{
+		`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">`,
+		`<atom:link href="https://manga.example/feed.atom" rel="self" type="application/rss+xml">`,
+		`<guid isPermaLink="true">https://manga.example/chapter/1</guid>`,
+		`<pubDate>Wed, 01 May 2024 09:00:00 +0000</pubDate>`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("В RSS отсутствует %s:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/mail"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	netmail "net/mail"
+	"strings"
+)
+
+func validatePassword(field, password string) error {
+	if password == "" {
+		return apperror.NewValidationError("Поле "+field+" не может быть пустым",
+			map[string]string{field: "Это поле обязательно"})
+	}
+	if len(password) < 6 {
+		return apperror.NewValidationError("Пароль слишком короткий",
+			map[string]string{field: "Минимальная длина - 6 символов"})
+	}
+	return nil
+}
+
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+		return apperror.NewValidationError("Некорректный email",
+			map[string]string{"email": "Ожидается адрес вида user@example.com"})
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", apperror.NewInternalServerError("Ошибка хеширования пароля", err)
+	}
+	return string(hashed), nil
+}
+
+// currentUserID возвращает ID аутентифицированного пользователя.
+func currentUserID(r *http.Request) (int64, error) {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok {
+		return 0, apperror.NewUnauthorizedError("Требуется аутентификация", nil)
+	}
+	return userID, nil
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+
+	response.Success(w, http.StatusOK, user)
+	return nil
+}
+
+type UpdateAccountRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	// PreferredLanguage задает язык списка глав по умолчанию; пустая
+	// строка сбрасывает его.
+	PreferredLanguage *string `json:"preferred_language"`
+}
+
+func (h *UserHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateAccountRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	before := *user
+
+	if req.Username != nil && *req.Username != user.Username {
+		username := strings.TrimSpace(*req.Username)
+		if username == "" {
+			return apperror.NewValidationError("Поле username не может быть пустым",
+				map[string]string{"username": "Это поле обязательно"})
+		}
+		if existing, err := h.UserRepo.GetByUsername(username); err == nil && existing.ID != user.ID {
+			return apperror.NewConflictError("Имя пользователя уже занято", nil)
+		}
+		user.Username = username
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		if err = validateEmail(*req.Email); err != nil {
+			return err
+		}
+		if *req.Email != "" {
+			if existing, err := h.UserRepo.GetByEmail(*req.Email); err == nil && existing.ID != user.ID {
+				return apperror.NewConflictError("Email уже используется", nil)
+			}
+		}
+		user.Email = *req.Email
+	}
+
+	if req.PreferredLanguage != nil {
+		language := strings.ToLower(strings.TrimSpace(*req.PreferredLanguage))
+		if language != "" && !models.ValidLanguage(language) {
+			return apperror.NewValidationError("Некорректный код языка",
+				map[string]string{"preferred_language": "Ожидается код ISO 639, например ru, en или pt-br"})
+		}
+		user.PreferredLanguage = language
+	}
+
+	if err = h.UserRepo.Update(user); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить учетную запись", err)
+	}
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID, before, user)
+
+	response.Success(w, http.StatusOK, user)
+	return nil
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Хеш пароля в журнал аудита не попадает: записывается только факт смены.
+var (
+	passwordUnchanged = map[string]string{}
+	passwordChanged   = map[string]string{"password": "changed"}
+)
+
+// ChangePassword меняет пароль и завершает все остальные сессии,
+// возвращая новую пару токенов для текущего клиента.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req ChangePasswordRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if err = validatePassword("new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return apperror.NewUnauthorizedError("Неверный текущий пароль", nil)
+	}
+
+	hashed, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err = h.UserRepo.UpdatePassword(user.ID, hashed); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить пароль", err)
+	}
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID, passwordUnchanged, passwordChanged)
+
+	if err = auth.RevokeAllTokens(r.Context(), user.ID); err != nil {
+		return apperror.NewInternalServerError("Ошибка отзыва токенов", err)
+	}
+	tokens, err := auth.IssueTokens(r.Context(), user.ID, user.Role)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось сгенерировать токен", err)
+	}
+
+	response.Success(w, http.StatusOK, tokens)
+	return nil
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// ForgotPassword отправляет письмо со ссылкой для сброса пароля. Ответ не
+// зависит от того, найден ли пользователь, чтобы нельзя было перебирать
+// существующие учетные записи.
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.Username == "" && req.Email == "" {
+		return apperror.NewValidationError("Необходимо указать username или email", nil)
+	}
+	if h.Mailer == nil {
+		return apperror.NewServiceUnavailableError("Сброс пароля по email отключен", nil)
+	}
+
+	resp := map[string]string{"status": "success", "message": "Если учетная запись существует, на ее email отправлено письмо"}
+
+	var (
+		user *models.User
+		err  error
+	)
+	if req.Email != "" {
+		user, err = h.UserRepo.GetByEmail(req.Email)
+	} else {
+		user, err = h.UserRepo.GetByUsername(req.Username)
+	}
+	if err != nil || user.Email == "" {
+		response.Success(w, http.StatusOK, resp)
+		return nil
+	}
+
+	token, err := auth.IssuePasswordResetToken(r.Context(), user.ID)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось создать токен сброса пароля", err)
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля сброса пароля перейдите по ссылке:\n%s/user/password/reset?token=%s\n\n"+
+			"Ссылка действительна %d мин. Если вы не запрашивали сброс, проигнорируйте это письмо.\n",
+			user.Username, strings.TrimRight(h.PublicURL, "/"), token, int(auth.PasswordResetTTL.Minutes())),
+	}
+	if err = h.Mailer.Send(r.Context(), msg); err != nil {
+		h.Logger.Error("Ошибка отправки письма сброса пароля", "user_id", user.ID, "err", err)
+	}
+
+	response.Success(w, http.StatusOK, resp)
+	return nil
+}
+
+// resetPasswordPage — страница сброса пароля, на которую ведет ссылка из
+// письма. Токен берется скриптом из адреса страницы и отправляется вместе с
+// новым паролем POST-запросом на тот же адрес.
+const resetPasswordPage = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Сброс пароля</title>
+</head>
+<body>
+<h1>Сброс пароля</h1>
+<form id="reset">
+<label>Новый пароль <input type="password" name="new_password" autocomplete="new-password" required></label>
+<button type="submit">Сохранить</button>
+</form>
+<p id="result"></p>
+<script>
+document.getElementById("reset").addEventListener("submit", async (e) => {
+	e.preventDefault();
+	const token = new URLSearchParams(location.search).get("token") || "";
+	const resp = await fetch(location.pathname, {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({token: token, new_password: e.target.new_password.value}),
+	});
+	const body = await resp.json().catch(() => ({}));
+	document.getElementById("result").textContent = body.message || (resp.ok ? "Пароль изменен" : "Не удалось изменить пароль");
+	if (resp.ok) {
+		e.target.remove();
+	}
+});
+</script>
+</body>
+</html>
+`
+
+// ResetPasswordForm отдает страницу сброса пароля для ссылки из письма
+// (GET /user/password/reset?token=...). Страница не кешируется и не
+// передает адрес с токеном в заголовке Referer.
+func (h *UserHandler) ResetPasswordForm(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	_, err := io.WriteString(w, resetPasswordPage)
+	return err
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.Token == "" {
+		return apperror.NewValidationError("Поле token не может быть пустым",
+			map[string]string{"token": "Это поле обязательно"})
+	}
+	if err := validatePassword("new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	userID, err := auth.ConsumePasswordResetToken(r.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return apperror.NewBadRequestError("Недействительный или просроченный токен сброса пароля", nil)
+		}
+		return apperror.NewInternalServerError("Ошибка проверки токена сброса пароля", err)
+	}
+
+	hashed, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err = h.UserRepo.UpdatePassword(userID, hashed); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить пароль", err)
+	}
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, userID, passwordUnchanged, passwordChanged)
+	if err = auth.RevokeAllTokens(r.Context(), userID); err != nil {
+		return apperror.NewInternalServerError("Ошибка отзыва токенов", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Пароль изменен"})
+	return nil
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccount удаляет учетную запись после подтверждения паролем.
+// Связанные данные удаляются вместе с пользователем, а все токены отзываются.
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req DeleteAccountRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return apperror.NewUnauthorizedError("Неверный пароль", nil)
+	}
+
+	if err = auth.RevokeAllTokens(r.Context(), user.ID); err != nil {
+		return apperror.NewInternalServerError("Ошибка отзыва токенов", err)
+	}
+	if err = h.UserRepo.Delete(user.ID); err != nil {
+		return apperror.NewDatabaseError("Не удалось удалить учетную запись", err)
+	}
+	h.Audit.Record(r, models.AuditDelete, models.AuditUser, user.ID, user, nil)
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Учетная запись удалена"})
+	return nil
+}
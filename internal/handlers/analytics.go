@@ -4,34 +4,50 @@ import (
 	"log/slog"
 	"manga-reader/internal/analytics"
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/db"
+	"manga-reader/internal/middleware"
 	"manga-reader/internal/response"
+	"manga-reader/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type AnalyticsHandler struct {
 	MangaRepo db.MangaRepository
+	Chapters  db.ChapterRepository
+	Pages     db.PageRepository
 	Analytics *analytics.AnalyticsService
+	History   db.ViewHistoryRepository
 	Logger    *slog.Logger
 }
 
+// maxHistoryRange — наибольший диапазон временного ряда просмотров.
+const maxHistoryRange = 2 * 366 * 24 * time.Hour
+
+// GetPopularManga возвращает рейтинг манги: GET /analytics/popular?period=&by=&limit=.
+// by=unique строит рейтинг по уникальным посетителям вместо просмотров.
+// period=trending упорядочивает мангу по просмотрам, затухающим со
+// временем, а period=rising — по приросту за последние сутки.
 func (h *AnalyticsHandler) GetPopularManga(w http.ResponseWriter, r *http.Request) error {
 	period := r.URL.Query().Get("period")
 	limitStr := r.URL.Query().Get("limit")
+	metric := analytics.ParseMetric(r.URL.Query().Get("by"))
 
 	if period == "" {
 		period = "all"
 	}
 	limit := int64(10)
-	if limitStr == "" {
+	if limitStr != "" {
 		parsedLimit, err := strconv.ParseInt(limitStr, 10, 64)
 		if err == nil && parsedLimit > 0 {
 			limit = parsedLimit
 		}
 	}
 
-	topEntries, err := h.Analytics.GetTopManga(r.Context(), period, limit)
+	topEntries, err := h.Analytics.GetTopManga(r.Context(), period, metric, limit)
 	if err != nil {
 		return apperror.NewInternalServerError("Ошибка получения рейтинга манги", err)
 	}
@@ -44,10 +60,13 @@ func (h *AnalyticsHandler) GetPopularManga(w http.ResponseWriter, r *http.Reques
 			continue
 		}
 		result = append(result, analytics.MangaWithViews{
-			ID:          manga.ID,
-			Title:       manga.Title,
-			Description: manga.Description,
-			Views:       entry.Views,
+			ID:            manga.ID,
+			Title:         manga.Title,
+			Description:   manga.Description,
+			Views:         entry.Views,
+			UniqueViewers: entry.UniqueViewers,
+			Score:         entry.Score,
+			Previous:      entry.Previous,
 		})
 	}
 
@@ -55,8 +74,161 @@ func (h *AnalyticsHandler) GetPopularManga(w http.ResponseWriter, r *http.Reques
 	return nil
 }
 
+// GetMangaViews возвращает временной ряд просмотров манги из истории:
+// GET /analytics/manga/{id}/views?from=&to=&interval=. Даты from и to
+// передаются в формате 2006-01-02 и входят в диапазон; по умолчанию это
+// последние 30 дней. Шаг interval — day (по умолчанию), week или month.
+// История обновляется периодически, поэтому последние просмотры могут
+// появиться в ней с задержкой.
+func (h *AnalyticsHandler) GetMangaViews(w http.ResponseWriter, r *http.Request) error {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/analytics/manga/"), "/views")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID", err)
+	}
+
+	q := r.URL.Query()
+	interval := analytics.IntervalDay
+	if s := q.Get("interval"); s != "" {
+		interval = analytics.Interval(s)
+	}
+	if !interval.Valid() {
+		return apperror.NewValidationError("Некорректный параметр interval",
+			map[string]string{"interval": "Допустимые значения: day, week, month"})
+	}
+
+	parseDay := func(name string, def time.Time) (time.Time, error) {
+		s := q.Get(name)
+		if s == "" {
+			return def, nil
+		}
+		day, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return time.Time{}, apperror.NewValidationError("Некорректный параметр "+name,
+				map[string]string{name: "Ожидается дата в формате 2006-01-02"})
+		}
+		return day, nil
+	}
+	to, err := parseDay("to", time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	from, err := parseDay("from", to.AddDate(0, 0, -29))
+	if err != nil {
+		return err
+	}
+	if from.After(to) {
+		return apperror.NewValidationError("Некорректный диапазон дат",
+			map[string]string{"from": "Должна быть не позже to"})
+	}
+	if to.Sub(from) > maxHistoryRange {
+		return apperror.NewValidationError("Некорректный диапазон дат",
+			map[string]string{"from": "Диапазон не должен превышать двух лет"})
+	}
+
+	if _, err = h.MangaRepo.GetByID(id); err != nil {
+		return apperror.NewNotFoundError("Манга не найдена", err)
+	}
+
+	days, err := h.History.List(models.ViewManga, id, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения истории просмотров", err)
+	}
+
+	response.Success(w, http.StatusOK, analytics.ViewsSeries{
+		EntityID: id,
+		Interval: interval,
+		From:     from.Format(time.DateOnly),
+		To:       to.Format(time.DateOnly),
+		Points:   analytics.BuildSeries(days, from, to, interval),
+	})
+	return nil
+}
+
+// GetChapterFunnel возвращает воронку дочитывания главы по страницам:
+// GET /analytics/chapter/{id}/funnel. Неопубликованные главы доступны
+// только загрузчикам и модераторам.
+func (h *AnalyticsHandler) GetChapterFunnel(w http.ResponseWriter, r *http.Request) error {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/analytics/chapter/"), "/funnel")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID главы", err)
+	}
+
+	ch, err := h.Chapters.GetByID(id)
+	if err != nil || (!ch.Published() && !canSeeUnpublished(r)) {
+		return apperror.NewNotFoundError("Глава не найдена", err)
+	}
+	pages, err := h.Pages.ListByChapter(id)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения страниц главы", err)
+	}
+
+	funnel, err := h.Analytics.ChapterFunnel(r.Context(), id, pages)
+	if err != nil {
+		return apperror.NewInternalServerError("Ошибка построения воронки дочитывания", err)
+	}
+
+	response.Success(w, http.StatusOK, funnel)
+	return nil
+}
+
+// GetMangaRetention возвращает удержание читателей манги от главы к главе:
+// GET /analytics/manga/{id}/retention?lang=. Параметр lang оставляет только
+// переводы на этот язык; без него переводы главы с одним номером
+// считаются вместе.
+func (h *AnalyticsHandler) GetMangaRetention(w http.ResponseWriter, r *http.Request) error {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/analytics/manga/"), "/retention")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID", err)
+	}
+
+	lang := strings.ToLower(r.URL.Query().Get("lang"))
+	if lang == "all" {
+		lang = ""
+	}
+	if lang != "" && !models.ValidLanguage(lang) {
+		return apperror.NewValidationError("Некорректный код языка",
+			map[string]string{"lang": "Ожидается код ISO 639 или all"})
+	}
+
+	if _, err = h.MangaRepo.GetByID(id); err != nil {
+		return apperror.NewNotFoundError("Манга не найдена", err)
+	}
+	chapters, err := h.Chapters.ListByManga(id)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения списка глав", err)
+	}
+	chapters = visibleChapters(r, chapters)
+	if lang != "" {
+		chapters = filterChapters(chapters, func(ch *models.Chapter) bool { return ch.Language == lang })
+	}
+
+	retention, err := h.Analytics.MangaRetention(r.Context(), id, chapters)
+	if err != nil {
+		return apperror.NewInternalServerError("Ошибка расчета удержания читателей", err)
+	}
+
+	response.Success(w, http.StatusOK, retention)
+	return nil
+}
+
+// GetIngestStats возвращает счетчики асинхронной записи просмотров, в том
+// числе число отброшенных из-за переполнения очереди: GET /analytics/ingest.
+func (h *AnalyticsHandler) GetIngestStats(w http.ResponseWriter, r *http.Request) error {
+	response.Success(w, http.StatusOK, h.Analytics.Stats())
+	return nil
+}
+
+// viewerID определяет посетителя запроса для подсчета уникальных просмотров.
+func viewerID(s *analytics.AnalyticsService, r *http.Request) string {
+	userID, _ := auth.UserIDFrom(r.Context())
+	return s.ViewerID(userID, middleware.ClientIP(r), r.UserAgent())
+}
+
 func (h *AnalyticsHandler) ResetDailyStats(w http.ResponseWriter, r *http.Request) error {
-	if err := h.Analytics.InitializeDailyStats(r.Context()); err != nil {
+	if err := h.Analytics.ResetRanking(r.Context(), analytics.PeriodDay); err != nil {
 		return apperror.NewInternalServerError("Ошибка сброса дневной статистики", err)
 	}
 
@@ -65,7 +237,7 @@ func (h *AnalyticsHandler) ResetDailyStats(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *AnalyticsHandler) ResetWeeklyStats(w http.ResponseWriter, r *http.Request) error {
-	if err := h.Analytics.InitializeWeeklyStats(r.Context()); err != nil {
+	if err := h.Analytics.ResetRanking(r.Context(), analytics.PeriodWeek); err != nil {
 		return apperror.NewInternalServerError("Ошибка сброса недельной статистики", err)
 	}
 
@@ -74,7 +246,7 @@ func (h *AnalyticsHandler) ResetWeeklyStats(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *AnalyticsHandler) ResetMonthlyStats(w http.ResponseWriter, r *http.Request) error {
-	if err := h.Analytics.InitializeMonthlyStats(r.Context()); err != nil {
+	if err := h.Analytics.ResetRanking(r.Context(), analytics.PeriodMonth); err != nil {
 		return apperror.NewInternalServerError("Ошибка сброса месячной статистики", err)
 	}
 
@@ -4,7 +4,9 @@ import (
 	"manga-reader/internal/apperror"
 	"manga-reader/internal/auth"
 	"manga-reader/internal/middleware"
+	"manga-reader/models"
 	"net/http"
+	"strings"
 )
 
 func RegisterAnalyticsRoutes(mux *http.ServeMux, ah *AnalyticsHandler) {
@@ -15,21 +17,54 @@ func RegisterAnalyticsRoutes(mux *http.ServeMux, ah *AnalyticsHandler) {
 		return apperror.NewBadRequestError("Метод не поддерживается", nil)
 	}))
 
-	mux.Handle("/analytics/reset/daily", auth.AuthMiddleware(middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	// Подробные отчеты доступны только пользователям и API-ключам с
+	// областью read:analytics; рейтинг /analytics/popular остается публичным.
+	mux.Handle("/analytics/manga/", auth.RequireScope(models.RoleReader, models.ScopeReadAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/views"):
+			return ah.GetMangaViews(w, r)
+		case strings.HasSuffix(r.URL.Path, "/retention"):
+			return ah.GetMangaRetention(w, r)
+		default:
+			return apperror.NewNotFoundError("Ресурс не найден", nil)
+		}
+	})))
+
+	mux.Handle("/analytics/chapter/", auth.RequireScope(models.RoleReader, models.ScopeReadAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		if !strings.HasSuffix(r.URL.Path, "/funnel") {
+			return apperror.NewNotFoundError("Ресурс не найден", nil)
+		}
+		return ah.GetChapterFunnel(w, r)
+	})))
+
+	mux.Handle("/analytics/ingest", auth.RequireScope(models.RoleAdmin, models.ScopeManageAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodGet {
+			return ah.GetIngestStats(w, r)
+		}
+		return apperror.NewBadRequestError("Метод не поддерживается", nil)
+	})))
+
+	mux.Handle("/analytics/reset/daily", auth.RequireScope(models.RoleAdmin, models.ScopeManageAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodPost {
 			return ah.ResetDailyStats(w, r)
 		}
 		return apperror.NewBadRequestError("Метод не поддерживается", nil)
 	})))
 
-	mux.Handle("/analytics/reset/weekly", auth.AuthMiddleware(middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/analytics/reset/weekly", auth.RequireScope(models.RoleAdmin, models.ScopeManageAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodPost {
 			return ah.ResetWeeklyStats(w, r)
 		}
 		return apperror.NewBadRequestError("Метод не поддерживается", nil)
 	})))
 
-	mux.Handle("/analytics/reset/monthly", auth.AuthMiddleware(middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/analytics/reset/monthly", auth.RequireScope(models.RoleAdmin, models.ScopeManageAnalytics, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodPost {
 			return ah.ResetMonthlyStats(w, r)
 		}
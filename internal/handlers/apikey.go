@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIKeyHandler struct {
+	Repo     db.APIKeyRepository
+	UserRepo db.UserRepository
+	Logger   *slog.Logger
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string         `json:"name"`
+	Scopes    []models.Scope `json:"scopes"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse содержит сам ключ — он возвращается только при создании.
+type CreateAPIKeyResponse struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req CreateAPIKeyRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return apperror.NewValidationError("Поле name не может быть пустым",
+			map[string]string{"name": "Это поле обязательно"})
+	}
+	if len(req.Scopes) == 0 {
+		return apperror.NewValidationError("Необходимо указать хотя бы одну область",
+			map[string]string{"scopes": "Это поле обязательно"})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return apperror.NewValidationError("Срок действия ключа уже истек",
+			map[string]string{"expires_at": "Должен быть в будущем"})
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			return apperror.NewValidationError("Неизвестная область "+string(scope),
+				map[string]string{"scopes": "Допустимые значения: read:analytics, manage:analytics, write:manga, write:chapters, upload:pages, delete:content"})
+		}
+		if !scope.AllowedFor(user.Role) {
+			return apperror.NewForbiddenError("Роль не позволяет выдать область "+string(scope), nil)
+		}
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось создать API-ключ", err)
+	}
+	apiKey := &models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+	id, err := h.Repo.Create(apiKey)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось сохранить API-ключ", err)
+	}
+	apiKey.ID = id
+
+	response.Success(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: apiKey, Key: key})
+	return nil
+}
+
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.Repo.ListByUser(userID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить список API-ключей", err)
+	}
+
+	response.Success(w, http.StatusOK, keys)
+	return nil
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/user/apikeys/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID ключа", err)
+	}
+
+	if err = h.Repo.Delete(id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("API-ключ не найден", err)
+		}
+		return apperror.NewDatabaseError("Не удалось отозвать API-ключ", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "API-ключ отозван"})
+	return nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"net/http"
+)
+
+func RegisterAPIKeyRoutes(mux *http.ServeMux, kh *APIKeyHandler) {
+	mux.Handle("/user/apikeys", auth.RequireSession(middleware.ErrorHandler(kh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return kh.List(w, r)
+		case http.MethodPost:
+			return kh.Create(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+	mux.Handle("/user/apikeys/", auth.RequireSession(middleware.ErrorHandler(kh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodDelete {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return kh.Revoke(w, r)
+	})))
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AuditHandler отдает администраторам журнал аудита.
+type AuditHandler struct {
+	Repo   db.AuditRepository
+	Logger *slog.Logger
+}
+
+// List возвращает записи журнала от новых к старым:
+// GET /audit?actor_id=&action=&entity_type=&entity_id=&since=&until=&limit=&offset=.
+// Время в since и until передается в формате RFC 3339.
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) error {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		return err
+	}
+
+	entries, err := h.Repo.List(filter, limit, offset)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения журнала аудита", err)
+	}
+
+	response.Success(w, http.StatusOK, entries)
+	return nil
+}
+
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	var filter models.AuditFilter
+	var err error
+
+	parseID := func(name string) (int64, error) {
+		s := q.Get(name)
+		if s == "" {
+			return 0, nil
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return 0, apperror.NewValidationError("Некорректный параметр "+name,
+				map[string]string{name: "Должен быть положительным числом"})
+		}
+		return id, nil
+	}
+	parseTime := func(name string) (time.Time, error) {
+		s := q.Get(name)
+		if s == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, apperror.NewValidationError("Некорректный параметр "+name,
+				map[string]string{name: "Ожидается время в формате RFC 3339"})
+		}
+		return t, nil
+	}
+
+	if filter.ActorID, err = parseID("actor_id"); err != nil {
+		return filter, err
+	}
+	if filter.EntityID, err = parseID("entity_id"); err != nil {
+		return filter, err
+	}
+	if filter.Since, err = parseTime("since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseTime("until"); err != nil {
+		return filter, err
+	}
+
+	if s := q.Get("action"); s != "" {
+		filter.Action = models.AuditAction(s)
+		if !filter.Action.Valid() {
+			return filter, apperror.NewValidationError("Некорректный параметр action",
+				map[string]string{"action": "Допустимые значения: create, update, delete, restore"})
+		}
+	}
+	if s := q.Get("entity_type"); s != "" {
+		filter.EntityType = models.AuditEntity(s)
+		if !filter.EntityType.Valid() {
+			return filter, apperror.NewValidationError("Некорректный параметр entity_type",
+				map[string]string{"entity_type": "Допустимые значения: manga, chapter, page, user"})
+		}
+	}
+	return filter, nil
+}
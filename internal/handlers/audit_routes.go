@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+)
+
+func RegisterAuditRoutes(mux *http.ServeMux, ah *AuditHandler) {
+	mux.Handle("/audit", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(ah.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return ah.List(w, r)
+	})))
+}
@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"manga-reader/internal/analytics"
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/cache"
 	"manga-reader/internal/db"
 	"manga-reader/internal/response"
+	"manga-reader/internal/webhook"
 	"manga-reader/models"
 	"net/http"
 	"strconv"
@@ -19,8 +26,20 @@ import (
 type ChapterHandler struct {
 	Repo      db.ChapterRepository
 	Logger    *slog.Logger
-	Cache     *cache.RedisCache
+	Cache     cache.Cache
 	Analytics *analytics.AnalyticsService
+	// Notifications рассылает подписчикам уведомления о новых главах.
+	Notifications db.NotificationRepository
+	Webhooks      *webhook.Dispatcher
+	// Groups проверяет команды переводчиков, указанные у глав.
+	Groups db.ScanlationGroupRepository
+	// Users нужен для языка по умолчанию в списке глав.
+	Users db.UserRepository
+	// MangaRepo проверяет мангу новых глав; вместе с Pages нужен для
+	// манифеста читалки.
+	MangaRepo db.MangaRepository
+	Pages     db.PageRepository
+	Audit     *audit.Recorder
 }
 
 func (h *ChapterHandler) Delete(w http.ResponseWriter, r *http.Request) error {
@@ -39,12 +58,21 @@ func (h *ChapterHandler) Delete(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewDatabaseError("Ошибка удаления главы", err)
 	}
 
-	cacheKey := fmt.Sprintf("manga:%d:chapters", chapter.MangaID)
 	if h.Cache != nil {
-		if err = h.Cache.Delete(r.Context(), cacheKey); err != nil {
-			h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
+		for _, cacheKey := range []string{
+			fmt.Sprintf("manga:%d:chapters", chapter.MangaID),
+			fmt.Sprintf("chapter:%d", id),
+			fmt.Sprintf("chapter:%d:pages", id),
+			readerCacheKey(id),
+		} {
+			if err = h.Cache.Delete(r.Context(), cacheKey); err != nil {
+				h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
+			}
 		}
 	}
+	h.invalidateReaders(r.Context(), chapter.MangaID)
+	h.Webhooks.Emit(models.EventChapterDeleted, chapter)
+	h.Audit.Record(r, models.AuditDelete, models.AuditChapter, id, chapter, nil)
 
 	response.Success(w, http.StatusNoContent, nil)
 	return nil
@@ -64,6 +92,29 @@ func (h *ChapterHandler) Create(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewValidationError("Некорректный ID манги",
 			map[string]string{"manga_id": "Должен быть положительным числом"})
 	}
+	// Глава не должна появиться у манги из корзины: при очистке корзины
+	// удаляются только главы, перенесенные туда вместе с мангой.
+	if _, err := h.MangaRepo.GetByID(ch.MangaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("Манга не найдена", err)
+		}
+		return apperror.NewDatabaseError("Не удалось получить мангу", err)
+	}
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	ch.PublishedAt = nil
+	if err := applyChapterStatus(&ch, time.Now().UTC(), true); err != nil {
+		return err
+	}
+	if err := normalizeChapterLanguage(&ch); err != nil {
+		return err
+	}
+	if ch.GroupID != nil {
+		if err := h.checkGroup(r, *ch.GroupID); err != nil {
+			return err
+		}
+	}
 
 	id, err := h.Repo.Create(&ch)
 	if err != nil {
@@ -78,11 +129,109 @@ func (h *ChapterHandler) Create(w http.ResponseWriter, r *http.Request) error {
 			h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
 		}
 	}
+	h.Webhooks.Emit(models.EventChapterCreated, ch)
+	h.Audit.Record(r, models.AuditCreate, models.AuditChapter, ch.ID, nil, ch)
+	if ch.Published() {
+		h.Published(r.Context(), &ch)
+	}
 
 	response.Success(w, http.StatusCreated, ch)
 	return nil
 }
 
+// normalizeChapterLanguage приводит код языка главы к нижнему регистру и
+// проверяет его. Глава без языка считается переведенной на язык по умолчанию.
+func normalizeChapterLanguage(ch *models.Chapter) error {
+	ch.Language = strings.ToLower(strings.TrimSpace(ch.Language))
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
+	if !models.ValidLanguage(ch.Language) {
+		return apperror.NewValidationError("Некорректный код языка",
+			map[string]string{"language": "Ожидается код ISO 639, например ru, en или pt-br"})
+	}
+	return nil
+}
+
+// checkGroup проверяет, что команда существует и отправитель запроса может
+// публиковать главы от ее имени: состоит в ней или является модератором.
+func (h *ChapterHandler) checkGroup(r *http.Request, groupID int64) error {
+	if h.Groups == nil {
+		return apperror.NewValidationError("Команды переводчиков не поддерживаются",
+			map[string]string{"group_id": "Поле не поддерживается"})
+	}
+	if _, err := h.Groups.GetByID(groupID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewValidationError("Команда не найдена",
+				map[string]string{"group_id": "Команда с таким ID не существует"})
+		}
+		return apperror.NewDatabaseError("Не удалось получить команду", err)
+	}
+	if auth.RoleFrom(r.Context()).Includes(models.RoleModerator) {
+		return nil
+	}
+	userID, _ := auth.UserIDFrom(r.Context())
+	member, err := h.Groups.IsMember(groupID, userID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось проверить участие в команде", err)
+	}
+	if !member {
+		return apperror.NewForbiddenError("Публиковать главы от имени команды могут только ее участники", nil)
+	}
+	return nil
+}
+
+// applyChapterStatus проверяет состояние публикации главы и заполняет
+// связанные с ним поля: опубликованная глава получает время публикации,
+// а время планирования сохраняется только у запланированной. При
+// rescheduled время планирования должно быть в будущем.
+func applyChapterStatus(ch *models.Chapter, now time.Time, rescheduled bool) error {
+	if !ch.Status.Valid() {
+		return apperror.NewValidationError("Некорректное состояние главы",
+			map[string]string{"status": "Допустимые значения: draft, scheduled, published"})
+	}
+	switch ch.Status {
+	case models.ChapterScheduled:
+		if ch.PublishAt == nil || (rescheduled && !ch.PublishAt.After(now)) {
+			return apperror.NewValidationError("Некорректное время публикации",
+				map[string]string{"publish_at": "Для запланированной главы нужно время в будущем"})
+		}
+		publishAt := ch.PublishAt.UTC()
+		ch.PublishAt = &publishAt
+		ch.PublishedAt = nil
+	case models.ChapterPublished:
+		ch.PublishAt = nil
+		if ch.PublishedAt == nil {
+			ch.PublishedAt = &now
+		}
+	default:
+		ch.PublishAt = nil
+		ch.PublishedAt = nil
+	}
+	return nil
+}
+
+// Published выполняет действия, связанные с выходом главы: сбрасывает
+// кеш манги, рассылает уведомления подписчикам и событие вебхука.
+// Вызывается и обработчиками, и планировщиком публикаций.
+func (h *ChapterHandler) Published(ctx context.Context, ch *models.Chapter) {
+	if h.Cache != nil {
+		for _, cacheKey := range []string{
+			fmt.Sprintf("manga:%d:chapters", ch.MangaID),
+			fmt.Sprintf("manga:%d", ch.MangaID),
+			fmt.Sprintf("chapter:%d", ch.ID),
+			"manga:list",
+		} {
+			if err := h.Cache.Delete(ctx, cacheKey); err != nil {
+				h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
+			}
+		}
+	}
+	h.invalidateReaders(ctx, ch.MangaID)
+	notifyNewChapter(h.Notifications, h.Logger, ch)
+	h.Webhooks.Emit(models.EventChapterPublished, ch)
+}
+
 func (h *ChapterHandler) Update(w http.ResponseWriter, r *http.Request) error {
 	idStr := strings.TrimPrefix(r.URL.Path, "/chapter/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -95,32 +244,90 @@ func (h *ChapterHandler) Update(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewNotFoundError("Глава не найдена", err)
 	}
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperror.NewBadRequestError("Ошибка чтения запроса", err)
+	}
 	var ch models.Chapter
-	if err = json.NewDecoder(r.Body).Decode(&ch); err != nil {
+	if err = json.Unmarshal(body, &ch); err != nil {
+		return apperror.NewBadRequestError("Ошибка декодирования запроса", err)
+	}
+	// Команда сохраняется, если поле group_id не передано; null снимает ее.
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(body, &fields); err != nil {
 		return apperror.NewBadRequestError("Ошибка декодирования запроса", err)
 	}
 
 	ch.ID = id
+	ch.MangaID = oldChapter.MangaID
+	if ch.Language == "" {
+		ch.Language = oldChapter.Language
+	}
+	if err = normalizeChapterLanguage(&ch); err != nil {
+		return err
+	}
+	if _, ok := fields["group_id"]; !ok {
+		ch.GroupID = oldChapter.GroupID
+	} else if ch.GroupID != nil && (oldChapter.GroupID == nil || *ch.GroupID != *oldChapter.GroupID) {
+		if err = h.checkGroup(r, *ch.GroupID); err != nil {
+			return err
+		}
+	}
+	if ch.Status == "" {
+		ch.Status = oldChapter.Status
+		if ch.PublishAt == nil {
+			ch.PublishAt = oldChapter.PublishAt
+		}
+	}
+	ch.PublishedAt = oldChapter.PublishedAt
+	// Правка запланированной главы без смены времени не должна
+	// отклоняться, если планировщик еще не успел ее опубликовать.
+	rescheduled := oldChapter.Status != models.ChapterScheduled || ch.PublishAt == nil ||
+		oldChapter.PublishAt == nil || !ch.PublishAt.Equal(*oldChapter.PublishAt)
+	if err = applyChapterStatus(&ch, time.Now().UTC(), rescheduled); err != nil {
+		return err
+	}
 	if err = h.Repo.Update(&ch); err != nil {
 		return apperror.NewDatabaseError("Ошибка обновления главы", err)
 	}
+	ch.CreatedAt = oldChapter.CreatedAt
 
-	cacheKey := fmt.Sprintf("manga:%d:chapters", oldChapter.MangaID)
 	if h.Cache != nil {
-		if err = h.Cache.Delete(r.Context(), cacheKey); err != nil {
-			h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
-		}
-
-		chapterKey := fmt.Sprintf("manga:%d:chapters", id)
-		if err = h.Cache.Delete(r.Context(), chapterKey); err != nil {
-			h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
+		for _, cacheKey := range []string{
+			fmt.Sprintf("manga:%d:chapters", oldChapter.MangaID),
+			fmt.Sprintf("chapter:%d", id),
+		} {
+			if err = h.Cache.Delete(r.Context(), cacheKey); err != nil {
+				h.Logger.Error("Ошибка инвалидации кеша", "key", cacheKey, "err", err)
+			}
 		}
 	}
+	h.invalidateReaders(r.Context(), oldChapter.MangaID)
+	h.Webhooks.Emit(models.EventChapterUpdated, ch)
+	h.Audit.Record(r, models.AuditUpdate, models.AuditChapter, id, oldChapter, ch)
+	if ch.Published() && !oldChapter.Published() {
+		h.Published(r.Context(), &ch)
+	}
 
 	response.Success(w, http.StatusNoContent, nil)
 	return nil
 }
 
+// canSeeUnpublished сообщает, видит ли отправитель запроса черновики и
+// запланированные главы. Они доступны загрузчикам и вышестоящим ролям.
+func canSeeUnpublished(r *http.Request) bool {
+	return auth.RoleFrom(r.Context()).Includes(models.RoleUploader)
+}
+
+// visibleChapters убирает из списка неопубликованные главы, если
+// отправитель запроса не может их видеть.
+func visibleChapters(r *http.Request, chapters []*models.Chapter) []*models.Chapter {
+	if canSeeUnpublished(r) {
+		return chapters
+	}
+	return filterChapters(chapters, (*models.Chapter).Published)
+}
+
 func (h *ChapterHandler) GetById(w http.ResponseWriter, r *http.Request) error {
 	idStr := strings.TrimPrefix(r.URL.Path, "/chapter/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -162,9 +369,13 @@ func (h *ChapterHandler) GetById(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	var views int64 = 0
+	if !ch.Published() && !canSeeUnpublished(r) {
+		return apperror.NewNotFoundError("Глава не найдена", nil)
+	}
+
+	var views, uniqueViewers int64
 	if h.Analytics != nil {
-		if err = h.Analytics.RecordChapterView(r.Context(), id, mangaID); err != nil {
+		if err = h.Analytics.RecordChapterView(r.Context(), id, mangaID, viewerID(h.Analytics, r)); err != nil {
 			h.Logger.Error("Ошибка записи просмотра главы", "err", err, "chapter_id", id)
 		}
 
@@ -173,14 +384,25 @@ func (h *ChapterHandler) GetById(w http.ResponseWriter, r *http.Request) error {
 		if err == nil {
 			views, _ = strconv.ParseInt(viewsStr, 10, 64)
 		}
+		if uniqueViewers, err = h.Analytics.GetChapterUniqueViewers(r.Context(), id); err != nil {
+			h.Logger.Error("Ошибка получения числа посетителей главы", "err", err, "chapter_id", id)
+		}
 	}
 
 	chapterWithViews := analytics.ChapterWithViews{
-		ID:      ch.ID,
-		MangaID: ch.MangaID,
-		Number:  ch.Number,
-		Title:   ch.Title,
-		Views:   views,
+		ID:            ch.ID,
+		MangaID:       ch.MangaID,
+		Number:        ch.Number,
+		Title:         ch.Title,
+		Language:      ch.Language,
+		GroupID:       ch.GroupID,
+		Status:        ch.Status,
+		PublishAt:     ch.PublishAt,
+		PublishedAt:   ch.PublishedAt,
+		CreatedAt:     ch.CreatedAt,
+		UpdatedAt:     ch.UpdatedAt,
+		Views:         views,
+		UniqueViewers: uniqueViewers,
 	}
 
 	response.Success(w, http.StatusOK, chapterWithViews)
@@ -204,12 +426,11 @@ func (h *ChapterHandler) ListByManga(w http.ResponseWriter, r *http.Request) err
 		if err == nil && cachedData != "" {
 			h.Logger.Info("Cache hit for chapters list", "manga_id", mangaID)
 
-			var chapters []models.Chapter
+			var chapters []*models.Chapter
 			if err = json.Unmarshal([]byte(cachedData), &chapters); err != nil {
-				h.Logger.Error("")
+				h.Logger.Error("Ошибка десериализации списка глав из кеша", "err", err)
 			} else {
-				response.Success(w, http.StatusOK, chapters)
-				return nil
+				return h.writeChapterList(w, r, chapters)
 			}
 		}
 	}
@@ -228,6 +449,109 @@ func (h *ChapterHandler) ListByManga(w http.ResponseWriter, r *http.Request) err
 		}
 	}
 
+	return h.writeChapterList(w, r, chapters)
+}
+
+// writeChapterList отдает список глав манги с учетом прав и фильтров
+// запроса. Фильтры применяются после кеша, чтобы для манги хранилась
+// одна запись кеша.
+func (h *ChapterHandler) writeChapterList(w http.ResponseWriter, r *http.Request, chapters []*models.Chapter) error {
+	chapters, err := h.filterTranslations(r, visibleChapters(r, chapters))
+	if err != nil {
+		return err
+	}
+	if chapters == nil {
+		chapters = []*models.Chapter{}
+	}
+	response.Success(w, http.StatusOK, chapters)
+	return nil
+}
+
+// filterTranslations применяет параметры group и lang списка глав.
+// lang=all отключает фильтр по языку. Без параметра lang используется
+// предпочитаемый язык пользователя: для номеров глав, у которых есть
+// перевод на этот язык, остается только он, остальные главы выводятся
+// на всех доступных языках.
+func (h *ChapterHandler) filterTranslations(r *http.Request, chapters []*models.Chapter) ([]*models.Chapter, error) {
+	query := r.URL.Query()
+	if groupStr := query.Get("group"); groupStr != "" {
+		groupID, err := strconv.ParseInt(groupStr, 10, 64)
+		if err != nil {
+			return nil, apperror.NewBadRequestError("Некорректный ID команды", err)
+		}
+		chapters = filterChapters(chapters, func(ch *models.Chapter) bool {
+			return ch.GroupID != nil && *ch.GroupID == groupID
+		})
+	}
+
+	lang := strings.ToLower(query.Get("lang"))
+	switch {
+	case lang == "all":
+		return chapters, nil
+	case lang != "":
+		if !models.ValidLanguage(lang) {
+			return nil, apperror.NewValidationError("Некорректный код языка",
+				map[string]string{"lang": "Ожидается код ISO 639 или all"})
+		}
+		return filterChapters(chapters, func(ch *models.Chapter) bool { return ch.Language == lang }), nil
+	}
+
+	preferred := h.preferredLanguage(r)
+	if preferred == "" {
+		return chapters, nil
+	}
+	translated := make(map[int]bool)
+	for _, ch := range chapters {
+		if ch.Language == preferred {
+			translated[ch.Number] = true
+		}
+	}
+	return filterChapters(chapters, func(ch *models.Chapter) bool {
+		return ch.Language == preferred || !translated[ch.Number]
+	}), nil
+}
+
+// preferredLanguage возвращает предпочитаемый язык аутентифицированного
+// пользователя или пустую строку.
+func (h *ChapterHandler) preferredLanguage(r *http.Request) string {
+	userID, ok := auth.UserIDFrom(r.Context())
+	if !ok || h.Users == nil {
+		return ""
+	}
+	user, err := h.Users.GetByID(userID)
+	if err != nil {
+		h.Logger.Error("Ошибка получения языка пользователя", "user_id", userID, "err", err)
+		return ""
+	}
+	return user.PreferredLanguage
+}
+
+func filterChapters(chapters []*models.Chapter, keep func(*models.Chapter) bool) []*models.Chapter {
+	filtered := make([]*models.Chapter, 0, len(chapters))
+	for _, ch := range chapters {
+		if keep(ch) {
+			filtered = append(filtered, ch)
+		}
+	}
+	return filtered
+}
+
+// ListLatest возвращает недавно добавленные главы всего каталога:
+// GET /chapters/latest?limit=&offset=.
+func (h *ChapterHandler) ListLatest(w http.ResponseWriter, r *http.Request) error {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+
+	chapters, err := h.Repo.ListLatest(limit, offset)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения последних глав", err)
+	}
+	if chapters == nil {
+		chapters = []*models.Chapter{}
+	}
+
 	response.Success(w, http.StatusOK, chapters)
 	return nil
 }
@@ -2,26 +2,50 @@ package handlers
 
 import (
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/middleware"
+	"manga-reader/models"
 	"net/http"
+	"strings"
 )
 
 func RegisterChapterRoutes(mux *http.ServeMux, ch *ChapterHandler) {
-	mux.HandleFunc("/chapter", middleware.ErrorHandler(ch.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/chapter", auth.RequireScope(models.RoleUploader, models.ScopeWriteChapters, middleware.ErrorHandler(ch.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodPost {
 			return ch.Create(w, r)
 		} else {
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
-	}))
+	})))
+
+	mux.Handle("/chapters/latest", auth.OptionalAuth(middleware.ErrorHandler(ch.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodGet {
+			return ch.ListLatest(w, r)
+		} else {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+
+	updateChapter := auth.RequireScope(models.RoleUploader, models.ScopeWriteChapters, middleware.ErrorHandler(ch.Logger, ch.Update))
+	deleteChapter := auth.RequireScope(models.RoleModerator, models.ScopeDeleteContent, middleware.ErrorHandler(ch.Logger, ch.Delete))
+	getChapter := auth.OptionalAuth(middleware.ErrorHandler(ch.Logger, ch.GetById))
+	getReader := auth.OptionalAuth(middleware.ErrorHandler(ch.Logger, ch.Reader))
+
 	mux.HandleFunc("/chapter/", middleware.ErrorHandler(ch.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		switch r.Method {
 		case http.MethodGet:
-			return ch.GetById(w, r)
+			if strings.HasSuffix(r.URL.Path, "/reader") {
+				getReader.ServeHTTP(w, r)
+				return nil
+			}
+			getChapter.ServeHTTP(w, r)
+			return nil
 		case http.MethodPut:
-			return ch.Update(w, r)
+			updateChapter.ServeHTTP(w, r)
+			return nil
 		case http.MethodDelete:
-			return ch.Delete(w, r)
+			deleteChapter.ServeHTTP(w, r)
+			return nil
 		default:
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/feed"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feedSize — число последних глав в ленте.
+const feedSize = 50
+
+// FeedHandler отдает ленты новых глав в форматах Atom и RSS.
+type FeedHandler struct {
+	Chapters  db.ChapterRepository
+	MangaRepo db.MangaRepository
+	// PublicURL — внешний адрес сервиса для абсолютных ссылок в лентах.
+	PublicURL string
+	Logger    *slog.Logger
+}
+
+func (h *FeedHandler) url(path string) string {
+	return strings.TrimRight(h.PublicURL, "/") + path
+}
+
+// SiteFeed отдает ленту новых глав всего каталога: /feed.atom или /feed.rss.
+func (h *FeedHandler) SiteFeed(w http.ResponseWriter, r *http.Request) error {
+	chapters, err := h.Chapters.ListPublished(0, feedSize)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения новых глав", err)
+	}
+
+	titles := map[int64]string{}
+	f := &feed.Feed{
+		ID:    h.url("/feed.atom"),
+		Title: "Новые главы",
+		Link:  h.url("/manga"),
+		Self:  h.url(r.URL.Path),
+	}
+	for _, ch := range chapters {
+		title, ok := titles[ch.MangaID]
+		if !ok {
+			if m, err := h.MangaRepo.GetByID(ch.MangaID); err == nil {
+				title = m.Title
+			}
+			titles[ch.MangaID] = title
+		}
+		f.Items = append(f.Items, h.chapterItem(title, ch))
+	}
+	return h.serve(w, r, f)
+}
+
+// MangaFeed отдает ленту новых глав манги: /manga/{id}/feed.atom или /manga/{id}/feed.rss.
+func (h *FeedHandler) MangaFeed(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 4 {
+		return apperror.NewBadRequestError("Некорректный URL", nil)
+	}
+	mangaID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID манги", err)
+	}
+
+	manga, err := h.MangaRepo.GetByID(mangaID)
+	if err != nil {
+		return apperror.NewNotFoundError("Манга не найдена", err)
+	}
+	chapters, err := h.Chapters.ListPublished(mangaID, feedSize)
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения новых глав", err)
+	}
+
+	f := &feed.Feed{
+		ID:    h.url(fmt.Sprintf("/manga/%d/feed.atom", mangaID)),
+		Title: manga.Title + " — новые главы",
+		Link:  h.url(fmt.Sprintf("/manga/%d", mangaID)),
+		Self:  h.url(r.URL.Path),
+	}
+	for _, ch := range chapters {
+		f.Items = append(f.Items, h.chapterItem(manga.Title, ch))
+	}
+	return h.serve(w, r, f)
+}
+
+func (h *FeedHandler) chapterItem(mangaTitle string, ch *models.Chapter) feed.Item {
+	link := h.url(fmt.Sprintf("/chapter/%d", ch.ID))
+	title := chapterTitle(ch)
+	item := feed.Item{ID: link, Title: title, Link: link, Summary: title, Published: *ch.PublishedAt}
+	if mangaTitle != "" {
+		item.Title = mangaTitle + " — " + title
+	}
+	return item
+}
+
+// serve выбирает формат по расширению пути и отвечает 304, если лента
+// не изменилась с прошлого запроса клиента.
+func (h *FeedHandler) serve(w http.ResponseWriter, r *http.Request, f *feed.Feed) error {
+	for _, item := range f.Items {
+		if item.Published.After(f.Updated) {
+			f.Updated = item.Published
+		}
+	}
+
+	var (
+		body        []byte
+		err         error
+		contentType string
+	)
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".atom"):
+		body, err = feed.Atom(f)
+		contentType = feed.AtomContentType
+	case strings.HasSuffix(r.URL.Path, ".rss"):
+		body, err = feed.RSS(f)
+		contentType = feed.RSSContentType
+	default:
+		return apperror.NewNotFoundError("Неизвестный формат ленты", nil)
+	}
+	if err != nil {
+		return apperror.NewInternalServerError("Ошибка формирования ленты", err)
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if notModified(w, r, etag, f.Updated) {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		if _, err = w.Write(body); err != nil {
+			h.Logger.Error("Ошибка отправки ленты", "err", err)
+		}
+	}
+	return nil
+}
+
+// notModified выставляет ETag и Last-Modified и, если версия клиента
+// актуальна, отвечает 304. If-None-Match имеет приоритет над
+// If-Modified-Since (RFC 9110, раздел 13.2.2).
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				w.WriteHeader(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/middleware"
+	"net/http"
+)
+
+func RegisterFeedRoutes(mux *http.ServeMux, fh *FeedHandler) {
+	siteFeed := middleware.ErrorHandler(fh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return fh.SiteFeed(w, r)
+	})
+	mux.HandleFunc("/feed.atom", siteFeed)
+	mux.HandleFunc("/feed.rss", siteFeed)
+}
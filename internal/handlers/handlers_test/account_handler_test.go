@@ -0,0 +1,301 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/internal/mail"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newAccountTestHandler(t *testing.T) (*handlers.UserHandler, *mail.LogSender) {
+	userRepo := setupTestUserRepo(t)
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mailer := mail.NewLogSender(nil, "")
+	return &handlers.UserHandler{
+		UserRepo:  userRepo,
+		Logger:    testLogger,
+		Mailer:    mailer,
+		PublicURL: "http://example.test",
+	}, mailer
+}
+
+func registerTestUser(t *testing.T, h *handlers.UserHandler, body string) models.User {
+	resp := httptest.NewRecorder()
+	if err := h.Register(resp, httptest.NewRequest(http.MethodPost, "/user/register", bytes.NewBufferString(body))); err != nil {
+		t.Fatalf("Неожиданная ошибка при регистрации: %v", err)
+	}
+	var user models.User
+	if err := helper.ExtractData(resp.Body, &user); err != nil {
+		t.Fatalf("Ошибка парсинга ответа регистрации: %v", err)
+	}
+	return user
+}
+
+func login(h *handlers.UserHandler, username, password string) error {
+	body := `{"username": "` + username + `", "password": "` + password + `"}`
+	return h.Login(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewBufferString(body)))
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	h, _ := newAccountTestHandler(t)
+	user := registerTestUser(t, h, `{"username": "alice", "password": "secret123"}`)
+
+	wrong := asUser(httptest.NewRequest(http.MethodPost, "/user/password",
+		bytes.NewBufferString(`{"current_password": "bad", "new_password": "newsecret"}`)), user.ID, models.RoleReader)
+	if err := h.ChangePassword(httptest.NewRecorder(), wrong); err == nil {
+		t.Error("Ожидалась ошибка при неверном текущем пароле")
+	}
+
+	req := asUser(httptest.NewRequest(http.MethodPost, "/user/password",
+		bytes.NewBufferString(`{"current_password": "secret123", "new_password": "newsecret"}`)), user.ID, models.RoleReader)
+	resp := httptest.NewRecorder()
+	if err := h.ChangePassword(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при смене пароля: %v", err)
+	}
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, resp.Code)
+	}
+
+	if err := login(h, "alice", "secret123"); err == nil {
+		t.Error("Старый пароль не должен подходить")
+	}
+	if err := login(h, "alice", "newsecret"); err != nil {
+		t.Errorf("Новый пароль должен подходить: %v", err)
+	}
+}
+
+func TestUserHandler_ChangePasswordReturnsLiveTokens(t *testing.T) {
+	h, _ := newAccountTestHandler(t)
+	user := registerTestUser(t, h, `{"username": "alice", "password": "secret123"}`)
+	mux := http.NewServeMux()
+	handlers.RegisterUserRoutes(mux, h)
+
+	before, err := auth.IssueTokens(context.Background(), user.ID, user.Role)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токенов: %v", err)
+	}
+
+	resp := httptest.NewRecorder()
+	change := httptest.NewRequest(http.MethodPost, "/user/password",
+		bytes.NewBufferString(`{"current_password": "secret123", "new_password": "newsecret"}`))
+	change.Header.Set("Authorization", "Bearer "+before.AccessToken)
+	mux.ServeHTTP(resp, change)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d: %s", http.StatusOK, resp.Code, resp.Body)
+	}
+	var tokens auth.TokenPair
+	if err = helper.ExtractData(resp.Body, &tokens); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+
+	// Токены из ответа выпущены сразу после отзыва и обязаны действовать.
+	for token, want := range map[string]int{tokens.AccessToken: http.StatusOK, before.AccessToken: http.StatusUnauthorized} {
+		me := httptest.NewRequest(http.MethodGet, "/user/me", nil)
+		me.Header.Set("Authorization", "Bearer "+token)
+		resp = httptest.NewRecorder()
+		mux.ServeHTTP(resp, me)
+		if resp.Code != want {
+			t.Errorf("Ожидался статус %d для /user/me, получен %d", want, resp.Code)
+		}
+	}
+
+	resp = httptest.NewRecorder()
+	mux.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/user/refresh",
+		bytes.NewBufferString(`{"refresh_token": "`+tokens.RefreshToken+`"}`)))
+	if resp.Code != http.StatusOK {
+		t.Errorf("Токен обновления из ответа должен действовать, получен статус %d", resp.Code)
+	}
+}
+
+func TestUserHandler_PasswordReset(t *testing.T) {
+	h, mailer := newAccountTestHandler(t)
+	registerTestUser(t, h, `{"username": "bob", "email": "bob@example.com", "password": "secret123"}`)
+
+	forgot := httptest.NewRequest(http.MethodPost, "/user/password/forgot", bytes.NewBufferString(`{"email": "bob@example.com"}`))
+	if err := h.ForgotPassword(httptest.NewRecorder(), forgot); err != nil {
+		t.Fatalf("Неожиданная ошибка при запросе сброса: %v", err)
+	}
+
+	unknown := httptest.NewRequest(http.MethodPost, "/user/password/forgot", bytes.NewBufferString(`{"email": "nobody@example.com"}`))
+	unknownResp := httptest.NewRecorder()
+	if err := h.ForgotPassword(unknownResp, unknown); err != nil || unknownResp.Code != http.StatusOK {
+		t.Errorf("Для неизвестного email ожидался успешный ответ, получено %d (err=%v)", unknownResp.Code, err)
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("Ожидалось 1 письмо, отправлено %d", len(sent))
+	}
+	if sent[0].To != "bob@example.com" {
+		t.Errorf("Письмо отправлено на %q", sent[0].To)
+	}
+	match := regexp.MustCompile(`http://example\.test(/\S+token=([0-9a-f]+))`).FindStringSubmatch(sent[0].Body)
+	if match == nil {
+		t.Fatalf("Ссылка с токеном не найдена в письме: %s", sent[0].Body)
+	}
+	link, token := match[1], match[2]
+
+	// Ссылка из письма открывается в браузере GET-запросом.
+	mux := http.NewServeMux()
+	handlers.RegisterUserRoutes(mux, h)
+	page := httptest.NewRecorder()
+	mux.ServeHTTP(page, httptest.NewRequest(http.MethodGet, link, nil))
+	if page.Code != http.StatusOK || !strings.HasPrefix(page.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Ожидалась страница сброса пароля, получено %d %q", page.Code, page.Header().Get("Content-Type"))
+	}
+
+	resetBody := `{"token": "` + token + `", "new_password": "resetpass"}`
+	if err := h.ResetPassword(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/password/reset", bytes.NewBufferString(resetBody))); err != nil {
+		t.Fatalf("Неожиданная ошибка при сбросе пароля: %v", err)
+	}
+	if err := h.ResetPassword(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/password/reset", bytes.NewBufferString(resetBody))); err == nil {
+		t.Error("Токен сброса должен быть одноразовым")
+	}
+	if err := login(h, "bob", "resetpass"); err != nil {
+		t.Errorf("Новый пароль должен подходить: %v", err)
+	}
+}
+
+func TestUserHandler_ForgotPasswordWithoutMailer(t *testing.T) {
+	h, _ := newAccountTestHandler(t)
+	h.Mailer = nil
+	registerTestUser(t, h, `{"username": "bob", "email": "bob@example.com", "password": "secret123"}`)
+
+	forgot := httptest.NewRequest(http.MethodPost, "/user/password/forgot", bytes.NewBufferString(`{"email": "bob@example.com"}`))
+	err := h.ForgotPassword(httptest.NewRecorder(), forgot)
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Без почты ожидалась ошибка 503, получено %v", err)
+	}
+}
+
+func TestUserHandler_UpdateAndDeleteAccount(t *testing.T) {
+	h, _ := newAccountTestHandler(t)
+	carol := registerTestUser(t, h, `{"username": "carol", "password": "secret123"}`)
+	registerTestUser(t, h, `{"username": "dave", "password": "secret123"}`)
+
+	taken := asUser(httptest.NewRequest(http.MethodPut, "/user/me", bytes.NewBufferString(`{"username": "dave"}`)), carol.ID, models.RoleReader)
+	if err := h.UpdateAccount(httptest.NewRecorder(), taken); err == nil {
+		t.Error("Ожидалась ошибка при занятом имени пользователя")
+	}
+
+	rename := asUser(httptest.NewRequest(http.MethodPut, "/user/me", bytes.NewBufferString(`{"username": "caroline"}`)), carol.ID, models.RoleReader)
+	if err := h.UpdateAccount(httptest.NewRecorder(), rename); err != nil {
+		t.Fatalf("Неожиданная ошибка при смене имени: %v", err)
+	}
+
+	meResp := httptest.NewRecorder()
+	if err := h.Me(meResp, asUser(httptest.NewRequest(http.MethodGet, "/user/me", nil), carol.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении профиля: %v", err)
+	}
+	var me models.User
+	if err := helper.ExtractData(meResp.Body, &me); err != nil {
+		t.Fatalf("Ошибка парсинга профиля: %v", err)
+	}
+	if me.Username != "caroline" {
+		t.Errorf("Ожидалось имя %q, получено %q", "caroline", me.Username)
+	}
+
+	del := asUser(httptest.NewRequest(http.MethodDelete, "/user/me", bytes.NewBufferString(`{"password": "secret123"}`)), carol.ID, models.RoleReader)
+	if err := h.DeleteAccount(httptest.NewRecorder(), del); err != nil {
+		t.Fatalf("Неожиданная ошибка при удалении учетной записи: %v", err)
+	}
+	if err := login(h, "caroline", "secret123"); err == nil {
+		t.Error("Удаленный пользователь не должен входить")
+	}
+}
+
+func TestUserHandler_DeleteAccountRemovesData(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	userRepo := sqlite.NewSQLiteUserRepository(conn, logger)
+	keyRepo := sqlite.NewAPIKeyRepository(conn, logger)
+	followRepo := sqlite.NewFollowRepository(conn, logger)
+	notificationRepo := sqlite.NewNotificationRepository(conn, logger)
+	groupRepo := sqlite.NewScanlationGroupRepository(conn, logger)
+	auth.SetJWTSecret("test-secret")
+	h := &handlers.UserHandler{UserRepo: userRepo, Logger: logger}
+
+	user := registerTestUser(t, h, `{"username": "erin", "password": "secret123"}`)
+	mangaID, _ := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	groupID, _ := groupRepo.Create(&models.ScanlationGroup{Name: "Команда"})
+	_, err := keyRepo.Create(&models.APIKey{UserID: user.ID, Name: "bot", KeyHash: "hash"})
+	for _, err := range []error{
+		err,
+		userRepo.LinkIdentity(user.ID, "https://idp.example", "erin"),
+		followRepo.Follow(user.ID, mangaID),
+		notificationRepo.SetPreference(user.ID, models.EventNewChapter, false),
+		groupRepo.AddMember(groupID, user.ID),
+	} {
+		if err != nil {
+			t.Fatalf("Ошибка подготовки данных: %v", err)
+		}
+	}
+
+	del := asUser(httptest.NewRequest(http.MethodDelete, "/user/me", bytes.NewBufferString(`{"password": "secret123"}`)), user.ID, models.RoleReader)
+	if err = h.DeleteAccount(httptest.NewRecorder(), del); err != nil {
+		t.Fatalf("Неожиданная ошибка при удалении учетной записи: %v", err)
+	}
+
+	for _, table := range []string{"user_identities", "api_keys", "manga_follows", "notification_preferences", "scanlation_group_members"} {
+		var count int
+		if err = conn.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", user.ID).Scan(&count); err != nil {
+			t.Fatalf("Ошибка подсчета строк %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("В таблице %s остались строки удаленного пользователя: %d", table, count)
+		}
+	}
+}
+
+func TestUserHandler_LoginLockout(t *testing.T) {
+	h, _ := newAccountTestHandler(t)
+	h.Limiter = auth.NewLoginLimiter(cache.NewMemoryCache())
+	h.Limiter.UserThreshold = 3
+	registerTestUser(t, h, `{"username": "alice", "password": "secret123"}`)
+
+	for i := 0; i < 3; i++ {
+		if err := login(h, "alice", "wrong"); err == nil {
+			t.Fatal("Ожидалась ошибка при неверном пароле")
+		}
+	}
+
+	err := login(h, "alice", "secret123")
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Ожидалась ошибка 429, получено %v", err)
+	}
+	if appErr.RetryAfter <= 0 {
+		t.Error("Ожидалось значение Retry-After")
+	}
+
+	rr := httptest.NewRecorder()
+	response.Error(rr, slog.New(slog.NewTextHandler(io.Discard, nil)), err)
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Ответ 429 должен содержать заголовок Retry-After")
+	}
+
+	unlock := httptest.NewRequest(http.MethodPost, "/user/unlock", bytes.NewBufferString(`{"username": "alice"}`))
+	if err = h.UnlockAccount(httptest.NewRecorder(), unlock); err != nil {
+		t.Fatalf("Неожиданная ошибка при снятии блокировки: %v", err)
+	}
+	if err = login(h, "alice", "secret123"); err != nil {
+		t.Errorf("После снятия блокировки вход должен работать: %v", err)
+	}
+}
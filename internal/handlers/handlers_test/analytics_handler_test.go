@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/analytics"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupViewHistory(t *testing.T) (*handlers.AnalyticsHandler, int64) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	mangaID, err := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	if err != nil {
+		t.Fatalf("Ошибка создания манги: %v", err)
+	}
+	history := sqlite.NewViewHistoryRepository(conn, logger)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	err = history.Save([]*models.DailyViews{
+		{EntityType: models.ViewManga, EntityID: mangaID, Day: day(4), Views: 10, UniqueViewers: 4},
+		{EntityType: models.ViewManga, EntityID: mangaID, Day: day(6), Views: 5, UniqueViewers: 2},
+		{EntityType: models.ViewManga, EntityID: mangaID, Day: day(11), Views: 7, UniqueViewers: 3},
+		{EntityType: models.ViewManga, EntityID: mangaID + 1, Day: day(6), Views: 100, UniqueViewers: 50},
+		{EntityType: models.ViewChapter, EntityID: mangaID, Day: day(6), Views: 100, UniqueViewers: 50},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка сохранения истории: %v", err)
+	}
+
+	return &handlers.AnalyticsHandler{MangaRepo: mangaRepo, History: history, Logger: logger}, mangaID
+}
+
+func TestAnalyticsHandler_GetMangaViews(t *testing.T) {
+	h, mangaID := setupViewHistory(t)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/analytics/manga/%d/views?from=2024-03-04&to=2024-03-06", mangaID), nil)
+	resp := httptest.NewRecorder()
+	if err := h.GetMangaViews(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	var series analytics.ViewsSeries
+	if err := helper.ExtractData(resp.Body, &series); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	want := []analytics.ViewsPoint{
+		{Date: "2024-03-04", Views: 10, UniqueViewers: 4},
+		{Date: "2024-03-05"},
+		{Date: "2024-03-06", Views: 5, UniqueViewers: 2},
+	}
+	if series.Interval != analytics.IntervalDay || len(series.Points) != len(want) {
+		t.Fatalf("Неожиданный ряд: %+v", series)
+	}
+	for i := range want {
+		if series.Points[i] != want[i] {
+			t.Errorf("Ожидалось %v, получено %v", want, series.Points)
+			break
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/analytics/manga/%d/views?from=2024-03-01&to=2024-03-31&interval=week", mangaID), nil)
+	resp = httptest.NewRecorder()
+	if err := h.GetMangaViews(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := helper.ExtractData(resp.Body, &series); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if len(series.Points) != 5 || series.Points[1].Date != "2024-03-04" || series.Points[1].Views != 15 || series.Points[2].Views != 7 {
+		t.Errorf("Неожиданный ряд по неделям: %+v", series.Points)
+	}
+}
+
+func TestAnalyticsHandler_GetMangaViewsInvalid(t *testing.T) {
+	h, mangaID := setupViewHistory(t)
+
+	for _, query := range []string{"?interval=hour", "?from=03.03.2024", "?from=2024-03-10&to=2024-03-01", "?from=2020-01-01&to=2024-01-01"} {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/analytics/manga/%d/views%s", mangaID, query), nil)
+		if err := h.GetMangaViews(httptest.NewRecorder(), req); err == nil {
+			t.Errorf("Ожидалась ошибка для %s", query)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/analytics/manga/%d/views", mangaID+100), nil)
+	if err := h.GetMangaViews(httptest.NewRecorder(), req); err == nil {
+		t.Error("Ожидалась ошибка для несуществующей манги")
+	}
+}
+
+func TestAnalyticsHandler_ChapterFunnel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	chapterRepo := sqlite.NewChapterRepository(conn, logger)
+	pageRepo := sqlite.NewPageRepository(conn, logger)
+	mangaID, err := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	if err != nil {
+		t.Fatalf("Ошибка создания манги: %v", err)
+	}
+	chapterID, err := chapterRepo.Create(&models.Chapter{MangaID: mangaID, Number: 1, Language: "ru", Status: models.ChapterDraft})
+	if err != nil {
+		t.Fatalf("Ошибка создания главы: %v", err)
+	}
+	var pageIDs []int64
+	for n := 1; n <= 2; n++ {
+		id, err := pageRepo.Create(&models.Page{ChapterID: chapterID, Number: n, ImagePath: fmt.Sprintf("%d.jpg", n)})
+		if err != nil {
+			t.Fatalf("Ошибка создания страницы: %v", err)
+		}
+		pageIDs = append(pageIDs, id)
+	}
+
+	service := analytics.NewAnalyticsService(cache.NewMemoryCache(), logger)
+	for _, viewer := range []string{"a", "b"} {
+		if err = service.RecordPageView(context.Background(), pageIDs[0], chapterID, mangaID, viewer); err != nil {
+			t.Fatalf("Ошибка записи просмотра: %v", err)
+		}
+	}
+	if err = service.RecordPageView(context.Background(), pageIDs[1], chapterID, mangaID, "a"); err != nil {
+		t.Fatalf("Ошибка записи просмотра: %v", err)
+	}
+	h := &handlers.AnalyticsHandler{MangaRepo: mangaRepo, Chapters: chapterRepo, Pages: pageRepo, Analytics: service, Logger: logger}
+
+	path := fmt.Sprintf("/analytics/chapter/%d/funnel", chapterID)
+	if err = h.GetChapterFunnel(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)); err == nil {
+		t.Error("Воронка черновика не должна быть доступна читателям")
+	}
+
+	resp := httptest.NewRecorder()
+	if err = h.GetChapterFunnel(resp, asUser(httptest.NewRequest(http.MethodGet, path, nil), 1, models.RoleUploader)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	var funnel analytics.ChapterFunnel
+	if err = helper.ExtractData(resp.Body, &funnel); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if funnel.FirstPageViewers != 2 || funnel.CompletionRate != 0.5 || len(funnel.Pages) != 2 || funnel.Pages[1].DropOff != 0.5 {
+		t.Errorf("Неожиданная воронка: %+v", funnel)
+	}
+
+	path = fmt.Sprintf("/analytics/manga/%d/retention", mangaID)
+	resp = httptest.NewRecorder()
+	if err = h.GetMangaRetention(resp, httptest.NewRequest(http.MethodGet, path, nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	var retention analytics.MangaRetention
+	if err = helper.ExtractData(resp.Body, &retention); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if len(retention.Chapters) != 0 {
+		t.Errorf("Черновики не должны попадать в удержание для читателей: %+v", retention)
+	}
+	if err = h.GetMangaRetention(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path+"?lang=Русский", nil)); err == nil {
+		t.Error("Ожидалась ошибка для некорректного языка")
+	}
+}
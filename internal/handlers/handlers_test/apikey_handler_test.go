@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupAPIKeyHandler(t *testing.T) (*handlers.APIKeyHandler, *handlers.UserHandler) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, _ := newTestDB(t, logger)
+	userRepo := sqlite.NewSQLiteUserRepository(conn, logger)
+	keyRepo := sqlite.NewAPIKeyRepository(conn, logger)
+	auth.SetJWTSecret("test-secret")
+	auth.SetAPIKeyStore(keyRepo)
+	t.Cleanup(func() { auth.SetAPIKeyStore(nil) })
+
+	return &handlers.APIKeyHandler{Repo: keyRepo, UserRepo: userRepo, Logger: logger},
+		&handlers.UserHandler{UserRepo: userRepo, Logger: logger}
+}
+
+func TestAPIKeyHandler_Lifecycle(t *testing.T) {
+	kh, uh := setupAPIKeyHandler(t)
+	user := registerTestUser(t, uh, `{"username": "bot", "password": "secret123"}`)
+	if err := uh.UserRepo.UpdateRole(user.ID, models.RoleUploader); err != nil {
+		t.Fatalf("Ошибка назначения роли: %v", err)
+	}
+
+	forbidden := asUser(httptest.NewRequest(http.MethodPost, "/user/apikeys",
+		bytes.NewBufferString(`{"name": "bot", "scopes": ["delete:content"]}`)), user.ID, models.RoleReader)
+	if err := kh.Create(httptest.NewRecorder(), forbidden); err == nil {
+		t.Error("Область выше роли владельца не должна выдаваться")
+	}
+
+	resp := httptest.NewRecorder()
+	create := asUser(httptest.NewRequest(http.MethodPost, "/user/apikeys",
+		bytes.NewBufferString(`{"name": "uploader bot", "scopes": ["upload:pages"]}`)), user.ID, models.RoleReader)
+	if err := kh.Create(resp, create); err != nil {
+		t.Fatalf("Неожиданная ошибка при создании ключа: %v", err)
+	}
+	var created handlers.CreateAPIKeyResponse
+	if err := helper.ExtractData(resp.Body, &created); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if created.Key == "" || created.APIKey == nil || created.ID == 0 {
+		t.Fatalf("Ожидался созданный ключ, получено %+v", created)
+	}
+
+	protected := auth.RequireScope(models.RoleUploader, models.ScopeUploadPages,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) }))
+	call := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/page/upload", nil)
+		req.Header.Set("X-API-Key", created.Key)
+		rr := httptest.NewRecorder()
+		protected.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := call(); code != http.StatusOK {
+		t.Fatalf("Ожидался доступ по ключу, статус %d", code)
+	}
+
+	resp = httptest.NewRecorder()
+	if err := kh.List(resp, asUser(httptest.NewRequest(http.MethodGet, "/user/apikeys", nil), user.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении списка: %v", err)
+	}
+	var keys []models.APIKey
+	if err := helper.ExtractData(resp.Body, &keys); err != nil {
+		t.Fatalf("Ошибка парсинга списка: %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Fatalf("Ожидался один ключ с временем использования, получено %+v", keys)
+	}
+
+	revokePath := fmt.Sprintf("/user/apikeys/%d", created.ID)
+	if err := kh.Revoke(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodDelete, revokePath, nil), user.ID+1, models.RoleReader)); err == nil {
+		t.Error("Чужой ключ не должен отзываться")
+	}
+	if err := kh.Revoke(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodDelete, revokePath, nil), user.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при отзыве ключа: %v", err)
+	}
+	if code := call(); code != http.StatusUnauthorized {
+		t.Errorf("Отозванный ключ должен отклоняться, статус %d", code)
+	}
+}
+
+func createAPIKey(t *testing.T, kh *handlers.APIKeyHandler, userID int64, body string) string {
+	resp := httptest.NewRecorder()
+	req := asUser(httptest.NewRequest(http.MethodPost, "/user/apikeys", bytes.NewBufferString(body)), userID, models.RoleReader)
+	if err := kh.Create(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при создании ключа: %v", err)
+	}
+	var created handlers.CreateAPIKeyResponse
+	if err := helper.ExtractData(resp.Body, &created); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return created.Key
+}
+
+func TestAnalyticsRoutes_RequireReadScope(t *testing.T) {
+	kh, uh := setupAPIKeyHandler(t)
+	user := registerTestUser(t, uh, `{"username": "stats", "password": "secret123"}`)
+	if err := uh.UserRepo.UpdateRole(user.ID, models.RoleUploader); err != nil {
+		t.Fatalf("Ошибка назначения роли: %v", err)
+	}
+	uploadKey := createAPIKey(t, kh, user.ID, `{"name": "uploader", "scopes": ["upload:pages"]}`)
+	readKey := createAPIKey(t, kh, user.ID, `{"name": "stats", "scopes": ["read:analytics"]}`)
+
+	ah, mangaID := setupViewHistory(t)
+	mux := http.NewServeMux()
+	handlers.RegisterAnalyticsRoutes(mux, ah)
+	call := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/analytics/manga/%d/views?from=2024-03-04&to=2024-03-06", mangaID), nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := call(""); code != http.StatusUnauthorized {
+		t.Errorf("Анонимный запрос должен отклоняться, статус %d", code)
+	}
+	if code := call(uploadKey); code != http.StatusForbidden {
+		t.Errorf("Ключ без области read:analytics должен отклоняться, статус %d", code)
+	}
+	if code := call(readKey); code != http.StatusOK {
+		t.Errorf("Ожидался доступ по ключу с областью read:analytics, статус %d", code)
+	}
+}
@@ -0,0 +1,244 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/audit"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type auditFixture struct {
+	ch      *handlers.ChapterHandler
+	uh      *handlers.UserHandler
+	ah      *handlers.AuditHandler
+	mangaID int64
+	userID  int64
+}
+
+func setupAudit(t *testing.T) *auditFixture {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	userRepo := sqlite.NewSQLiteUserRepository(conn, logger)
+	// Удаление главы переносит в корзину и ее страницы.
+	sqlite.NewPageRepository(conn, logger)
+	auditRepo := sqlite.NewAuditRepository(conn, logger)
+	recorder := audit.NewRecorder(auditRepo, logger)
+
+	mangaID, err := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	if err != nil {
+		t.Fatalf("Ошибка создания манги: %v", err)
+	}
+	userID, err := userRepo.Create(&models.User{Username: "reader", Password: "hash", Role: models.RoleReader})
+	if err != nil {
+		t.Fatalf("Ошибка создания пользователя: %v", err)
+	}
+
+	return &auditFixture{
+		ch: &handlers.ChapterHandler{
+			Repo:      sqlite.NewChapterRepository(conn, logger),
+			Logger:    logger,
+			MangaRepo: mangaRepo,
+			Audit:     recorder,
+		},
+		uh:      &handlers.UserHandler{UserRepo: userRepo, Logger: logger, Audit: recorder},
+		ah:      &handlers.AuditHandler{Repo: auditRepo, Logger: logger},
+		mangaID: mangaID,
+		userID:  userID,
+	}
+}
+
+// serve пропускает запрос через RequestIDMiddleware, как это делает сервер.
+func serve(t *testing.T, handler middleware.ErrorHandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	var handlerErr error
+	middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerErr = handler(w, r)
+	})).ServeHTTP(resp, req)
+	if handlerErr != nil {
+		t.Fatalf("Неожиданная ошибка: %v", handlerErr)
+	}
+	return resp
+}
+
+func (f *auditFixture) list(t *testing.T, query string) []models.AuditEntry {
+	resp := httptest.NewRecorder()
+	if err := f.ah.List(resp, httptest.NewRequest(http.MethodGet, "/audit"+query, nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении журнала: %v", err)
+	}
+	var entries []models.AuditEntry
+	if err := helper.ExtractData(resp.Body, &entries); err != nil {
+		t.Fatalf("Ошибка парсинга журнала: %v", err)
+	}
+	return entries
+}
+
+func TestAudit_ChapterChanges(t *testing.T) {
+	f := setupAudit(t)
+
+	body := fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Черновик", "status": "draft"}`, f.mangaID)
+	req := asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)), 7, models.RoleUploader)
+	req.Header.Set(middleware.RequestIDHeader, "req-create")
+	req.RemoteAddr = "203.0.113.5:41234"
+	resp := serve(t, f.ch.Create, req)
+	if got := resp.Header().Get(middleware.RequestIDHeader); got != "req-create" {
+		t.Errorf("Ожидался заголовок %s=req-create, получено %q", middleware.RequestIDHeader, got)
+	}
+	var chapter models.Chapter
+	if err := helper.ExtractData(resp.Body, &chapter); err != nil {
+		t.Fatalf("Ошибка парсинга главы: %v", err)
+	}
+
+	path := fmt.Sprintf("/chapter/%d", chapter.ID)
+	req = asUser(httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"number": 1, "title": "Глава 1", "status": "draft"}`)), 8, models.RoleModerator)
+	resp = serve(t, f.ch.Update, req)
+	generatedID := resp.Header().Get(middleware.RequestIDHeader)
+	if generatedID == "" {
+		t.Error("Идентификатор запроса должен генерироваться, если клиент его не передал")
+	}
+	serve(t, f.ch.Delete, asUser(httptest.NewRequest(http.MethodDelete, path, nil), 8, models.RoleModerator))
+
+	entries := f.list(t, fmt.Sprintf("?entity_type=chapter&entity_id=%d", chapter.ID))
+	if len(entries) != 3 {
+		t.Fatalf("Ожидалось 3 записи, получено %d", len(entries))
+	}
+	deleted, updated, created := entries[0], entries[1], entries[2]
+	if created.Action != models.AuditCreate || updated.Action != models.AuditUpdate || deleted.Action != models.AuditDelete {
+		t.Errorf("Неожиданный порядок действий: %s, %s, %s", deleted.Action, updated.Action, created.Action)
+	}
+	if created.ActorID != 7 || created.RequestID != "req-create" || created.IP != "203.0.113.5" {
+		t.Errorf("Неожиданные данные о запросе: actor=%d request_id=%q ip=%q", created.ActorID, created.RequestID, created.IP)
+	}
+	if change, ok := created.Changes["title"]; !ok || change.Before != nil || string(change.After) != `"Черновик"` {
+		t.Errorf("Неожиданное изменение title при создании: %+v", created.Changes["title"])
+	}
+
+	if updated.ActorID != 8 || updated.RequestID != generatedID {
+		t.Errorf("Неожиданные данные о запросе: actor=%d request_id=%q", updated.ActorID, updated.RequestID)
+	}
+	title := updated.Changes["title"]
+	if string(title.Before) != `"Черновик"` || string(title.After) != `"Глава 1"` {
+		t.Errorf("Неожиданное изменение title: %s -> %s", title.Before, title.After)
+	}
+	for _, field := range []string{"manga_id", "number", "status", "created_at"} {
+		if _, ok := updated.Changes[field]; ok {
+			t.Errorf("Неизменившееся поле %s не должно попадать в журнал", field)
+		}
+	}
+
+	if change, ok := deleted.Changes["title"]; !ok || string(change.Before) != `"Глава 1"` || change.After != nil {
+		t.Errorf("Неожиданное изменение title при удалении: %+v", deleted.Changes["title"])
+	}
+
+	if entries = f.list(t, "?entity_type=chapter&limit=1&offset=1"); len(entries) != 1 || entries[0].ID != updated.ID {
+		t.Errorf("Неожиданная страница журнала: %+v", entries)
+	}
+	if entries = f.list(t, "?actor_id=7"); len(entries) != 1 || entries[0].ID != created.ID {
+		t.Errorf("Неожиданный фильтр по пользователю: %+v", entries)
+	}
+}
+
+func TestAudit_UserRoleChange(t *testing.T) {
+	f := setupAudit(t)
+
+	body := fmt.Sprintf(`{"user_id": %d, "role": "uploader"}`, f.userID)
+	req := asUser(httptest.NewRequest(http.MethodPut, "/user/role", bytes.NewBufferString(body)), 1, models.RoleAdmin)
+	serve(t, f.uh.UpdateRole, req)
+
+	entries := f.list(t, "?entity_type=user&action=update")
+	if len(entries) != 1 {
+		t.Fatalf("Ожидалась 1 запись, получено %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.EntityID != f.userID || entry.ActorID != 1 {
+		t.Errorf("Неожиданная запись: %+v", entry)
+	}
+	want := map[string]models.AuditChange{"role": {Before: json.RawMessage(`"reader"`), After: json.RawMessage(`"uploader"`)}}
+	got, _ := json.Marshal(entry.Changes)
+	expected, _ := json.Marshal(want)
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Ожидались изменения %s, получено %s", expected, got)
+	}
+}
+
+func TestAudit_AccountSecurityEvents(t *testing.T) {
+	current := time.Unix(1_700_000_000, 0)
+	auth.SetClock(func() time.Time { return current })
+	defer auth.SetClock(nil)
+
+	auth.SetJWTSecret("test-secret")
+	f := setupAudit(t)
+	f.uh.Limiter = auth.NewLoginLimiter(cache.NewMemoryCache())
+	user := registerTestUser(t, f.uh, `{"username": "alice", "password": "secret123"}`)
+
+	serve(t, f.uh.ChangePassword, asUser(httptest.NewRequest(http.MethodPost, "/user/password",
+		bytes.NewBufferString(`{"current_password": "secret123", "new_password": "newsecret"}`)), user.ID, models.RoleReader))
+
+	token, err := auth.IssuePasswordResetToken(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("Ошибка выпуска токена сброса: %v", err)
+	}
+	serve(t, f.uh.ResetPassword, httptest.NewRequest(http.MethodPost, "/user/password/reset",
+		bytes.NewBufferString(`{"token": "`+token+`", "new_password": "resetpass"}`)))
+
+	resp := serve(t, f.uh.EnrollTwoFactor, asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/enroll", nil), user.ID, models.RoleReader))
+	var enroll handlers.EnrollTwoFactorResponse
+	if err = helper.ExtractData(resp.Body, &enroll); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	code, _ := auth.TOTPCode(enroll.Secret, current)
+	serve(t, f.uh.ConfirmTwoFactor, asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/confirm",
+		bytes.NewBufferString(`{"code": "`+code+`"}`)), user.ID, models.RoleReader))
+	serve(t, f.uh.DisableTwoFactor, asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/disable",
+		bytes.NewBufferString(`{"password": "resetpass"}`)), user.ID, models.RoleReader))
+
+	serve(t, f.uh.UnlockAccount, asUser(httptest.NewRequest(http.MethodPost, "/user/unlock",
+		bytes.NewBufferString(`{"username": "alice"}`)), 1, models.RoleAdmin))
+	serve(t, f.uh.UnlockAccount, asUser(httptest.NewRequest(http.MethodPost, "/user/unlock",
+		bytes.NewBufferString(`{"username": "nobody"}`)), 1, models.RoleAdmin))
+
+	entries := f.list(t, fmt.Sprintf("?entity_type=user&entity_id=%d&action=update", user.ID))
+	want := []struct {
+		actorID int64
+		changes string
+	}{
+		{1, `{"locked":{"before":true,"after":false}}`},
+		{user.ID, `{"two_factor_enabled":{"before":true,"after":false}}`},
+		{user.ID, `{"two_factor_enabled":{"before":false,"after":true}}`},
+		{0, `{"password":{"after":"changed"}}`},
+		{user.ID, `{"password":{"after":"changed"}}`},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Ожидалось %d записей, получено %d: %+v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		got, _ := json.Marshal(entries[i].Changes)
+		if entries[i].ActorID != w.actorID || string(got) != w.changes {
+			t.Errorf("Запись %d: ожидалось actor=%d %s, получено actor=%d %s", i, w.actorID, w.changes, entries[i].ActorID, got)
+		}
+	}
+}
+
+func TestAuditHandler_InvalidFilter(t *testing.T) {
+	f := setupAudit(t)
+	for _, query := range []string{"?action=rename", "?entity_type=volume", "?actor_id=-1", "?since=yesterday", "?limit=0"} {
+		err := f.ah.List(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/audit"+query, nil))
+		if err == nil {
+			t.Errorf("Ожидалась ошибка для %s", query)
+		}
+	}
+}
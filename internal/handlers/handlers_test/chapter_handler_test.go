@@ -10,9 +10,11 @@ import (
 	"manga-reader/models"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type MockChapterRepository struct {
@@ -33,6 +35,12 @@ func (m *MockChapterRepository) Create(ch *models.Chapter) (int64, error) {
 	defer m.mu.Unlock()
 	ch.ID = m.nextID
 	m.nextID++
+	if ch.Status == "" {
+		ch.Status = models.ChapterPublished
+	}
+	if ch.Language == "" {
+		ch.Language = models.DefaultLanguage
+	}
 	m.chapters[ch.ID] = ch
 	return ch.ID, nil
 }
@@ -79,12 +87,77 @@ func (m *MockChapterRepository) ListByManga(mangaID int64) ([]*models.Chapter, e
 	return chapters, nil
 }
 
+func (m *MockChapterRepository) ListPublished(mangaID int64, limit int) ([]*models.Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var chapters []*models.Chapter
+	for _, ch := range m.chapters {
+		if ch.PublishedAt != nil && (mangaID == 0 || ch.MangaID == mangaID) {
+			chapters = append(chapters, ch)
+		}
+	}
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].PublishedAt.After(*chapters[j].PublishedAt) })
+	if len(chapters) > limit {
+		chapters = chapters[:limit]
+	}
+	return chapters, nil
+}
+
+func (m *MockChapterRepository) ListLatest(limit, offset int) ([]*models.Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var chapters []*models.Chapter
+	for _, ch := range m.chapters {
+		if ch.Published() {
+			chapters = append(chapters, ch)
+		}
+	}
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].ID > chapters[j].ID })
+	if offset >= len(chapters) {
+		return nil, nil
+	}
+	chapters = chapters[offset:]
+	if len(chapters) > limit {
+		chapters = chapters[:limit]
+	}
+	return chapters, nil
+}
+
+func (m *MockChapterRepository) PublishDue(now time.Time) ([]*models.Chapter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []*models.Chapter
+	for _, ch := range m.chapters {
+		if ch.Status == models.ChapterScheduled && !ch.PublishAt.After(now) {
+			ch.Status = models.ChapterPublished
+			ch.PublishedAt = ch.PublishAt
+			due = append(due, ch)
+		}
+	}
+	return due, nil
+}
+
+func (m *MockChapterRepository) ListDeleted() ([]*models.Chapter, error) {
+	return nil, nil
+}
+
+func (m *MockChapterRepository) Restore(id int64) error {
+	return errors.New("chapter not found in trash")
+}
+
+func (m *MockChapterRepository) PurgeDeleted(before time.Time) (int64, error) {
+	return 0, nil
+}
+
 func TestChapterHandler_CreateAndGet(t *testing.T) {
 	mockRepo := NewMockChapterRepository()
+	mangaRepo := NewMockMangaRepository()
+	mangaRepo.Create(&models.Manga{Title: "Берсерк"})
 	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	chapterHandler := &handlers.ChapterHandler{
-		Repo:   mockRepo,
-		Logger: testLogger,
+		Repo:      mockRepo,
+		Logger:    testLogger,
+		MangaRepo: mangaRepo,
 	}
 
 	// Тест создания главы (POST /chapter)
@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/internal/scheduler"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func (f *notificationFixture) createChapterWith(t *testing.T, body string) (models.Chapter, error) {
+	resp := httptest.NewRecorder()
+	if err := f.ch.Create(resp, httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body))); err != nil {
+		return models.Chapter{}, err
+	}
+	var ch models.Chapter
+	if err := helper.ExtractData(resp.Body, &ch); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return ch, nil
+}
+
+func (f *notificationFixture) listChapters(t *testing.T, role models.Role) []*models.Chapter {
+	resp := httptest.NewRecorder()
+	req := asUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/manga/%d/chapters", f.mangaID), nil), 1, role)
+	if err := f.ch.ListByManga(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении списка глав: %v", err)
+	}
+	var chapters []*models.Chapter
+	if err := helper.ExtractData(resp.Body, &chapters); err != nil {
+		t.Fatalf("Ошибка парсинга списка глав: %v", err)
+	}
+	return chapters
+}
+
+func TestChapterPublishing_DraftVisibility(t *testing.T) {
+	f := setupNotificationHandler(t)
+	follower := registerTestUser(t, f.uh, `{"username": "guts", "password": "secret123"}`)
+	if err := f.nh.Follow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost,
+		fmt.Sprintf("/user/follows/%d", f.mangaID), nil), follower.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при подписке: %v", err)
+	}
+
+	draft, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Черновик", "status": "draft"}`, f.mangaID))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при создании черновика: %v", err)
+	}
+	if draft.Status != models.ChapterDraft || draft.PublishedAt != nil {
+		t.Errorf("Неожиданный черновик: %+v", draft)
+	}
+	if list := f.inbox(t, follower.ID, ""); len(list.Items) != 0 {
+		t.Error("Черновик не должен рассылать уведомления")
+	}
+
+	if chapters := f.listChapters(t, models.RoleReader); len(chapters) != 0 {
+		t.Errorf("Читатель не должен видеть черновики: %+v", chapters)
+	}
+	if chapters := f.listChapters(t, models.RoleUploader); len(chapters) != 1 {
+		t.Errorf("Загрузчик должен видеть черновик, получено %d глав", len(chapters))
+	}
+
+	path := fmt.Sprintf("/chapter/%d", draft.ID)
+	if err = f.ch.GetById(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)); err == nil {
+		t.Error("Черновик не должен отдаваться анонимному читателю")
+	}
+	if err = f.ch.GetById(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodGet, path, nil), 1, models.RoleModerator)); err != nil {
+		t.Errorf("Модератор должен видеть черновик: %v", err)
+	}
+
+	ph := &handlers.PageHandler{
+		Repo:     NewMockPageRepository(),
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Chapters: f.ch.Repo,
+	}
+	pagesPath := fmt.Sprintf("/pages/chapter/%d", draft.ID)
+	if err = ph.ListByChapter(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, pagesPath, nil)); err == nil {
+		t.Error("Страницы черновика не должны отдаваться читателю")
+	}
+	if err = ph.ListByChapter(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodGet, pagesPath, nil), 1, models.RoleUploader)); err != nil {
+		t.Errorf("Загрузчик должен видеть страницы черновика: %v", err)
+	}
+
+	update := asUser(httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"number": 1, "title": "Глава", "status": "published"}`)), 1,
+		models.RoleUploader)
+	if err = f.ch.Update(httptest.NewRecorder(), update); err != nil {
+		t.Fatalf("Неожиданная ошибка при публикации черновика: %v", err)
+	}
+	if chapters := f.listChapters(t, models.RoleReader); len(chapters) != 1 || chapters[0].PublishedAt == nil {
+		t.Errorf("Опубликованная глава должна быть видна читателю: %+v", chapters)
+	}
+	if list := f.inbox(t, follower.ID, ""); len(list.Items) != 1 {
+		t.Errorf("Публикация должна разослать уведомление, получено %d", len(list.Items))
+	}
+}
+
+func TestChapterPublishing_Scheduled(t *testing.T) {
+	f := setupNotificationHandler(t)
+	follower := registerTestUser(t, f.uh, `{"username": "guts", "password": "secret123"}`)
+	if err := f.nh.Follow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost,
+		fmt.Sprintf("/user/follows/%d", f.mangaID), nil), follower.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при подписке: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if _, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава", "status": "scheduled", "publish_at": %q}`,
+		f.mangaID, past)); err == nil {
+		t.Error("Время публикации в прошлом должно отклоняться")
+	}
+	if _, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава", "status": "hidden"}`, f.mangaID)); err == nil {
+		t.Error("Неизвестное состояние должно отклоняться")
+	}
+
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава", "status": "scheduled", "publish_at": %q}`,
+		f.mangaID, publishAt.Format(time.RFC3339)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при планировании главы: %v", err)
+	}
+
+	publisher := scheduler.NewChapterPublisher(f.ch.Repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	publisher.OnPublish = f.ch.Published
+	if n, err := publisher.RunOnce(time.Now()); err != nil || n != 0 {
+		t.Fatalf("Глава не должна публиковаться раньше срока: %d, %v", n, err)
+	}
+	if chapters := f.listChapters(t, models.RoleReader); len(chapters) != 0 {
+		t.Errorf("Запланированная глава не должна быть видна читателю: %+v", chapters)
+	}
+
+	if n, err := publisher.RunOnce(publishAt.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("Ожидалась публикация одной главы: %d, %v", n, err)
+	}
+	chapters := f.listChapters(t, models.RoleReader)
+	if len(chapters) != 1 || chapters[0].ID != scheduled.ID || chapters[0].PublishedAt == nil ||
+		!chapters[0].PublishedAt.Equal(publishAt) {
+		t.Errorf("Глава должна быть опубликована с запланированным временем: %+v", chapters)
+	}
+	if list := f.inbox(t, follower.ID, ""); len(list.Items) != 1 {
+		t.Errorf("Публикация по расписанию должна разослать уведомление, получено %d", len(list.Items))
+	}
+	if n, _ := publisher.RunOnce(publishAt.Add(time.Hour)); n != 0 {
+		t.Error("Глава не должна публиковаться повторно")
+	}
+}
+
+func TestChapterPublishing_ScheduledLeadsLatest(t *testing.T) {
+	f := setupNotificationHandler(t)
+
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава", "status": "scheduled", "publish_at": %q}`,
+		f.mangaID, publishAt.Format(time.RFC3339)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при планировании главы: %v", err)
+	}
+	published, err := f.createChapterWith(t, fmt.Sprintf(`{"manga_id": %d, "number": 2, "title": "Глава"}`, f.mangaID))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при создании главы: %v", err)
+	}
+
+	publisher := scheduler.NewChapterPublisher(f.ch.Repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if n, err := publisher.RunOnce(publishAt.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("Ожидалась публикация одной главы: %d, %v", n, err)
+	}
+
+	resp := httptest.NewRecorder()
+	if err = f.ch.ListLatest(resp, httptest.NewRequest(http.MethodGet, "/chapters/latest", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении последних глав: %v", err)
+	}
+	var chapters []*models.Chapter
+	if err = helper.ExtractData(resp.Body, &chapters); err != nil {
+		t.Fatalf("Ошибка парсинга списка глав: %v", err)
+	}
+	if len(chapters) != 2 || chapters[0].ID != scheduled.ID || chapters[1].ID != published.ID {
+		t.Errorf("Глава, опубликованная по расписанию позже, должна быть первой: %+v", chapters)
+	}
+}
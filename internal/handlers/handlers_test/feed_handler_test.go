@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"manga-reader/internal/handlers"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupFeedHandler(t *testing.T) (*handlers.FeedHandler, *http.ServeMux) {
+	mangaRepo := NewMockMangaRepository()
+	chapterRepo := NewMockChapterRepository()
+	berserk, _ := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	vagabond, _ := mangaRepo.Create(&models.Manga{Title: "Бродяга"})
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, ch := range []*models.Chapter{
+		{MangaID: berserk, Number: 1, Title: "Черный мечник"},
+		{MangaID: vagabond, Number: 1, Title: "Такудзо"},
+		{MangaID: berserk, Number: 2, Title: "Клеймо"},
+		// Глава без даты публикации не попадает в ленты.
+		{MangaID: berserk, Number: 0, Title: "Пролог"},
+	} {
+		if ch.Number != 0 {
+			published := base.Add(time.Duration(i) * time.Hour)
+			ch.PublishedAt = &published
+		}
+		chapterRepo.Create(ch)
+	}
+
+	fh := &handlers.FeedHandler{
+		Chapters:  chapterRepo,
+		MangaRepo: mangaRepo,
+		PublicURL: "https://manga.example/",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	mux := http.NewServeMux()
+	handlers.RegisterFeedRoutes(mux, fh)
+	return fh, mux
+}
+
+func TestFeedHandler_SiteAtom(t *testing.T) {
+	_, mux := setupFeedHandler(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.atom", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Errorf("Неожиданный Content-Type: %s", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 14:00:00 GMT" {
+		t.Errorf("Неожиданный Last-Modified: %s", rec.Header().Get("Last-Modified"))
+	}
+
+	var doc struct {
+		Entries []struct {
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Ошибка разбора Atom: %v", err)
+	}
+	if len(doc.Entries) != 3 {
+		t.Fatalf("Ожидалось 3 записи, получено %d", len(doc.Entries))
+	}
+	if doc.Entries[0].Title != "Берсерк — Глава 2: Клеймо" || doc.Entries[0].Link.Href != "https://manga.example/chapter/3" {
+		t.Errorf("Неожиданная первая запись: %+v", doc.Entries[0])
+	}
+}
+
+func TestFeedHandler_ConditionalGet(t *testing.T) {
+	_, mux := setupFeedHandler(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed.rss", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Ожидался статус 200 с ETag, получено %d, %q", rec.Code, etag)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"совпадающий ETag", "If-None-Match", etag, http.StatusNotModified},
+		{"слабый ETag в списке", "If-None-Match", `"other", W/` + etag, http.StatusNotModified},
+		{"другой ETag", "If-None-Match", `"other"`, http.StatusOK},
+		{"не изменялась", "If-Modified-Since", "Wed, 01 May 2024 14:00:00 GMT", http.StatusNotModified},
+		{"изменилась", "If-Modified-Since", "Wed, 01 May 2024 13:00:00 GMT", http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+			req.Header.Set(tc.header, tc.value)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("Ожидался статус %d, получен %d", tc.status, rec.Code)
+			}
+			if tc.status == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Error("Ответ 304 не должен содержать тело")
+			}
+		})
+	}
+}
+
+func TestFeedHandler_MangaFeed(t *testing.T) {
+	fh, _ := setupFeedHandler(t)
+
+	rec := httptest.NewRecorder()
+	if err := fh.MangaFeed(rec, httptest.NewRequest(http.MethodGet, "/manga/2/feed.rss", nil)); err != nil {
+		t.Fatalf("Ошибка получения ленты манги: %v", err)
+	}
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title string `xml:"title"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Ошибка разбора RSS: %v", err)
+	}
+	if doc.Channel.Title != "Бродяга — новые главы" || len(doc.Channel.Items) != 1 ||
+		doc.Channel.Items[0].Title != "Бродяга — Глава 1: Такудзо" {
+		t.Errorf("Неожиданная лента манги: %+v", doc.Channel)
+	}
+
+	if err := fh.MangaFeed(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/manga/42/feed.atom", nil)); err == nil {
+		t.Error("Ожидалась ошибка для несуществующей манги")
+	}
+}
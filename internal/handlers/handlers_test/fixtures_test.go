@@ -0,0 +1,33 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"log/slog"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/db"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/models"
+	"net/http"
+	"testing"
+)
+
+// newTestDB открывает in-memory базу SQLite и возвращает ее вместе с
+// репозиторием манги, создавшим соединение. Соединение одно: каждое новое
+// получило бы собственную пустую базу. Таблица команд создается сразу,
+// так как на нее ссылаются главы.
+func newTestDB(t *testing.T, logger *slog.Logger) (*sql.DB, db.MangaRepository) {
+	mangaRepo, err := sqlite.NewMangaRepository(":memory:", logger)
+	if err != nil {
+		t.Fatalf("Ошибка открытия in-memory базы: %v", err)
+	}
+	conn := mangaRepo.(*sqlite.SQLiteMangaRepository).GetDB()
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	sqlite.NewScanlationGroupRepository(conn, logger)
+	return conn, mangaRepo
+}
+
+// asUser выполняет запрос от имени пользователя с токеном доступа.
+func asUser(req *http.Request, userID int64, role models.Role) *http.Request {
+	return req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{UserID: userID, Role: role, Method: auth.AuthMethodToken}))
+}
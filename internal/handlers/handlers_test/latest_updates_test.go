@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLatestUpdates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	mh := &handlers.MangaHandler{Repo: mangaRepo, Logger: logger, Cache: &DummyRedisCache{}}
+	ch := &handlers.ChapterHandler{Repo: sqlite.NewChapterRepository(conn, logger), Logger: logger, MangaRepo: mangaRepo}
+
+	older := &models.Manga{Title: "Берсерк"}
+	olderID, _ := mangaRepo.Create(older)
+	newerID, _ := mangaRepo.Create(&models.Manga{Title: "Бродяга"})
+	if older.CreatedAt.IsZero() || !older.UpdatedAt.Equal(older.CreatedAt) {
+		t.Fatalf("Create должен заполнить временные метки: %+v", older)
+	}
+
+	for _, number := range []int{1, 2} {
+		body := fmt.Sprintf(`{"manga_id": %d, "number": %d, "title": "Глава"}`, olderID, number)
+		if err := ch.Create(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body))); err != nil {
+			t.Fatalf("Неожиданная ошибка при создании главы: %v", err)
+		}
+	}
+
+	stored, err := mangaRepo.GetByID(olderID)
+	if err != nil {
+		t.Fatalf("Ошибка получения манги: %v", err)
+	}
+	if !stored.UpdatedAt.After(stored.CreatedAt) {
+		t.Errorf("Добавление главы должно обновить updated_at: %+v", stored)
+	}
+
+	resp := httptest.NewRecorder()
+	if err = mh.List(resp, httptest.NewRequest(http.MethodGet, "/manga?sort=updated", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении списка манги: %v", err)
+	}
+	var mangas []*models.Manga
+	if err = helper.ExtractData(resp.Body, &mangas); err != nil {
+		t.Fatalf("Ошибка парсинга списка: %v", err)
+	}
+	if len(mangas) != 2 || mangas[0].ID != olderID || mangas[1].ID != newerID {
+		t.Errorf("Манга с новой главой должна быть первой: %+v", mangas)
+	}
+
+	if err = mh.List(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/manga?sort=views", nil)); err == nil {
+		t.Error("Ожидалась ошибка для неизвестной сортировки")
+	}
+
+	resp = httptest.NewRecorder()
+	if err = ch.ListLatest(resp, httptest.NewRequest(http.MethodGet, "/chapters/latest?limit=1", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении последних глав: %v", err)
+	}
+	var chapters []*models.Chapter
+	if err = helper.ExtractData(resp.Body, &chapters); err != nil {
+		t.Fatalf("Ошибка парсинга списка глав: %v", err)
+	}
+	if len(chapters) != 1 || chapters[0].Number != 2 || chapters[0].CreatedAt.IsZero() {
+		t.Errorf("Ожидалась последняя добавленная глава: %+v", chapters)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/go-redis/redis/v8"
 	"io"
 	"log/slog"
+	"manga-reader/internal/cache"
 	"manga-reader/internal/handlers"
 	"manga-reader/internal/handlers/handlers_test/helper"
 	"manga-reader/models"
@@ -79,16 +81,120 @@ func (m *MockMangaRepository) Delete(id int64) error {
 	return nil
 }
 
+func (m *MockMangaRepository) ListDeleted() ([]*models.Manga, error) {
+	return nil, nil
+}
+
+func (m *MockMangaRepository) Restore(id int64) error {
+	return errors.New("manga not found in trash")
+}
+
+func (m *MockMangaRepository) PurgeDeleted(before time.Time) (int64, error) {
+	return 0, nil
+}
+
 type DummyRedisCache struct{}
 
 func (d *DummyRedisCache) Get(ctx context.Context, key string) (string, error) {
 	return "", errors.New("not found in cache")
 }
 
+func (d *DummyRedisCache) GetDel(ctx context.Context, key string) (string, error) {
+	return "", errors.New("not found in cache")
+}
+
 func (d *DummyRedisCache) Set(ctx context.Context, key, value string, expiration time.Duration) error {
 	return nil
 }
 
+func (d *DummyRedisCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+func (d *DummyRedisCache) Exists(ctx context.Context, key string) (bool, error) {
+	return false, nil
+}
+
+func (d *DummyRedisCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return nil
+}
+
+func (d *DummyRedisCache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	return nil
+}
+
+func (d *DummyRedisCache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return nil
+}
+
+func (d *DummyRedisCache) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return nil, nil
+}
+
+func (d *DummyRedisCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return nil
+}
+
+func (d *DummyRedisCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
+func (d *DummyRedisCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return nil
+}
+
+func (d *DummyRedisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return 0, nil
+}
+
+func (d *DummyRedisCache) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	return 0, nil
+}
+
+func (d *DummyRedisCache) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	return nil
+}
+
+func (d *DummyRedisCache) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	return 0, nil
+}
+
+func (d *DummyRedisCache) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return nil, nil
+}
+
+func (d *DummyRedisCache) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) (map[string]float64, error) {
+	return nil, nil
+}
+
+func (d *DummyRedisCache) ZUnionStore(ctx context.Context, dest string, keys ...string) error {
+	return nil
+}
+
+func (d *DummyRedisCache) ZUnionStoreWeights(ctx context.Context, dest string, keys []string, weights []float64) error {
+	return nil
+}
+
+func (d *DummyRedisCache) ZScore(ctx context.Context, key, member string) (float64, error) {
+	return 0, redis.Nil
+}
+
+func (d *DummyRedisCache) PFAdd(ctx context.Context, key string, elements ...interface{}) (bool, error) {
+	return false, nil
+}
+
+func (d *DummyRedisCache) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return 0, nil
+}
+
+func (d *DummyRedisCache) Pipeline() cache.Pipeline {
+	return cache.NewSequentialPipeline(d)
+}
+
+func (d *DummyRedisCache) GetClient() *redis.Client {
+	return nil
+}
+
 func TestMangaHandler_CreateAndGet(t *testing.T) {
 	mockRepo := NewMockMangaRepository()
 	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
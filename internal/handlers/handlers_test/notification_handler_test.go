@@ -0,0 +1,158 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type notificationFixture struct {
+	nh      *handlers.NotificationHandler
+	ch      *handlers.ChapterHandler
+	uh      *handlers.UserHandler
+	mangaID int64
+}
+
+func setupNotificationHandler(t *testing.T) *notificationFixture {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	userRepo := sqlite.NewSQLiteUserRepository(conn, logger)
+	notificationRepo := sqlite.NewNotificationRepository(conn, logger)
+	mangaID, err := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	if err != nil {
+		t.Fatalf("Ошибка создания манги: %v", err)
+	}
+
+	return &notificationFixture{
+		nh: &handlers.NotificationHandler{
+			Repo:      notificationRepo,
+			Follows:   sqlite.NewFollowRepository(conn, logger),
+			MangaRepo: mangaRepo,
+			Logger:    logger,
+		},
+		ch: &handlers.ChapterHandler{
+			Repo:          sqlite.NewChapterRepository(conn, logger),
+			Logger:        logger,
+			Notifications: notificationRepo,
+			MangaRepo:     mangaRepo,
+		},
+		uh:      &handlers.UserHandler{UserRepo: userRepo, Logger: logger},
+		mangaID: mangaID,
+	}
+}
+
+func (f *notificationFixture) createChapter(t *testing.T, number int) {
+	body := fmt.Sprintf(`{"manga_id": %d, "number": %d, "title": "Глава"}`, f.mangaID, number)
+	if err := f.ch.Create(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body))); err != nil {
+		t.Fatalf("Неожиданная ошибка при создании главы: %v", err)
+	}
+}
+
+func (f *notificationFixture) inbox(t *testing.T, userID int64, query string) handlers.NotificationList {
+	resp := httptest.NewRecorder()
+	if err := f.nh.List(resp, asUser(httptest.NewRequest(http.MethodGet, "/user/notifications"+query, nil), userID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении уведомлений: %v", err)
+	}
+	var list handlers.NotificationList
+	if err := helper.ExtractData(resp.Body, &list); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return list
+}
+
+func TestNotificationHandler_NewChapterFanOut(t *testing.T) {
+	f := setupNotificationHandler(t)
+	follower := registerTestUser(t, f.uh, `{"username": "guts", "password": "secret123"}`)
+	other := registerTestUser(t, f.uh, `{"username": "casca", "password": "secret123"}`)
+
+	followPath := fmt.Sprintf("/user/follows/%d", f.mangaID)
+	if err := f.nh.Follow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, followPath, nil), follower.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при подписке: %v", err)
+	}
+	if err := f.nh.Follow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, "/user/follows/999", nil), follower.ID, models.RoleReader)); err == nil {
+		t.Error("Подписка на несуществующую мангу должна завершаться ошибкой")
+	}
+
+	f.createChapter(t, 1)
+	f.createChapter(t, 2)
+
+	list := f.inbox(t, follower.ID, "?limit=1")
+	if list.Unread != 2 || len(list.Items) != 1 || !list.HasMore {
+		t.Fatalf("Ожидалась страница из 1 уведомления из 2 непрочитанных, получено %+v", list)
+	}
+	latest := list.Items[0]
+	if latest.Event != models.EventNewChapter || latest.MangaTitle != "Берсерк" || latest.Read {
+		t.Errorf("Неожиданное уведомление: %+v", latest)
+	}
+	if list := f.inbox(t, other.ID, ""); len(list.Items) != 0 {
+		t.Errorf("Пользователь без подписки не должен получать уведомления, получено %d", len(list.Items))
+	}
+
+	readPath := fmt.Sprintf("/user/notifications/%d/read", latest.ID)
+	if err := f.nh.MarkRead(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, readPath, nil), other.ID, models.RoleReader)); err == nil {
+		t.Error("Чужое уведомление не должно отмечаться прочитанным")
+	}
+	if err := f.nh.MarkRead(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, readPath, nil), follower.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при отметке уведомления: %v", err)
+	}
+	if list := f.inbox(t, follower.ID, "?unread=true"); list.Unread != 1 || len(list.Items) != 1 || list.Items[0].ID == latest.ID {
+		t.Errorf("Ожидалось одно непрочитанное уведомление, получено %+v", list)
+	}
+
+	if err := f.nh.MarkAllRead(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, "/user/notifications/read-all", nil), follower.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при отметке всех уведомлений: %v", err)
+	}
+	if list := f.inbox(t, follower.ID, ""); list.Unread != 0 || len(list.Items) != 2 {
+		t.Errorf("Ожидались 2 прочитанных уведомления, получено %+v", list)
+	}
+}
+
+func TestNotificationHandler_Preferences(t *testing.T) {
+	f := setupNotificationHandler(t)
+	user := registerTestUser(t, f.uh, `{"username": "guts", "password": "secret123"}`)
+	followPath := fmt.Sprintf("/user/follows/%d", f.mangaID)
+	if err := f.nh.Follow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, followPath, nil), user.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при подписке: %v", err)
+	}
+
+	invalid := asUser(httptest.NewRequest(http.MethodPut, "/user/notifications/preferences",
+		bytes.NewBufferString(`{"unknown": true}`)), user.ID, models.RoleReader)
+	if err := f.nh.UpdatePreferences(httptest.NewRecorder(), invalid); err == nil {
+		t.Error("Неизвестное событие должно отклоняться")
+	}
+
+	resp := httptest.NewRecorder()
+	disable := asUser(httptest.NewRequest(http.MethodPut, "/user/notifications/preferences",
+		bytes.NewBufferString(`{"new_chapter": false}`)), user.ID, models.RoleReader)
+	if err := f.nh.UpdatePreferences(resp, disable); err != nil {
+		t.Fatalf("Неожиданная ошибка при сохранении настроек: %v", err)
+	}
+	var prefs map[models.NotificationEvent]bool
+	if err := helper.ExtractData(resp.Body, &prefs); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if enabled, ok := prefs[models.EventNewChapter]; !ok || enabled {
+		t.Errorf("Ожидалось отключенное событие new_chapter, получено %v", prefs)
+	}
+
+	f.createChapter(t, 1)
+	if list := f.inbox(t, user.ID, ""); len(list.Items) != 0 {
+		t.Errorf("Отключенное событие не должно создавать уведомления, получено %d", len(list.Items))
+	}
+
+	if err := f.nh.Unfollow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodDelete, followPath, nil), user.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при отмене подписки: %v", err)
+	}
+	if err := f.nh.Unfollow(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodDelete, followPath, nil), user.ID, models.RoleReader)); err == nil {
+		t.Error("Повторная отмена подписки должна завершаться ошибкой")
+	}
+}
@@ -0,0 +1,212 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/internal/oidc"
+	"manga-reader/internal/oidc/oidctest"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newOIDCTestHandler(t *testing.T) (*handlers.OIDCHandler, *oidctest.Issuer) {
+	issuer := oidctest.NewIssuer("manga-reader", "client-secret")
+	t.Cleanup(issuer.Close)
+
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		IssuerURL:    issuer.URL(),
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://app.test/auth/oidc/callback",
+		RoleClaim:    "groups",
+		RoleMapping:  map[string]models.Role{"manga-staff": models.RoleModerator},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Ошибка discovery: %v", err)
+	}
+
+	return &handlers.OIDCHandler{
+		Provider: provider,
+		UserRepo: setupTestUserRepo(t),
+		Store:    cache.NewMemoryCache(),
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, issuer
+}
+
+// withOIDCAudit подключает к обработчику журнал аудита и возвращает его.
+func withOIDCAudit(t *testing.T, h *handlers.OIDCHandler) db.AuditRepository {
+	conn, _ := newTestDB(t, h.Logger)
+	repo := sqlite.NewAuditRepository(conn, h.Logger)
+	h.Audit = audit.NewRecorder(repo, h.Logger)
+	return repo
+}
+
+// oidcLogin проходит весь цикл: /auth/oidc/login → провайдер → callback.
+// Возвращает запрос callback, чтобы тесты могли повторить его.
+func oidcLogin(t *testing.T, h *handlers.OIDCHandler) *http.Request {
+	resp := httptest.NewRecorder()
+	if err := h.Login(resp, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при начале входа: %v", err)
+	}
+	if resp.Code != http.StatusFound {
+		t.Fatalf("Ожидалось перенаправление, статус %d", resp.Code)
+	}
+	return oidcAuthorize(t, resp.Header().Get("Location"), resp.Result().Cookies())
+}
+
+// oidcAuthorize проходит страницу провайдера authURL и возвращает запрос
+// callback из того же браузера, то есть с cookies, выданными при начале входа.
+func oidcAuthorize(t *testing.T, authURL string, cookies []*http.Cookie) *http.Request {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	providerResp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Ошибка запроса к провайдеру: %v", err)
+	}
+	providerResp.Body.Close()
+	req := httptest.NewRequest(http.MethodGet, providerResp.Header.Get("Location"), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+// oidcStartLink начинает привязку провайдера к пользователю userID и
+// возвращает адрес провайдера и cookies ответа.
+func oidcStartLink(t *testing.T, h *handlers.OIDCHandler, userID int64) (string, []*http.Cookie) {
+	resp := httptest.NewRecorder()
+	if err := h.StartLink(resp, asUser(httptest.NewRequest(http.MethodPost, "/auth/oidc/link", nil), userID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при начале привязки: %v", err)
+	}
+	var body map[string]string
+	if err := helper.ExtractData(resp.Body, &body); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return body["url"], resp.Result().Cookies()
+}
+
+func oidcCallback(t *testing.T, h *handlers.OIDCHandler, req *http.Request) int64 {
+	resp := httptest.NewRecorder()
+	if err := h.Callback(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка в callback: %v", err)
+	}
+	var tokens handlers.LoginResponse
+	if err := helper.ExtractData(resp.Body, &tokens); err != nil {
+		t.Fatalf("Ошибка парсинга токенов: %v", err)
+	}
+	claims, err := auth.ParseClaims(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Выдан недействительный токен: %v", err)
+	}
+	return claims.UserID
+}
+
+func TestOIDCHandler_ProvisionAndLogin(t *testing.T) {
+	h, issuer := newOIDCTestHandler(t)
+	issuer.SetUser(map[string]interface{}{
+		"sub":                "staff-1",
+		"preferred_username": "alice",
+		"email":              "alice@corp.test",
+		"email_verified":     true,
+		"groups":             []string{"manga-staff"},
+	})
+
+	callback := oidcLogin(t, h)
+	userID := oidcCallback(t, h, callback)
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		t.Fatalf("Пользователь не создан: %v", err)
+	}
+	if user.Username != "alice" || user.Role != models.RoleModerator || user.Email != "alice@corp.test" {
+		t.Errorf("Неожиданный пользователь: %+v", user)
+	}
+
+	if err = h.Callback(httptest.NewRecorder(), callback); err == nil {
+		t.Error("Повторный callback с тем же state должен отклоняться")
+	}
+
+	// Повторный вход находит ту же учетную запись по sub.
+	issuer.SetUser(map[string]interface{}{"sub": "staff-1", "preferred_username": "alice", "groups": []string{}})
+	if again := oidcCallback(t, h, oidcLogin(t, h)); again != userID {
+		t.Errorf("Ожидался тот же пользователь %d, получен %d", userID, again)
+	}
+}
+
+func TestOIDCHandler_ExplicitLink(t *testing.T) {
+	h, issuer := newOIDCTestHandler(t)
+	auditRepo := withOIDCAudit(t, h)
+	uh := &handlers.UserHandler{UserRepo: h.UserRepo, Logger: h.Logger}
+	local := registerTestUser(t, uh, `{"username": "bob", "email": "bob@corp.test", "password": "secret123"}`)
+	staff := map[string]interface{}{
+		"sub": "u-3", "email": "bob@corp.test", "email_verified": true, "groups": []string{"manga-staff"},
+	}
+
+	// Совпадение email не привязывает учетную запись: email в профиле
+	// не подтверждается и мог быть указан кем угодно.
+	issuer.SetUser(staff)
+	err := h.Callback(httptest.NewRecorder(), oidcLogin(t, h))
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusConflict {
+		t.Fatalf("Ожидалась ошибка 409, получено %v", err)
+	}
+	if user, _ := h.UserRepo.GetByID(local.ID); user.Role != models.RoleReader {
+		t.Fatalf("Роль не должна меняться без привязки, получена %q", user.Role)
+	}
+
+	authURL, cookies := oidcStartLink(t, h, local.ID)
+	if userID := oidcCallback(t, h, oidcAuthorize(t, authURL, cookies)); userID != local.ID {
+		t.Fatalf("Ожидалась привязка к пользователю %d, получен %d", local.ID, userID)
+	}
+	if user, _ := h.UserRepo.GetByID(local.ID); user.Role != models.RoleModerator {
+		t.Errorf("Ожидалась роль провайдера после привязки, получена %q", user.Role)
+	}
+	if userID := oidcCallback(t, h, oidcLogin(t, h)); userID != local.ID {
+		t.Errorf("Вход через провайдера должен находить привязанного пользователя %d, получен %d", local.ID, userID)
+	}
+	if err = login(uh, "bob", "secret123"); err != nil {
+		t.Errorf("Локальный вход должен продолжать работать: %v", err)
+	}
+
+	entries, err := auditRepo.List(models.AuditFilter{EntityType: models.AuditUser, EntityID: local.ID}, 10, 0)
+	if err != nil {
+		t.Fatalf("Ошибка чтения журнала аудита: %v", err)
+	}
+	changed := map[string]bool{}
+	for _, e := range entries {
+		for field := range e.Changes {
+			changed[field] = true
+		}
+	}
+	if !changed["oidc_identity"] || !changed["role"] {
+		t.Errorf("В журнале аудита ожидались привязка и смена роли, получено %v", changed)
+	}
+}
+
+func TestOIDCHandler_CallbackRequiresStateCookie(t *testing.T) {
+	h, issuer := newOIDCTestHandler(t)
+	uh := &handlers.UserHandler{UserRepo: h.UserRepo, Logger: h.Logger}
+	attacker := registerTestUser(t, uh, `{"username": "mallory", "password": "secret123"}`)
+
+	// Злоумышленник начинает привязку и отправляет ссылку сотруднику,
+	// который проходит вход у провайдера в своем браузере.
+	authURL, _ := oidcStartLink(t, h, attacker.ID)
+	issuer.SetUser(map[string]interface{}{"sub": "staff-1", "groups": []string{"manga-staff"}})
+	if err := h.Callback(httptest.NewRecorder(), oidcAuthorize(t, authURL, nil)); err == nil {
+		t.Fatal("Callback без cookie со state должен отклоняться")
+	}
+	if user, _ := h.UserRepo.GetByID(attacker.ID); user.Role != models.RoleReader {
+		t.Errorf("Роль злоумышленника не должна меняться, получена %q", user.Role)
+	}
+}
@@ -0,0 +1,224 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/opds"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type opdsFeed struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Rel   string `xml:"rel,attr"`
+			Href  string `xml:"href,attr"`
+			Type  string `xml:"type,attr"`
+			Count int    `xml:"http://vaemendis.net/opds-pse/ns count,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+func setupOPDS(t *testing.T) *http.ServeMux {
+	mangaRepo := NewMockMangaRepository()
+	chapterRepo := NewMockChapterRepository()
+	pageRepo := NewMockPageRepository()
+
+	berserk, _ := mangaRepo.Create(&models.Manga{Title: "Берсерк", Description: "Темное фэнтези"})
+	mangaRepo.Create(&models.Manga{Title: "Бродяга"})
+	chapterRepo.Create(&models.Chapter{MangaID: berserk, Number: 2, Title: "Клеймо"})
+	chapterID, _ := chapterRepo.Create(&models.Chapter{MangaID: berserk, Number: 1, Title: "Черный мечник"})
+
+	dir := t.TempDir()
+	// Страницы создаются не по порядку, чтобы проверить сортировку по номеру.
+	for _, p := range []struct {
+		number int
+		name   string
+	}{{2, "b.png"}, {1, "a.jpg"}} {
+		path := filepath.Join(dir, p.name)
+		if err := os.WriteFile(path, []byte("image-"+p.name), 0o644); err != nil {
+			t.Fatalf("Ошибка создания файла страницы: %v", err)
+		}
+		pageRepo.Create(&models.Page{ChapterID: chapterID, Number: p.number, ImagePath: path})
+	}
+
+	mux := http.NewServeMux()
+	handlers.RegisterOPDSRoutes(mux, &handlers.OPDSHandler{
+		MangaRepo: mangaRepo,
+		Chapters:  chapterRepo,
+		Pages:     pageRepo,
+		PublicURL: "https://manga.example",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	return mux
+}
+
+func getOPDS(t *testing.T, mux *http.ServeMux, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestOPDSHandler_RootAndSearch(t *testing.T) {
+	mux := setupOPDS(t)
+
+	rec := getOPDS(t, mux, "/opds")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), opds.NavigationType) {
+		t.Fatalf("Неожиданный ответ: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var root opdsFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &root); err != nil {
+		t.Fatalf("Ошибка разбора корневой ленты: %v", err)
+	}
+	var search string
+	for _, l := range root.Links {
+		if l.Rel == opds.RelSearch {
+			search = l.Href
+		}
+	}
+	if search != "https://manga.example/opds/search.xml" {
+		t.Errorf("Неожиданная ссылка поиска: %q", search)
+	}
+
+	rec = getOPDS(t, mux, "/opds/search.xml")
+	if !strings.Contains(rec.Body.String(), `template="https://manga.example/opds/manga?q={searchTerms}"`) {
+		t.Errorf("Описание OpenSearch без шаблона поиска:\n%s", rec.Body.String())
+	}
+
+	var found opdsFeed
+	rec = getOPDS(t, mux, "/opds/manga?q="+"%D0%B1%D0%B5%D1%80") // "бер"
+	if err := xml.Unmarshal(rec.Body.Bytes(), &found); err != nil {
+		t.Fatalf("Ошибка разбора результатов поиска: %v", err)
+	}
+	if len(found.Entries) != 1 || found.Entries[0].Title != "Берсерк" {
+		t.Errorf("Поиск должен вернуть только «Берсерк», получено %+v", found.Entries)
+	}
+}
+
+func TestOPDSHandler_AcquisitionFeed(t *testing.T) {
+	mux := setupOPDS(t)
+
+	rec := getOPDS(t, mux, "/opds/manga/1")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), opds.AcquisitionType) {
+		t.Fatalf("Неожиданный ответ: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var f opdsFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &f); err != nil {
+		t.Fatalf("Ошибка разбора ленты: %v", err)
+	}
+	if len(f.Entries) != 2 || f.Entries[0].Title != "Глава 1: Черный мечник" {
+		t.Fatalf("Главы должны идти по номеру: %+v", f.Entries)
+	}
+
+	links := f.Entries[0].Links
+	if len(links) != 2 {
+		t.Fatalf("Ожидались ссылки на CBZ и потоковое чтение, получено %+v", links)
+	}
+	if links[0].Rel != opds.RelAcquisition || links[0].Type != opds.CBZType ||
+		links[0].Href != "https://manga.example/opds/chapter/2/download" {
+		t.Errorf("Неожиданная ссылка на CBZ: %+v", links[0])
+	}
+	if links[1].Rel != opds.RelStream || links[1].Count != 2 ||
+		links[1].Href != "https://manga.example/opds/chapter/2/pages/{pageNumber}" {
+		t.Errorf("Неожиданная ссылка OPDS-PSE: %+v", links[1])
+	}
+	if len(f.Entries[1].Links) != 1 {
+		t.Error("Главе без страниц не нужна ссылка потокового чтения")
+	}
+
+	if rec = getOPDS(t, mux, "/opds/manga/42"); rec.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404, получен %d", rec.Code)
+	}
+}
+
+func TestOPDSHandler_StreamPage(t *testing.T) {
+	mux := setupOPDS(t)
+
+	rec := getOPDS(t, mux, "/opds/chapter/2/pages/0")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://manga.example/page/image/2" {
+		t.Errorf("Первая страница должна вести на /page/image/2: %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec = getOPDS(t, mux, "/opds/chapter/2/pages/2"); rec.Code != http.StatusNotFound {
+		t.Errorf("Ожидался статус 404 за пределами главы, получен %d", rec.Code)
+	}
+}
+
+func TestOPDSHandler_DownloadCBZ(t *testing.T) {
+	mux := setupOPDS(t)
+
+	rec := getOPDS(t, mux, "/opds/chapter/2/download")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != opds.CBZType {
+		t.Fatalf("Неожиданный ответ: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "filename*=UTF-8''") {
+		t.Errorf("Неожиданный Content-Disposition: %s", rec.Header().Get("Content-Disposition"))
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Ошибка чтения CBZ: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "ComicInfo.xml,001.jpg,002.png" {
+		t.Fatalf("Неожиданное содержимое архива: %v", names)
+	}
+	page, _ := zr.File[1].Open()
+	data, _ := io.ReadAll(page)
+	page.Close()
+	if string(data) != "image-a.jpg" {
+		t.Errorf("Неожиданное содержимое первой страницы: %q", data)
+	}
+}
+
+func TestOPDSHandler_V2(t *testing.T) {
+	mux := setupOPDS(t)
+
+	rec := getOPDS(t, mux, "/opds/v2/manga/1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != opds.JSONType {
+		t.Fatalf("Неожиданный ответ: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var c opds.Catalog
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+		t.Fatalf("Ошибка разбора OPDS 2.0: %v", err)
+	}
+	if c.Metadata.Title != "Берсерк" || len(c.Publications) != 2 {
+		t.Fatalf("Неожиданный каталог: %+v", c)
+	}
+	if link := c.Publications[0].Links[0]; link.Type != opds.CBZType || link.Href != "https://manga.example/opds/chapter/2/download" {
+		t.Errorf("Неожиданная ссылка на CBZ: %+v", link)
+	}
+
+	rec = getOPDS(t, mux, "/opds/v2/manga?limit=1")
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+		t.Fatalf("Ошибка разбора OPDS 2.0: %v", err)
+	}
+	var next string
+	for _, l := range c.Links {
+		if l.Rel == opds.RelNext {
+			next = l.Href
+		}
+	}
+	if len(c.Navigation) != 1 || next != "https://manga.example/opds/v2/manga?limit=1&offset=1" {
+		t.Errorf("Неожиданная страница списка: %+v, next=%q", c.Navigation, next)
+	}
+}
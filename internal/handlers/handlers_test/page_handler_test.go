@@ -19,6 +19,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type MockPageRepository struct {
@@ -86,6 +87,18 @@ func (r *MockPageRepository) Update(p *models.Page) error {
 	return nil
 }
 
+func (r *MockPageRepository) ListDeleted() ([]*models.Page, error) {
+	return nil, nil
+}
+
+func (r *MockPageRepository) Restore(id int64) error {
+	return errors.New("page not found in trash")
+}
+
+func (r *MockPageRepository) PurgeDeleted(before time.Time) ([]*models.Page, error) {
+	return nil, nil
+}
+
 func createTestImage(t *testing.T) string {
 	tempDir := t.TempDir()
 
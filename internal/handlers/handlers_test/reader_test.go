@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupReader(t *testing.T) (*handlers.ChapterHandler, *MockPageRepository, *cache.MemoryCache, int64) {
+	mangaRepo := NewMockMangaRepository()
+	mangaID, _ := mangaRepo.Create(&models.Manga{Title: "Берсерк", Description: "Темное фэнтези"})
+	pageRepo := NewMockPageRepository()
+	memCache := cache.NewMemoryCache()
+	return &handlers.ChapterHandler{
+		Repo:      NewMockChapterRepository(),
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Cache:     memCache,
+		MangaRepo: mangaRepo,
+		Pages:     pageRepo,
+	}, pageRepo, memCache, mangaID
+}
+
+func getReader(t *testing.T, h *handlers.ChapterHandler, req *http.Request) handlers.ReaderManifest {
+	resp := httptest.NewRecorder()
+	if err := h.Reader(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении манифеста: %v", err)
+	}
+	var manifest handlers.ReaderManifest
+	if err := helper.ExtractData(resp.Body, &manifest); err != nil {
+		t.Fatalf("Ошибка парсинга манифеста: %v", err)
+	}
+	return manifest
+}
+
+func chapterIDOrZero(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func TestChapterHandler_Reader(t *testing.T) {
+	h, pageRepo, memCache, mangaID := setupReader(t)
+	create := func(number int, language, status string) int64 {
+		body := fmt.Sprintf(`{"manga_id": %d, "number": %d, "title": "Глава", "language": %q, "status": %q}`,
+			mangaID, number, language, status)
+		resp := httptest.NewRecorder()
+		req := asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)), 1, models.RoleModerator)
+		if err := h.Create(resp, req); err != nil {
+			t.Fatalf("Неожиданная ошибка при создании главы: %v", err)
+		}
+		var ch models.Chapter
+		if err := helper.ExtractData(resp.Body, &ch); err != nil {
+			t.Fatalf("Ошибка парсинга ответа: %v", err)
+		}
+		return ch.ID
+	}
+	first := create(1, "ru", "published")
+	create(2, "ru", "draft")
+	third := create(3, "ru", "published")
+	create(2, "en", "published")
+	for _, number := range []int{2, 1} {
+		pageRepo.Create(&models.Page{ChapterID: third, Number: number, ImagePath: "x.png"})
+	}
+
+	path := fmt.Sprintf("/chapter/%d/reader", third)
+	manifest := getReader(t, h, httptest.NewRequest(http.MethodGet, path, nil))
+	if manifest.Chapter == nil || manifest.Chapter.ID != third || manifest.Manga.ID != mangaID || manifest.Manga.Title != "Берсерк" {
+		t.Fatalf("Неожиданный манифест: %+v", manifest)
+	}
+	if len(manifest.Pages) != 2 || manifest.Pages[0].Number != 1 || manifest.Pages[1].Number != 2 {
+		t.Errorf("Страницы должны идти по порядку: %+v", manifest.Pages)
+	}
+	if want := fmt.Sprintf("/page/image/%d", manifest.Pages[0].ID); manifest.Pages[0].ImageURL != want {
+		t.Errorf("Ожидался адрес %s, получен %s", want, manifest.Pages[0].ImageURL)
+	}
+	// Черновик и перевод на другой язык пропускаются.
+	if chapterIDOrZero(manifest.PrevChapterID) != first || manifest.NextChapterID != nil {
+		t.Errorf("Неожиданные соседние главы: prev=%d next=%d", chapterIDOrZero(manifest.PrevChapterID),
+			chapterIDOrZero(manifest.NextChapterID))
+	}
+
+	if cached, _ := memCache.Get(context.Background(), fmt.Sprintf("chapter:%d:reader", third)); cached == "" {
+		t.Fatal("Манифест должен кешироваться")
+	}
+	fourth := create(4, "ru", "published")
+	manifest = getReader(t, h, httptest.NewRequest(http.MethodGet, path, nil))
+	if chapterIDOrZero(manifest.NextChapterID) != fourth {
+		t.Errorf("Публикация новой главы должна обновлять навигацию, next=%d", chapterIDOrZero(manifest.NextChapterID))
+	}
+
+	draftPath := fmt.Sprintf("/chapter/%d/reader", first+1)
+	if err := h.Reader(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, draftPath, nil)); err == nil {
+		t.Error("Читатель не должен получать манифест черновика")
+	}
+	manifest = getReader(t, h, asUser(httptest.NewRequest(http.MethodGet, draftPath, nil), 1, models.RoleUploader))
+	if chapterIDOrZero(manifest.PrevChapterID) != first || chapterIDOrZero(manifest.NextChapterID) != third {
+		t.Errorf("Неожиданные соседние главы черновика: %+v", manifest)
+	}
+}
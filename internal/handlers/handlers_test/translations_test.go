@@ -0,0 +1,263 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type translationFixture struct {
+	ch      *handlers.ChapterHandler
+	gh      *handlers.GroupHandler
+	uh      *handlers.UserHandler
+	mangaID int64
+}
+
+func setupTranslations(t *testing.T) *translationFixture {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	userRepo := sqlite.NewSQLiteUserRepository(conn, logger)
+	groupRepo := sqlite.NewScanlationGroupRepository(conn, logger)
+	mangaID, err := mangaRepo.Create(&models.Manga{Title: "Берсерк"})
+	if err != nil {
+		t.Fatalf("Ошибка создания манги: %v", err)
+	}
+
+	return &translationFixture{
+		ch: &handlers.ChapterHandler{
+			Repo:      sqlite.NewChapterRepository(conn, logger),
+			Logger:    logger,
+			Groups:    groupRepo,
+			Users:     userRepo,
+			MangaRepo: mangaRepo,
+		},
+		gh:      &handlers.GroupHandler{Repo: groupRepo, UserRepo: userRepo, Logger: logger},
+		uh:      &handlers.UserHandler{UserRepo: userRepo, Logger: logger},
+		mangaID: mangaID,
+	}
+}
+
+func (f *translationFixture) createGroup(t *testing.T, body string) models.ScanlationGroup {
+	resp := httptest.NewRecorder()
+	req := asUser(httptest.NewRequest(http.MethodPost, "/groups", bytes.NewBufferString(body)), 1, models.RoleModerator)
+	if err := f.gh.Create(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при создании команды: %v", err)
+	}
+	var group models.ScanlationGroup
+	if err := helper.ExtractData(resp.Body, &group); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return group
+}
+
+func (f *translationFixture) createChapter(t *testing.T, req *http.Request) (models.Chapter, error) {
+	resp := httptest.NewRecorder()
+	if err := f.ch.Create(resp, req); err != nil {
+		return models.Chapter{}, err
+	}
+	var ch models.Chapter
+	if err := helper.ExtractData(resp.Body, &ch); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	return ch, nil
+}
+
+func (f *translationFixture) list(t *testing.T, req *http.Request) []*models.Chapter {
+	resp := httptest.NewRecorder()
+	if err := f.ch.ListByManga(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении списка глав: %v", err)
+	}
+	var chapters []*models.Chapter
+	if err := helper.ExtractData(resp.Body, &chapters); err != nil {
+		t.Fatalf("Ошибка парсинга списка глав: %v", err)
+	}
+	return chapters
+}
+
+func chapterLanguages(chapters []*models.Chapter) []string {
+	languages := make([]string, len(chapters))
+	for i, ch := range chapters {
+		languages[i] = fmt.Sprintf("%d:%s", ch.Number, ch.Language)
+	}
+	return languages
+}
+
+func TestGroupHandler_Members(t *testing.T) {
+	f := setupTranslations(t)
+	user := registerTestUser(t, f.uh, `{"username": "guts", "password": "secret123"}`)
+
+	group := f.createGroup(t, `{"name": "Band of the Hawk", "website": "https://hawk.example.com"}`)
+	if group.ID == 0 || group.Website != "https://hawk.example.com" {
+		t.Fatalf("Неожиданная команда: %+v", group)
+	}
+	req := asUser(httptest.NewRequest(http.MethodPost, "/groups", bytes.NewBufferString(`{"name": "band of the hawk"}`)), 1, models.RoleModerator)
+	if err := f.gh.Create(httptest.NewRecorder(), req); err == nil {
+		t.Error("Ожидалась ошибка при повторном названии команды")
+	}
+	req = asUser(httptest.NewRequest(http.MethodPost, "/groups", bytes.NewBufferString(`{"name": "Skull", "website": "ftp://x"}`)), 1, models.RoleModerator)
+	if err := f.gh.Create(httptest.NewRecorder(), req); err == nil {
+		t.Error("Ожидалась ошибка при некорректном адресе сайта")
+	}
+
+	path := fmt.Sprintf("/groups/%d/members", group.ID)
+	body := fmt.Sprintf(`{"user_id": %d}`, user.ID)
+	if err := f.gh.AddMember(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))); err != nil {
+		t.Fatalf("Неожиданная ошибка при добавлении участника: %v", err)
+	}
+
+	resp := httptest.NewRecorder()
+	if err := f.gh.Get(resp, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/groups/%d", group.ID), nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении команды: %v", err)
+	}
+	var got models.ScanlationGroup
+	if err := helper.ExtractData(resp.Body, &got); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if len(got.Members) != 1 || got.Members[0].UserID != user.ID || got.Members[0].Username != "guts" {
+		t.Errorf("Неожиданные участники команды: %+v", got.Members)
+	}
+
+	memberPath := fmt.Sprintf("%s/%d", path, user.ID)
+	if err := f.gh.RemoveMember(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, memberPath, nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при исключении участника: %v", err)
+	}
+	if err := f.gh.RemoveMember(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, memberPath, nil)); err == nil {
+		t.Error("Ожидалась ошибка при повторном исключении участника")
+	}
+}
+
+func TestChapterHandler_GroupMembership(t *testing.T) {
+	f := setupTranslations(t)
+	member := registerTestUser(t, f.uh, `{"username": "casca", "password": "secret123"}`)
+	outsider := registerTestUser(t, f.uh, `{"username": "griffith", "password": "secret123"}`)
+	group := f.createGroup(t, `{"name": "Band of the Hawk"}`)
+	if err := f.gh.AddMember(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, fmt.Sprintf("/groups/%d/members", group.ID),
+		bytes.NewBufferString(fmt.Sprintf(`{"user_id": %d}`, member.ID)))); err != nil {
+		t.Fatalf("Неожиданная ошибка при добавлении участника: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава", "language": "EN", "group_id": %d}`, f.mangaID, group.ID)
+	if _, err := f.createChapter(t, asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)),
+		outsider.ID, models.RoleUploader)); err == nil {
+		t.Error("Загрузчик не из команды не должен публиковать главы от ее имени")
+	}
+	ch, err := f.createChapter(t, asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)),
+		member.ID, models.RoleUploader))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при создании главы участником команды: %v", err)
+	}
+	if ch.Language != "en" || ch.GroupID == nil || *ch.GroupID != group.ID {
+		t.Errorf("Неожиданная глава: %+v", ch)
+	}
+
+	body = fmt.Sprintf(`{"manga_id": %d, "number": 2, "title": "Глава", "group_id": 999}`, f.mangaID)
+	if _, err = f.createChapter(t, asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)), 1,
+		models.RoleModerator)); err == nil {
+		t.Error("Ожидалась ошибка для несуществующей команды")
+	}
+	body = fmt.Sprintf(`{"manga_id": %d, "number": 2, "title": "Глава", "language": "english"}`, f.mangaID)
+	if _, err = f.createChapter(t, asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)), 1,
+		models.RoleModerator)); err == nil {
+		t.Error("Ожидалась ошибка для некорректного кода языка")
+	}
+
+	// Правка без group_id сохраняет команду, null снимает ее.
+	path := fmt.Sprintf("/chapter/%d", ch.ID)
+	req := asUser(httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"number": 1, "title": "Новое название"}`)),
+		outsider.ID, models.RoleUploader)
+	if err = f.ch.Update(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("Неожиданная ошибка при обновлении главы: %v", err)
+	}
+	updated, _ := f.ch.Repo.GetByID(ch.ID)
+	if updated.GroupID == nil || updated.Language != "en" {
+		t.Errorf("Правка без group_id и language не должна их менять: %+v", updated)
+	}
+	req = asUser(httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"number": 1, "title": "Глава", "group_id": null}`)), 1,
+		models.RoleModerator)
+	if err = f.ch.Update(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("Неожиданная ошибка при обновлении главы: %v", err)
+	}
+	if updated, _ = f.ch.Repo.GetByID(ch.ID); updated.GroupID != nil {
+		t.Errorf("group_id: null должен снять команду: %+v", updated)
+	}
+}
+
+func TestChapterHandler_ListTranslations(t *testing.T) {
+	f := setupTranslations(t)
+	group := f.createGroup(t, `{"name": "Band of the Hawk"}`)
+	for _, body := range []string{
+		fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Глава 1"}`, f.mangaID),
+		fmt.Sprintf(`{"manga_id": %d, "number": 1, "title": "Chapter 1", "language": "en", "group_id": %d}`, f.mangaID, group.ID),
+		fmt.Sprintf(`{"manga_id": %d, "number": 2, "title": "Глава 2"}`, f.mangaID),
+	} {
+		if _, err := f.createChapter(t, asUser(httptest.NewRequest(http.MethodPost, "/chapter", bytes.NewBufferString(body)), 1,
+			models.RoleModerator)); err != nil {
+			t.Fatalf("Неожиданная ошибка при создании главы: %v", err)
+		}
+	}
+	reader := registerTestUser(t, f.uh, `{"username": "puck", "password": "secret123"}`)
+	path := fmt.Sprintf("/manga/%d/chapters", f.mangaID)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Без предпочтений", "", "[1:ru 1:en 2:ru]"},
+		{"Язык", "?lang=en", "[1:en]"},
+		{"Команда", fmt.Sprintf("?group=%d", group.ID), "[1:en]"},
+		{"Команда и язык", fmt.Sprintf("?group=%d&lang=ru", group.ID), "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chapters := f.list(t, asUser(httptest.NewRequest(http.MethodGet, path+tt.query, nil), reader.ID, models.RoleReader))
+			if got := fmt.Sprint(chapterLanguages(chapters)); got != tt.want {
+				t.Errorf("Ожидалось %s, получено %s", tt.want, got)
+			}
+		})
+	}
+
+	req := asUser(httptest.NewRequest(http.MethodPut, "/user/me", bytes.NewBufferString(`{"preferred_language": "EN"}`)), reader.ID, models.RoleReader)
+	resp := httptest.NewRecorder()
+	if err := f.uh.UpdateAccount(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при обновлении учетной записи: %v", err)
+	}
+	var user models.User
+	if err := helper.ExtractData(resp.Body, &user); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if user.PreferredLanguage != "en" {
+		t.Errorf("Ожидался язык en, получен %q", user.PreferredLanguage)
+	}
+
+	// Для главы 1 есть английский перевод, глава 2 выводится на русском.
+	chapters := f.list(t, asUser(httptest.NewRequest(http.MethodGet, path, nil), reader.ID, models.RoleReader))
+	if got := fmt.Sprint(chapterLanguages(chapters)); got != "[1:en 2:ru]" {
+		t.Errorf("Ожидался список с учетом языка пользователя, получено %s", got)
+	}
+	chapters = f.list(t, asUser(httptest.NewRequest(http.MethodGet, path+"?lang=all", nil), reader.ID, models.RoleReader))
+	if len(chapters) != 3 {
+		t.Errorf("lang=all должен вернуть все переводы, получено %s", chapterLanguages(chapters))
+	}
+	chapters = f.list(t, httptest.NewRequest(http.MethodGet, path, nil))
+	if len(chapters) != 3 {
+		t.Errorf("Анонимный читатель должен видеть все переводы, получено %s", chapterLanguages(chapters))
+	}
+
+	if err := f.ch.ListByManga(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path+"?lang=xx_YY", nil)); err == nil {
+		t.Error("Ожидалась ошибка для некорректного кода языка")
+	}
+	req = asUser(httptest.NewRequest(http.MethodPut, "/user/me", bytes.NewBufferString(`{"preferred_language": "english"}`)), reader.ID, models.RoleReader)
+	if err := f.uh.UpdateAccount(httptest.NewRecorder(), req); err == nil {
+		t.Error("Ожидалась ошибка для некорректного языка пользователя")
+	}
+}
@@ -0,0 +1,227 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/internal/scheduler"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type trashFixture struct {
+	th       *handlers.TrashHandler
+	manga    db.MangaRepository
+	chapters db.ChapterRepository
+	pages    db.PageRepository
+	logger   *slog.Logger
+}
+
+func setupTrash(t *testing.T) *trashFixture {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, mangaRepo := newTestDB(t, logger)
+
+	chapterRepo := sqlite.NewChapterRepository(conn, logger)
+	pageRepo := sqlite.NewPageRepository(conn, logger)
+	return &trashFixture{
+		th:       &handlers.TrashHandler{MangaRepo: mangaRepo, Chapters: chapterRepo, Pages: pageRepo, Logger: logger},
+		manga:    mangaRepo,
+		chapters: chapterRepo,
+		pages:    pageRepo,
+		logger:   logger,
+	}
+}
+
+func (f *trashFixture) createPage(t *testing.T, chapterID int64, number int) *models.Page {
+	path := filepath.Join(t.TempDir(), fmt.Sprintf("%d_%d.png", chapterID, number))
+	if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+		t.Fatalf("Ошибка создания файла: %v", err)
+	}
+	page := &models.Page{ChapterID: chapterID, Number: number, ImagePath: path}
+	id, err := f.pages.Create(page)
+	if err != nil {
+		t.Fatalf("Ошибка создания страницы: %v", err)
+	}
+	page.ID = id
+	return page
+}
+
+func (f *trashFixture) listing(t *testing.T) handlers.TrashListing {
+	resp := httptest.NewRecorder()
+	if err := f.th.List(resp, httptest.NewRequest(http.MethodGet, "/trash", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении корзины: %v", err)
+	}
+	var listing handlers.TrashListing
+	if err := helper.ExtractData(resp.Body, &listing); err != nil {
+		t.Fatalf("Ошибка парсинга корзины: %v", err)
+	}
+	return listing
+}
+
+func (f *trashFixture) restore(kind string, id int64) error {
+	path := fmt.Sprintf("/trash/%s/%d/restore", kind, id)
+	return f.th.Restore(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+}
+
+func TestTrashHandler_DeleteAndRestore(t *testing.T) {
+	f := setupTrash(t)
+	mangaID, _ := f.manga.Create(&models.Manga{Title: "Берсерк"})
+	first, _ := f.chapters.Create(&models.Chapter{MangaID: mangaID, Number: 1, Title: "Глава 1"})
+	second, _ := f.chapters.Create(&models.Chapter{MangaID: mangaID, Number: 2, Title: "Глава 2"})
+	f.createPage(t, first, 1)
+	f.createPage(t, first, 2)
+	loose := f.createPage(t, second, 1)
+
+	if err := f.pages.Delete(loose.ID); err != nil {
+		t.Fatalf("Ошибка удаления страницы: %v", err)
+	}
+	if err := f.chapters.Delete(first); err != nil {
+		t.Fatalf("Ошибка удаления главы: %v", err)
+	}
+	if _, err := f.chapters.GetByID(first); err == nil {
+		t.Error("Удаленная глава не должна находиться")
+	}
+	if chapters, _ := f.chapters.ListByManga(mangaID); len(chapters) != 1 || chapters[0].ID != second {
+		t.Errorf("Удаленная глава не должна попадать в список: %+v", chapters)
+	}
+
+	listing := f.listing(t)
+	if len(listing.Chapters) != 1 || listing.Chapters[0].ID != first || listing.Chapters[0].DeletedAt == nil {
+		t.Errorf("Неожиданные главы в корзине: %+v", listing.Chapters)
+	}
+	// Страницы удаленной главы восстанавливаются вместе с ней.
+	if len(listing.Pages) != 1 || listing.Pages[0].ID != loose.ID {
+		t.Errorf("Неожиданные страницы в корзине: %+v", listing.Pages)
+	}
+
+	if err := f.restore("chapter", first); err != nil {
+		t.Fatalf("Неожиданная ошибка при восстановлении главы: %v", err)
+	}
+	if pages, _ := f.pages.ListByChapter(first); len(pages) != 2 {
+		t.Errorf("Ожидалось 2 восстановленные страницы, получено %d", len(pages))
+	}
+	if err := f.restore("chapter", first); err == nil {
+		t.Error("Ожидалась ошибка при повторном восстановлении")
+	}
+
+	if err := f.manga.Delete(mangaID); err != nil {
+		t.Fatalf("Ошибка удаления манги: %v", err)
+	}
+	listing = f.listing(t)
+	if len(listing.Manga) != 1 || len(listing.Chapters) != 0 || len(listing.Pages) != 0 {
+		t.Errorf("В корзине должна быть только манга: %+v", listing)
+	}
+	if err := f.restore("chapter", second); err == nil {
+		t.Error("Главу удаленной манги нельзя восстановить отдельно")
+	}
+	if err := f.restore("manga", mangaID); err != nil {
+		t.Fatalf("Неожиданная ошибка при восстановлении манги: %v", err)
+	}
+	if chapters, _ := f.chapters.ListByManga(mangaID); len(chapters) != 2 {
+		t.Errorf("Ожидалось 2 восстановленные главы, получено %d", len(chapters))
+	}
+	// Страница, удаленная до манги, остается в корзине.
+	if listing = f.listing(t); len(listing.Pages) != 1 || listing.Pages[0].ID != loose.ID {
+		t.Errorf("Неожиданные страницы в корзине: %+v", listing.Pages)
+	}
+	if err := f.restore("page", loose.ID); err != nil {
+		t.Fatalf("Неожиданная ошибка при восстановлении страницы: %v", err)
+	}
+	if err := f.restore("volume", 1); err == nil {
+		t.Error("Ожидалась ошибка для неизвестного типа записи")
+	}
+}
+
+func TestTrashPurger(t *testing.T) {
+	f := setupTrash(t)
+	mangaID, _ := f.manga.Create(&models.Manga{Title: "Берсерк"})
+	chapterID, _ := f.chapters.Create(&models.Chapter{MangaID: mangaID, Number: 1, Title: "Глава 1"})
+	pages := []*models.Page{f.createPage(t, chapterID, 1), f.createPage(t, chapterID, 2)}
+	if err := f.chapters.Delete(chapterID); err != nil {
+		t.Fatalf("Ошибка удаления главы: %v", err)
+	}
+
+	purger := scheduler.NewTrashPurger(f.manga, f.chapters, f.pages, f.logger)
+	purger.Retention = time.Hour
+	if purged, err := purger.RunOnce(time.Now()); err != nil || purged != 0 {
+		t.Fatalf("До истечения срока ничего не должно удаляться: purged=%d err=%v", purged, err)
+	}
+	if _, err := os.Stat(pages[0].ImagePath); err != nil {
+		t.Fatalf("Файл страницы в корзине должен сохраняться: %v", err)
+	}
+
+	purged, err := purger.RunOnce(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка очистки корзины: %v", err)
+	}
+	if purged != 3 {
+		t.Errorf("Ожидалось удаление 3 записей, удалено %d", purged)
+	}
+	for _, page := range pages {
+		if _, err = os.Stat(page.ImagePath); !os.IsNotExist(err) {
+			t.Errorf("Файл %s должен быть удален", page.ImagePath)
+		}
+	}
+	if listing := f.listing(t); len(listing.Chapters) != 0 || len(listing.Pages) != 0 {
+		t.Errorf("Корзина должна быть пуста: %+v", listing)
+	}
+	if err = f.restore("chapter", chapterID); err == nil {
+		t.Error("Очищенную главу нельзя восстановить")
+	}
+}
+
+func TestTrashPurger_MangaWithChapters(t *testing.T) {
+	f := setupTrash(t)
+	mangaID, _ := f.manga.Create(&models.Manga{Title: "Берсерк"})
+	trashedID, _ := f.chapters.Create(&models.Chapter{MangaID: mangaID, Number: 1, Title: "Глава 1"})
+	pages := []*models.Page{f.createPage(t, trashedID, 1)}
+	if err := f.manga.Delete(mangaID); err != nil {
+		t.Fatalf("Ошибка удаления манги: %v", err)
+	}
+
+	ch := &handlers.ChapterHandler{Repo: f.chapters, Logger: f.logger, MangaRepo: f.manga}
+	body := fmt.Sprintf(`{"manga_id": %d, "number": 2, "title": "Глава 2"}`, mangaID)
+	err := ch.Create(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chapter", strings.NewReader(body)))
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Глава манги из корзины должна отклоняться с 404, получено %v", err)
+	}
+
+	// Глава, добавленная к манге уже в корзине, удаляется вместе с мангой.
+	liveID, _ := f.chapters.Create(&models.Chapter{MangaID: mangaID, Number: 2, Title: "Глава 2"})
+	pages = append(pages, f.createPage(t, liveID, 1))
+
+	purger := scheduler.NewTrashPurger(f.manga, f.chapters, f.pages, f.logger)
+	purger.Retention = time.Hour
+	purged, err := purger.RunOnce(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка очистки корзины: %v", err)
+	}
+	if purged != 5 {
+		t.Errorf("Ожидалось удаление 5 записей, удалено %d", purged)
+	}
+	for _, page := range pages {
+		if _, err = os.Stat(page.ImagePath); !os.IsNotExist(err) {
+			t.Errorf("Файл %s должен быть удален", page.ImagePath)
+		}
+	}
+	if _, err = f.chapters.GetByID(liveID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Глава манги должна быть удалена, получено %v", err)
+	}
+	if err = f.restore("manga", mangaID); err == nil {
+		t.Error("Очищенную мангу нельзя восстановить")
+	}
+}
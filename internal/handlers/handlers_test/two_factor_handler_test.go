@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"bytes"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loginChallenge(t *testing.T, h *handlers.UserHandler, username, password string) handlers.TwoFactorChallenge {
+	body := `{"username": "` + username + `", "password": "` + password + `"}`
+	resp := httptest.NewRecorder()
+	if err := h.Login(resp, httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewBufferString(body))); err != nil {
+		t.Fatalf("Неожиданная ошибка при входе: %v", err)
+	}
+	var challenge handlers.TwoFactorChallenge
+	if err := helper.ExtractData(resp.Body, &challenge); err != nil {
+		t.Fatalf("Ошибка парсинга ответа входа: %v", err)
+	}
+	return challenge
+}
+
+func loginTwoFactor(h *handlers.UserHandler, body string) (*httptest.ResponseRecorder, error) {
+	resp := httptest.NewRecorder()
+	err := h.LoginTwoFactor(resp, httptest.NewRequest(http.MethodPost, "/user/login/2fa", bytes.NewBufferString(body)))
+	return resp, err
+}
+
+func TestUserHandler_TwoFactor(t *testing.T) {
+	current := time.Unix(1_700_000_000, 0)
+	auth.SetClock(func() time.Time { return current })
+	defer auth.SetClock(nil)
+
+	h, _ := newAccountTestHandler(t)
+	h.TOTPIssuer = "Manga Reader"
+	user := registerTestUser(t, h, `{"username": "alice", "password": "secret123"}`)
+
+	resp := httptest.NewRecorder()
+	if err := h.EnrollTwoFactor(resp, asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/enroll", nil), user.ID, models.RoleReader)); err != nil {
+		t.Fatalf("Неожиданная ошибка при подключении 2FA: %v", err)
+	}
+	var enroll handlers.EnrollTwoFactorResponse
+	if err := helper.ExtractData(resp.Body, &enroll); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if !strings.HasPrefix(enroll.OTPAuthURI, "otpauth://totp/Manga%20Reader:alice?") {
+		t.Errorf("Неожиданный otpauth URI: %s", enroll.OTPAuthURI)
+	}
+	if len(enroll.RecoveryCodes) == 0 {
+		t.Fatal("Ожидались коды восстановления")
+	}
+
+	// До подтверждения вход выполняется без второго фактора.
+	if challenge := loginChallenge(t, h, "alice", "secret123"); challenge.TwoFactorRequired {
+		t.Fatal("2FA не должна требоваться до подтверждения")
+	}
+
+	code, _ := auth.TOTPCode(enroll.Secret, current)
+	confirm := asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/confirm",
+		bytes.NewBufferString(`{"code": "`+code+`"}`)), user.ID, models.RoleReader)
+	if err := h.ConfirmTwoFactor(httptest.NewRecorder(), confirm); err != nil {
+		t.Fatalf("Неожиданная ошибка при подтверждении 2FA: %v", err)
+	}
+
+	challenge := loginChallenge(t, h, "alice", "secret123")
+	if !challenge.TwoFactorRequired || challenge.PreAuthToken == "" {
+		t.Fatal("Ожидался запрос второго фактора")
+	}
+
+	if _, err := loginTwoFactor(h, `{"pre_auth_token": "`+challenge.PreAuthToken+`", "code": "000000"}`); err == nil {
+		t.Error("Ожидалась ошибка при неверном коде")
+	}
+
+	current = current.Add(30 * time.Second)
+	code, _ = auth.TOTPCode(enroll.Secret, current)
+	resp, err := loginTwoFactor(h, `{"pre_auth_token": "`+challenge.PreAuthToken+`", "code": "`+code+`"}`)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при вводе кода: %v", err)
+	}
+	var tokens handlers.LoginResponse
+	if err = helper.ExtractData(resp.Body, &tokens); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("Ожидалась пара токенов: %v", err)
+	}
+
+	if _, err = loginTwoFactor(h, `{"pre_auth_token": "`+challenge.PreAuthToken+`", "code": "`+code+`"}`); err == nil {
+		t.Error("Токен предварительной аутентификации должен быть одноразовым")
+	}
+
+	challenge = loginChallenge(t, h, "alice", "secret123")
+	recovery := `{"pre_auth_token": "` + challenge.PreAuthToken + `", "recovery_code": "` + enroll.RecoveryCodes[0] + `"}`
+	if _, err = loginTwoFactor(h, recovery); err != nil {
+		t.Fatalf("Вход по коду восстановления должен работать: %v", err)
+	}
+	challenge = loginChallenge(t, h, "alice", "secret123")
+	recovery = `{"pre_auth_token": "` + challenge.PreAuthToken + `", "recovery_code": "` + enroll.RecoveryCodes[0] + `"}`
+	if _, err = loginTwoFactor(h, recovery); err == nil {
+		t.Error("Код восстановления должен быть одноразовым")
+	}
+
+	disable := asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/disable",
+		bytes.NewBufferString(`{"password": "wrong"}`)), user.ID, models.RoleReader)
+	if err = h.DisableTwoFactor(httptest.NewRecorder(), disable); err == nil {
+		t.Error("Отключение 2FA без верного пароля должно быть запрещено")
+	}
+	disable = asUser(httptest.NewRequest(http.MethodPost, "/user/2fa/disable",
+		bytes.NewBufferString(`{"password": "secret123"}`)), user.ID, models.RoleReader)
+	if err = h.DisableTwoFactor(httptest.NewRecorder(), disable); err != nil {
+		t.Fatalf("Неожиданная ошибка при отключении 2FA: %v", err)
+	}
+	if challenge = loginChallenge(t, h, "alice", "secret123"); challenge.TwoFactorRequired {
+		t.Error("После отключения 2FA второй фактор не должен требоваться")
+	}
+}
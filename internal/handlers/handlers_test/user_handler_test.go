@@ -2,10 +2,11 @@ package handlers_test
 
 import (
 	"bytes"
-	"database/sql"
+	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"io"
 	"log/slog"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/db/sqlite"
 	"manga-reader/internal/handlers"
 	"manga-reader/internal/handlers/handlers_test/helper"
@@ -17,12 +18,10 @@ import (
 )
 
 func setupTestUserRepo(t *testing.T) *sqlite.SQLiteUserRepository {
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Ошибка открытия in-memory базы: %v", err)
-	}
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	repo := sqlite.NewSQLiteUserRepository(db, logger)
+	conn, _ := newTestDB(t, logger)
+	repo := sqlite.NewSQLiteUserRepository(conn, logger)
+	auth.SetJWTSecret("test-secret")
 	return repo
 }
 
@@ -59,6 +58,9 @@ func TestUserHandler_RegisterAndLogin(t *testing.T) {
 	if registeredUser.ID == 0 {
 		t.Error("Ожидался валидный ID, получен 0")
 	}
+	if registeredUser.Role != models.RoleReader {
+		t.Errorf("Ожидалась роль %q, получена %q", models.RoleReader, registeredUser.Role)
+	}
 
 	// Тест логина
 	loginBody := `{"username": "testuser", "password": "secret123"}`
@@ -85,3 +87,88 @@ func TestUserHandler_RegisterAndLogin(t *testing.T) {
 		t.Error("Ожидался непустой токен")
 	}
 }
+
+func TestUserHandler_UpdateRole(t *testing.T) {
+	userRepo := setupTestUserRepo(t)
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	userHandler := &handlers.UserHandler{
+		UserRepo: userRepo,
+		Logger:   testLogger,
+	}
+
+	id, err := userRepo.Create(&models.User{Username: "uploader", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Ошибка создания пользователя: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"user_id": %d, "role": "uploader"}`, id)
+	req := httptest.NewRequest(http.MethodPut, "/user/role", bytes.NewBufferString(body))
+	resp := httptest.NewRecorder()
+
+	if err = userHandler.UpdateRole(resp, req); err != nil {
+		t.Fatalf("Неожиданная ошибка при смене роли: %v", err)
+	}
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, resp.Code)
+	}
+
+	user, err := userRepo.GetByID(id)
+	if err != nil {
+		t.Fatalf("Ошибка получения пользователя: %v", err)
+	}
+	if user.Role != models.RoleUploader {
+		t.Errorf("Ожидалась роль %q, получена %q", models.RoleUploader, user.Role)
+	}
+
+	badReq := httptest.NewRequest(http.MethodPut, "/user/role", bytes.NewBufferString(`{"user_id": 1, "role": "god"}`))
+	if err = userHandler.UpdateRole(httptest.NewRecorder(), badReq); err == nil {
+		t.Error("Ожидалась ошибка валидации для неизвестной роли")
+	}
+}
+
+func TestUserHandler_Refresh(t *testing.T) {
+	userRepo := setupTestUserRepo(t)
+	testLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	userHandler := &handlers.UserHandler{
+		UserRepo: userRepo,
+		Logger:   testLogger,
+	}
+
+	regReq := httptest.NewRequest(http.MethodPost, "/user/register", bytes.NewBufferString(`{"username": "reader", "password": "secret123"}`))
+	if err := userHandler.Register(httptest.NewRecorder(), regReq); err != nil {
+		t.Fatalf("Неожиданная ошибка при регистрации: %v", err)
+	}
+
+	loginReq := httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewBufferString(`{"username": "reader", "password": "secret123"}`))
+	loginResp := httptest.NewRecorder()
+	if err := userHandler.Login(loginResp, loginReq); err != nil {
+		t.Fatalf("Неожиданная ошибка при логине: %v", err)
+	}
+
+	var tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := helper.ExtractData(loginResp.Body, &tokens); err != nil {
+		t.Fatalf("Ошибка парсинга ответа логина: %v", err)
+	}
+	if tokens.RefreshToken == "" {
+		t.Fatal("Ожидался непустой токен обновления")
+	}
+
+	refreshBody := fmt.Sprintf(`{"refresh_token": %q}`, tokens.RefreshToken)
+	refreshResp := httptest.NewRecorder()
+	err := userHandler.Refresh(refreshResp, httptest.NewRequest(http.MethodPost, "/user/refresh", bytes.NewBufferString(refreshBody)))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка при обновлении токена: %v", err)
+	}
+	if refreshResp.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус %d, получен %d", http.StatusOK, refreshResp.Code)
+	}
+
+	// Токен обновления одноразовый
+	err = userHandler.Refresh(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/refresh", bytes.NewBufferString(refreshBody)))
+	if err == nil {
+		t.Error("Ожидалась ошибка при повторном использовании токена обновления")
+	}
+}
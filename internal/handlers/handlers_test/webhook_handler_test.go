@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/internal/handlers"
+	"manga-reader/internal/handlers/handlers_test/helper"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupWebhookHandler(t *testing.T) *handlers.WebhookHandler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, _ := newTestDB(t, logger)
+	return &handlers.WebhookHandler{Repo: sqlite.NewWebhookRepository(conn, logger), Logger: logger}
+}
+
+func TestWebhookHandler_UnknownEventListsAllowed(t *testing.T) {
+	h := setupWebhookHandler(t)
+
+	err := h.Create(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks",
+		bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["manga.exploded"]}`)))
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Ожидалась ошибка валидации, получено %v", err)
+	}
+	details, _ := appErr.Details.(map[string]string)
+	for _, event := range models.WebhookEvents {
+		if !strings.Contains(details["events"], string(event)) {
+			t.Errorf("Список допустимых событий должен содержать %s: %q", event, details["events"])
+		}
+	}
+}
+
+func TestWebhookHandler_CRUD(t *testing.T) {
+	h := setupWebhookHandler(t)
+
+	for _, body := range []string{
+		`{"url": "ftp://example.com/hook"}`,
+		`{"url": "https://example.com/hook", "events": ["manga.exploded"]}`,
+		`{"events": ["manga.created"]}`,
+	} {
+		if err := h.Create(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(body))); err == nil {
+			t.Errorf("Ожидалась ошибка валидации для %s", body)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	create := httptest.NewRequest(http.MethodPost, "/webhooks",
+		bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["chapter.created"]}`))
+	if err := h.Create(resp, create); err != nil {
+		t.Fatalf("Неожиданная ошибка при создании вебхука: %v", err)
+	}
+	var created handlers.WebhookSecretResponse
+	if err := helper.ExtractData(resp.Body, &created); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if created.Webhook == nil || created.ID == 0 || created.Secret == "" || !created.Active {
+		t.Fatalf("Ожидался активный вебхук со сгенерированным секретом, получено %+v", created)
+	}
+
+	path := fmt.Sprintf("/webhooks/%d", created.ID)
+	resp = httptest.NewRecorder()
+	update := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(`{"active": false, "events": []}`))
+	if err := h.Update(resp, update); err != nil {
+		t.Fatalf("Неожиданная ошибка при обновлении вебхука: %v", err)
+	}
+	var updated map[string]interface{}
+	if err := helper.ExtractData(resp.Body, &updated); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if updated["active"] != false || len(updated["events"].([]interface{})) != 0 {
+		t.Errorf("Изменения не применены: %v", updated)
+	}
+	if _, ok := updated["secret"]; ok {
+		t.Error("Секрет не должен возвращаться без его смены")
+	}
+
+	hook, err := h.Repo.GetByID(created.ID)
+	if err != nil {
+		t.Fatalf("Ошибка получения вебхука: %v", err)
+	}
+	if hook.Secret != created.Secret || hook.Matches(models.EventChapterCreated) {
+		t.Errorf("Неожиданное состояние вебхука: %+v", hook)
+	}
+
+	if err = h.Repo.LogDelivery(&models.WebhookDelivery{WebhookID: created.ID, EventID: "e1", Event: models.EventChapterCreated, Attempt: 1}); err != nil {
+		t.Fatalf("Ошибка записи журнала: %v", err)
+	}
+	resp = httptest.NewRecorder()
+	if err = h.Deliveries(resp, httptest.NewRequest(http.MethodGet, path+"/deliveries", nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при получении журнала: %v", err)
+	}
+	var deliveries []models.WebhookDelivery
+	if err = helper.ExtractData(resp.Body, &deliveries); err != nil {
+		t.Fatalf("Ошибка парсинга ответа: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].EventID != "e1" {
+		t.Errorf("Ожидалась одна запись журнала, получено %+v", deliveries)
+	}
+
+	if err = h.Delete(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, path, nil)); err != nil {
+		t.Fatalf("Неожиданная ошибка при удалении вебхука: %v", err)
+	}
+	if err = h.Get(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)); err == nil {
+		t.Error("Удаленный вебхук не должен находиться")
+	}
+}
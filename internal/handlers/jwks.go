@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"manga-reader/internal/auth"
+	"manga-reader/internal/response"
+	"net/http"
+)
+
+// JWKSHandler отдает открытые ключи проверки токенов в формате JWKS.
+// Ответ не оборачивается в SuccessResponse, так как формат задан RFC 7517.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	keySet := auth.CurrentKeySet()
+	if keySet == nil {
+		response.JSON(w, http.StatusOK, auth.JWKS{Keys: []auth.JWK{}})
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, keySet.JWKS())
+}
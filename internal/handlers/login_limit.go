@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+)
+
+// checkLoginLimit возвращает 429, если имя пользователя или IP-адрес
+// временно заблокированы. Ошибки хранилища не блокируют вход.
+func (h *UserHandler) checkLoginLimit(r *http.Request, username, ip string) error {
+	if h.Limiter == nil {
+		return nil
+	}
+	retryAfter, err := h.Limiter.Check(r.Context(), username, ip)
+	if err != nil {
+		h.Logger.Error("Ошибка проверки блокировки входа", "username", username, "ip", ip, "err", err)
+		return nil
+	}
+	if retryAfter > 0 {
+		return apperror.NewTooManyRequestsError("Слишком много неудачных попыток входа, повторите позже", retryAfter)
+	}
+	return nil
+}
+
+func (h *UserHandler) registerLoginFailure(r *http.Request, username, ip string) {
+	if h.Limiter == nil {
+		return
+	}
+	lockout, err := h.Limiter.RegisterFailure(r.Context(), username, ip)
+	if err != nil {
+		h.Logger.Error("Ошибка учета неудачного входа", "username", username, "ip", ip, "err", err)
+		return
+	}
+	if lockout > 0 {
+		h.Logger.Warn("Вход временно заблокирован", "username", username, "ip", ip, "lockout", lockout)
+	}
+}
+
+func (h *UserHandler) registerLoginSuccess(r *http.Request, username string) {
+	if h.Limiter == nil {
+		return
+	}
+	if err := h.Limiter.RegisterSuccess(r.Context(), username); err != nil {
+		h.Logger.Error("Ошибка сброса счетчика неудачных входов", "username", username, "err", err)
+	}
+}
+
+type UnlockAccountRequest struct {
+	Username string `json:"username"`
+}
+
+// UnlockAccount снимает блокировку входа с учетной записи (только для администраторов).
+func (h *UserHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) error {
+	var req UnlockAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.Username == "" {
+		return apperror.NewValidationError("Поле username не может быть пустым",
+			map[string]string{"username": "Это поле обязательно"})
+	}
+
+	if h.Limiter != nil {
+		if err := h.Limiter.Unlock(r.Context(), req.Username); err != nil {
+			return apperror.NewInternalServerError("Не удалось снять блокировку", err)
+		}
+	}
+	// Блокировка ведется и для несуществующих имен; в журнал попадают
+	// только учетные записи.
+	if user, err := h.UserRepo.GetByUsername(req.Username); err == nil {
+		h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID,
+			map[string]bool{"locked": true}, map[string]bool{"locked": false})
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Блокировка снята"})
+	return nil
+}
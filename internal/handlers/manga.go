@@ -6,11 +6,14 @@ import (
 	"log/slog"
 	"manga-reader/internal/analytics"
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
 	"manga-reader/internal/cache"
 	"manga-reader/internal/db"
 	"manga-reader/internal/response"
+	"manga-reader/internal/webhook"
 	"manga-reader/models"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,9 +24,37 @@ type MangaHandler struct {
 	Logger    *slog.Logger
 	Cache     cache.Cache
 	Analytics *analytics.AnalyticsService
+	Webhooks  *webhook.Dispatcher
+	Audit     *audit.Recorder
 }
 
+// List возвращает список манги. С параметром sort=updated манга
+// сортируется от недавно обновленной к давно не обновлявшейся.
 func (h *MangaHandler) List(w http.ResponseWriter, r *http.Request) error {
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "updated" {
+		return apperror.NewValidationError("Некорректный параметр sort",
+			map[string]string{"sort": "Допустимое значение: updated"})
+	}
+
+	mangas, err := h.listAll(r)
+	if err != nil {
+		return err
+	}
+	if sortBy == "updated" {
+		sort.SliceStable(mangas, func(i, j int) bool {
+			if !mangas[i].UpdatedAt.Equal(mangas[j].UpdatedAt) {
+				return mangas[i].UpdatedAt.After(mangas[j].UpdatedAt)
+			}
+			return mangas[i].ID > mangas[j].ID
+		})
+	}
+
+	response.Success(w, http.StatusOK, mangas)
+	return nil
+}
+
+func (h *MangaHandler) listAll(r *http.Request) ([]*models.Manga, error) {
 	cacheKey := "manga:list"
 	cachedDate, err := h.Cache.Get(r.Context(), cacheKey)
 	if err == nil && cachedDate != "" {
@@ -33,14 +64,13 @@ func (h *MangaHandler) List(w http.ResponseWriter, r *http.Request) error {
 		if err := json.Unmarshal([]byte(cachedDate), &mangas); err != nil {
 			h.Logger.Error("Ошибка десериализации из кеша", "err", err)
 		} else {
-			response.Success(w, http.StatusOK, mangas)
-			return nil
+			return mangas, nil
 		}
 	}
 	h.Logger.Info("Cache miss", "key", cacheKey)
 	mangas, err := h.Repo.List()
 	if err != nil {
-		return apperror.NewDatabaseError("Ошибка получения списка манги", err)
+		return nil, apperror.NewDatabaseError("Ошибка получения списка манги", err)
 	}
 
 	if h.Cache != nil {
@@ -53,9 +83,7 @@ func (h *MangaHandler) List(w http.ResponseWriter, r *http.Request) error {
 			h.Logger.Error("Ошибка сериализации для кеша", "err", err)
 		}
 	}
-
-	response.Success(w, http.StatusOK, mangas)
-	return nil
+	return mangas, nil
 }
 
 func (h *MangaHandler) Create(w http.ResponseWriter, r *http.Request) error {
@@ -75,6 +103,8 @@ func (h *MangaHandler) Create(w http.ResponseWriter, r *http.Request) error {
 	m.ID = id
 
 	_ = h.Cache.Delete(r.Context(), "manga:list")
+	h.Webhooks.Emit(models.EventMangaCreated, m)
+	h.Audit.Record(r, models.AuditCreate, models.AuditManga, m.ID, nil, m)
 
 	response.Success(w, http.StatusCreated, m)
 	return nil
@@ -119,9 +149,9 @@ func (h *MangaHandler) Detail(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	var views int64 = 0
+	var views, uniqueViewers int64
 	if h.Analytics != nil {
-		if err = h.Analytics.RecordMangaView(r.Context(), id); err != nil {
+		if err = h.Analytics.RecordMangaView(r.Context(), id, viewerID(h.Analytics, r)); err != nil {
 			h.Logger.Error("Ошибка записи просмотра манги", "err", err, "manga_id", id)
 		}
 
@@ -129,22 +159,31 @@ func (h *MangaHandler) Detail(w http.ResponseWriter, r *http.Request) error {
 		if err != nil {
 			h.Logger.Error("Ошибка получения счетчика просмотров", "err", err, "manga_id", id)
 		}
+		if uniqueViewers, err = h.Analytics.GetMangaUniqueViewers(r.Context(), id); err != nil {
+			h.Logger.Error("Ошибка получения числа посетителей манги", "err", err, "manga_id", id)
+		}
 	}
 
 	mangaWithViews := analytics.MangaWithViews{
-		ID:          manga.ID,
-		Title:       manga.Title,
-		Description: manga.Description,
-		Views:       views,
+		ID:            manga.ID,
+		Title:         manga.Title,
+		Description:   manga.Description,
+		CreatedAt:     manga.CreatedAt,
+		UpdatedAt:     manga.UpdatedAt,
+		Views:         views,
+		UniqueViewers: uniqueViewers,
 	}
 
 	response.Success(w, http.StatusOK, mangaWithViews)
 	return nil
 }
 
+// GetPopular возвращает рейтинг манги за период period; параметр
+// by=unique строит его по уникальным посетителям вместо просмотров.
 func (h *MangaHandler) GetPopular(w http.ResponseWriter, r *http.Request) error {
 	period := r.URL.Query().Get("period")
 	limitStr := r.URL.Query().Get("limit")
+	metric := analytics.ParseMetric(r.URL.Query().Get("by"))
 
 	if period == "" {
 		period = "all"
@@ -157,7 +196,7 @@ func (h *MangaHandler) GetPopular(w http.ResponseWriter, r *http.Request) error
 		}
 	}
 
-	cacheKey := fmt.Sprintf("manga:popular:%s:%d", period, limit)
+	cacheKey := fmt.Sprintf("manga:popular:%s:%s:%d", period, metric, limit)
 	cachedDate, err := h.Cache.Get(r.Context(), cacheKey)
 	if err == nil && cachedDate != "" {
 		h.Logger.Info("Cache hit for popular manga", "period", period, "limit", limit)
@@ -171,11 +210,11 @@ func (h *MangaHandler) GetPopular(w http.ResponseWriter, r *http.Request) error
 		}
 	}
 
-	if h.Analytics != nil {
+	if h.Analytics == nil {
 		return apperror.NewInternalServerError("Сервис аналитики недоступен", nil)
 	}
 
-	topEntries, err := h.Analytics.GetTopManga(r.Context(), period, limit)
+	topEntries, err := h.Analytics.GetTopManga(r.Context(), period, metric, limit)
 	if err != nil {
 		return apperror.NewInternalServerError("Ошибка получения рейтинга манги", err)
 	}
@@ -188,22 +227,29 @@ func (h *MangaHandler) GetPopular(w http.ResponseWriter, r *http.Request) error
 			continue
 		}
 		result = append(result, analytics.MangaWithViews{
-			ID:          manga.ID,
-			Title:       manga.Title,
-			Description: manga.Description,
-			Views:       entry.Views,
+			ID:            manga.ID,
+			Title:         manga.Title,
+			Description:   manga.Description,
+			CreatedAt:     manga.CreatedAt,
+			UpdatedAt:     manga.UpdatedAt,
+			Views:         entry.Views,
+			UniqueViewers: entry.UniqueViewers,
+			Score:         entry.Score,
+			Previous:      entry.Previous,
 		})
 	}
 
 	jsonData, err := json.Marshal(result)
 	if err == nil {
 		var ttl time.Duration
-		switch period {
-		case "daily":
+		switch analytics.ParsePeriod(period) {
+		case analytics.PeriodTrending, analytics.PeriodRising:
+			ttl = 15 * time.Minute
+		case analytics.PeriodDay:
 			ttl = 1 * time.Hour
-		case "weekly":
+		case analytics.PeriodWeek:
 			ttl = 4 * time.Hour
-		case "monthly":
+		case analytics.PeriodMonth:
 			ttl = 12 * time.Hour
 		default:
 			ttl = 24 * time.Hour
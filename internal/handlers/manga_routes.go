@@ -2,27 +2,37 @@ package handlers
 
 import (
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/middleware"
+	"manga-reader/models"
 	"net/http"
 	"strings"
 )
 
-func RegisterMangaRoutes(mux *http.ServeMux, mh *MangaHandler, ch *ChapterHandler) {
+func RegisterMangaRoutes(mux *http.ServeMux, mh *MangaHandler, ch *ChapterHandler, fh *FeedHandler) {
+	createManga := auth.RequireScope(models.RoleUploader, models.ScopeWriteManga, middleware.ErrorHandler(mh.Logger, mh.Create))
+	listManga := auth.OptionalAuth(middleware.ErrorHandler(mh.Logger, mh.List))
+
 	mux.HandleFunc("/manga", middleware.ErrorHandler(mh.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		switch r.Method {
 		case http.MethodGet:
-			return mh.List(w, r)
+			listManga.ServeHTTP(w, r)
+			return nil
 		case http.MethodPost:
-			return mh.Create(w, r)
+			createManga.ServeHTTP(w, r)
+			return nil
 		default:
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
 	}))
 
-	mux.HandleFunc("/manga/", middleware.ErrorHandler(mh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/manga/", auth.OptionalAuth(middleware.ErrorHandler(mh.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if strings.HasSuffix(r.URL.Path, "/chapters") {
 			return ch.ListByManga(w, r)
 		}
+		if strings.HasSuffix(r.URL.Path, "/feed.atom") || strings.HasSuffix(r.URL.Path, "/feed.rss") {
+			return fh.MangaFeed(w, r)
+		}
 		return mh.Detail(w, r)
-	}))
+	})))
 }
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// NotificationHandler управляет подписками на мангу, входящими
+// уведомлениями и настройками уведомлений пользователя.
+type NotificationHandler struct {
+	Repo      db.NotificationRepository
+	Follows   db.FollowRepository
+	MangaRepo db.MangaRepository
+	Logger    *slog.Logger
+}
+
+// parsePagination читает параметры limit и offset запроса.
+func parsePagination(r *http.Request) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	q := r.URL.Query()
+	if s := q.Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			return 0, 0, apperror.NewValidationError("Некорректный параметр limit",
+				map[string]string{"limit": fmt.Sprintf("Ожидается число от 1 до %d", maxPageLimit)})
+		}
+	}
+	if s := q.Get("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return 0, 0, apperror.NewValidationError("Некорректный параметр offset",
+				map[string]string{"offset": "Ожидается неотрицательное число"})
+		}
+	}
+	return limit, offset, nil
+}
+
+func (h *NotificationHandler) mangaIDFromPath(r *http.Request) (int64, error) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/user/follows/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, apperror.NewBadRequestError("Некорректный ID манги", err)
+	}
+	return id, nil
+}
+
+func (h *NotificationHandler) Follow(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+	mangaID, err := h.mangaIDFromPath(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err = h.MangaRepo.GetByID(mangaID); err != nil {
+		return apperror.NewNotFoundError("Манга не найдена", err)
+	}
+	if err = h.Follows.Follow(userID, mangaID); err != nil {
+		return apperror.NewDatabaseError("Не удалось подписаться на мангу", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{"manga_id": mangaID, "following": true})
+	return nil
+}
+
+func (h *NotificationHandler) Unfollow(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+	mangaID, err := h.mangaIDFromPath(r)
+	if err != nil {
+		return err
+	}
+
+	if err = h.Follows.Unfollow(userID, mangaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("Подписка не найдена", err)
+		}
+		return apperror.NewDatabaseError("Не удалось отменить подписку", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{"manga_id": mangaID, "following": false})
+	return nil
+}
+
+func (h *NotificationHandler) FollowStatus(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+	mangaID, err := h.mangaIDFromPath(r)
+	if err != nil {
+		return err
+	}
+
+	following, err := h.Follows.IsFollowing(userID, mangaID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось проверить подписку", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{"manga_id": mangaID, "following": following})
+	return nil
+}
+
+func (h *NotificationHandler) ListFollows(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	follows, err := h.Follows.ListByUser(userID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить список подписок", err)
+	}
+
+	response.Success(w, http.StatusOK, follows)
+	return nil
+}
+
+// NotificationList — страница входящих уведомлений.
+type NotificationList struct {
+	Items   []*models.Notification `json:"items"`
+	Unread  int64                  `json:"unread"`
+	Limit   int                    `json:"limit"`
+	Offset  int                    `json:"offset"`
+	HasMore bool                   `json:"has_more"`
+}
+
+// List возвращает уведомления от новых к старым. Параметр unread=true
+// оставляет только непрочитанные.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	// Запрашиваем на одну запись больше, чтобы узнать о следующей странице
+	// без отдельного COUNT.
+	items, err := h.Repo.ListByUser(userID, unreadOnly, limit+1, offset)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить уведомления", err)
+	}
+	unread, err := h.Repo.CountUnread(userID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить уведомления", err)
+	}
+
+	list := NotificationList{Items: items, Unread: unread, Limit: limit, Offset: offset}
+	if len(items) > limit {
+		list.Items = items[:limit]
+		list.HasMore = true
+	}
+
+	response.Success(w, http.StatusOK, list)
+	return nil
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/user/notifications/"), "/read")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID уведомления", err)
+	}
+
+	if err = h.Repo.MarkRead(id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("Уведомление не найдено", err)
+		}
+		return apperror.NewDatabaseError("Не удалось отметить уведомление", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Уведомление прочитано"})
+	return nil
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	updated, err := h.Repo.MarkAllRead(userID)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось отметить уведомления", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{"status": "success", "updated": updated})
+	return nil
+}
+
+func (h *NotificationHandler) preferences(userID int64) (map[models.NotificationEvent]bool, error) {
+	stored, err := h.Repo.GetPreferences(userID)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Не удалось получить настройки уведомлений", err)
+	}
+	prefs := make(map[models.NotificationEvent]bool, len(models.NotificationEvents))
+	for _, event := range models.NotificationEvents {
+		enabled, ok := stored[event]
+		prefs[event] = !ok || enabled
+	}
+	return prefs, nil
+}
+
+// GetPreferences возвращает настройки по всем известным событиям.
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	prefs, err := h.preferences(userID)
+	if err != nil {
+		return err
+	}
+
+	response.Success(w, http.StatusOK, prefs)
+	return nil
+}
+
+// UpdatePreferences принимает объект вида {"new_chapter": false}.
+// Не указанные события не меняются.
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req map[models.NotificationEvent]bool
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	for event := range req {
+		if !event.Valid() {
+			return apperror.NewValidationError("Неизвестное событие "+string(event),
+				map[string]string{string(event): "Неизвестное событие"})
+		}
+	}
+	for event, enabled := range req {
+		if err = h.Repo.SetPreference(userID, event, enabled); err != nil {
+			return apperror.NewDatabaseError("Не удалось сохранить настройки уведомлений", err)
+		}
+	}
+
+	prefs, err := h.preferences(userID)
+	if err != nil {
+		return err
+	}
+
+	response.Success(w, http.StatusOK, prefs)
+	return nil
+}
+
+// notifyNewChapter рассылает подписчикам уведомление о новой главе. Ошибка
+// рассылки не отменяет создание главы и только логируется.
+func notifyNewChapter(repo db.NotificationRepository, logger *slog.Logger, ch *models.Chapter) {
+	if repo == nil {
+		return
+	}
+	n := &models.Notification{
+		Event:     models.EventNewChapter,
+		MangaID:   ch.MangaID,
+		ChapterID: ch.ID,
+		Message:   fmt.Sprintf("Вышла глава %d: %s", ch.Number, ch.Title),
+	}
+	sent, err := repo.NotifyFollowers(n)
+	if err != nil {
+		logger.Error("Ошибка рассылки уведомлений о новой главе", "chapter_id", ch.ID, "err", err)
+		return
+	}
+	if sent > 0 {
+		logger.Info("Разосланы уведомления о новой главе", "chapter_id", ch.ID, "recipients", sent)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"net/http"
+	"strings"
+)
+
+func RegisterNotificationRoutes(mux *http.ServeMux, nh *NotificationHandler) {
+	mux.Handle("/user/follows", auth.RequireSession(middleware.ErrorHandler(nh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return nh.ListFollows(w, r)
+	})))
+	mux.Handle("/user/follows/", auth.RequireSession(middleware.ErrorHandler(nh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return nh.FollowStatus(w, r)
+		case http.MethodPost, http.MethodPut:
+			return nh.Follow(w, r)
+		case http.MethodDelete:
+			return nh.Unfollow(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+	mux.Handle("/user/notifications", auth.RequireSession(middleware.ErrorHandler(nh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return nh.List(w, r)
+	})))
+	mux.Handle("/user/notifications/", auth.RequireSession(middleware.ErrorHandler(nh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch {
+		case r.URL.Path == "/user/notifications/preferences":
+			switch r.Method {
+			case http.MethodGet:
+				return nh.GetPreferences(w, r)
+			case http.MethodPut:
+				return nh.UpdatePreferences(w, r)
+			}
+		case r.URL.Path == "/user/notifications/read-all":
+			if r.Method == http.MethodPost {
+				return nh.MarkAllRead(w, r)
+			}
+		case strings.HasSuffix(r.URL.Path, "/read"):
+			if r.Method == http.MethodPost {
+				return nh.MarkRead(w, r)
+			}
+		default:
+			return apperror.NewNotFoundError("Ресурс не найден", nil)
+		}
+		return apperror.NewBadRequestError("Метод не поддерживается", nil)
+	})))
+}
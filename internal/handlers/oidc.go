@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db"
+	"manga-reader/internal/oidc"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	oidcStatePrefix = "oidc:state:"
+	oidcStateTTL    = 10 * time.Minute
+	// oidcStateCookie привязывает state к браузеру, начавшему вход: без
+	// нее ссылку на провайдера можно было бы подсунуть другому человеку и
+	// привязать его внешнюю учетную запись к чужому аккаунту.
+	oidcStateCookie = "oidc_state"
+)
+
+// OIDCHandler реализует вход через внешнего провайдера OpenID Connect.
+// Второй фактор проверяет сам провайдер, поэтому локальная 2FA при таком
+// входе не запрашивается.
+type OIDCHandler struct {
+	Provider *oidc.Provider
+	UserRepo db.UserRepository
+	// Store хранит state, nonce и code_verifier между перенаправлениями.
+	Store  cache.Cache
+	Logger *slog.Logger
+	Audit  *audit.Recorder
+	// SecureCookie выставляет флаг Secure у cookie со state; включается,
+	// когда приложение доступно по HTTPS.
+	SecureCookie bool
+}
+
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserID — пользователь, запросивший привязку внешней учетной
+	// записи; 0 для обычного входа.
+	LinkUserID int64 `json:"link_user_id,omitempty"`
+}
+
+// Login перенаправляет пользователя на страницу входа провайдера.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) error {
+	authURL, err := h.begin(w, r, 0)
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+	return nil
+}
+
+// StartLink начинает привязку внешней учетной записи к текущему
+// пользователю: POST /auth/oidc/link возвращает адрес провайдера, на
+// который клиент должен перейти в том же браузере.
+func (h *OIDCHandler) StartLink(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+	authURL, err := h.begin(w, r, userID)
+	if err != nil {
+		return err
+	}
+	response.Success(w, http.StatusOK, map[string]string{"url": authURL})
+	return nil
+}
+
+// begin сохраняет state, nonce и code_verifier, выставляет cookie со state
+// и возвращает адрес страницы входа провайдера.
+func (h *OIDCHandler) begin(w http.ResponseWriter, r *http.Request, linkUserID int64) (string, error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", apperror.NewInternalServerError("Не удалось начать вход", err)
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", apperror.NewInternalServerError("Не удалось начать вход", err)
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", apperror.NewInternalServerError("Не удалось начать вход", err)
+	}
+
+	data, err := json.Marshal(oidcState{Nonce: nonce, Verifier: verifier, LinkUserID: linkUserID})
+	if err != nil {
+		return "", apperror.NewInternalServerError("Не удалось начать вход", err)
+	}
+	if err = h.Store.Set(r.Context(), oidcStatePrefix+state, string(data), oidcStateTTL); err != nil {
+		return "", apperror.NewInternalServerError("Не удалось сохранить состояние входа", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return h.Provider.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// Callback принимает код авторизации, находит или создает пользователя и
+// выдает собственные токены приложения.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	if errCode := q.Get("error"); errCode != "" {
+		return apperror.NewUnauthorizedError("Провайдер отклонил вход: "+errCode, nil)
+	}
+	code, stateID := q.Get("code"), q.Get("state")
+	if code == "" || stateID == "" {
+		return apperror.NewBadRequestError("Отсутствуют параметры code или state", nil)
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateID)) != 1 {
+		return apperror.NewBadRequestError("Вход начат в другом браузере", nil)
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/", MaxAge: -1})
+
+	// state одноразовый: повторный callback с тем же кодом отклоняется.
+	data, err := h.Store.GetDel(r.Context(), oidcStatePrefix+stateID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return apperror.NewBadRequestError("Недействительный или просроченный state", nil)
+		}
+		return apperror.NewInternalServerError("Ошибка проверки состояния входа", err)
+	}
+	var state oidcState
+	if err = json.Unmarshal([]byte(data), &state); err != nil {
+		return apperror.NewBadRequestError("Недействительный state", err)
+	}
+
+	claims, err := h.Provider.Exchange(r.Context(), code, state.Verifier, state.Nonce)
+	if err != nil {
+		h.Logger.Warn("Ошибка входа через OIDC", "err", err)
+		return apperror.NewUnauthorizedError("Не удалось выполнить вход через провайдера", err)
+	}
+
+	var user *models.User
+	if state.LinkUserID != 0 {
+		user, err = h.linkUser(r, state.LinkUserID, claims)
+	} else {
+		user, err = h.resolveUser(r, claims)
+	}
+	if err != nil {
+		return err
+	}
+
+	tokens, err := auth.IssueTokens(r.Context(), user.ID, user.Role)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось сгенерировать токен", err)
+	}
+
+	response.Success(w, http.StatusOK, tokens)
+	return nil
+}
+
+// resolveUser находит пользователя по привязке или создает нового. Локальные
+// учетные записи не привязываются автоматически даже при совпадении email:
+// email при регистрации не подтверждается, и чужой адрес в своем профиле
+// позволил бы получить роль сотрудника при его входе через провайдера.
+// Существующую учетную запись нужно привязать явно через StartLink.
+func (h *OIDCHandler) resolveUser(r *http.Request, claims *oidc.Claims) (*models.User, error) {
+	user, err := h.UserRepo.GetByIdentity(claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NewDatabaseError("Ошибка поиска пользователя", err)
+	}
+	if user == nil {
+		return h.provisionUser(r, claims)
+	}
+	return h.syncRole(r, user, claims)
+}
+
+// linkUser привязывает внешнюю учетную запись к пользователю userID,
+// начавшему привязку, и синхронизирует его роль.
+func (h *OIDCHandler) linkUser(r *http.Request, userID int64, claims *oidc.Claims) (*models.User, error) {
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return nil, apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+
+	linked, err := h.UserRepo.GetByIdentity(claims.Issuer, claims.Subject)
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, apperror.NewDatabaseError("Ошибка поиска пользователя", err)
+	case linked != nil && linked.ID != user.ID:
+		return nil, apperror.NewConflictError("Внешняя учетная запись уже привязана к другому пользователю", nil)
+	case linked == nil:
+		if err = h.UserRepo.LinkIdentity(user.ID, claims.Issuer, claims.Subject); err != nil {
+			return nil, apperror.NewDatabaseError("Не удалось привязать учетную запись", err)
+		}
+		h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID,
+			map[string]string{}, map[string]string{"oidc_identity": claims.Issuer})
+		h.Logger.Info("Внешняя учетная запись привязана", "user_id", user.ID, "issuer", claims.Issuer)
+	}
+	return h.syncRole(r, user, claims)
+}
+
+// syncRole приводит роль пользователя к сопоставленной группам провайдера,
+// если такое сопоставление есть, и записывает изменение в журнал аудита.
+func (h *OIDCHandler) syncRole(r *http.Request, user *models.User, claims *oidc.Claims) (*models.User, error) {
+	mappedRole, hasRole := h.Provider.MapRole(claims)
+	if !hasRole || user.Role == mappedRole {
+		return user, nil
+	}
+	if err := h.UserRepo.UpdateRole(user.ID, mappedRole); err != nil {
+		return nil, apperror.NewDatabaseError("Не удалось обновить роль пользователя", err)
+	}
+	before := *user
+	user.Role = mappedRole
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID, before, *user)
+	h.Logger.Info("Роль пользователя обновлена по данным провайдера", "user_id", user.ID, "role", mappedRole)
+	return user, nil
+}
+
+func (h *OIDCHandler) provisionUser(r *http.Request, claims *oidc.Claims) (*models.User, error) {
+	role, hasRole := h.Provider.MapRole(claims)
+	if !hasRole {
+		role = models.RoleReader
+	}
+	username, err := h.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пароль случайный: вход по паролю возможен только после его сброса.
+	secret, err := oidc.RandomString()
+	if err != nil {
+		return nil, apperror.NewInternalServerError("Не удалось создать пользователя", err)
+	}
+	hashed, err := hashPassword(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: username, Password: hashed, Role: role}
+	if claims.EmailVerified && claims.Email != "" {
+		if _, err = h.UserRepo.GetByEmail(claims.Email); err == nil {
+			return nil, apperror.NewConflictError("Учетная запись с таким email уже существует: "+
+				"войдите в нее и привяжите провайдера через /auth/oidc/link", nil)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NewDatabaseError("Ошибка поиска пользователя", err)
+		}
+		user.Email = claims.Email
+	}
+	id, err := h.UserRepo.Create(user)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Не удалось создать пользователя", err)
+	}
+	user.ID = id
+	if err = h.UserRepo.LinkIdentity(user.ID, claims.Issuer, claims.Subject); err != nil {
+		return nil, apperror.NewDatabaseError("Не удалось привязать учетную запись", err)
+	}
+
+	h.Audit.Record(r, models.AuditCreate, models.AuditUser, user.ID, nil, user)
+	h.Logger.Info("Создан пользователь по данным провайдера", "user_id", user.ID, "username", username, "role", role)
+	return user, nil
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// availableUsername подбирает свободное имя на основе preferred_username
+// или email, добавляя числовой суффикс при совпадении.
+func (h *OIDCHandler) availableUsername(claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Trim(usernameUnsafe.ReplaceAllString(base, "_"), "_")
+	if base == "" {
+		base = "user"
+	}
+
+	for i := 0; i < 100; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", base, i+1)
+		}
+		if _, err := h.UserRepo.GetByUsername(candidate); errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		} else if err != nil {
+			return "", apperror.NewDatabaseError("Ошибка проверки имени пользователя", err)
+		}
+	}
+	return "", apperror.NewConflictError("Не удалось подобрать свободное имя пользователя", nil)
+}
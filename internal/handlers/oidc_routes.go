@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"net/http"
+)
+
+func RegisterOIDCRoutes(mux *http.ServeMux, oh *OIDCHandler) {
+	mux.HandleFunc("/auth/oidc/login", middleware.ErrorHandler(oh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return oh.Login(w, r)
+	}))
+	mux.HandleFunc("/auth/oidc/callback", middleware.ErrorHandler(oh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return oh.Callback(w, r)
+	}))
+	mux.Handle("/auth/oidc/link", auth.RequireSession(middleware.ErrorHandler(oh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return oh.StartLink(w, r)
+	})))
+}
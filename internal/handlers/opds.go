@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/opds"
+	"manga-reader/models"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OPDSHandler отдает каталог для приложений-читалок: OPDS 1.2 под /opds,
+// OPDS 2.0 под /opds/v2, главы в формате CBZ и постраничное чтение OPDS-PSE.
+type OPDSHandler struct {
+	MangaRepo db.MangaRepository
+	Chapters  db.ChapterRepository
+	Pages     db.PageRepository
+	// PublicURL — внешний адрес сервиса для абсолютных ссылок каталога.
+	PublicURL string
+	Logger    *slog.Logger
+}
+
+func (h *OPDSHandler) url(path string) string {
+	return strings.TrimRight(h.PublicURL, "/") + path
+}
+
+// Root отдает корневую навигационную ленту OPDS 1.2: GET /opds.
+func (h *OPDSHandler) Root(w http.ResponseWriter, r *http.Request) error {
+	now := time.Now()
+	f := opds.NewFeed(h.url("/opds"), "Каталог манги", now)
+	f.Links = h.commonLinks(h.url("/opds"), opds.NavigationType)
+	f.Entries = []opds.Entry{{
+		ID:      h.url("/opds/manga"),
+		Title:   "Вся манга",
+		Updated: opds.FormatTime(now),
+		Content: &opds.Content{Type: "text", Text: "Список всех серий каталога"},
+		Links:   []opds.Link{{Rel: opds.RelSubsection, Href: h.url("/opds/manga"), Type: opds.NavigationType}},
+	}}
+	return h.writeXML(w, opds.NavigationType, f)
+}
+
+// OpenSearch отдает описание поиска: GET /opds/search.xml.
+func (h *OPDSHandler) OpenSearch(w http.ResponseWriter, r *http.Request) error {
+	desc := opds.NewOpenSearch("Манга", "Поиск манги по названию",
+		opds.OpenSearchURL{Type: opds.NavigationType, Template: h.url("/opds/manga?q={searchTerms}")},
+		opds.OpenSearchURL{Type: opds.JSONType, Template: h.url("/opds/v2/manga?q={searchTerms}")},
+	)
+	return h.writeXML(w, opds.OpenSearchType, desc)
+}
+
+// MangaList отдает навигационную ленту серий с поиском по названию (?q=)
+// и постраничным выводом: GET /opds/manga.
+func (h *OPDSHandler) MangaList(w http.ResponseWriter, r *http.Request) error {
+	mangas, limit, offset, hasMore, err := h.findManga(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	self := h.listURL("/opds/manga", r.URL.Query().Get("q"), limit, offset)
+	f := opds.NewFeed(h.url("/opds/manga"), "Вся манга", now)
+	f.Links = h.commonLinks(self, opds.NavigationType)
+	f.Links = append(f.Links, opds.Link{Rel: opds.RelUp, Href: h.url("/opds"), Type: opds.NavigationType})
+	if hasMore {
+		f.Links = append(f.Links, opds.Link{Rel: opds.RelNext, Type: opds.NavigationType,
+			Href: h.listURL("/opds/manga", r.URL.Query().Get("q"), limit, offset+limit)})
+	}
+	for _, m := range mangas {
+		href := h.url(fmt.Sprintf("/opds/manga/%d", m.ID))
+		updated := m.UpdatedAt
+		if updated.IsZero() {
+			updated = now
+		}
+		f.Entries = append(f.Entries, opds.Entry{
+			ID:      href,
+			Title:   m.Title,
+			Updated: opds.FormatTime(updated),
+			Content: &opds.Content{Type: "text", Text: m.Description},
+			Links:   []opds.Link{{Rel: opds.RelSubsection, Href: href, Type: opds.AcquisitionType}},
+		})
+	}
+	return h.writeXML(w, opds.NavigationType, f)
+}
+
+// MangaFeed отдает ленту глав серии со ссылками на CBZ и потоковое
+// чтение: GET /opds/manga/{id}.
+func (h *OPDSHandler) MangaFeed(w http.ResponseWriter, r *http.Request) error {
+	manga, chapters, err := h.mangaChapters(r, strings.TrimPrefix(r.URL.Path, "/opds/manga/"))
+	if err != nil {
+		return err
+	}
+
+	updated := latestPublished(chapters)
+	self := fmt.Sprintf("/opds/manga/%d", manga.ID)
+	f := opds.NewFeed(h.url(self), manga.Title, updated)
+	f.Links = h.commonLinks(h.url(self), opds.AcquisitionType)
+	f.Links = append(f.Links, opds.Link{Rel: opds.RelUp, Href: h.url("/opds/manga"), Type: opds.NavigationType})
+	for _, ch := range chapters {
+		pages, err := h.Pages.ListByChapter(ch.ID)
+		if err != nil {
+			return apperror.NewDatabaseError("Ошибка получения списка страниц", err)
+		}
+		entryUpdated := updated
+		if ch.PublishedAt != nil {
+			entryUpdated = *ch.PublishedAt
+		}
+		entry := opds.Entry{
+			ID:      h.url(fmt.Sprintf("/chapter/%d", ch.ID)),
+			Title:   chapterTitle(ch),
+			Updated: opds.FormatTime(entryUpdated),
+			Links: []opds.Link{{
+				Rel:  opds.RelAcquisition,
+				Href: h.url(fmt.Sprintf("/opds/chapter/%d/download", ch.ID)),
+				Type: opds.CBZType,
+			}},
+		}
+		if len(pages) > 0 {
+			entry.Links = append(entry.Links, opds.Link{
+				Rel:   opds.RelStream,
+				Href:  h.url(fmt.Sprintf("/opds/chapter/%d/pages/{pageNumber}", ch.ID)),
+				Type:  "image/jpeg",
+				Count: len(pages),
+			})
+		}
+		f.Entries = append(f.Entries, entry)
+	}
+	return h.writeXML(w, opds.AcquisitionType, f)
+}
+
+// RootV2 отдает корневую навигацию OPDS 2.0: GET /opds/v2.
+func (h *OPDSHandler) RootV2(w http.ResponseWriter, r *http.Request) error {
+	c := opds.Catalog{
+		Metadata: opds.Metadata{Title: "Каталог манги"},
+		Links:    h.commonLinksV2(h.url("/opds/v2")),
+		Navigation: []opds.JSONLink{
+			{Rel: opds.RelSubsection, Href: h.url("/opds/v2/manga"), Type: opds.JSONType, Title: "Вся манга"},
+		},
+	}
+	return h.writeJSON(w, c)
+}
+
+// MangaListV2 отдает список серий OPDS 2.0: GET /opds/v2/manga.
+func (h *OPDSHandler) MangaListV2(w http.ResponseWriter, r *http.Request) error {
+	mangas, limit, offset, hasMore, err := h.findManga(r)
+	if err != nil {
+		return err
+	}
+
+	q := r.URL.Query().Get("q")
+	c := opds.Catalog{
+		Metadata: opds.Metadata{Title: "Вся манга", ItemsPerPage: limit, CurrentPage: offset/limit + 1},
+		Links:    h.commonLinksV2(h.listURL("/opds/v2/manga", q, limit, offset)),
+	}
+	if hasMore {
+		c.Links = append(c.Links, opds.JSONLink{Rel: opds.RelNext, Type: opds.JSONType,
+			Href: h.listURL("/opds/v2/manga", q, limit, offset+limit)})
+	}
+	for _, m := range mangas {
+		c.Navigation = append(c.Navigation, opds.JSONLink{
+			Rel:   opds.RelSubsection,
+			Href:  h.url(fmt.Sprintf("/opds/v2/manga/%d", m.ID)),
+			Type:  opds.JSONType,
+			Title: m.Title,
+		})
+	}
+	return h.writeJSON(w, c)
+}
+
+// MangaFeedV2 отдает главы серии как публикации OPDS 2.0: GET /opds/v2/manga/{id}.
+func (h *OPDSHandler) MangaFeedV2(w http.ResponseWriter, r *http.Request) error {
+	manga, chapters, err := h.mangaChapters(r, strings.TrimPrefix(r.URL.Path, "/opds/v2/manga/"))
+	if err != nil {
+		return err
+	}
+
+	c := opds.Catalog{
+		Metadata: opds.Metadata{Title: manga.Title, Description: manga.Description, NumberOfItems: len(chapters)},
+		Links:    h.commonLinksV2(h.url(fmt.Sprintf("/opds/v2/manga/%d", manga.ID))),
+	}
+	c.Publications = make([]opds.Publication, 0, len(chapters))
+	for _, ch := range chapters {
+		pub := opds.Publication{
+			Metadata: opds.Metadata{
+				Title:      chapterTitle(ch),
+				Identifier: h.url(fmt.Sprintf("/chapter/%d", ch.ID)),
+				Type:       "http://schema.org/ComicIssue",
+				Language:   ch.Language,
+			},
+			Links: []opds.JSONLink{{
+				Rel:  opds.RelAcquisition,
+				Href: h.url(fmt.Sprintf("/opds/chapter/%d/download", ch.ID)),
+				Type: opds.CBZType,
+			}},
+		}
+		if ch.PublishedAt != nil {
+			pub.Metadata.Published = opds.FormatTime(*ch.PublishedAt)
+		}
+		c.Publications = append(c.Publications, pub)
+	}
+	return h.writeJSON(w, c)
+}
+
+// Download отдает главу архивом CBZ: GET /opds/chapter/{id}/download.
+// Страницы пишутся без сжатия — изображения уже сжаты.
+func (h *OPDSHandler) Download(w http.ResponseWriter, r *http.Request) error {
+	chapter, pages, err := h.chapterPages(r)
+	if err != nil {
+		return err
+	}
+	// Заголовки нельзя изменить после начала записи архива, поэтому
+	// отсутствие файлов проверяется заранее.
+	for _, p := range pages {
+		if _, err = os.Stat(p.ImagePath); err != nil {
+			return apperror.NewInternalServerError("Файл страницы недоступен", err)
+		}
+	}
+
+	mangaTitle := ""
+	if manga, err := h.MangaRepo.GetByID(chapter.MangaID); err == nil {
+		mangaTitle = manga.Title
+	}
+	filename := fmt.Sprintf("%s - Глава %d.cbz", mangaTitle, chapter.Number)
+	if mangaTitle == "" {
+		filename = fmt.Sprintf("Глава %d.cbz", chapter.Number)
+	}
+
+	w.Header().Set("Content-Type", opds.CBZType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chapter-%d.cbz"; filename*=UTF-8''%s`,
+		chapter.ID, url.PathEscape(filename)))
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	if err = writeComicInfo(zw, mangaTitle, chapter, len(pages)); err == nil {
+		for i, p := range pages {
+			name := fmt.Sprintf("%03d%s", i+1, strings.ToLower(filepath.Ext(p.ImagePath)))
+			if err = copyToZip(zw, name, p.ImagePath); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		h.Logger.Error("Ошибка формирования CBZ", "chapter_id", chapter.ID, "err", err)
+	}
+	return nil
+}
+
+// StreamPage перенаправляет на изображение страницы по ее порядковому
+// номеру (с нуля), как того требует OPDS-PSE: GET /opds/chapter/{id}/pages/{n}.
+func (h *OPDSHandler) StreamPage(w http.ResponseWriter, r *http.Request) error {
+	_, pages, err := h.chapterPages(r)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/opds/chapter/"), "/")
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || n < 0 {
+		return apperror.NewBadRequestError("Некорректный номер страницы", err)
+	}
+	if n >= len(pages) {
+		return apperror.NewNotFoundError("Страница не найдена", nil)
+	}
+	http.Redirect(w, r, h.url(fmt.Sprintf("/page/image/%d", pages[n].ID)), http.StatusFound)
+	return nil
+}
+
+func (h *OPDSHandler) findManga(r *http.Request) (mangas []*models.Manga, limit, offset int, hasMore bool, err error) {
+	limit, offset, err = parsePagination(r)
+	if err != nil {
+		return nil, 0, 0, false, err
+	}
+	all, err := h.MangaRepo.List()
+	if err != nil {
+		return nil, 0, 0, false, apperror.NewDatabaseError("Ошибка получения списка манги", err)
+	}
+
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	for _, m := range all {
+		if q == "" || strings.Contains(strings.ToLower(m.Title), q) {
+			mangas = append(mangas, m)
+		}
+	}
+	sort.Slice(mangas, func(i, j int) bool { return mangas[i].Title < mangas[j].Title })
+
+	if offset >= len(mangas) {
+		return nil, limit, offset, false, nil
+	}
+	mangas = mangas[offset:]
+	if len(mangas) > limit {
+		return mangas[:limit], limit, offset, true, nil
+	}
+	return mangas, limit, offset, false, nil
+}
+
+func (h *OPDSHandler) mangaChapters(r *http.Request, idStr string) (*models.Manga, []*models.Chapter, error) {
+	mangaID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, nil, apperror.NewBadRequestError("Некорректный ID манги", err)
+	}
+	manga, err := h.MangaRepo.GetByID(mangaID)
+	if err != nil {
+		return nil, nil, apperror.NewNotFoundError("Манга не найдена", err)
+	}
+	chapters, err := h.Chapters.ListByManga(mangaID)
+	if err != nil {
+		return nil, nil, apperror.NewDatabaseError("Ошибка получения списка глав", err)
+	}
+	// Каталог публичный, поэтому неопубликованные главы в нем не показываются.
+	chapters = visibleChapters(r, chapters)
+	sort.Slice(chapters, func(i, j int) bool { return chapters[i].Number < chapters[j].Number })
+	return manga, chapters, nil
+}
+
+// chapterPages возвращает главу из пути /opds/chapter/{id}/... и ее
+// страницы по порядку.
+func (h *OPDSHandler) chapterPages(r *http.Request) (*models.Chapter, []*models.Page, error) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/opds/chapter/"), "/")
+	chapterID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, nil, apperror.NewBadRequestError("Некорректный ID главы", err)
+	}
+	chapter, err := h.Chapters.GetByID(chapterID)
+	if err != nil {
+		return nil, nil, apperror.NewNotFoundError("Глава не найдена", err)
+	}
+	if !chapter.Published() && !canSeeUnpublished(r) {
+		return nil, nil, apperror.NewNotFoundError("Глава не найдена", nil)
+	}
+	pages, err := h.Pages.ListByChapter(chapterID)
+	if err != nil {
+		return nil, nil, apperror.NewDatabaseError("Ошибка получения списка страниц", err)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Number < pages[j].Number })
+	return chapter, pages, nil
+}
+
+// commonLinks возвращает ссылки, общие для всех лент; self — абсолютный адрес.
+func (h *OPDSHandler) commonLinks(self, selfType string) []opds.Link {
+	return []opds.Link{
+		{Rel: opds.RelSelf, Href: self, Type: selfType},
+		{Rel: opds.RelStart, Href: h.url("/opds"), Type: opds.NavigationType},
+		{Rel: opds.RelSearch, Href: h.url("/opds/search.xml"), Type: opds.OpenSearchType},
+	}
+}
+
+func (h *OPDSHandler) commonLinksV2(self string) []opds.JSONLink {
+	return []opds.JSONLink{
+		{Rel: opds.RelSelf, Href: self, Type: opds.JSONType},
+		{Rel: opds.RelStart, Href: h.url("/opds/v2"), Type: opds.JSONType},
+		{Rel: opds.RelSearch, Href: h.url("/opds/v2/manga{?q}"), Type: opds.JSONType, Templated: true},
+	}
+}
+
+func (h *OPDSHandler) listURL(path, q string, limit, offset int) string {
+	v := url.Values{}
+	if q != "" {
+		v.Set("q", q)
+	}
+	if limit != defaultPageLimit {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		v.Set("offset", strconv.Itoa(offset))
+	}
+	if len(v) == 0 {
+		return h.url(path)
+	}
+	return h.url(path + "?" + v.Encode())
+}
+
+func (h *OPDSHandler) writeXML(w http.ResponseWriter, contentType string, v interface{}) error {
+	body, err := opds.Marshal(v)
+	if err != nil {
+		return apperror.NewInternalServerError("Ошибка формирования каталога", err)
+	}
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(body); err != nil {
+		h.Logger.Error("Ошибка отправки каталога", "err", err)
+	}
+	return nil
+}
+
+func (h *OPDSHandler) writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", opds.JSONType)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.Logger.Error("Ошибка отправки каталога", "err", err)
+	}
+	return nil
+}
+
+func chapterTitle(ch *models.Chapter) string {
+	if ch.Title == "" {
+		return fmt.Sprintf("Глава %d", ch.Number)
+	}
+	return fmt.Sprintf("Глава %d: %s", ch.Number, ch.Title)
+}
+
+// latestPublished возвращает время последней публикации или текущее
+// время, если даты публикации неизвестны.
+func latestPublished(chapters []*models.Chapter) time.Time {
+	var latest time.Time
+	for _, ch := range chapters {
+		if ch.PublishedAt != nil && ch.PublishedAt.After(latest) {
+			latest = *ch.PublishedAt
+		}
+	}
+	if latest.IsZero() {
+		return time.Now()
+	}
+	return latest
+}
+
+// comicInfo — метаданные ComicInfo.xml, которые читалки берут из CBZ.
+type comicInfo struct {
+	XMLName   xml.Name `xml:"ComicInfo"`
+	Title     string   `xml:"Title,omitempty"`
+	Series    string   `xml:"Series,omitempty"`
+	Number    int      `xml:"Number"`
+	PageCount int      `xml:"PageCount"`
+}
+
+func writeComicInfo(zw *zip.Writer, series string, ch *models.Chapter, pageCount int) error {
+	body, err := opds.Marshal(comicInfo{Title: ch.Title, Series: series, Number: ch.Number, PageCount: pageCount})
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create("ComicInfo.xml")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	return err
+}
+
+func copyToZip(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/middleware"
+	"net/http"
+	"strings"
+)
+
+func RegisterOPDSRoutes(mux *http.ServeMux, oh *OPDSHandler) {
+	get := func(f middleware.ErrorHandlerFunc) http.HandlerFunc {
+		return middleware.ErrorHandler(oh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+			if r.Method != http.MethodGet {
+				return apperror.NewBadRequestError("Метод не поддерживается", nil)
+			}
+			return f(w, r)
+		})
+	}
+
+	mux.HandleFunc("/opds", get(oh.Root))
+	mux.HandleFunc("/opds/search.xml", get(oh.OpenSearch))
+	mux.HandleFunc("/opds/manga", get(oh.MangaList))
+	mux.HandleFunc("/opds/manga/", get(oh.MangaFeed))
+	mux.HandleFunc("/opds/v2", get(oh.RootV2))
+	mux.HandleFunc("/opds/v2/manga", get(oh.MangaListV2))
+	mux.HandleFunc("/opds/v2/manga/", get(oh.MangaFeedV2))
+	mux.HandleFunc("/opds/chapter/", get(func(w http.ResponseWriter, r *http.Request) error {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/opds/chapter/"), "/")
+		switch {
+		case len(parts) == 2 && parts[1] == "download":
+			return oh.Download(w, r)
+		case len(parts) == 3 && parts[1] == "pages":
+			return oh.StreamPage(w, r)
+		default:
+			return apperror.NewNotFoundError("Ресурс не найден", nil)
+		}
+	}))
+}
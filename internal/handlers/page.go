@@ -7,9 +7,11 @@ import (
 	"log/slog"
 	"manga-reader/internal/analytics"
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
 	"manga-reader/internal/cache"
 	"manga-reader/internal/db"
 	"manga-reader/internal/response"
+	"manga-reader/internal/webhook"
 	"manga-reader/models"
 	"net/http"
 	"os"
@@ -24,6 +26,26 @@ type PageHandler struct {
 	Logger    *slog.Logger
 	Cache     cache.Cache
 	Analytics *analytics.AnalyticsService
+	Webhooks  *webhook.Dispatcher
+	// Chapters используется для проверки, опубликована ли глава страницы.
+	Chapters db.ChapterRepository
+	Audit    *audit.Recorder
+}
+
+// visibleChapter возвращает главу, если она доступна отправителю запроса:
+// страницы неопубликованных глав видны только загрузчикам и выше.
+func (h *PageHandler) visibleChapter(r *http.Request, chapterID int64) (*models.Chapter, error) {
+	if h.Chapters == nil {
+		return nil, nil
+	}
+	chapter, err := h.Chapters.GetByID(chapterID)
+	if err != nil {
+		return nil, apperror.NewNotFoundError("Глава не найдена", err)
+	}
+	if !chapter.Published() && !canSeeUnpublished(r) {
+		return nil, apperror.NewNotFoundError("Глава не найдена", nil)
+	}
+	return chapter, nil
 }
 
 func (h *PageHandler) Delete(w http.ResponseWriter, r *http.Request) error {
@@ -38,21 +60,23 @@ func (h *PageHandler) Delete(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewNotFoundError("Страница не найдена", err)
 	}
 
+	// Страница переносится в корзину; файл изображения удаляется при ее очистке.
 	if err = h.Repo.Delete(id); err != nil {
 		return apperror.NewDatabaseError("Ошибка удаления страницы из БД", err)
 	}
 
-	if err = os.Remove(page.ImagePath); err != nil {
-		h.Logger.Error("Ошибка удаления файла изображения", "err", err)
-		// Не возвращаем ошибку, так как запись из БД уже удалена
-	}
-
 	if h.Cache != nil {
-		cacheKey := fmt.Sprintf("chapter:%d:pages", page.ChapterID)
-		if err = h.Cache.Delete(r.Context(), cacheKey); err != nil {
-			h.Logger.Error("Ошибка инвалидации кеша списка страниц", "key", cacheKey, "err", err)
+		for _, cacheKey := range []string{
+			fmt.Sprintf("chapter:%d:pages", page.ChapterID),
+			readerCacheKey(page.ChapterID),
+		} {
+			if err := h.Cache.Delete(r.Context(), cacheKey); err != nil {
+				h.Logger.Error("Ошибка инвалидации кеша списка страниц", "key", cacheKey, "err", err)
+			}
 		}
 	}
+	h.Webhooks.Emit(models.EventPageDeleted, page)
+	h.Audit.Record(r, models.AuditDelete, models.AuditPage, id, page, nil)
 
 	response.Success(w, http.StatusNoContent, nil)
 	return nil
@@ -77,6 +101,12 @@ func (h *PageHandler) UploadImage(w http.ResponseWriter, r *http.Request) error
 			map[string]string{"chapter_id": "Должно быть целое число"})
 	}
 
+	if h.Chapters != nil {
+		if _, err = h.Chapters.GetByID(chapterID); err != nil {
+			return apperror.NewNotFoundError("Глава не найдена", err)
+		}
+	}
+
 	numberStr := r.FormValue("number")
 	if numberStr == "" {
 		return apperror.NewValidationError("Поле number не может быть пустым",
@@ -134,11 +164,17 @@ func (h *PageHandler) UploadImage(w http.ResponseWriter, r *http.Request) error
 	page.ID = id
 
 	if h.Cache != nil {
-		cacheKey := fmt.Sprintf("chapter:%d:pages", page.ChapterID)
-		if err := h.Cache.Delete(r.Context(), cacheKey); err != nil {
-			h.Logger.Error("Ошибка инвалидации кеша списка страниц", "key", cacheKey, "err", err)
+		for _, cacheKey := range []string{
+			fmt.Sprintf("chapter:%d:pages", page.ChapterID),
+			readerCacheKey(page.ChapterID),
+		} {
+			if err := h.Cache.Delete(r.Context(), cacheKey); err != nil {
+				h.Logger.Error("Ошибка инвалидации кеша списка страниц", "key", cacheKey, "err", err)
+			}
 		}
 	}
+	h.Webhooks.Emit(models.EventPageCreated, page)
+	h.Audit.Record(r, models.AuditCreate, models.AuditPage, page.ID, nil, page)
 
 	response.Success(w, http.StatusCreated, page)
 	return nil
@@ -155,6 +191,9 @@ func (h *PageHandler) ListByChapter(w http.ResponseWriter, r *http.Request) erro
 	if err != nil {
 		return apperror.NewBadRequestError("Некорректный ID главы", err)
 	}
+	if _, err = h.visibleChapter(r, chapterID); err != nil {
+		return err
+	}
 
 	cacheKey := fmt.Sprintf("chapter:%d:pages", chapterID)
 	if h.Cache != nil {
@@ -201,11 +240,18 @@ func (h *PageHandler) ServeImage(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return apperror.NewNotFoundError("Страница не найдена", err)
 	}
+	chapter, err := h.visibleChapter(r, page.ChapterID)
+	if err != nil {
+		return err
+	}
 
 	if h.Analytics != nil {
 		var mangaID int64 = 0
+		if chapter != nil {
+			mangaID = chapter.MangaID
+		}
 
-		if h.Cache != nil {
+		if mangaID == 0 && h.Cache != nil {
 			chapterCacheKey := fmt.Sprintf("chapter:%d", page.ChapterID)
 			chapterData, err := h.Cache.Get(r.Context(), chapterCacheKey)
 
@@ -224,7 +270,7 @@ func (h *PageHandler) ServeImage(w http.ResponseWriter, r *http.Request) error {
 		}
 
 		if mangaID > 0 {
-			if err := h.Analytics.RecordPageView(r.Context(), id, page.ChapterID, mangaID); err != nil {
+			if err := h.Analytics.RecordPageView(r.Context(), id, page.ChapterID, mangaID, viewerID(h.Analytics, r)); err != nil {
 				h.Logger.Error("Ошибка записи просмотра страницы", "err", err, "page_id", id)
 			}
 		}
@@ -2,40 +2,42 @@ package handlers
 
 import (
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/middleware"
+	"manga-reader/models"
 	"net/http"
 )
 
 func RegisterPageRoutes(mux *http.ServeMux, ph *PageHandler) {
-	mux.HandleFunc("/page/upload", middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/page/upload", auth.RequireScope(models.RoleUploader, models.ScopeUploadPages, middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodPost {
 			return ph.UploadImage(w, r)
 		} else {
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
-	}))
+	})))
 
-	mux.HandleFunc("/pages/chapter/", middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/pages/chapter/", auth.OptionalAuth(middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodGet {
 			return ph.ListByChapter(w, r)
 		} else {
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
-	}))
+	})))
 
-	mux.HandleFunc("/page/", middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/page/", auth.RequireScope(models.RoleModerator, models.ScopeDeleteContent, middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodDelete {
 			return ph.Delete(w, r)
 		} else {
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
-	}))
+	})))
 
-	mux.HandleFunc("/page/image/", middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
+	mux.Handle("/page/image/", auth.OptionalAuth(middleware.ErrorHandler(ph.Logger, func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method == http.MethodGet {
 			return ph.ServeImage(w, r)
 		} else {
 			return apperror.NewBadRequestError("Метод не поддерживается", nil)
 		}
-	}))
+	})))
 }
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readerCacheTTL ограничивает устаревание сводки манги в манифесте: при
+// изменении глав и страниц запись удаляется сразу, при правке манги — нет.
+const readerCacheTTL = 5 * time.Minute
+
+// ReaderManifest содержит все, что нужно читалке для вывода главы.
+type ReaderManifest struct {
+	Chapter *models.Chapter `json:"chapter"`
+	Manga   MangaSummary    `json:"manga"`
+	Pages   []ReaderPage    `json:"pages"`
+	// PrevChapterID и NextChapterID указывают на соседние опубликованные
+	// главы на том же языке.
+	PrevChapterID *int64 `json:"prev_chapter_id"`
+	NextChapterID *int64 `json:"next_chapter_id"`
+}
+
+type MangaSummary struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type ReaderPage struct {
+	ID       int64  `json:"id"`
+	Number   int    `json:"number"`
+	ImageURL string `json:"image_url"`
+}
+
+func readerCacheKey(chapterID int64) string {
+	return fmt.Sprintf("chapter:%d:reader", chapterID)
+}
+
+// Reader отдает манифест главы для читалки: GET /chapter/{id}/reader.
+func (h *ChapterHandler) Reader(w http.ResponseWriter, r *http.Request) error {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/chapter/"), "/reader")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID главы", err)
+	}
+
+	var manifest *ReaderManifest
+	cacheKey := readerCacheKey(id)
+	if h.Cache != nil {
+		cachedData, err := h.Cache.Get(r.Context(), cacheKey)
+		if err == nil && cachedData != "" {
+			h.Logger.Info("Cache hit for reader manifest", "chapter_id", id)
+
+			if err = json.Unmarshal([]byte(cachedData), &manifest); err != nil {
+				h.Logger.Error("Ошибка десериализации манифеста главы из кеша", "err", err)
+				manifest = nil
+			}
+		}
+	}
+
+	if manifest == nil {
+		if manifest, err = h.buildReaderManifest(id); err != nil {
+			return err
+		}
+		if h.Cache != nil {
+			jsonData, err := json.Marshal(manifest)
+			if err == nil {
+				if err = h.Cache.Set(r.Context(), cacheKey, string(jsonData), readerCacheTTL); err != nil {
+					h.Logger.Error("Ошибка кеширования манифеста главы", "err", err)
+				}
+			}
+		}
+	}
+
+	if !manifest.Chapter.Published() && !canSeeUnpublished(r) {
+		return apperror.NewNotFoundError("Глава не найдена", nil)
+	}
+
+	if h.Analytics != nil {
+		if err = h.Analytics.RecordChapterView(r.Context(), id, manifest.Chapter.MangaID, viewerID(h.Analytics, r)); err != nil {
+			h.Logger.Error("Ошибка записи просмотра главы", "err", err, "chapter_id", id)
+		}
+	}
+
+	response.Success(w, http.StatusOK, manifest)
+	return nil
+}
+
+func (h *ChapterHandler) buildReaderManifest(id int64) (*ReaderManifest, error) {
+	if h.MangaRepo == nil || h.Pages == nil {
+		return nil, apperror.NewInternalServerError("Манифест главы недоступен", nil)
+	}
+
+	ch, err := h.Repo.GetByID(id)
+	if err != nil {
+		return nil, apperror.NewNotFoundError("Глава не найдена", err)
+	}
+	manga, err := h.MangaRepo.GetByID(ch.MangaID)
+	if err != nil {
+		return nil, apperror.NewNotFoundError("Манга не найдена", err)
+	}
+	pages, err := h.Pages.ListByChapter(id)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Ошибка получения списка страниц", err)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Number < pages[j].Number })
+	chapters, err := h.Repo.ListByManga(ch.MangaID)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Ошибка получения списка глав", err)
+	}
+
+	manifest := &ReaderManifest{
+		Chapter: ch,
+		Manga:   MangaSummary{ID: manga.ID, Title: manga.Title, Description: manga.Description},
+		Pages:   make([]ReaderPage, 0, len(pages)),
+	}
+	for _, p := range pages {
+		manifest.Pages = append(manifest.Pages, ReaderPage{
+			ID:       p.ID,
+			Number:   p.Number,
+			ImageURL: fmt.Sprintf("/page/image/%d", p.ID),
+		})
+	}
+	manifest.PrevChapterID, manifest.NextChapterID = chapterNeighbors(ch, chapters)
+	return manifest, nil
+}
+
+// chapterNeighbors находит ближайшие по номеру опубликованные главы на
+// языке ch. Если у соседнего номера несколько переводов на этом языке,
+// предпочитается глава той же команды. Черновики в навигацию не попадают,
+// поэтому манифест одинаков для всех читателей и кешируется целиком.
+func chapterNeighbors(ch *models.Chapter, chapters []*models.Chapter) (prev, next *int64) {
+	var prevCh, nextCh *models.Chapter
+	closer := func(candidate, current *models.Chapter, before bool) bool {
+		if current == nil {
+			return true
+		}
+		if candidate.Number != current.Number {
+			return (candidate.Number > current.Number) == before
+		}
+		return !sameGroup(current, ch) && sameGroup(candidate, ch)
+	}
+	for _, other := range chapters {
+		if other.ID == ch.ID || !other.Published() || other.Language != ch.Language {
+			continue
+		}
+		switch {
+		case other.Number < ch.Number && closer(other, prevCh, true):
+			prevCh = other
+		case other.Number > ch.Number && closer(other, nextCh, false):
+			nextCh = other
+		}
+	}
+	if prevCh != nil {
+		prev = &prevCh.ID
+	}
+	if nextCh != nil {
+		next = &nextCh.ID
+	}
+	return prev, next
+}
+
+func sameGroup(a, b *models.Chapter) bool {
+	if a.GroupID == nil || b.GroupID == nil {
+		return a.GroupID == nil && b.GroupID == nil
+	}
+	return *a.GroupID == *b.GroupID
+}
+
+// invalidateReaders удаляет манифесты всех глав манги: добавление,
+// удаление или перенумерация главы меняет навигацию соседних глав.
+func (h *ChapterHandler) invalidateReaders(ctx context.Context, mangaID int64) {
+	deleteReaderManifests(ctx, h.Cache, h.Repo, h.Logger, mangaID)
+}
+
+func deleteReaderManifests(ctx context.Context, c cache.Cache, repo db.ChapterRepository, logger *slog.Logger, mangaID int64) {
+	if c == nil {
+		return
+	}
+	chapters, err := repo.ListByManga(mangaID)
+	if err != nil {
+		logger.Error("Ошибка получения глав для инвалидации кеша", "manga_id", mangaID, "err", err)
+		return
+	}
+	for _, ch := range chapters {
+		if err = c.Delete(ctx, readerCacheKey(ch.ID)); err != nil {
+			logger.Error("Ошибка инвалидации кеша", "key", readerCacheKey(ch.ID), "err", err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GroupHandler управляет командами переводчиков и их участниками.
+type GroupHandler struct {
+	Repo     db.ScanlationGroupRepository
+	UserRepo db.UserRepository
+	Logger   *slog.Logger
+}
+
+type GroupRequest struct {
+	Name    *string `json:"name"`
+	Website *string `json:"website"`
+}
+
+type GroupMemberRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+// groupPath разбирает путь /groups/{id}[/members[/{userID}]] и возвращает
+// ID команды и оставшуюся часть пути.
+func groupPath(r *http.Request) (int64, []string, error) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/groups/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, nil, apperror.NewBadRequestError("Некорректный ID команды", err)
+	}
+	return id, parts[1:], nil
+}
+
+func (h *GroupHandler) get(id int64) (*models.ScanlationGroup, error) {
+	group, err := h.Repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NewNotFoundError("Команда не найдена", err)
+		}
+		return nil, apperror.NewDatabaseError("Не удалось получить команду", err)
+	}
+	return group, nil
+}
+
+// applyGroupRequest проверяет и переносит переданные поля в команду.
+// Имя команды должно быть уникальным.
+func (h *GroupHandler) applyGroupRequest(group *models.ScanlationGroup, req GroupRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return apperror.NewValidationError("Поле name не может быть пустым",
+				map[string]string{"name": "Это поле обязательно"})
+		}
+		groups, err := h.Repo.List()
+		if err != nil {
+			return apperror.NewDatabaseError("Не удалось получить список команд", err)
+		}
+		for _, other := range groups {
+			if other.ID != group.ID && strings.EqualFold(other.Name, name) {
+				return apperror.NewConflictError("Команда с таким названием уже существует", nil)
+			}
+		}
+		group.Name = name
+	}
+	if req.Website != nil {
+		website := strings.TrimSpace(*req.Website)
+		if website != "" {
+			if err := validateWebsiteURL(website); err != nil {
+				return err
+			}
+		}
+		group.Website = website
+	}
+	return nil
+}
+
+func validateWebsiteURL(raw string) error {
+	if err := validateWebhookURL(raw); err != nil {
+		return apperror.NewValidationError("Некорректный адрес сайта",
+			map[string]string{"website": "Ожидается абсолютный адрес http или https"})
+	}
+	return nil
+}
+
+func (h *GroupHandler) List(w http.ResponseWriter, r *http.Request) error {
+	groups, err := h.Repo.List()
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить список команд", err)
+	}
+	if groups == nil {
+		groups = []*models.ScanlationGroup{}
+	}
+
+	response.Success(w, http.StatusOK, groups)
+	return nil
+}
+
+func (h *GroupHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	id, rest, err := groupPath(r)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return apperror.NewBadRequestError("Некорректный URL", nil)
+	}
+	group, err := h.get(id)
+	if err != nil {
+		return err
+	}
+
+	response.Success(w, http.StatusOK, group)
+	return nil
+}
+
+func (h *GroupHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	var req GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.Name == nil {
+		return apperror.NewValidationError("Поле name не может быть пустым",
+			map[string]string{"name": "Это поле обязательно"})
+	}
+
+	group := &models.ScanlationGroup{}
+	if err := h.applyGroupRequest(group, req); err != nil {
+		return err
+	}
+	id, err := h.Repo.Create(group)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось создать команду", err)
+	}
+	group.ID = id
+	group.Members = []models.GroupMember{}
+
+	response.Success(w, http.StatusCreated, group)
+	return nil
+}
+
+// Update меняет только переданные поля.
+func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) error {
+	id, _, err := groupPath(r)
+	if err != nil {
+		return err
+	}
+	var req GroupRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	group, err := h.get(id)
+	if err != nil {
+		return err
+	}
+	if err = h.applyGroupRequest(group, req); err != nil {
+		return err
+	}
+	if err = h.Repo.Update(group); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить команду", err)
+	}
+
+	response.Success(w, http.StatusOK, group)
+	return nil
+}
+
+// Delete удаляет команду. Ее главы остаются в каталоге без указания команды.
+func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) error {
+	id, _, err := groupPath(r)
+	if err != nil {
+		return err
+	}
+	if _, err = h.get(id); err != nil {
+		return err
+	}
+	if err = h.Repo.Delete(id); err != nil {
+		return apperror.NewDatabaseError("Не удалось удалить команду", err)
+	}
+
+	response.Success(w, http.StatusNoContent, nil)
+	return nil
+}
+
+// AddMember добавляет пользователя в команду: POST /groups/{id}/members.
+func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) error {
+	id, _, err := groupPath(r)
+	if err != nil {
+		return err
+	}
+	var req GroupMemberRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.UserID <= 0 {
+		return apperror.NewValidationError("Некорректный ID пользователя",
+			map[string]string{"user_id": "Должен быть положительным числом"})
+	}
+
+	if _, err = h.get(id); err != nil {
+		return err
+	}
+	if _, err = h.UserRepo.GetByID(req.UserID); err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if err = h.Repo.AddMember(id, req.UserID); err != nil {
+		return apperror.NewDatabaseError("Не удалось добавить участника команды", err)
+	}
+
+	group, err := h.get(id)
+	if err != nil {
+		return err
+	}
+	response.Success(w, http.StatusOK, group)
+	return nil
+}
+
+// RemoveMember исключает пользователя из команды:
+// DELETE /groups/{id}/members/{userID}.
+func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) error {
+	id, rest, err := groupPath(r)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return apperror.NewBadRequestError("Некорректный URL", nil)
+	}
+	userID, err := strconv.ParseInt(rest[1], 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID пользователя", err)
+	}
+
+	if err = h.Repo.RemoveMember(id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("Пользователь не состоит в команде", err)
+		}
+		return apperror.NewDatabaseError("Не удалось исключить участника команды", err)
+	}
+
+	response.Success(w, http.StatusNoContent, nil)
+	return nil
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+	"strings"
+)
+
+// RegisterGroupRoutes регистрирует маршруты команд переводчиков. Список и
+// карточка команды доступны всем, изменения — модераторам.
+func RegisterGroupRoutes(mux *http.ServeMux, gh *GroupHandler) {
+	createGroup := auth.RequireRole(models.RoleModerator, middleware.ErrorHandler(gh.Logger, gh.Create))
+	mux.HandleFunc("/groups", middleware.ErrorHandler(gh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return gh.List(w, r)
+		case http.MethodPost:
+			createGroup.ServeHTTP(w, r)
+			return nil
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	}))
+
+	manageGroup := auth.RequireRole(models.RoleModerator, middleware.ErrorHandler(gh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if strings.Contains(strings.TrimPrefix(r.URL.Path, "/groups/"), "/members") {
+			switch r.Method {
+			case http.MethodPost:
+				return gh.AddMember(w, r)
+			case http.MethodDelete:
+				return gh.RemoveMember(w, r)
+			default:
+				return apperror.NewBadRequestError("Метод не поддерживается", nil)
+			}
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return gh.Update(w, r)
+		case http.MethodDelete:
+			return gh.Delete(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	}))
+	mux.HandleFunc("/groups/", middleware.ErrorHandler(gh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodGet {
+			return gh.Get(w, r)
+		}
+		manageGroup.ServeHTTP(w, r)
+		return nil
+	}))
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
+	"manga-reader/internal/cache"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TrashHandler показывает администраторам удаленные мангу, главы и
+// страницы и восстанавливает их до окончательной очистки.
+type TrashHandler struct {
+	MangaRepo db.MangaRepository
+	Chapters  db.ChapterRepository
+	Pages     db.PageRepository
+	Cache     cache.Cache
+	Logger    *slog.Logger
+	Audit     *audit.Recorder
+}
+
+// TrashListing — содержимое корзины. Главы удаленной манги и страницы
+// удаленной главы отдельно не выводятся: они восстанавливаются вместе с ней.
+type TrashListing struct {
+	Manga    []*models.Manga   `json:"manga"`
+	Chapters []*models.Chapter `json:"chapters"`
+	Pages    []*models.Page    `json:"pages"`
+}
+
+// List отдает содержимое корзины: GET /trash.
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) error {
+	manga, err := h.MangaRepo.ListDeleted()
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения манги из корзины", err)
+	}
+	chapters, err := h.Chapters.ListDeleted()
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения глав из корзины", err)
+	}
+	pages, err := h.Pages.ListDeleted()
+	if err != nil {
+		return apperror.NewDatabaseError("Ошибка получения страниц из корзины", err)
+	}
+
+	listing := TrashListing{Manga: manga, Chapters: chapters, Pages: pages}
+	if listing.Manga == nil {
+		listing.Manga = []*models.Manga{}
+	}
+	if listing.Chapters == nil {
+		listing.Chapters = []*models.Chapter{}
+	}
+	if listing.Pages == nil {
+		listing.Pages = []*models.Page{}
+	}
+
+	response.Success(w, http.StatusOK, listing)
+	return nil
+}
+
+// Restore возвращает запись из корзины:
+// POST /trash/{manga|chapter|page}/{id}/restore.
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/trash/"), "/")
+	if len(parts) != 3 || parts[2] != "restore" {
+		return apperror.NewBadRequestError("Некорректный URL", nil)
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return apperror.NewBadRequestError("Некорректный ID", err)
+	}
+
+	var restored any
+	switch parts[0] {
+	case "manga":
+		restored, err = h.restoreManga(r, id)
+	case "chapter":
+		restored, err = h.restoreChapter(r, id)
+	case "page":
+		restored, err = h.restorePage(r, id)
+	default:
+		return apperror.NewBadRequestError("Некорректный тип записи", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	response.Success(w, http.StatusOK, restored)
+	return nil
+}
+
+func restoreError(err error, notFound string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.NewNotFoundError(notFound, err)
+	}
+	return apperror.NewDatabaseError("Ошибка восстановления из корзины", err)
+}
+
+func (h *TrashHandler) restoreManga(r *http.Request, id int64) (*models.Manga, error) {
+	if err := h.MangaRepo.Restore(id); err != nil {
+		return nil, restoreError(err, "Манга не найдена в корзине")
+	}
+	manga, err := h.MangaRepo.GetByID(id)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Ошибка получения манги", err)
+	}
+	h.invalidate(r, "manga:list", fmt.Sprintf("manga:%d", id), fmt.Sprintf("manga:%d:chapters", id))
+	deleteReaderManifests(r.Context(), h.Cache, h.Chapters, h.Logger, id)
+	h.Audit.Record(r, models.AuditRestore, models.AuditManga, id, nil, manga)
+	return manga, nil
+}
+
+func (h *TrashHandler) restoreChapter(r *http.Request, id int64) (*models.Chapter, error) {
+	if err := h.Chapters.Restore(id); err != nil {
+		return nil, restoreError(err, "Глава не найдена в корзине")
+	}
+	chapter, err := h.Chapters.GetByID(id)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Ошибка получения главы", err)
+	}
+	h.invalidate(r, fmt.Sprintf("manga:%d:chapters", chapter.MangaID), fmt.Sprintf("chapter:%d", id),
+		fmt.Sprintf("chapter:%d:pages", id))
+	deleteReaderManifests(r.Context(), h.Cache, h.Chapters, h.Logger, chapter.MangaID)
+	h.Audit.Record(r, models.AuditRestore, models.AuditChapter, id, nil, chapter)
+	return chapter, nil
+}
+
+func (h *TrashHandler) restorePage(r *http.Request, id int64) (*models.Page, error) {
+	if err := h.Pages.Restore(id); err != nil {
+		return nil, restoreError(err, "Страница не найдена в корзине")
+	}
+	page, err := h.Pages.GetByID(id)
+	if err != nil {
+		return nil, apperror.NewDatabaseError("Ошибка получения страницы", err)
+	}
+	h.invalidate(r, fmt.Sprintf("chapter:%d:pages", page.ChapterID), readerCacheKey(page.ChapterID))
+	h.Audit.Record(r, models.AuditRestore, models.AuditPage, id, nil, page)
+	return page, nil
+}
+
+func (h *TrashHandler) invalidate(r *http.Request, keys ...string) {
+	if h.Cache == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := h.Cache.Delete(r.Context(), key); err != nil {
+			h.Logger.Error("Ошибка инвалидации кеша", "key", key, "err", err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+)
+
+func RegisterTrashRoutes(mux *http.ServeMux, th *TrashHandler) {
+	mux.Handle("/trash", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(th.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodGet {
+			return th.List(w, r)
+		} else {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+
+	mux.Handle("/trash/", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(th.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodPost {
+			return th.Restore(w, r)
+		} else {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"manga-reader/internal/response"
+	"manga-reader/models"
+	"net/http"
+)
+
+// recoveryCodeCount — число кодов восстановления, выдаваемых при подключении 2FA.
+const recoveryCodeCount = 10
+
+// TwoFactorChallenge возвращается при входе, если у пользователя включена 2FA.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	PreAuthToken      string `json:"pre_auth_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+func (h *UserHandler) startTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	token, err := auth.IssuePreAuthToken(r.Context(), user.ID)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось начать проверку второго фактора", err)
+	}
+	response.Success(w, http.StatusOK, TwoFactorChallenge{
+		TwoFactorRequired: true,
+		PreAuthToken:      token,
+		ExpiresIn:         int64(auth.PreAuthTokenTTL.Seconds()),
+	})
+	return nil
+}
+
+type LoginTwoFactorRequest struct {
+	PreAuthToken string `json:"pre_auth_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginTwoFactor завершает вход кодом TOTP или одноразовым кодом восстановления.
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	var req LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.PreAuthToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return apperror.NewValidationError("Необходимо указать pre_auth_token и code или recovery_code", nil)
+	}
+
+	userID, err := auth.PreAuthUser(r.Context(), req.PreAuthToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPreAuthToken) {
+			return apperror.NewUnauthorizedError("Недействительный или просроченный токен входа", nil)
+		}
+		return apperror.NewInternalServerError("Ошибка проверки токена входа", err)
+	}
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil || !user.TOTPEnabled {
+		return apperror.NewUnauthorizedError("Недействительный или просроченный токен входа", nil)
+	}
+
+	ip := middleware.ClientIP(r)
+	if err = h.checkLoginLimit(r, user.Username, ip); err != nil {
+		return err
+	}
+
+	var ok bool
+	if req.RecoveryCode != "" {
+		var remaining []string
+		if remaining, ok = auth.MatchRecoveryCode(user.RecoveryCodes, req.RecoveryCode); ok {
+			if err = h.UserRepo.UpdateTOTP(user.ID, user.TOTPSecret, true, remaining); err != nil {
+				return apperror.NewDatabaseError("Не удалось использовать код восстановления", err)
+			}
+		}
+	} else {
+		ok, err = auth.VerifyTOTP(r.Context(), user.ID, user.TOTPSecret, req.Code)
+		if err != nil {
+			return apperror.NewInternalServerError("Ошибка проверки кода", err)
+		}
+	}
+	if !ok {
+		h.registerLoginFailure(r, user.Username, ip)
+		if err = auth.FailPreAuthToken(r.Context(), req.PreAuthToken); err != nil {
+			h.Logger.Error("Ошибка учета неверного кода 2FA", "user_id", user.ID, "err", err)
+		}
+		return apperror.NewUnauthorizedError("Неверный код подтверждения", nil)
+	}
+
+	if err = auth.RevokePreAuthToken(r.Context(), req.PreAuthToken); err != nil {
+		return apperror.NewInternalServerError("Ошибка завершения входа", err)
+	}
+	h.registerLoginSuccess(r, user.Username)
+
+	tokens, err := auth.IssueTokens(r.Context(), user.ID, user.Role)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось сгенерировать токен", err)
+	}
+
+	response.Success(w, http.StatusOK, tokens)
+	return nil
+}
+
+type EnrollTwoFactorResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTwoFactor создает новый секрет TOTP и коды восстановления. 2FA
+// включается только после подтверждения кодом через ConfirmTwoFactor.
+func (h *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if user.TOTPEnabled {
+		return apperror.NewConflictError("Двухфакторная аутентификация уже включена", nil)
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось создать секрет TOTP", err)
+	}
+	codes, hashes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось создать коды восстановления", err)
+	}
+	if err = h.UserRepo.UpdateTOTP(user.ID, secret, false, hashes); err != nil {
+		return apperror.NewDatabaseError("Не удалось сохранить настройки 2FA", err)
+	}
+
+	response.Success(w, http.StatusOK, EnrollTwoFactorResponse{
+		Secret:        secret,
+		OTPAuthURI:    auth.TOTPURI(h.TOTPIssuer, user.Username, secret),
+		RecoveryCodes: codes,
+	})
+	return nil
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+// ConfirmTwoFactor включает 2FA, если код из приложения совпадает с
+// секретом, выданным при подключении.
+func (h *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req ConfirmTwoFactorRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if user.TOTPEnabled {
+		return apperror.NewConflictError("Двухфакторная аутентификация уже включена", nil)
+	}
+	if user.TOTPSecret == "" {
+		return apperror.NewBadRequestError("Сначала выполните подключение 2FA", nil)
+	}
+
+	ok, err := auth.VerifyTOTP(r.Context(), user.ID, user.TOTPSecret, req.Code)
+	if err != nil {
+		return apperror.NewInternalServerError("Ошибка проверки кода", err)
+	}
+	if !ok {
+		return apperror.NewValidationError("Неверный код подтверждения",
+			map[string]string{"code": "Введите текущий код из приложения"})
+	}
+
+	if err = h.UserRepo.UpdateTOTP(user.ID, user.TOTPSecret, true, user.RecoveryCodes); err != nil {
+		return apperror.NewDatabaseError("Не удалось сохранить настройки 2FA", err)
+	}
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID,
+		map[string]bool{"two_factor_enabled": false}, map[string]bool{"two_factor_enabled": true})
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Двухфакторная аутентификация включена"})
+	return nil
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+}
+
+// DisableTwoFactor отключает 2FA после подтверждения паролем.
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	var req DisableTwoFactorRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return apperror.NewUnauthorizedError("Неверный пароль", nil)
+	}
+
+	if err = h.UserRepo.UpdateTOTP(user.ID, "", false, nil); err != nil {
+		return apperror.NewDatabaseError("Не удалось отключить 2FA", err)
+	}
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID,
+		map[string]bool{"two_factor_enabled": user.TOTPEnabled}, map[string]bool{"two_factor_enabled": false})
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Двухфакторная аутентификация отключена"})
+	return nil
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"io"
 	"log/slog"
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/audit"
 	"manga-reader/internal/auth"
 	"manga-reader/internal/db"
+	"manga-reader/internal/mail"
+	"manga-reader/internal/middleware"
 	"manga-reader/internal/response"
 	"manga-reader/models"
 	"net/http"
@@ -16,10 +20,20 @@ import (
 type UserHandler struct {
 	UserRepo db.UserRepository
 	Logger   *slog.Logger
+	// Mailer отправляет письма сброса пароля; nil отключает сброс по email.
+	Mailer mail.Sender
+	// PublicURL — внешний адрес сервиса для ссылок в письмах.
+	PublicURL string
+	// Limiter ограничивает число неудачных попыток входа; nil отключает защиту.
+	Limiter *auth.LoginLimiter
+	// TOTPIssuer — название сервиса в приложении-аутентификаторе.
+	TOTPIssuer string
+	Audit      *audit.Recorder
 }
 
 type RegisterRequest struct {
 	Username string `json:"username"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
@@ -39,21 +53,19 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewValidationError("Поле username не может быть пустым",
 			map[string]string{"username": "Это поле обязательно"})
 	}
-	if req.Password == "" {
-		return apperror.NewValidationError("Поле password не может быть пустым",
-			map[string]string{"password": "Это поле обязательно"})
+	if err = validatePassword("password", req.Password); err != nil {
+		return err
 	}
-	if len(req.Password) < 6 {
-		return apperror.NewValidationError("Пароль слишком короткий",
-			map[string]string{"password": "Минимальная длина - 6 символов"})
+	if err = validateEmail(req.Email); err != nil {
+		return err
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := hashPassword(req.Password)
 	if err != nil {
-		return apperror.NewInternalServerError("Ошибка хеширования пароля", err)
+		return err
 	}
 
-	user := &models.User{Username: req.Username, Password: string(hashed)}
+	user := &models.User{Username: req.Username, Email: req.Email, Password: hashed, Role: models.RoleReader}
 	id, err := h.UserRepo.Create(user)
 	if err != nil {
 		return apperror.NewDatabaseError("Не удалось создать пользователя", err)
@@ -61,6 +73,7 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) error {
 
 	user.ID = id
 	user.Password = "" // Не возвращаем пароль в ответе
+	h.Audit.Record(r, models.AuditCreate, models.AuditUser, user.ID, nil, user)
 
 	response.Success(w, http.StatusCreated, user)
 	return nil
@@ -71,9 +84,7 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-type LoginResponse struct {
-	Token string `json:"token"`
-}
+type LoginResponse = auth.TokenPair
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) error {
 	var req LoginRequest
@@ -86,21 +97,148 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewValidationError("Имя пользователя и пароль обязательны", nil)
 	}
 
+	ip := middleware.ClientIP(r)
+	if err := h.checkLoginLimit(r, req.Username, ip); err != nil {
+		return err
+	}
+
 	user, err := h.UserRepo.GetByUsername(req.Username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	}
 	if err != nil {
+		h.registerLoginFailure(r, req.Username, ip)
 		return apperror.NewUnauthorizedError("Неверное имя пользователя или пароль", nil)
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return apperror.NewUnauthorizedError("Неверное имя пользователя или пароль", nil)
+	// При включенной 2FA счетчик неудач не сбрасывается до ввода второго
+	// фактора, иначе знание пароля позволяло бы перебирать коды без ограничений.
+	if user.TOTPEnabled {
+		return h.startTwoFactor(w, r, user)
 	}
 
-	token, err := auth.GenerateToken(user.ID)
+	h.registerLoginSuccess(r, req.Username)
+
+	tokens, err := auth.IssueTokens(r.Context(), user.ID, user.Role)
 	if err != nil {
 		return apperror.NewInternalServerError("Не удалось сгенерировать токен", err)
 	}
 
-	resp := LoginResponse{Token: token}
-	response.Success(w, http.StatusOK, resp)
+	response.Success(w, http.StatusOK, tokens)
+	return nil
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.RefreshToken == "" {
+		return apperror.NewValidationError("Поле refresh_token не может быть пустым",
+			map[string]string{"refresh_token": "Это поле обязательно"})
+	}
+
+	userID, err := auth.ConsumeRefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return apperror.NewUnauthorizedError("Недействительный токен обновления", nil)
+		}
+		return apperror.NewInternalServerError("Ошибка проверки токена обновления", err)
+	}
+
+	// Роль берется из БД, чтобы изменения прав применялись при обновлении токена.
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		return apperror.NewUnauthorizedError("Пользователь не найден", err)
+	}
+
+	tokens, err := auth.IssueTokens(r.Context(), user.ID, user.Role)
+	if err != nil {
+		return apperror.NewInternalServerError("Не удалось сгенерировать токен", err)
+	}
+
+	response.Success(w, http.StatusOK, tokens)
+	return nil
+}
+
+// Logout отзывает текущий токен доступа и, если передан, токен обновления.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) error {
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return apperror.NewBadRequestError("Неверный формат запроса", err)
+		}
+	}
+
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return apperror.NewUnauthorizedError("Требуется аутентификация", nil)
+	}
+	if err := auth.RevokeAccessToken(r.Context(), principal.TokenID, principal.TokenExpiresAt); err != nil {
+		return apperror.NewInternalServerError("Ошибка отзыва токена", err)
+	}
+
+	if req.RefreshToken != "" {
+		if err := auth.RevokeRefreshToken(r.Context(), req.RefreshToken); err != nil {
+			return apperror.NewInternalServerError("Ошибка отзыва токена обновления", err)
+		}
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Выход выполнен"})
+	return nil
+}
+
+// LogoutAll отзывает все токены пользователя на всех устройствах.
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) error {
+	userID, err := currentUserID(r)
+	if err != nil {
+		return err
+	}
+
+	if err = auth.RevokeAllTokens(r.Context(), userID); err != nil {
+		return apperror.NewInternalServerError("Ошибка отзыва токенов", err)
+	}
+
+	response.Success(w, http.StatusOK, map[string]string{"status": "success", "message": "Выход выполнен на всех устройствах"})
+	return nil
+}
+
+type UpdateRoleRequest struct {
+	UserID int64       `json:"user_id"`
+	Role   models.Role `json:"role"`
+}
+
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) error {
+	var req UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	if req.UserID <= 0 {
+		return apperror.NewValidationError("Некорректный ID пользователя",
+			map[string]string{"user_id": "Должен быть положительным числом"})
+	}
+	if !req.Role.Valid() {
+		return apperror.NewValidationError("Неизвестная роль",
+			map[string]string{"role": "Допустимые значения: reader, uploader, moderator, admin"})
+	}
+
+	user, err := h.UserRepo.GetByID(req.UserID)
+	if err != nil {
+		return apperror.NewNotFoundError("Пользователь не найден", err)
+	}
+
+	if err = h.UserRepo.UpdateRole(user.ID, req.Role); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить роль пользователя", err)
+	}
+	before := *user
+	user.Role = req.Role
+	h.Audit.Record(r, models.AuditUpdate, models.AuditUser, user.ID, before, user)
+
+	response.Success(w, http.StatusOK, user)
 	return nil
 }
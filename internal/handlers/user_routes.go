@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
 	"manga-reader/internal/middleware"
+	"manga-reader/models"
 	"net/http"
 )
 
@@ -19,4 +21,92 @@ func RegisterUserRoutes(mux *http.ServeMux, uh *UserHandler) {
 		}
 		return uh.Login(w, r)
 	}))
+	mux.HandleFunc("/user/login/2fa", middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.LoginTwoFactor(w, r)
+	}))
+	mux.HandleFunc("/user/refresh", middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.Refresh(w, r)
+	}))
+	mux.Handle("/user/logout", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.Logout(w, r)
+	})))
+	mux.Handle("/user/logout-all", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.LogoutAll(w, r)
+	})))
+	mux.Handle("/user/me", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return uh.Me(w, r)
+		case http.MethodPut:
+			return uh.UpdateAccount(w, r)
+		case http.MethodDelete:
+			return uh.DeleteAccount(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+	mux.Handle("/user/password", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.ChangePassword(w, r)
+	})))
+	mux.Handle("/user/2fa/enroll", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.EnrollTwoFactor(w, r)
+	})))
+	mux.Handle("/user/2fa/confirm", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.ConfirmTwoFactor(w, r)
+	})))
+	mux.Handle("/user/2fa/disable", auth.RequireSession(middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.DisableTwoFactor(w, r)
+	})))
+	mux.HandleFunc("/user/password/forgot", middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.ForgotPassword(w, r)
+	}))
+	mux.HandleFunc("/user/password/reset", middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return uh.ResetPasswordForm(w, r)
+		case http.MethodPost:
+			return uh.ResetPassword(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	}))
+	mux.Handle("/user/role", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPut {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.UpdateRole(w, r)
+	})))
+	mux.Handle("/user/unlock", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(uh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+		return uh.UnlockAccount(w, r)
+	})))
 }
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/db"
+	"manga-reader/internal/response"
+	"manga-reader/internal/webhook"
+	"manga-reader/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookHandler управляет подписками внешних сервисов на события каталога.
+type WebhookHandler struct {
+	Repo   db.WebhookRepository
+	Logger *slog.Logger
+}
+
+type WebhookRequest struct {
+	URL    *string                `json:"url"`
+	Secret *string                `json:"secret"`
+	Events *[]models.WebhookEvent `json:"events"`
+	Active *bool                  `json:"active"`
+}
+
+// WebhookSecretResponse содержит секрет подписи — он возвращается только
+// при создании вебхука и при его смене.
+type WebhookSecretResponse struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apperror.NewValidationError("Некорректный URL вебхука",
+			map[string]string{"url": "Ожидается абсолютный адрес http или https"})
+	}
+	return nil
+}
+
+func validateWebhookEvents(events []models.WebhookEvent) error {
+	for _, event := range events {
+		if !event.Valid() {
+			allowed := make([]string, len(models.WebhookEvents))
+			for i, e := range models.WebhookEvents {
+				allowed[i] = string(e)
+			}
+			return apperror.NewValidationError("Неизвестное событие "+string(event),
+				map[string]string{"events": "Допустимые значения: " + strings.Join(allowed, ", ")})
+		}
+	}
+	return nil
+}
+
+func webhookIDFromPath(r *http.Request) (int64, error) {
+	rest := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+	idStr, _, _ := strings.Cut(rest, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, apperror.NewBadRequestError("Некорректный ID вебхука", err)
+	}
+	return id, nil
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+	if req.URL == nil || *req.URL == "" {
+		return apperror.NewValidationError("Поле url не может быть пустым",
+			map[string]string{"url": "Это поле обязательно"})
+	}
+	if err := validateWebhookURL(*req.URL); err != nil {
+		return err
+	}
+
+	hook := &models.Webhook{URL: *req.URL, Active: true, Events: []models.WebhookEvent{}, CreatedAt: time.Now().UTC()}
+	if req.Events != nil {
+		if err := validateWebhookEvents(*req.Events); err != nil {
+			return err
+		}
+		hook.Events = *req.Events
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		hook.Secret = *req.Secret
+	} else {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			return apperror.NewInternalServerError("Не удалось создать секрет вебхука", err)
+		}
+		hook.Secret = secret
+	}
+
+	id, err := h.Repo.Create(hook)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось сохранить вебхук", err)
+	}
+	hook.ID = id
+
+	response.Success(w, http.StatusCreated, WebhookSecretResponse{Webhook: hook, Secret: hook.Secret})
+	return nil
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) error {
+	hooks, err := h.Repo.List()
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить список вебхуков", err)
+	}
+
+	response.Success(w, http.StatusOK, hooks)
+	return nil
+}
+
+func (h *WebhookHandler) get(id int64) (*models.Webhook, error) {
+	hook, err := h.Repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperror.NewNotFoundError("Вебхук не найден", err)
+		}
+		return nil, apperror.NewDatabaseError("Не удалось получить вебхук", err)
+	}
+	return hook, nil
+}
+
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		return err
+	}
+	hook, err := h.get(id)
+	if err != nil {
+		return err
+	}
+
+	response.Success(w, http.StatusOK, hook)
+	return nil
+}
+
+// Update меняет только переданные поля. Если передан secret, новый секрет
+// возвращается в ответе.
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		return err
+	}
+	var req WebhookRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperror.NewBadRequestError("Неверный формат запроса", err)
+	}
+
+	hook, err := h.get(id)
+	if err != nil {
+		return err
+	}
+	if req.URL != nil {
+		if err = validateWebhookURL(*req.URL); err != nil {
+			return err
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if err = validateWebhookEvents(*req.Events); err != nil {
+			return err
+		}
+		hook.Events = *req.Events
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			return apperror.NewValidationError("Поле secret не может быть пустым",
+				map[string]string{"secret": "Укажите новый секрет"})
+		}
+		hook.Secret = *req.Secret
+	}
+
+	if err = h.Repo.Update(hook); err != nil {
+		return apperror.NewDatabaseError("Не удалось обновить вебхук", err)
+	}
+
+	if req.Secret != nil {
+		response.Success(w, http.StatusOK, WebhookSecretResponse{Webhook: hook, Secret: hook.Secret})
+		return nil
+	}
+	response.Success(w, http.StatusOK, hook)
+	return nil
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		return err
+	}
+
+	if err = h.Repo.Delete(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NewNotFoundError("Вебхук не найден", err)
+		}
+		return apperror.NewDatabaseError("Не удалось удалить вебхук", err)
+	}
+
+	response.Success(w, http.StatusNoContent, nil)
+	return nil
+}
+
+// Deliveries возвращает журнал попыток доставки от новых к старым.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) error {
+	id, err := webhookIDFromPath(r)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return err
+	}
+	if _, err = h.get(id); err != nil {
+		return err
+	}
+
+	deliveries, err := h.Repo.ListDeliveries(id, limit, offset)
+	if err != nil {
+		return apperror.NewDatabaseError("Не удалось получить журнал доставки", err)
+	}
+
+	response.Success(w, http.StatusOK, deliveries)
+	return nil
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"manga-reader/internal/apperror"
+	"manga-reader/internal/auth"
+	"manga-reader/internal/middleware"
+	"manga-reader/models"
+	"net/http"
+	"strings"
+)
+
+func RegisterWebhookRoutes(mux *http.ServeMux, wh *WebhookHandler) {
+	mux.Handle("/webhooks", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(wh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+			return wh.List(w, r)
+		case http.MethodPost:
+			return wh.Create(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+	mux.Handle("/webhooks/", auth.RequireRole(models.RoleAdmin, middleware.ErrorHandler(wh.Logger, func(w http.ResponseWriter, r *http.Request) error {
+		if strings.HasSuffix(r.URL.Path, "/deliveries") {
+			if r.Method != http.MethodGet {
+				return apperror.NewBadRequestError("Метод не поддерживается", nil)
+			}
+			return wh.Deliveries(w, r)
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return wh.Get(w, r)
+		case http.MethodPut:
+			return wh.Update(w, r)
+		case http.MethodDelete:
+			return wh.Delete(w, r)
+		default:
+			return apperror.NewBadRequestError("Метод не поддерживается", nil)
+		}
+	})))
+}
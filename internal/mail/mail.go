@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message — исходящее письмо.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender описывает способ доставки писем.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender отправляет письма через SMTP-сервер.
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+	return &SMTPSender{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	return smtp.SendMail(addr, auth, s.From, []string{msg.To}, buildMessage(s.From, msg))
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerValue(from) + "\r\n")
+	b.WriteString("To: " + headerValue(msg.To) + "\r\n")
+	b.WriteString("Subject: " + headerValue(msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+// LogSender не отправляет письма, а пишет их в лог и, если задан каталог,
+// сохраняет в файлы. Предназначен для локальной разработки и тестов.
+type LogSender struct {
+	Logger *slog.Logger
+	Dir    string
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewLogSender(logger *slog.Logger, dir string) *LogSender {
+	return &LogSender{Logger: logger, Dir: dir}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	s.sent = append(s.sent, msg)
+	s.mu.Unlock()
+
+	if s.Logger != nil {
+		s.Logger.Info("Письмо", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	}
+	if s.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), sanitizeFilename(msg.To))
+	return os.WriteFile(filepath.Join(s.Dir, name), buildMessage("noreply@localhost", msg), 0644)
+}
+
+// Sent возвращает копию всех отправленных писем.
+func (s *LogSender) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.sent...)
+}
+
+// headerValue удаляет переводы строк, чтобы нельзя было внедрить заголовки.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
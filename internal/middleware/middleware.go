@@ -1,21 +1,75 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log/slog"
 	"manga-reader/internal/apperror"
 	"manga-reader/internal/response"
+	"net"
 	"net/http"
+	"regexp"
 	"runtime/debug"
 	"time"
 )
 
+// RequestIDHeader — заголовок с идентификатором запроса. Он возвращается
+// клиенту и попадает в журналы и записи аудита.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern ограничивает идентификаторы, принимаемые от клиента или
+// прокси, чтобы в журналы не попадали произвольные строки.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// ClientIP возвращает адрес клиента из соединения. Заголовки вроде
+// X-Forwarded-For не учитываются: их может подделать сам клиент.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RequestIDMiddleware сохраняет в контексте идентификатор запроса из
+// заголовка X-Request-ID или генерирует новый.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFrom возвращает идентификатор запроса или пустую строку, если
+// запрос не прошел через RequestIDMiddleware.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
 func LoggingMiddleware(log *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Info("Начало обработки запроса", "method", r.Method, "path", r.URL.Path)
+		requestID := RequestIDFrom(r.Context())
+		log.Info("Начало обработки запроса", "method", r.Method, "path", r.URL.Path, "request_id", requestID)
 		next.ServeHTTP(w, r)
 		duration := time.Since(start)
-		log.Info("Запрос обработан", "method", r.Method, "path", r.URL.Path, "duration", duration)
+		log.Info("Запрос обработан", "method", r.Method, "path", r.URL.Path, "request_id", requestID, "duration", duration)
 	})
 }
 
@@ -0,0 +1,326 @@
+// Package oidc реализует вход через внешнего провайдера OpenID Connect
+// по схеме authorization code с PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"manga-reader/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Config описывает подключение к провайдеру.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// RoleClaim — имя утверждения ID-токена со списком групп или ролей.
+	RoleClaim string
+	// RoleMapping сопоставляет значения RoleClaim ролям приложения.
+	RoleMapping map[string]models.Role
+}
+
+// Claims — данные пользователя из проверенного ID-токена.
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Nonce             string
+	// Groups — значения утверждения RoleClaim.
+	Groups []string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider выполняет обмен кода на токены и проверку ID-токенов.
+type Provider struct {
+	cfg    Config
+	meta   discovery
+	client *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]interface{}
+}
+
+// Discover загружает метаданные провайдера из /.well-known/openid-configuration.
+// Если client равен nil, используется клиент с таймаутом 10 секунд.
+func Discover(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	wellKnown := strings.TrimRight(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var meta discovery
+	if err := getJSON(ctx, client, wellKnown, &meta); err != nil {
+		return nil, fmt.Errorf("загрузка метаданных OIDC: %w", err)
+	}
+	// Провайдер обязан сообщать тот же issuer, по которому его нашли,
+	// иначе подменный документ мог бы направить проверку на чужие ключи.
+	if strings.TrimRight(meta.Issuer, "/") != strings.TrimRight(cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("issuer %q не совпадает с настроенным %q", meta.Issuer, cfg.IssuerURL)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("метаданные OIDC неполные")
+	}
+
+	return &Provider{cfg: cfg, meta: meta, client: client, keys: map[string]interface{}{}}, nil
+}
+
+// AuthCodeURL формирует адрес страницы входа провайдера.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.meta.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange обменивает код авторизации на токены и возвращает проверенные
+// данные ID-токена. nonce должен совпадать с переданным в AuthCodeURL.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("запрос токена OIDC: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("провайдер OIDC вернул статус %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("разбор ответа OIDC: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("провайдер OIDC не вернул id_token")
+	}
+
+	claims, err := p.VerifyIDToken(ctx, tokens.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("nonce ID-токена не совпадает")
+	}
+	return claims, nil
+}
+
+// VerifyIDToken проверяет подпись, issuer, audience и срок действия ID-токена.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw string) (*Claims, error) {
+	parser := jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384"}}
+	token, err := parser.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("проверка ID-токена: %w", err)
+	}
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("недействительный ID-токен")
+	}
+	if !mc.VerifyIssuer(p.meta.Issuer, true) {
+		return nil, errors.New("issuer ID-токена не совпадает")
+	}
+	if !mc.VerifyAudience(p.cfg.ClientID, true) {
+		return nil, errors.New("ID-токен выпущен для другого клиента")
+	}
+	if _, ok := mc["exp"]; !ok {
+		return nil, errors.New("в ID-токене нет срока действия")
+	}
+
+	claims := &Claims{Issuer: p.meta.Issuer}
+	claims.Subject, _ = mc["sub"].(string)
+	claims.Email, _ = mc["email"].(string)
+	claims.EmailVerified, _ = mc["email_verified"].(bool)
+	claims.PreferredUsername, _ = mc["preferred_username"].(string)
+	claims.Name, _ = mc["name"].(string)
+	claims.Nonce, _ = mc["nonce"].(string)
+	if claims.Subject == "" {
+		return nil, errors.New("в ID-токене нет sub")
+	}
+	if p.cfg.RoleClaim != "" {
+		claims.Groups = stringList(mc[p.cfg.RoleClaim])
+	}
+	return claims, nil
+}
+
+// MapRole возвращает наибольшую роль, сопоставленную группам пользователя,
+// и false, если ни одна группа не сопоставлена.
+func (p *Provider) MapRole(claims *Claims) (models.Role, bool) {
+	var (
+		role  models.Role
+		found bool
+	)
+	for _, group := range claims.Groups {
+		mapped, ok := p.cfg.RoleMapping[group]
+		if !ok || !mapped.Valid() {
+			continue
+		}
+		if !found || !role.Includes(mapped) {
+			role = mapped
+			found = true
+		}
+	}
+	return role, found
+}
+
+// key возвращает открытый ключ по kid, перезагружая JWKS, если ключ не
+// найден: провайдер мог выполнить ротацию.
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	key, ok := p.lookup(kid)
+	p.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := fetchJWKS(ctx, p.client, p.meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	key, ok = p.lookup(kid)
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("неизвестный ключ подписи %q", kid)
+	}
+	return key, nil
+}
+
+// lookup вызывается под мьютексом. Пустой kid допустим, только если у
+// провайдера ровно один ключ.
+func (p *Provider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// RandomString возвращает случайную строку для state, nonce и code_verifier.
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge вычисляет code_challenge по методу S256 (RFC 7636).
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: статус %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// ParseRoleMapping разбирает сопоставление вида "группа:роль".
+func ParseRoleMapping(items []string) (map[string]models.Role, error) {
+	mapping := make(map[string]models.Role, len(items))
+	for _, item := range items {
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("ожидается формат группа:роль, получено %q", item)
+		}
+		role := models.Role(strings.TrimSpace(item[i+1:]))
+		if !role.Valid() {
+			return nil, fmt.Errorf("неизвестная роль %q", role)
+		}
+		mapping[strings.TrimSpace(item[:i])] = role
+	}
+	return mapping, nil
+}
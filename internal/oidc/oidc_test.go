@@ -0,0 +1,88 @@
+package oidc_test
+
+import (
+	"context"
+	"manga-reader/internal/oidc"
+	"manga-reader/internal/oidc/oidctest"
+	"manga-reader/models"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func newTestProvider(t *testing.T, issuer *oidctest.Issuer) *oidc.Provider {
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		IssuerURL:    issuer.URL(),
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://app.test/auth/oidc/callback",
+		RoleClaim:    "groups",
+		RoleMapping:  map[string]models.Role{"editors": models.RoleUploader, "staff": models.RoleModerator},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Ошибка discovery: %v", err)
+	}
+	return provider
+}
+
+// authorize проходит страницу входа заглушки и возвращает выданный код.
+func authorize(t *testing.T, authURL string) string {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Ошибка запроса authorize: %v", err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("Ожидалось перенаправление с кодом, статус %d", resp.StatusCode)
+	}
+	return location.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	issuer := oidctest.NewIssuer("client", "secret")
+	defer issuer.Close()
+	provider := newTestProvider(t, issuer)
+	issuer.SetUser(map[string]interface{}{
+		"sub":            "u-1",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"groups":         []string{"editors", "staff"},
+	})
+
+	code := authorize(t, provider.AuthCodeURL("state", "nonce-1", "verifier-1"))
+	claims, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Ошибка обмена кода: %v", err)
+	}
+	if claims.Subject != "u-1" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Errorf("Неожиданные данные ID-токена: %+v", claims)
+	}
+	if role, ok := provider.MapRole(claims); !ok || role != models.RoleModerator {
+		t.Errorf("Ожидалась роль moderator, получено %q", role)
+	}
+
+	code = authorize(t, provider.AuthCodeURL("state", "nonce-2", "verifier-2"))
+	if _, err = provider.Exchange(context.Background(), code, "wrong-verifier", "nonce-2"); err == nil {
+		t.Error("Обмен с неверным code_verifier должен отклоняться")
+	}
+
+	code = authorize(t, provider.AuthCodeURL("state", "nonce-3", "verifier-3"))
+	if _, err = provider.Exchange(context.Background(), code, "verifier-3", "other-nonce"); err == nil {
+		t.Error("ID-токен с чужим nonce должен отклоняться")
+	}
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := oidc.ParseRoleMapping([]string{"urn:corp:admins:admin", "editors:uploader"})
+	if err != nil {
+		t.Fatalf("Ошибка разбора: %v", err)
+	}
+	if mapping["urn:corp:admins"] != models.RoleAdmin || mapping["editors"] != models.RoleUploader {
+		t.Errorf("Неожиданное сопоставление: %v", mapping)
+	}
+	if _, err = oidc.ParseRoleMapping([]string{"editors:owner"}); err == nil {
+		t.Error("Неизвестная роль должна вызывать ошибку")
+	}
+}
@@ -0,0 +1,163 @@
+// Package opds описывает каталоги OPDS для приложений-читалок (KOReader,
+// Panels и т. п.): OPDS 1.2 поверх Atom, OPDS 2.0 в JSON, описание поиска
+// OpenSearch и ссылки потокового чтения страниц OPDS-PSE 1.1.
+package opds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	NavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	OpenSearchType  = "application/opensearchdescription+xml"
+	JSONType        = "application/opds+json"
+	CBZType         = "application/vnd.comicbook+zip"
+)
+
+// Отношения ссылок, определенные OPDS и OPDS-PSE.
+const (
+	RelStart       = "start"
+	RelSelf        = "self"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition/open-access"
+	RelStream      = "http://vaemendis.net/opds-pse/stream"
+)
+
+const (
+	atomNS = "http://www.w3.org/2005/Atom"
+	opdsNS = "http://opds-spec.org/2010/catalog"
+	pseNS  = "http://vaemendis.net/opds-pse/ns"
+	osNS   = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+// Feed — лента каталога OPDS 1.2.
+type Feed struct {
+	XMLName xml.Name `xml:"feed"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	OPDS    string   `xml:"xmlns:opds,attr"`
+	PSE     string   `xml:"xmlns:pse,attr"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []Link   `xml:"link"`
+	Entries []Entry  `xml:"entry"`
+}
+
+// Entry — запись ленты: раздел каталога или публикация.
+type Entry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Content *Content `xml:"content,omitempty"`
+	Links   []Link   `xml:"link"`
+}
+
+type Content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Link — ссылка Atom. Count — число страниц в ссылке OPDS-PSE,
+// выводится как pse:count.
+type Link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	Count int    `xml:"pse:count,attr,omitempty"`
+}
+
+// NewFeed создает ленту с объявленными пространствами имен.
+func NewFeed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:   atomNS,
+		OPDS:    opdsNS,
+		PSE:     pseNS,
+		ID:      id,
+		Title:   title,
+		Updated: FormatTime(updated),
+	}
+}
+
+// FormatTime форматирует время для элементов updated.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// OpenSearchDescription — описание поиска по каталогу.
+type OpenSearchDescription struct {
+	XMLName     xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	ShortName   string          `xml:"ShortName"`
+	Description string          `xml:"Description"`
+	InputEnc    string          `xml:"InputEncoding"`
+	OutputEnc   string          `xml:"OutputEncoding"`
+	URLs        []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// NewOpenSearch создает описание поиска. Шаблоны должны содержать
+// параметр {searchTerms}.
+func NewOpenSearch(name, description string, urls ...OpenSearchURL) *OpenSearchDescription {
+	return &OpenSearchDescription{
+		Xmlns:       osNS,
+		ShortName:   name,
+		Description: description,
+		InputEnc:    "UTF-8",
+		OutputEnc:   "UTF-8",
+		URLs:        urls,
+	}
+}
+
+// Marshal сериализует документ OPDS 1.2 или OpenSearch с XML-заголовком.
+func Marshal(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// Catalog — документ OPDS 2.0: навигация или список публикаций.
+type Catalog struct {
+	Metadata     Metadata      `json:"metadata"`
+	Links        []JSONLink    `json:"links"`
+	Navigation   []JSONLink    `json:"navigation,omitempty"`
+	Publications []Publication `json:"publications,omitempty"`
+}
+
+type Metadata struct {
+	Title         string `json:"title"`
+	Identifier    string `json:"identifier,omitempty"`
+	Type          string `json:"@type,omitempty"`
+	Description   string `json:"description,omitempty"`
+	Language      string `json:"language,omitempty"`
+	Published     string `json:"published,omitempty"`
+	Modified      string `json:"modified,omitempty"`
+	NumberOfItems int    `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int    `json:"itemsPerPage,omitempty"`
+	CurrentPage   int    `json:"currentPage,omitempty"`
+}
+
+type JSONLink struct {
+	Rel       string `json:"rel,omitempty"`
+	Href      string `json:"href"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+// Publication — публикация OPDS 2.0 (глава).
+type Publication struct {
+	Metadata Metadata   `json:"metadata"`
+	Links    []JSONLink `json:"links"`
+}
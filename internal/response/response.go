@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"log/slog"
 	"manga-reader/internal/apperror"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 )
 
 type ErrorResponse struct {
@@ -48,6 +50,11 @@ func Error(w http.ResponseWriter, logger *slog.Logger, err error) {
 		statusCode = appErr.StatusCode
 		errorResp = appErr
 
+		if appErr.RetryAfter > 0 {
+			seconds := int64(math.Ceil(appErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+
 		if appErr.Err != nil {
 			logger.Error("Ошибка обработки запроса",
 				"statusCode", statusCode,
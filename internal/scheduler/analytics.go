@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"manga-reader/internal/analytics"
+	"manga-reader/internal/db"
+	"time"
+)
+
+// AnalyticsRollup периодически переносит посуточные просмотры манги и глав
+// из Redis в историю просмотров в базе данных. Просмотры, набранные после
+// последнего переноса, теряются при очистке Redis.
+type AnalyticsRollup struct {
+	periodic
+	analytics *analytics.AnalyticsService
+	history   db.ViewHistoryRepository
+	logger    *slog.Logger
+}
+
+// NewAnalyticsRollup создает перенос просмотров с периодом в час. Перед
+// использованием его нужно запустить методом Start.
+func NewAnalyticsRollup(service *analytics.AnalyticsService, history db.ViewHistoryRepository, logger *slog.Logger) *AnalyticsRollup {
+	p := &AnalyticsRollup{analytics: service, history: history, logger: logger}
+	p.init(time.Hour, func(now time.Time) {
+		if _, err := p.RunOnce(now); err != nil {
+			p.logger.Error("Ошибка переноса просмотров в историю", "err", err)
+		}
+	})
+	return p
+}
+
+// RunOnce сохраняет просмотры за сутки, в которые входит now, и за
+// предыдущие — чтобы после полуночи дописать их последние часы, — и
+// возвращает число сохраненных записей.
+func (p *AnalyticsRollup) RunOnce(now time.Time) (int, error) {
+	saved := 0
+	for _, day := range []time.Time{now.Add(-24 * time.Hour), now} {
+		stats, err := p.analytics.DailyViews(context.Background(), day)
+		if err != nil {
+			return saved, err
+		}
+		if len(stats) == 0 {
+			continue
+		}
+		if err = p.history.Save(stats); err != nil {
+			return saved, err
+		}
+		saved += len(stats)
+	}
+	return saved, nil
+}
@@ -0,0 +1,93 @@
+// Package scheduler выполняет фоновые задачи сервера по расписанию.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"sync"
+	"time"
+)
+
+// periodic выполняет фоновую задачу раз в Interval. Задачи встраивают его
+// и получают методы Start и Close; сама задача передается в init.
+type periodic struct {
+	// Interval — период запуска задачи.
+	Interval time.Duration
+
+	run  func(now time.Time)
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func (p *periodic) init(interval time.Duration, run func(now time.Time)) {
+	p.Interval = interval
+	p.run = run
+	p.done = make(chan struct{})
+}
+
+// Start запускает задачу в фоне. Первый запуск выполняется сразу, чтобы
+// работа, накопившаяся, пока сервер был остановлен, не ждала целый период.
+func (p *periodic) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			p.run(time.Now())
+			select {
+			case <-ticker.C:
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close останавливает задачу и дожидается завершения текущего запуска.
+func (p *periodic) Close() {
+	p.once.Do(func() { close(p.done) })
+	p.wg.Wait()
+}
+
+// ChapterPublisher периодически публикует запланированные главы, время
+// публикации которых наступило. Для каждой опубликованной главы вызывается
+// OnPublish — он сбрасывает кеш и рассылает уведомления.
+type ChapterPublisher struct {
+	periodic
+	repo   db.ChapterRepository
+	logger *slog.Logger
+
+	// OnPublish вызывается после публикации каждой главы.
+	OnPublish func(ctx context.Context, ch *models.Chapter)
+}
+
+// NewChapterPublisher создает планировщик с проверкой раз в минуту. Перед
+// использованием его нужно запустить методом Start.
+func NewChapterPublisher(repo db.ChapterRepository, logger *slog.Logger) *ChapterPublisher {
+	p := &ChapterPublisher{repo: repo, logger: logger}
+	p.init(time.Minute, func(now time.Time) {
+		if _, err := p.RunOnce(now); err != nil {
+			p.logger.Error("Ошибка публикации запланированных глав", "err", err)
+		}
+	})
+	return p
+}
+
+// RunOnce публикует главы, запланированные не позже now, и возвращает их число.
+func (p *ChapterPublisher) RunOnce(now time.Time) (int, error) {
+	chapters, err := p.repo.PublishDue(now)
+	if err != nil {
+		return 0, err
+	}
+	for _, ch := range chapters {
+		p.logger.Info("Опубликована запланированная глава", "chapter_id", ch.ID, "manga_id", ch.MangaID)
+		if p.OnPublish != nil {
+			p.OnPublish(context.Background(), ch)
+		}
+	}
+	return len(chapters), nil
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"manga-reader/internal/db"
+	"os"
+	"time"
+)
+
+// TrashPurger периодически окончательно удаляет мангу, главы и страницы,
+// пролежавшие в корзине дольше Retention, вместе с файлами изображений.
+type TrashPurger struct {
+	periodic
+	manga    db.MangaRepository
+	chapters db.ChapterRepository
+	pages    db.PageRepository
+	logger   *slog.Logger
+
+	// Retention — срок хранения удаленных записей в корзине.
+	Retention time.Duration
+}
+
+// NewTrashPurger создает очистку корзины со сроком хранения 30 дней и
+// проверкой раз в час. Перед использованием ее нужно запустить методом Start.
+func NewTrashPurger(manga db.MangaRepository, chapters db.ChapterRepository, pages db.PageRepository, logger *slog.Logger) *TrashPurger {
+	p := &TrashPurger{
+		manga:     manga,
+		chapters:  chapters,
+		pages:     pages,
+		logger:    logger,
+		Retention: 30 * 24 * time.Hour,
+	}
+	p.init(time.Hour, func(now time.Time) {
+		if _, err := p.RunOnce(now); err != nil {
+			p.logger.Error("Ошибка очистки корзины", "err", err)
+		}
+	})
+	return p
+}
+
+// RunOnce удаляет записи, перенесенные в корзину раньше now-Retention, и
+// возвращает их число. Страницы удаляются первыми вместе со всеми
+// страницами очищаемых глав и манги, затем главы, поэтому к очистке манги
+// зависимых записей и их файлов уже не остается.
+func (p *TrashPurger) RunOnce(now time.Time) (int64, error) {
+	before := now.Add(-p.Retention)
+
+	pages, err := p.pages.PurgeDeleted(before)
+	if err != nil {
+		return 0, err
+	}
+	for _, page := range pages {
+		if err = os.Remove(page.ImagePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			p.logger.Error("Ошибка удаления файла изображения", "page_id", page.ID, "path", page.ImagePath, "err", err)
+		}
+	}
+	purged := int64(len(pages))
+
+	chapters, err := p.chapters.PurgeDeleted(before)
+	if err != nil {
+		return purged, err
+	}
+	purged += chapters
+
+	manga, err := p.manga.PurgeDeleted(before)
+	if err != nil {
+		return purged, err
+	}
+	purged += manga
+
+	if purged > 0 {
+		p.logger.Info("Очищена корзина", "pages", len(pages), "chapters", chapters, "manga", manga)
+	}
+	return purged, nil
+}
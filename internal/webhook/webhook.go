@@ -0,0 +1,305 @@
+// Package webhook доставляет события каталога внешним сервисам.
+//
+// Каждое событие отправляется POST-запросом с JSON-телом Event. Тело
+// подписывается HMAC-SHA256 секретом вебхука: подпись вычисляется от строки
+// "<timestamp>.<тело>" и передается в заголовке X-Webhook-Signature вида
+// "sha256=<hex>", а timestamp — в заголовке X-Webhook-Timestamp (Unix-время
+// в секундах). Получатель должен сверить подпись и отклонять запросы со
+// слишком старым timestamp, чтобы исключить повтор перехваченного запроса.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/models"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event — тело запроса, отправляемого вебхуку.
+type Event struct {
+	ID        string              `json:"id"`
+	Type      models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      interface{}         `json:"data"`
+}
+
+// Sign вычисляет значение заголовка X-Webhook-Signature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret возвращает случайный секрет для подписи.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// job — доставка события: без hook — рассылка всем подходящим вебхукам,
+// с hook — очередная попытка доставки конкретному вебхуку.
+type job struct {
+	event   *Event
+	body    []byte
+	hook    *models.Webhook
+	attempt int
+}
+
+// Dispatcher асинхронно доставляет события с повторными попытками и
+// записывает каждую попытку в журнал доставки. Методы безопасны для nil,
+// поэтому обработчики могут вызывать Emit, даже если вебхуки не настроены.
+type Dispatcher struct {
+	repo   db.WebhookRepository
+	logger *slog.Logger
+
+	// Client выполняет запросы к получателям.
+	Client *http.Client
+	// Workers — число параллельных обработчиков очереди.
+	Workers int
+	// MaxAttempts — число попыток доставки, включая первую.
+	MaxAttempts int
+	// BaseDelay — задержка перед второй попыткой; каждая следующая
+	// удваивается, но не превышает MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	queue chan job
+	done  chan struct{}
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewDispatcher создает диспетчер с настройками по умолчанию. Перед
+// использованием его нужно запустить методом Start.
+func NewDispatcher(repo db.WebhookRepository, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		logger:      logger,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Workers:     4,
+		MaxAttempts: 6,
+		BaseDelay:   10 * time.Second,
+		MaxDelay:    30 * time.Minute,
+		queue:       make(chan job, 1024),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start запускает обработчики очереди.
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Close прекращает прием событий, доставляет уже поставленные в очередь и
+// дожидается завершения доставок. Запланированные повторные попытки
+// отбрасываются.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.done)
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// Emit ставит событие в очередь доставки. Данные сериализуются сразу,
+// поэтому вызывающий код может изменять data после возврата.
+func (d *Dispatcher) Emit(eventType models.WebhookEvent, data interface{}) {
+	if d == nil {
+		return
+	}
+	id, err := newEventID()
+	if err != nil {
+		d.logger.Error("Ошибка генерации ID события вебхука", "event", eventType, "err", err)
+		return
+	}
+	event := &Event{ID: id, Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("Ошибка сериализации события вебхука", "event", eventType, "err", err)
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	select {
+	case d.queue <- job{event: event, body: body}:
+	default:
+		d.logger.Error("Очередь вебхуков переполнена, событие отброшено", "event", eventType, "event_id", id)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case j := <-d.queue:
+			d.handle(j)
+		case <-d.done:
+			// После остановки обрабатываем оставшиеся в очереди задания,
+			// включая поставленные рассылкой других обработчиков.
+			for {
+				select {
+				case j := <-d.queue:
+					d.handle(j)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) handle(j job) {
+	if j.hook == nil {
+		d.fanOut(j)
+	} else {
+		d.attempt(j)
+	}
+}
+
+// fanOut ставит доставку каждому подходящему вебхуку отдельным заданием,
+// чтобы медленный получатель не задерживал остальных. Если очередь
+// заполнена, доставка выполняется сразу: обработчик не может ждать места
+// в очереди, которую сам же разбирает.
+func (d *Dispatcher) fanOut(j job) {
+	hooks, err := d.repo.List()
+	if err != nil {
+		d.logger.Error("Ошибка получения списка вебхуков", "event_id", j.event.ID, "err", err)
+		return
+	}
+	for _, hook := range hooks {
+		if !hook.Matches(j.event.Type) {
+			continue
+		}
+		delivery := job{event: j.event, body: j.body, hook: hook, attempt: 1}
+		select {
+		case d.queue <- delivery:
+		default:
+			d.attempt(delivery)
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(j job) {
+	started := time.Now()
+	status, err := d.send(j)
+	delivery := &models.WebhookDelivery{
+		WebhookID:  j.hook.ID,
+		EventID:    j.event.ID,
+		Event:      j.event.Type,
+		Attempt:    j.attempt,
+		StatusCode: status,
+		Success:    err == nil,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if logErr := d.repo.LogDelivery(delivery); logErr != nil {
+		d.logger.Error("Ошибка записи журнала доставки вебхука", "webhook_id", j.hook.ID, "err", logErr)
+	}
+	if err == nil {
+		return
+	}
+
+	if !retryable(status) || j.attempt >= d.MaxAttempts {
+		d.logger.Warn("Доставка вебхука не удалась", "webhook_id", j.hook.ID, "event_id", j.event.ID,
+			"attempt", j.attempt, "err", err)
+		return
+	}
+	d.retry(job{event: j.event, body: j.body, hook: j.hook, attempt: j.attempt + 1})
+}
+
+// retry планирует следующую попытку. Таймер не занимает обработчик
+// очереди, а при остановке диспетчера попытка отбрасывается.
+func (d *Dispatcher) retry(j job) {
+	delay := d.BaseDelay << (j.attempt - 2)
+	if delay <= 0 || delay > d.MaxDelay {
+		delay = d.MaxDelay
+	}
+	time.AfterFunc(delay, func() {
+		select {
+		case d.queue <- j:
+		case <-d.done:
+		}
+	})
+}
+
+func (d *Dispatcher) send(j job) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "manga-reader-webhook/1")
+	req.Header.Set(HeaderEvent, string(j.event.Type))
+	req.Header.Set(HeaderEventID, j.event.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(j.hook.Secret, timestamp, j.body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("получатель вернул статус %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable сообщает, имеет ли смысл повторять доставку. Ответы 4xx, кроме
+// 408 и 429, означают, что получатель отклонил запрос, и повтор не поможет.
+func retryable(status int) bool {
+	return status == 0 || status >= 500 ||
+		status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+func newEventID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"manga-reader/internal/db"
+	"manga-reader/internal/db/sqlite"
+	"manga-reader/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver — тестовый получатель, отвечающий статусами из очереди
+// responses, а после ее исчерпания — 200.
+type receiver struct {
+	mu        sync.Mutex
+	responses []int
+	requests  []received
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, received{header: r.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(rc.responses) > 0 {
+		status, rc.responses = rc.responses[0], rc.responses[1:]
+	}
+	rc.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func setupDispatcher(t *testing.T) (*Dispatcher, db.WebhookRepository) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Ошибка открытия in-memory базы: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := sqlite.NewWebhookRepository(conn, logger)
+	d := NewDispatcher(repo, logger)
+	d.BaseDelay = 10 * time.Millisecond
+	d.MaxAttempts = 3
+	d.Start()
+	t.Cleanup(d.Close)
+	return d, repo
+}
+
+func createHook(t *testing.T, repo db.WebhookRepository, url string, events ...models.WebhookEvent) *models.Webhook {
+	hook := &models.Webhook{URL: url, Secret: "topsecret", Events: events, Active: true}
+	id, err := repo.Create(hook)
+	if err != nil {
+		t.Fatalf("Ошибка создания вебхука: %v", err)
+	}
+	hook.ID = id
+	return hook
+}
+
+func waitDeliveries(t *testing.T, repo db.WebhookRepository, hookID int64, want int) []*models.WebhookDelivery {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		deliveries, err := repo.ListDeliveries(hookID, 100, 0)
+		if err != nil {
+			t.Fatalf("Ошибка получения журнала доставки: %v", err)
+		}
+		if len(deliveries) >= want || time.Now().After(deadline) {
+			if len(deliveries) != want {
+				t.Fatalf("Ожидалось %d записей журнала, получено %d", want, len(deliveries))
+			}
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherSignsAndDelivers(t *testing.T) {
+	d, repo := setupDispatcher(t)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	hook := createHook(t, repo, server.URL, models.EventChapterCreated)
+	other := createHook(t, repo, server.URL, models.EventPageDeleted)
+
+	d.Emit(models.EventChapterCreated, models.Chapter{ID: 7, MangaID: 1, Number: 3, Title: "Затмение"})
+	deliveries := waitDeliveries(t, repo, hook.ID, 1)
+	if !deliveries[0].Success || deliveries[0].StatusCode != http.StatusOK || deliveries[0].Attempt != 1 {
+		t.Errorf("Неожиданная запись журнала: %+v", deliveries[0])
+	}
+	waitDeliveries(t, repo, other.ID, 0)
+
+	if rc.count() != 1 {
+		t.Fatalf("Ожидался 1 запрос, получено %d", rc.count())
+	}
+	req := rc.requests[0]
+	timestamp, err := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("Некорректный заголовок %s: %v", HeaderTimestamp, err)
+	}
+	if got := req.header.Get(HeaderSignature); got != Sign("topsecret", timestamp, req.body) {
+		t.Errorf("Подпись не совпадает: %s", got)
+	}
+	if req.header.Get(HeaderEvent) != string(models.EventChapterCreated) {
+		t.Errorf("Неожиданный заголовок события: %s", req.header.Get(HeaderEvent))
+	}
+
+	var event struct {
+		ID    string              `json:"id"`
+		Event models.WebhookEvent `json:"event"`
+		Data  models.Chapter      `json:"data"`
+	}
+	if err = json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("Ошибка разбора тела: %v", err)
+	}
+	if event.ID != req.header.Get(HeaderEventID) || event.Data.ID != 7 || event.Data.Title != "Затмение" {
+		t.Errorf("Неожиданное тело события: %+v", event)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	d, repo := setupDispatcher(t)
+	rc := &receiver{responses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := createHook(t, repo, server.URL)
+
+	d.Emit(models.EventMangaCreated, models.Manga{ID: 1, Title: "Берсерк"})
+	deliveries := waitDeliveries(t, repo, hook.ID, 3)
+	// Журнал возвращается от новых записей к старым.
+	for i, want := range []struct {
+		attempt int
+		status  int
+		success bool
+	}{
+		{3, http.StatusOK, true},
+		{2, http.StatusServiceUnavailable, false},
+		{1, http.StatusInternalServerError, false},
+	} {
+		got := deliveries[i]
+		if got.Attempt != want.attempt || got.StatusCode != want.status || got.Success != want.success {
+			t.Errorf("Попытка %d: ожидалось %+v, получено %+v", want.attempt, want, got)
+		}
+		if got.EventID != deliveries[0].EventID {
+			t.Error("Повторные попытки должны сохранять ID события")
+		}
+	}
+}
+
+func TestDispatcherStopsOnClientError(t *testing.T) {
+	d, repo := setupDispatcher(t)
+	rc := &receiver{responses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	hook := createHook(t, repo, server.URL)
+	inactive := createHook(t, repo, server.URL)
+	inactive.Active = false
+	if err := repo.Update(inactive); err != nil {
+		t.Fatalf("Ошибка обновления вебхука: %v", err)
+	}
+
+	d.Emit(models.EventPageCreated, models.Page{ID: 1})
+	deliveries := waitDeliveries(t, repo, hook.ID, 1)
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusBadRequest {
+		t.Errorf("Неожиданная запись журнала: %+v", deliveries[0])
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if rc.count() != 1 {
+		t.Errorf("Ответ 4xx не должен повторяться, получено запросов: %d", rc.count())
+	}
+	waitDeliveries(t, repo, inactive.ID, 0)
+}
+
+func TestDispatcherNilSafe(t *testing.T) {
+	var d *Dispatcher
+	d.Emit(models.EventMangaCreated, nil)
+	d.Close()
+}
+
+func TestDispatcherSlowReceiverDoesNotBlockOthers(t *testing.T) {
+	d, repo := setupDispatcher(t)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(&receiver{})
+	defer fast.Close()
+
+	createHook(t, repo, slow.URL, models.EventChapterCreated)
+	fastHook := createHook(t, repo, fast.URL, models.EventChapterCreated)
+
+	d.Emit(models.EventChapterCreated, models.Chapter{ID: 1})
+	if deliveries := waitDeliveries(t, repo, fastHook.ID, 1); !deliveries[0].Success {
+		t.Errorf("Неожиданная запись журнала: %+v", deliveries[0])
+	}
+}
+
+func TestDispatcherCloseDrainsQueue(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Ошибка открытия in-memory базы: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	defer conn.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := sqlite.NewWebhookRepository(conn, logger)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	createHook(t, repo, server.URL, models.EventChapterCreated)
+
+	// События ставятся в очередь до запуска обработчиков и доставляются
+	// при остановке диспетчера.
+	d := NewDispatcher(repo, logger)
+	for i := 0; i < 5; i++ {
+		d.Emit(models.EventChapterCreated, models.Chapter{ID: int64(i)})
+	}
+	d.Start()
+	d.Close()
+	if rc.count() != 5 {
+		t.Errorf("Ожидалось 5 доставок до завершения Close, получено %d", rc.count())
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chapters_published_at;
+ALTER TABLE chapters DROP COLUMN IF EXISTS published_at;
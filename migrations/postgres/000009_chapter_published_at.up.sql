@@ -0,0 +1,5 @@
+-- У существующих глав время публикации неизвестно и остается NULL,
+-- чтобы они не появились в лентах как новые.
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_chapters_published_at ON chapters(published_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_chapters_created_at;
+DROP INDEX IF EXISTS idx_manga_updated_at;
+
+ALTER TABLE pages DROP COLUMN IF EXISTS updated_at, DROP COLUMN IF EXISTS created_at;
+ALTER TABLE chapters DROP COLUMN IF EXISTS updated_at, DROP COLUMN IF EXISTS created_at;
+ALTER TABLE manga DROP COLUMN IF EXISTS updated_at, DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chapters_scheduled;
+ALTER TABLE chapters DROP COLUMN IF EXISTS publish_at, DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+-- Существующие главы уже доступны читателям.
+ALTER TABLE chapters
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'scheduled', 'published')),
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_chapters_scheduled ON chapters(publish_at) WHERE status = 'scheduled';
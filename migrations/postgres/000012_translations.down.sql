@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS preferred_language;
+DROP INDEX IF EXISTS idx_chapters_group_id;
+ALTER TABLE chapters DROP COLUMN IF EXISTS group_id, DROP COLUMN IF EXISTS language;
+DROP TABLE IF EXISTS scanlation_group_members;
+DROP TABLE IF EXISTS scanlation_groups;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS scanlation_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    website TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS scanlation_group_members (
+    group_id INTEGER NOT NULL REFERENCES scanlation_groups(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scanlation_group_members_user_id ON scanlation_group_members(user_id);
+
+-- Все существующие главы переведены на русский.
+ALTER TABLE chapters
+    ADD COLUMN IF NOT EXISTS language VARCHAR(16) NOT NULL DEFAULT 'ru',
+    ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES scanlation_groups(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_chapters_group_id ON chapters(group_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_language VARCHAR(16);
@@ -0,0 +1,11 @@
+-- Откат окончательно удаляет содержимое корзины.
+DELETE FROM pages WHERE deleted_at IS NOT NULL;
+DELETE FROM chapters WHERE deleted_at IS NOT NULL;
+DELETE FROM manga WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_pages_deleted_at;
+DROP INDEX IF EXISTS idx_chapters_deleted_at;
+DROP INDEX IF EXISTS idx_manga_deleted_at;
+ALTER TABLE pages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE chapters DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE manga DROP COLUMN IF EXISTS deleted_at;
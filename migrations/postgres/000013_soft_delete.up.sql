@@ -0,0 +1,8 @@
+-- Удаленные манга, главы и страницы хранятся в корзине до очистки.
+ALTER TABLE manga ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_manga_deleted_at ON manga(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_chapters_deleted_at ON chapters(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pages_deleted_at ON pages(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS view_history (
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    views BIGINT NOT NULL DEFAULT 0,
+    unique_viewers BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (entity_type, entity_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_view_history_day ON view_history(entity_type, day);
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Scope ограничивает набор операций, доступных по API-ключу.
+type Scope string
+
+const (
+	ScopeReadAnalytics   Scope = "read:analytics"
+	ScopeManageAnalytics Scope = "manage:analytics"
+	ScopeWriteManga      Scope = "write:manga"
+	ScopeWriteChapters   Scope = "write:chapters"
+	ScopeUploadPages     Scope = "upload:pages"
+	ScopeDeleteContent   Scope = "delete:content"
+)
+
+// scopeRoles задает минимальную роль владельца ключа для каждой области.
+var scopeRoles = map[Scope]Role{
+	ScopeReadAnalytics:   RoleReader,
+	ScopeManageAnalytics: RoleAdmin,
+	ScopeWriteManga:      RoleUploader,
+	ScopeWriteChapters:   RoleUploader,
+	ScopeUploadPages:     RoleUploader,
+	ScopeDeleteContent:   RoleModerator,
+}
+
+// Valid сообщает, является ли область известной.
+func (s Scope) Valid() bool {
+	_, ok := scopeRoles[s]
+	return ok
+}
+
+// AllowedFor сообщает, может ли пользователь с ролью role выдать ключ с этой областью.
+func (s Scope) AllowedFor(role Role) bool {
+	required, ok := scopeRoles[s]
+	return ok && role.Includes(required)
+}
+
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// OwnerRole — текущая роль владельца, заполняется при поиске по хешу.
+	OwnerRole Role `json:"-"`
+}
+
+// Expired сообщает, истек ли срок действия ключа к моменту now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction — вид изменения, записанного в журнал аудита.
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	// AuditRestore — восстановление записи из корзины.
+	AuditRestore AuditAction = "restore"
+)
+
+// Valid сообщает, является ли действие известным.
+func (a AuditAction) Valid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore:
+		return true
+	}
+	return false
+}
+
+// AuditEntity — тип сущности, изменение которой записано в журнал.
+type AuditEntity string
+
+const (
+	AuditManga   AuditEntity = "manga"
+	AuditChapter AuditEntity = "chapter"
+	AuditPage    AuditEntity = "page"
+	AuditUser    AuditEntity = "user"
+)
+
+// Valid сообщает, является ли тип сущности известным.
+func (e AuditEntity) Valid() bool {
+	switch e {
+	case AuditManga, AuditChapter, AuditPage, AuditUser:
+		return true
+	}
+	return false
+}
+
+// AuditChange — значения поля до и после изменения в JSON-представлении
+// сущности. Отсутствующее значение означает, что поля не было.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditEntry — запись журнала аудита об изменении сущности.
+type AuditEntry struct {
+	ID int64 `json:"id"`
+	// ActorID — пользователь, выполнивший изменение; 0 для анонимных
+	// запросов, например регистрации.
+	ActorID    int64       `json:"actor_id,omitempty"`
+	Action     AuditAction `json:"action"`
+	EntityType AuditEntity `json:"entity_type"`
+	EntityID   int64       `json:"entity_id"`
+	// Changes содержит только изменившиеся поля.
+	Changes   map[string]AuditChange `json:"changes"`
+	RequestID string                 `json:"request_id,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// AuditFilter задает условия выборки журнала аудита; нулевые поля не
+// ограничивают выборку.
+type AuditFilter struct {
+	ActorID    int64
+	Action     AuditAction
+	EntityType AuditEntity
+	EntityID   int64
+	Since      time.Time
+	Until      time.Time
+}
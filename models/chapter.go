@@ -1,8 +1,67 @@
 package models
 
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultLanguage — язык глав, для которых он не указан явно.
+const DefaultLanguage = "ru"
+
+// languagePattern допускает коды ISO 639-1/639-2 с необязательным
+// уточнением региона или письменности: ru, en, pt-br, zh-hant.
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// ValidLanguage сообщает, является ли строка допустимым кодом языка
+// в нижнем регистре.
+func ValidLanguage(code string) bool {
+	return languagePattern.MatchString(code)
+}
+
+// ChapterStatus — состояние видимости главы.
+type ChapterStatus string
+
+const (
+	// ChapterDraft — черновик, виден только загрузчикам и модераторам.
+	ChapterDraft ChapterStatus = "draft"
+	// ChapterScheduled — глава будет опубликована планировщиком в PublishAt.
+	ChapterScheduled ChapterStatus = "scheduled"
+	// ChapterPublished — глава доступна всем читателям.
+	ChapterPublished ChapterStatus = "published"
+)
+
+// Valid сообщает, является ли состояние известным.
+func (s ChapterStatus) Valid() bool {
+	switch s {
+	case ChapterDraft, ChapterScheduled, ChapterPublished:
+		return true
+	}
+	return false
+}
+
 type Chapter struct {
 	ID      int64  `json:"id"`
 	MangaID int64  `json:"manga_id"`
 	Number  int    `json:"number"`
 	Title   string `json:"title"`
+	// Language — код языка перевода; у одной главы манги может быть
+	// несколько переводов с одинаковым номером.
+	Language string `json:"language"`
+	// GroupID — команда переводчиков, выпустившая главу.
+	GroupID *int64        `json:"group_id,omitempty"`
+	Status  ChapterStatus `json:"status"`
+	// PublishAt — запланированное время публикации для состояния scheduled.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// PublishedAt — время публикации; у глав, созданных до появления
+	// этого поля, оно не известно.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// DeletedAt — время переноса главы в корзину.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Published сообщает, доступна ли глава читателям.
+func (ch *Chapter) Published() bool {
+	return ch.Status == ChapterPublished
 }
@@ -1,7 +1,14 @@
 package models
 
+import "time"
+
 type Manga struct {
-	ID          int64  `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	// UpdatedAt меняется при изменении манги и при добавлении главы.
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt — время переноса манги в корзину.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
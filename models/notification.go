@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// NotificationEvent — тип события, о котором пользователь получает уведомления.
+type NotificationEvent string
+
+const (
+	// EventNewChapter — вышла новая глава манги, на которую подписан пользователь.
+	EventNewChapter NotificationEvent = "new_chapter"
+)
+
+// NotificationEvents перечисляет известные события в порядке вывода настроек.
+var NotificationEvents = []NotificationEvent{EventNewChapter}
+
+// Valid сообщает, является ли событие известным.
+func (e NotificationEvent) Valid() bool {
+	for _, event := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification — запись во входящих уведомлениях пользователя.
+type Notification struct {
+	ID         int64             `json:"id"`
+	UserID     int64             `json:"-"`
+	Event      NotificationEvent `json:"event"`
+	MangaID    int64             `json:"manga_id"`
+	MangaTitle string            `json:"manga_title"`
+	ChapterID  int64             `json:"chapter_id,omitempty"`
+	Message    string            `json:"message"`
+	Read       bool              `json:"read"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Follow — подписка пользователя на мангу.
+type Follow struct {
+	MangaID    int64     `json:"manga_id"`
+	MangaTitle string    `json:"manga_title"`
+	CreatedAt  time.Time `json:"created_at"`
+}
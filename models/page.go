@@ -1,8 +1,14 @@
 package models
 
+import "time"
+
 type Page struct {
-	ID        int64  `json:"id"`
-	ChapterID int64  `json:"chapter_id"`
-	Number    int    `json:"number"`
-	ImagePath string `json:"image_path"`
+	ID        int64     `json:"id"`
+	ChapterID int64     `json:"chapter_id"`
+	Number    int       `json:"number"`
+	ImagePath string    `json:"image_path"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt — время переноса страницы в корзину.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ScanlationGroup — команда переводчиков, выпускающая главы.
+type ScanlationGroup struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Website string `json:"website,omitempty"`
+	// Members заполняется только при получении команды по ID.
+	Members   []GroupMember `json:"members,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// GroupMember — участник команды переводчиков.
+type GroupMember struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
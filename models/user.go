@@ -1,7 +1,52 @@
 package models
 
+// Role определяет уровень прав пользователя.
+type Role string
+
+const (
+	RoleReader    Role = "reader"
+	RoleUploader  Role = "uploader"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// roleLevels задает иерархию ролей: каждая роль включает права нижестоящих.
+var roleLevels = map[Role]int{
+	RoleReader:    1,
+	RoleUploader:  2,
+	RoleModerator: 3,
+	RoleAdmin:     4,
+}
+
+// Valid сообщает, является ли роль известной.
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes сообщает, покрывает ли роль r права роли other.
+func (r Role) Includes(other Role) bool {
+	level, ok := roleLevels[r]
+	if !ok {
+		return false
+	}
+	return level >= roleLevels[other]
+}
+
 type User struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
 	Password string `json:"-"`
+	Role     Role   `json:"role"`
+	// PreferredLanguage — язык, переводы на котором показываются в списке
+	// глав по умолчанию; пустая строка означает все языки.
+	PreferredLanguage string `json:"preferred_language,omitempty"`
+
+	// TOTPSecret хранится и до подтверждения подключения 2FA, но проверяется
+	// при входе только при TOTPEnabled.
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	// RecoveryCodes — хеши неиспользованных кодов восстановления.
+	RecoveryCodes []string `json:"-"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ViewEntity — тип сущности, просмотры которой сохраняются в истории.
+type ViewEntity string
+
+const (
+	ViewManga   ViewEntity = "manga"
+	ViewChapter ViewEntity = "chapter"
+)
+
+// DailyViews — просмотры сущности за сутки UTC, перенесенные из Redis в базу.
+type DailyViews struct {
+	EntityType ViewEntity `json:"entity_type"`
+	EntityID   int64      `json:"entity_id"`
+	// Day — начало суток в UTC.
+	Day   time.Time `json:"day"`
+	Views int64     `json:"views"`
+	// UniqueViewers — уникальные посетители за эти сутки.
+	UniqueViewers int64 `json:"unique_viewers"`
+}
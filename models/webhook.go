@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// WebhookEvent — событие каталога, о котором уведомляются подписчики вебхуков.
+type WebhookEvent string
+
+const (
+	EventMangaCreated   WebhookEvent = "manga.created"
+	EventChapterCreated WebhookEvent = "chapter.created"
+	EventChapterUpdated WebhookEvent = "chapter.updated"
+	// EventChapterPublished отправляется, когда глава становится доступна
+	// читателям: при создании опубликованной главы, при смене состояния
+	// и при публикации по расписанию.
+	EventChapterPublished WebhookEvent = "chapter.published"
+	EventChapterDeleted   WebhookEvent = "chapter.deleted"
+	EventPageCreated      WebhookEvent = "page.created"
+	EventPageDeleted      WebhookEvent = "page.deleted"
+)
+
+// WebhookEvents перечисляет все события, доступные для подписки.
+var WebhookEvents = []WebhookEvent{
+	EventMangaCreated,
+	EventChapterCreated,
+	EventChapterUpdated,
+	EventChapterPublished,
+	EventChapterDeleted,
+	EventPageCreated,
+	EventPageDeleted,
+}
+
+// Valid сообщает, является ли событие известным.
+func (e WebhookEvent) Valid() bool {
+	for _, event := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook — подписка внешнего сервиса на события каталога.
+type Webhook struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Secret используется для подписи HMAC-SHA256 и не возвращается в ответах.
+	Secret string `json:"-"`
+	// Events — фильтр событий; пустой список означает подписку на все события.
+	Events    []WebhookEvent `json:"events"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Matches сообщает, нужно ли доставлять событие этому вебхуку.
+func (w *Webhook) Matches(event WebhookEvent) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery — запись журнала об одной попытке доставки.
+type WebhookDelivery struct {
+	ID         int64        `json:"id"`
+	WebhookID  int64        `json:"webhook_id"`
+	EventID    string       `json:"event_id"`
+	Event      WebhookEvent `json:"event"`
+	Attempt    int          `json:"attempt"`
+	StatusCode int          `json:"status_code,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Success    bool         `json:"success"`
+	DurationMS int64        `json:"duration_ms"`
+	CreatedAt  time.Time    `json:"created_at"`
+}